@@ -0,0 +1,54 @@
+package basic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestActiveDeadline(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	instance, err := knuu.NewInstance("active-deadline")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = instance.SetImage("docker.io/alpine:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	err = instance.SetCommand("sleep", "infinity")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+	err = instance.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+	err = instance.SetActiveDeadline(10 * time.Second)
+	if err != nil {
+		t.Fatalf("Error setting active deadline: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(instance))
+	})
+
+	// Test logic
+
+	err = instance.Start()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+
+	// the command never exits, so the pod is terminated by Kubernetes
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	err = instance.WaitInstanceCompleted(ctx)
+	require.ErrorIs(t, err, knuu.ErrInstanceDeadlineExceeded)
+}
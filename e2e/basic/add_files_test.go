@@ -0,0 +1,65 @@
+package basic
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestAddFiles(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	instance, err := knuu.NewInstance("add-files")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = instance.SetImage("docker.io/alpine:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	err = instance.SetCommand("sleep", "infinity")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+
+	files := make([]knuu.FileSpec, 0, 3)
+	for n := 1; n <= 3; n++ {
+		files = append(files, knuu.FileSpec{
+			Src:   fmt.Sprintf("resources/file_cm_to_folder/test_%d", n),
+			Dest:  fmt.Sprintf("/etc/app/test_%d", n),
+			Chown: "0:0",
+		})
+	}
+	err = instance.AddFiles(files)
+	if err != nil {
+		t.Fatalf("Error adding files: %v", err)
+	}
+	err = instance.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(instance))
+	})
+
+	// Test logic
+
+	err = instance.Start()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+
+	for _, file := range files {
+		expected, err := os.ReadFile(file.Src)
+		require.NoError(t, err)
+		content, err := instance.ExecuteCommand("cat", file.Dest)
+		require.NoError(t, err)
+		require.Equal(t, string(expected), content)
+	}
+}
@@ -0,0 +1,50 @@
+package basic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestAutomountServiceAccountToken(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	instance, err := knuu.NewInstance("no-token")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = instance.SetImage("docker.io/alpine:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	err = instance.SetCommand("sleep", "infinity")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+	err = instance.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+	err = instance.SetAutomountServiceAccountToken(false)
+	if err != nil {
+		t.Fatalf("Error disabling automount service account token: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(instance))
+	})
+
+	// Test logic
+
+	err = instance.Start()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+
+	output, err := instance.ExecuteCommand("test -e /var/run/secrets/kubernetes.io/serviceaccount && echo mounted || echo absent")
+	require.NoError(t, err)
+	require.Equal(t, "absent\n", output)
+}
@@ -0,0 +1,63 @@
+package basic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestClientset(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	instance, err := knuu.NewInstance("clientset")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = instance.SetImage("docker.io/alpine:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	err = instance.SetCommand("sleep", "infinity")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+	err = instance.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(instance))
+	})
+
+	// Test logic
+
+	err = instance.Start()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+
+	podName, err := instance.GetPodName()
+	require.NoError(t, err)
+
+	clientset := knuu.Clientset()
+	require.NotNil(t, clientset)
+	require.NotNil(t, knuu.RESTConfig())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	pods, err := clientset.CoreV1().Pods(instance.GetNamespace()).List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+
+	found := false
+	for _, pod := range pods.Items {
+		found = found || pod.Name == podName
+	}
+	require.True(t, found, "the pod of the instance should be listed in the knuu namespace")
+}
@@ -0,0 +1,56 @@
+package basic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestDebugContainer(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	web, err := knuu.NewInstance("web")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = web.SetImage(nginxImage)
+	if err != nil {
+		t.Fatalf("Error setting image '%v':", err)
+	}
+	err = web.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(web))
+	})
+
+	// Test logic
+
+	err = web.Start()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	exec, err := web.AddDebugContainer(ctx, "docker.io/busybox:latest")
+	if err != nil {
+		t.Fatalf("Error adding debug container: %v", err)
+	}
+
+	ps, err := exec(ctx, "ps")
+	if err != nil {
+		t.Fatalf("Error executing command in debug container: %v", err)
+	}
+
+	assert.Contains(t, ps, "nginx: master process")
+}
@@ -0,0 +1,61 @@
+package basic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestEnvFromConfigMap(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	instance, err := knuu.NewInstance("env-configmap")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = instance.SetImage("docker.io/alpine:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	err = instance.SetCommand("sleep", "infinity")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+	err = instance.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+	err = instance.CreateEnvConfigMap("app-config", map[string]string{"LOG_LEVEL": "debug", "MODE": "test"})
+	if err != nil {
+		t.Fatalf("Error creating env configmap: %v", err)
+	}
+	err = instance.SetEnvFromConfigMap("APP_MODE", "app-config", "MODE")
+	if err != nil {
+		t.Fatalf("Error setting env from configmap: %v", err)
+	}
+	err = instance.AddEnvFromConfigMap("app-config")
+	if err != nil {
+		t.Fatalf("Error adding env from configmap: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(instance))
+	})
+
+	// Test logic
+
+	err = instance.Start()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+
+	for name, expected := range map[string]string{"APP_MODE": "test", "LOG_LEVEL": "debug", "MODE": "test"} {
+		value, err := instance.ExecuteCommand("printenv", name)
+		require.NoError(t, err)
+		require.Equal(t, expected, strings.TrimSpace(value), name)
+	}
+}
@@ -0,0 +1,53 @@
+package basic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestEnvFromField(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	instance, err := knuu.NewInstance("env-from-field")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = instance.SetImage("docker.io/alpine:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	err = instance.SetCommand("sleep", "infinity")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+	err = instance.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+	err = instance.SetEnvFromField("POD_NAME", "metadata.name")
+	if err != nil {
+		t.Fatalf("Error setting env from field: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(instance))
+	})
+
+	// Test logic
+
+	err = instance.Start()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+
+	// the hostname of a pod is its name
+	podName, err := instance.ExecuteCommand("hostname")
+	require.NoError(t, err)
+	envPodName, err := instance.ExecuteCommand("printenv", "POD_NAME")
+	require.NoError(t, err)
+	require.Equal(t, podName, envPodName)
+}
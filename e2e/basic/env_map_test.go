@@ -0,0 +1,61 @@
+package basic
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestEnvMap(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	instance, err := knuu.NewInstance("env-map")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = instance.SetImage("docker.io/alpine:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	err = instance.SetCommand("sleep", "infinity")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+	err = instance.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+
+	vars := map[string]string{
+		"FIRST":  "1",
+		"SECOND": "2",
+		"THIRD":  "3",
+		"FOURTH": "4",
+		"FIFTH":  "5",
+	}
+	err = instance.SetEnvMap(vars)
+	if err != nil {
+		t.Fatalf("Error setting env map: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(instance))
+	})
+
+	// Test logic
+
+	err = instance.Start()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+
+	for key, value := range vars {
+		output, err := instance.ExecuteCommand("printenv", key)
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("%s\n", value), output, key)
+	}
+}
@@ -0,0 +1,65 @@
+package basic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestEnvQuoting(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	instance, err := knuu.NewInstance("env-quoting")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = instance.SetImage("docker.io/alpine:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	err = instance.SetCommand("sleep", "infinity")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+
+	// the variables are set in the image, so they are parsed by the builder
+	err = instance.SetEnvironmentVariable("GREETING", `say "hello  world"`)
+	if err != nil {
+		t.Fatalf("Error setting environment variable: %v", err)
+	}
+	vars := map[string]string{
+		"EQUALS":    "a=b=c",
+		"SINGLE":    "it's quoted",
+		"BACKSLASH": `C:\dir\`,
+	}
+	err = instance.SetEnvMap(vars)
+	if err != nil {
+		t.Fatalf("Error setting env map: %v", err)
+	}
+	vars["GREETING"] = `say "hello  world"`
+
+	err = instance.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(instance))
+	})
+
+	// Test logic
+
+	err = instance.Start()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+
+	for key, value := range vars {
+		output, err := instance.ExecuteCommand("printenv", key)
+		require.NoError(t, err)
+		require.Equal(t, value+"\n", output, key)
+	}
+}
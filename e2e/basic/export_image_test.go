@@ -0,0 +1,102 @@
+package basic
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/builder"
+	"github.com/celestiaorg/knuu/pkg/builder/kaniko"
+	"github.com/celestiaorg/knuu/pkg/k8s"
+	"github.com/celestiaorg/knuu/pkg/knuu"
+	"github.com/celestiaorg/knuu/pkg/minio"
+)
+
+func TestExportImage(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	namespace := k8s.SanitizeName(knuu.Scope())
+	kb := &kaniko.Kaniko{
+		K8sClientset: knuu.Clientset(),
+		K8sNamespace: namespace,
+		Minio:        &minio.Minio{Clientset: knuu.Clientset(), Namespace: namespace},
+	}
+
+	contextDir := t.TempDir()
+	dockerfile := "FROM docker.io/alpine:3.20\nRUN echo exported > /exported\n"
+	require.NoError(t, os.WriteFile(filepath.Join(contextDir, "Dockerfile"), []byte(dockerfile), 0o644))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	// Test logic
+
+	destination := "ttl.sh/" + uuid.New().String() + ":1h"
+	result, err := kb.BuildWithResult(ctx, &builder.BuilderOptions{
+		ImageName:    "export-image",
+		BuildContext: builder.DirContext{Path: contextDir}.BuildContext(),
+		Destination:  destination,
+		Export:       &builder.ExportOptions{NoPush: true},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, result.ExportName)
+
+	var image bytes.Buffer
+	require.NoError(t, kb.DownloadExportedImage(ctx, result.ExportName, &image))
+
+	// the tarball is in the format of `docker save`: a manifest listing the config and the layers of the image
+	files := map[string]bool{}
+	var manifest []struct {
+		Config   string
+		RepoTags []string
+		Layers   []string
+	}
+	reader := tar.NewReader(bytes.NewReader(image.Bytes()))
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		files[header.Name] = true
+		if header.Name == "manifest.json" {
+			require.NoError(t, json.NewDecoder(reader).Decode(&manifest))
+		}
+	}
+	require.Len(t, manifest, 1)
+	assert.Equal(t, []string{destination}, manifest[0].RepoTags)
+	assert.True(t, files[manifest[0].Config], "the config of the image should be in the tarball")
+	require.Len(t, manifest[0].Layers, 2, "the image should have the layer of alpine and the layer of the RUN instruction")
+	for _, layer := range manifest[0].Layers {
+		assert.True(t, files[layer], "layer %s should be in the tarball", layer)
+	}
+
+	// load the tarball like in an air-gapped environment, if docker is available
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Log("docker is not available, skipping docker load")
+		return
+	}
+	load := exec.CommandContext(ctx, "docker", "load")
+	load.Stdin = &image
+	output, err := load.CombinedOutput()
+	require.NoError(t, err, string(output))
+	assert.Contains(t, string(output), destination)
+
+	run := exec.CommandContext(ctx, "docker", "run", "--rm", destination, "cat", "/exported")
+	output, err = run.CombinedOutput()
+	require.NoError(t, err, string(output))
+	assert.Equal(t, "exported\n", string(output))
+}
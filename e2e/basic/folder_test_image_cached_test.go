@@ -1,9 +1,11 @@
 package basic
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -70,7 +72,10 @@ func TestFolderCached(t *testing.T) {
 			t.Fatalf("Error waiting for instance to be running: %v", err)
 		}
 
-		wget, err := executor.ExecuteCommand("wget", "-q", "-O", "-", webIP)
+		// the web server may still be starting after the pod is running
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		wget, err := executor.ExecuteCommandWithRetry(ctx, 5, 2*time.Second, "wget", "-q", "-O", "-", webIP)
+		cancel()
 		if err != nil {
 			t.Fatalf("Error executing command: %v", err)
 		}
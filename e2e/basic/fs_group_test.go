@@ -0,0 +1,76 @@
+package basic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestFSGroup(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	newInstance := func(name string) *knuu.Instance {
+		instance, err := knuu.NewInstance(name)
+		if err != nil {
+			t.Fatalf("Error creating instance '%v':", err)
+		}
+		err = instance.SetImage("docker.io/alpine:latest")
+		if err != nil {
+			t.Fatalf("Error setting image: %v", err)
+		}
+		err = instance.SetUser("1000:1000")
+		if err != nil {
+			t.Fatalf("Error setting user: %v", err)
+		}
+		err = instance.SetCommand("sleep", "infinity")
+		if err != nil {
+			t.Fatalf("Error setting command: %v", err)
+		}
+		err = instance.AddVolume("/data", "1Gi")
+		if err != nil {
+			t.Fatalf("Error adding volume: %v", err)
+		}
+		return instance
+	}
+
+	withoutGroup := newInstance("fs-group-unset")
+	withGroup := newInstance("fs-group")
+	err := withGroup.SetFSGroup(1000)
+	if err != nil {
+		t.Fatalf("Error setting fsGroup: %v", err)
+	}
+	for _, instance := range []*knuu.Instance{withoutGroup, withGroup} {
+		err = instance.Commit()
+		if err != nil {
+			t.Fatalf("Error committing instance: %v", err)
+		}
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(withoutGroup, withGroup))
+	})
+
+	// Test logic
+
+	for _, instance := range []*knuu.Instance{withoutGroup, withGroup} {
+		err = instance.Start()
+		if err != nil {
+			t.Fatalf("Error starting instance: %v", err)
+		}
+		err = instance.WaitInstanceIsRunning()
+		if err != nil {
+			t.Fatalf("Error waiting for instance to be running: %v", err)
+		}
+	}
+
+	// the volume is owned by root, so the non-root user can only write to it with the fsGroup
+	_, err = withoutGroup.ExecuteCommand("touch", "/data/file")
+	assert.Error(t, err)
+
+	_, err = withGroup.ExecuteCommand("touch", "/data/file")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,71 @@
+package basic
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestImageArchitecture(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	nodes, err := knuu.Clientset().CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: "kubernetes.io/arch=arm64"})
+	require.NoError(t, err)
+	if len(nodes.Items) == 0 {
+		t.Skip("the cluster has no arm64 node")
+	}
+
+	instance, err := knuu.NewInstance("image-arch")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = instance.SetImage("docker.io/alpine:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	err = instance.SetCommand("sleep", "infinity")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+	err = instance.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+	err = instance.SetImageArchitecture("arm64")
+	if err != nil {
+		t.Fatalf("Error setting image architecture: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(instance))
+	})
+
+	// Test logic
+
+	err = instance.Start()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+
+	machine, err := instance.ExecuteCommand("uname", "-m")
+	require.NoError(t, err)
+	require.Equal(t, "aarch64", strings.TrimSpace(machine), "the arm64 image should run")
+
+	podName, err := instance.GetPodName()
+	require.NoError(t, err)
+	pod, err := knuu.Clientset().CoreV1().Pods(instance.GetNamespace()).Get(ctx, podName, metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Contains(t, pod.Spec.Containers[0].Image, "@sha256:", "the image should be pinned to the digest of the arm64 manifest")
+	node, err := knuu.Clientset().CoreV1().Nodes().Get(ctx, pod.Spec.NodeName, metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "arm64", node.Labels["kubernetes.io/arch"])
+}
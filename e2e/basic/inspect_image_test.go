@@ -0,0 +1,51 @@
+package basic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestInspectBuiltImage(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	instance, err := knuu.NewInstance("inspect-image")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = instance.SetImage("docker.io/alpine:3.20")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	err = instance.SetEnvironmentVariable("FOO", "bar")
+	if err != nil {
+		t.Fatalf("Error setting environment variable: %v", err)
+	}
+	err = instance.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(instance))
+	})
+
+	// Test logic
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	info, err := instance.InspectBuiltImage(ctx)
+	require.NoError(t, err)
+
+	// alpine is a single layer of about 3.5MB, the ENV instruction adds no layer
+	assert.Len(t, info.LayerSizes, 1)
+	assert.Greater(t, info.Size, int64(1<<20))
+	assert.Less(t, info.Size, int64(10<<20))
+	assert.Contains(t, info.Config.Env, "FOO=bar")
+}
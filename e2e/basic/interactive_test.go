@@ -0,0 +1,59 @@
+package basic
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestExecuteInteractiveCommand(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	instance, err := knuu.NewInstance("interactive")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = instance.SetImage("docker.io/alpine:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	err = instance.SetCommand("sleep", "infinity")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+	err = instance.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(instance))
+	})
+
+	// Test logic
+
+	err = instance.Start()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// the shell only reports a terminal if the TTY was allocated
+	var stdout bytes.Buffer
+	err = instance.ExecuteInteractiveCommand(ctx, knuu.InteractiveOptions{
+		Stdin:  strings.NewReader("[ -t 0 ] && echo \"tty $((20+22))\"\nexit\n"),
+		Stdout: &stdout,
+		TTY:    true,
+	}, "sh")
+	require.NoError(t, err)
+	require.Contains(t, stdout.String(), "tty 42")
+}
@@ -0,0 +1,59 @@
+package basic
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestLogBuffer(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	const bufferSize = 5
+
+	instance, err := knuu.NewInstance("log-buffer")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = instance.SetImage("docker.io/alpine:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	err = instance.SetCommand("sh", "-c", "for i in $(seq 1 20); do echo line $i; done; sleep infinity")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+	err = instance.SetLogBufferSize(bufferSize)
+	if err != nil {
+		t.Fatalf("Error setting log buffer size: %v", err)
+	}
+	err = instance.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(instance))
+	})
+
+	// Test logic
+
+	err = instance.Start()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+
+	expected := make([]string, 0, bufferSize)
+	for n := 20 - bufferSize + 1; n <= 20; n++ {
+		expected = append(expected, fmt.Sprintf("line %d", n))
+	}
+	require.Eventually(t, func() bool {
+		return instance.DumpRecentLogs() == strings.Join(expected, "\n")
+	}, 30*time.Second, time.Second, "only the last %d lines should be retained", bufferSize)
+}
@@ -0,0 +1,69 @@
+package basic
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/celestiaorg/knuu/pkg/k8s"
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	instance, err := knuu.NewInstance("metrics")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = instance.SetImage("docker.io/alpine:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	err = instance.SetCommand("sleep", "infinity")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+	err = instance.SetMemory("64Mi", "64Mi")
+	if err != nil {
+		t.Fatalf("Error setting memory: %v", err)
+	}
+	err = instance.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(instance))
+	})
+
+	// Test logic
+
+	err = instance.Start()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+
+	usage, err := instance.GetMetrics(ctx)
+	if errors.Is(err, k8s.ErrMetricsServerNotInstalled) {
+		t.Skip("metrics-server is not installed in the cluster")
+	}
+
+	// metrics-server needs some time to scrape a new pod
+	require.Eventually(t, func() bool {
+		usage, err = instance.GetMetrics(ctx)
+		return err == nil
+	}, 2*time.Minute, 5*time.Second, "metrics should be available for the instance")
+
+	assert.Equal(t, 1, usage.Memory.Cmp(resource.MustParse("0")), "memory usage should be reported")
+	assert.Equal(t, -1, usage.Memory.Cmp(resource.MustParse("64Mi")), "memory usage should be under the limit")
+}
@@ -0,0 +1,100 @@
+package basic
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestNamedPorts(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	server, err := knuu.NewInstance("named-ports")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = server.SetImage("docker.io/busybox:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	// each port serves its own name
+	err = server.SetCommand("sh", "-c", "mkdir -p /www/http /www/metrics && "+
+		"echo http > /www/http/index.html && echo metrics > /www/metrics/index.html && "+
+		"httpd -p 8080 -h /www/http && httpd -p 9090 -h /www/metrics && sleep infinity")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+	err = server.AddPort("http", 8080, "tcp")
+	if err != nil {
+		t.Fatalf("Error adding port: %v", err)
+	}
+	err = server.AddPort("metrics", 9090, "tcp")
+	if err != nil {
+		t.Fatalf("Error adding port: %v", err)
+	}
+	err = server.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+
+	client, err := knuu.NewInstance("named-ports-client")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = client.SetImage("docker.io/busybox:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	err = client.SetCommand("sleep", "infinity")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+	err = client.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(server, client))
+	})
+
+	// Test logic
+
+	for _, instance := range []*knuu.Instance{server, client} {
+		err = instance.Start()
+		if err != nil {
+			t.Fatalf("Error starting instance: %v", err)
+		}
+		err = instance.WaitInstanceIsRunning()
+		if err != nil {
+			t.Fatalf("Error waiting for instance to be running: %v", err)
+		}
+	}
+
+	ip, err := server.GetIP()
+	if err != nil {
+		t.Fatalf("Error getting IP: %v", err)
+	}
+
+	for _, name := range []string{"http", "metrics"} {
+		port, err := server.GetPort(name)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		err = server.WaitForPort(ctx, port)
+		cancel()
+		require.NoError(t, err)
+
+		out, err := client.ExecuteCommand("wget", "-qO-", fmt.Sprintf("http://%s:%d", ip, port))
+		require.NoError(t, err, name)
+		assert.Equal(t, name, strings.TrimSpace(out))
+	}
+}
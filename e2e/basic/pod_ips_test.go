@@ -0,0 +1,63 @@
+package basic
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestPodIPs(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	instance, err := knuu.NewInstance("pod-ips")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = instance.SetImage("docker.io/alpine:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	err = instance.SetCommand("sleep", "infinity")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+	err = instance.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(instance))
+	})
+
+	// Test logic
+
+	err = instance.Start()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+
+	ips, err := instance.GetPodIPs()
+	require.NoError(t, err)
+	require.NotEmpty(t, ips)
+	if len(ips) < 2 {
+		t.Skip("the cluster is not dual-stack")
+	}
+
+	families := map[bool]bool{}
+	for _, ip := range ips {
+		parsed := net.ParseIP(ip)
+		require.NotNil(t, parsed, "invalid pod IP %s", ip)
+		families[parsed.To4() != nil] = true
+	}
+	require.True(t, families[true], "the pod should have an IPv4 address")
+	require.True(t, families[false], "the pod should have an IPv6 address")
+
+	ipv6, err := instance.GetIPv6()
+	require.NoError(t, err)
+	require.Contains(t, ips, ipv6)
+}
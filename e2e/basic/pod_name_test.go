@@ -0,0 +1,58 @@
+package basic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestGetPodName(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	instance, err := knuu.NewInstance("pod-name")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = instance.SetImage("docker.io/alpine:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	err = instance.SetCommand("sleep", "infinity")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+	err = instance.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(instance))
+	})
+
+	// Test logic
+
+	_, err = instance.GetPodName()
+	require.ErrorIs(t, err, knuu.ErrGettingPodNameNotAllowed)
+
+	err = instance.Start()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+
+	podName, err := instance.GetPodName()
+	require.NoError(t, err)
+
+	// the hostname of a pod is its name
+	hostname, err := instance.ExecuteCommand("hostname")
+	require.NoError(t, err)
+	require.Equal(t, podName, strings.TrimSpace(hostname))
+
+	namespace, err := instance.ExecuteCommand("cat", "/var/run/secrets/kubernetes.io/serviceaccount/namespace")
+	require.NoError(t, err)
+	require.Equal(t, instance.GetNamespace(), strings.TrimSpace(namespace))
+}
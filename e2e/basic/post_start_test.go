@@ -0,0 +1,54 @@
+package basic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestPostStartCommand(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	instance, err := knuu.NewInstance("post-start")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = instance.SetImage("docker.io/alpine:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	err = instance.SetCommand("sleep", "infinity")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+	err = instance.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+	err = instance.SetPostStartCommand([]string{"sh", "-c", "echo registered > /tmp/marker"})
+	if err != nil {
+		t.Fatalf("Error setting post start command: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(instance))
+	})
+
+	// Test logic
+
+	err = instance.Start()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+
+	// the container is only running once the hook has completed, so the marker is there when Start returns
+	marker, err := instance.ExecuteCommand("cat", "/tmp/marker")
+	require.NoError(t, err)
+	require.Equal(t, "registered", strings.TrimSpace(marker))
+
+	require.Error(t, instance.SetPostStartCommand([]string{"true"}), "the hook cannot be changed once started")
+}
@@ -0,0 +1,98 @@
+package basic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+// securedHealthConfig is a nginx config whose health endpoint is only ok with the Authorization header
+const securedHealthConfig = `server {
+    listen 8080;
+    location /healthz {
+        if ($http_authorization != "Bearer secret") {
+            return 401;
+        }
+        return 200 "ok";
+    }
+}
+`
+
+func TestProbeHTTPHeaders(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	executor, err := knuu.NewExecutor()
+	if err != nil {
+		t.Fatalf("Error creating executor: %v", err)
+	}
+
+	web, err := knuu.NewInstance("probe-headers")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = web.SetImage("docker.io/nginx:alpine")
+	if err != nil {
+		t.Fatalf("Error setting image '%v':", err)
+	}
+	err = web.AddPortTCP(8080)
+	if err != nil {
+		t.Fatalf("Error adding port: %v", err)
+	}
+	err = web.AddFileBytes([]byte(securedHealthConfig), "/etc/nginx/conf.d/default.conf", "0:0")
+	if err != nil {
+		t.Fatalf("Error adding file '%v':", err)
+	}
+	err = web.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+	err = web.SetReadinessProbe(&v1.Probe{
+		ProbeHandler: v1.ProbeHandler{
+			HTTPGet: &v1.HTTPGetAction{Path: "/healthz", Port: intstr.FromInt(8080)},
+		},
+		PeriodSeconds: 2,
+	})
+	if err != nil {
+		t.Fatalf("Error setting readiness probe '%v':", err)
+	}
+	err = web.SetReadinessHTTPHeaders(map[string]string{"Authorization": "Bearer secret"})
+	if err != nil {
+		t.Fatalf("Error setting readiness probe headers '%v':", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(executor.Instance, web))
+	})
+
+	// Test logic
+
+	webIP, err := web.GetIP()
+	if err != nil {
+		t.Fatalf("Error getting IP '%v':", err)
+	}
+
+	// the instance is only ready if the probe sends the header
+	err = web.Start()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+	err = web.WaitInstanceIsRunning()
+	if err != nil {
+		t.Fatalf("Error waiting for instance to be running: %v", err)
+	}
+
+	_, err = executor.ExecuteCommand("wget", "-q", "-O", "-", "http://"+webIP+":8080/healthz")
+	assert.Error(t, err, "the health endpoint should reject requests without the header")
+
+	out, err := executor.ExecuteCommand("wget", "-q", "-O", "-", "--header", "Authorization: Bearer secret", "http://"+webIP+":8080/healthz")
+	if err != nil {
+		t.Fatalf("Error executing command '%v':", err)
+	}
+	assert.Contains(t, out, "ok")
+}
@@ -0,0 +1,62 @@
+package basic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestProgressDeadline(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	instance, err := knuu.NewInstance("progress-deadline")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = instance.SetImage("docker.io/alpine:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	err = instance.SetCommand("sleep", "infinity")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+	// no node has that many CPUs, so the pod is never scheduled
+	err = instance.SetCPU("1000")
+	if err != nil {
+		t.Fatalf("Error setting CPU: %v", err)
+	}
+	err = instance.SetProgressDeadline(20 * time.Second)
+	if err != nil {
+		t.Fatalf("Error setting progress deadline: %v", err)
+	}
+	err = instance.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(instance))
+	})
+
+	// Test logic
+
+	err = instance.StartWithoutWait()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	start := time.Now()
+	err = instance.WaitForReplicas(ctx, 1)
+	assert.ErrorIs(t, err, knuu.ErrProgressDeadlineExceeded)
+	assert.ErrorContains(t, err, "Unschedulable")
+	assert.Less(t, time.Since(start), time.Minute, "the wait should fail at the progress deadline")
+}
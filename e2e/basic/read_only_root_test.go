@@ -0,0 +1,67 @@
+package basic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestReadOnlyRootFilesystem(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	instance, err := knuu.NewInstance("read-only-root")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = instance.SetImage("docker.io/alpine:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	err = instance.SetCommand("sleep", "infinity")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+	err = instance.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+	err = instance.SetReadOnlyRootFilesystem(true)
+	if err != nil {
+		t.Fatalf("Error setting read-only root filesystem: %v", err)
+	}
+	err = instance.AddTmpfsMount("/scratch", "16Mi")
+	if err != nil {
+		t.Fatalf("Error adding tmpfs mount: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(instance))
+	})
+
+	// Test logic
+
+	err = instance.Start()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+	err = instance.WaitInstanceIsRunning()
+	if err != nil {
+		t.Fatalf("Error waiting for instance to be running: %v", err)
+	}
+
+	_, err = instance.ExecuteCommand("touch", "/file")
+	assert.Error(t, err, "the root filesystem should be read-only")
+
+	_, err = instance.ExecuteCommand("sh", "-c", "echo hello > /scratch/file")
+	assert.NoError(t, err, "the tmpfs mount should be writable")
+
+	out, err := instance.ExecuteCommand("sh", "-c", "grep ' /scratch ' /proc/mounts | cut -d ' ' -f 3")
+	if err != nil {
+		t.Fatalf("Error reading mounts: %v", err)
+	}
+	assert.Contains(t, out, "tmpfs")
+}
@@ -0,0 +1,81 @@
+package basic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/rbac/v1"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestServiceAccount(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	createKubectl := func(name string) *knuu.Instance {
+		instance, err := knuu.NewInstance(name)
+		if err != nil {
+			t.Fatalf("Error creating instance '%v':", err)
+		}
+		err = instance.SetImage("docker.io/bitnami/kubectl:latest")
+		if err != nil {
+			t.Fatalf("Error setting image: %v", err)
+		}
+		err = instance.SetCommand("sleep", "infinity")
+		if err != nil {
+			t.Fatalf("Error setting command: %v", err)
+		}
+		err = instance.Commit()
+		if err != nil {
+			t.Fatalf("Error committing instance: %v", err)
+		}
+		return instance
+	}
+
+	// owner creates the service account that is allowed to list pods
+	owner := createKubectl("sa-owner")
+	err := owner.AddPolicyRule(v1.PolicyRule{
+		Verbs:     []string{"get", "list", "watch"},
+		APIGroups: []string{""},
+		Resources: []string{"pods"},
+	})
+	if err != nil {
+		t.Fatalf("Error adding policy rule: %v", err)
+	}
+
+	user := createKubectl("sa-user")
+	err = user.SetServiceAccount(owner.ServiceAccountName())
+	if err != nil {
+		t.Fatalf("Error setting service account: %v", err)
+	}
+
+	t.Cleanup(func() {
+		// the user must be destroyed first as the owner deletes the service account
+		require.NoError(t, knuu.BatchDestroy(user))
+		require.NoError(t, knuu.BatchDestroy(owner))
+	})
+
+	// Test logic
+
+	err = owner.Start()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+	err = user.Start()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+	err = user.WaitInstanceIsRunning()
+	if err != nil {
+		t.Fatalf("Error waiting for instance to be running: %v", err)
+	}
+
+	pods, err := user.ExecuteCommand("kubectl", "get", "pods", "-o", "name")
+	if err != nil {
+		t.Fatalf("Error executing command '%v':", err)
+	}
+
+	assert.Contains(t, pods, "pod/sa-user")
+}
@@ -0,0 +1,72 @@
+package basic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestShareProcessNamespace(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	instance, err := knuu.NewInstance("share-pid")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = instance.SetImage("docker.io/alpine:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	// the unique argument identifies the process of the main container
+	err = instance.SetCommand("sleep", "31536000")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+	err = instance.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+	err = instance.SetShareProcessNamespace(true)
+	if err != nil {
+		t.Fatalf("Error setting share process namespace: %v", err)
+	}
+
+	sidecar, err := knuu.NewInstance("share-pid-sidecar")
+	if err != nil {
+		t.Fatalf("Error creating sidecar '%v':", err)
+	}
+	err = sidecar.SetImage("docker.io/alpine:latest")
+	if err != nil {
+		t.Fatalf("Error setting sidecar image: %v", err)
+	}
+	err = sidecar.SetCommand("sleep", "infinity")
+	if err != nil {
+		t.Fatalf("Error setting sidecar command: %v", err)
+	}
+	err = sidecar.Commit()
+	if err != nil {
+		t.Fatalf("Error committing sidecar: %v", err)
+	}
+	err = instance.AddSidecar(sidecar)
+	if err != nil {
+		t.Fatalf("Error adding sidecar: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(instance))
+	})
+
+	// Test logic
+
+	err = instance.Start()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+
+	processes, err := sidecar.ExecuteCommand("ps", "-o", "args")
+	require.NoError(t, err)
+	require.Contains(t, processes, "sleep 31536000", "the sidecar should see the processes of the main container")
+}
@@ -0,0 +1,64 @@
+package basic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestShareVolume(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	writer, err := knuu.NewInstance("shared-volume-writer")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = writer.SetImage("docker.io/alpine:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	err = writer.SetCommand("sleep", "infinity")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+	err = writer.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+
+	reader, err := writer.CloneWithName("shared-volume-reader")
+	if err != nil {
+		t.Fatalf("Error cloning instance: %v", err)
+	}
+
+	err = writer.ShareVolumeWith(reader, "/shared")
+	if err != nil {
+		t.Fatalf("Error sharing volume: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(writer, reader))
+	})
+
+	// Test logic
+
+	err = writer.Start()
+	if err != nil {
+		t.Fatalf("Error starting writer: %v", err)
+	}
+	err = reader.Start()
+	if err != nil {
+		t.Fatalf("Error starting reader: %v", err)
+	}
+
+	_, err = writer.ExecuteCommand("sh", "-c", "echo shared > /shared/hello")
+	require.NoError(t, err)
+
+	content, err := reader.ExecuteCommand("cat", "/shared/hello")
+	require.NoError(t, err)
+	require.Equal(t, "shared", strings.TrimSpace(content))
+}
@@ -0,0 +1,76 @@
+package basic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestStartupProbe(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	instance, err := knuu.NewInstance("startup-probe")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = instance.SetImage("docker.io/busybox:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	// the app takes 30s to boot before it serves
+	err = instance.SetCommand("sh", "-c", "sleep 30 && mkdir -p /www && echo ok > /www/index.html && httpd -f -p 8080 -h /www")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+	err = instance.AddPortTCP(8080)
+	if err != nil {
+		t.Fatalf("Error adding port: %v", err)
+	}
+	err = instance.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+	httpGet := v1.ProbeHandler{HTTPGet: &v1.HTTPGetAction{Path: "/", Port: intstr.FromInt(8080)}}
+	// without the startup probe, the liveness probe would restart the app before it boots
+	err = instance.SetLivenessProbe(&v1.Probe{ProbeHandler: httpGet, PeriodSeconds: 5, FailureThreshold: 1})
+	if err != nil {
+		t.Fatalf("Error setting liveness probe: %v", err)
+	}
+	err = instance.SetStartupProbe(&v1.Probe{ProbeHandler: httpGet, PeriodSeconds: 5, FailureThreshold: 12})
+	if err != nil {
+		t.Fatalf("Error setting startup probe: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(instance))
+	})
+
+	// Test logic
+
+	err = instance.Start()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+	err = instance.WaitInstanceIsRunning()
+	if err != nil {
+		t.Fatalf("Error waiting for instance to be running: %v", err)
+	}
+
+	statuses, err := instance.GetContainerStatuses(context.Background())
+	require.NoError(t, err)
+	status := statuses[instance.GetUniqueName()]
+	assert.True(t, status.Ready)
+	assert.Zero(t, status.RestartCount, "the app should not be restarted while it boots")
+
+	out, err := instance.ExecuteCommandWithRetry(context.Background(), 3, time.Second, "wget", "-qO-", "http://localhost:8080")
+	require.NoError(t, err)
+	assert.Contains(t, out, "ok")
+}
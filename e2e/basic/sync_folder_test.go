@@ -0,0 +1,85 @@
+package basic
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestSyncFolder(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	src := t.TempDir()
+	files := map[string]string{"app.toml": "version = 1", "genesis.json": "{}"}
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("Error writing file: %v", err)
+		}
+	}
+
+	instance, err := knuu.NewInstance("sync-folder")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = instance.SetImage("docker.io/alpine:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	err = instance.SetCommand("sleep", "infinity")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+	_, err = instance.SyncFolder(context.Background(), src, "/data", "0:0")
+	if err != nil {
+		t.Fatalf("Error syncing folder: %v", err)
+	}
+	err = instance.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(instance))
+	})
+
+	// Test logic
+
+	err = instance.Start()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+	err = instance.WaitInstanceIsRunning()
+	if err != nil {
+		t.Fatalf("Error waiting for instance to be running: %v", err)
+	}
+
+	err = os.WriteFile(filepath.Join(src, "app.toml"), []byte("version = 2"), 0644)
+	if err != nil {
+		t.Fatalf("Error writing file: %v", err)
+	}
+	synced, err := instance.SyncFolder(context.Background(), src, "/data", "0:0")
+	if err != nil {
+		t.Fatalf("Error syncing folder: %v", err)
+	}
+	assert.Equal(t, []string{"app.toml"}, synced, "only the changed file should be synced")
+
+	out, err := instance.ExecuteCommand("cat", "/data/app.toml", "/data/genesis.json")
+	if err != nil {
+		t.Fatalf("Error executing command: %v", err)
+	}
+	assert.Equal(t, "version = 2{}", out)
+
+	synced, err = instance.SyncFolder(context.Background(), src, "/data", "0:0")
+	if err != nil {
+		t.Fatalf("Error syncing folder: %v", err)
+	}
+	assert.Empty(t, synced)
+}
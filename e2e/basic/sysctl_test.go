@@ -0,0 +1,60 @@
+package basic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestSysctl(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	instance, err := knuu.NewInstance("sysctl")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = instance.SetImage("docker.io/alpine:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	err = instance.SetCommand("sleep", "infinity")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+	err = instance.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+	// tcp_fin_timeout is a safe sysctl, so it is allowed without any kubelet configuration
+	err = instance.SetSysctl("net.ipv4.tcp_fin_timeout", "42")
+	if err != nil {
+		t.Fatalf("Error setting sysctl: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(instance))
+	})
+
+	// Test logic
+
+	err = instance.Start()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+	err = instance.WaitInstanceIsRunning()
+	if err != nil {
+		t.Fatalf("Error waiting for instance to be running: %v", err)
+	}
+
+	out, err := instance.ExecuteCommand("sysctl", "-n", "net.ipv4.tcp_fin_timeout")
+	if err != nil {
+		t.Fatalf("Error executing command '%v':", err)
+	}
+
+	assert.Equal(t, "42", strings.TrimSpace(out))
+}
@@ -0,0 +1,58 @@
+package basic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestTerminationMessage(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	instance, err := knuu.NewInstance("termination-message")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = instance.SetImage("docker.io/alpine:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	err = instance.SetCommand("sh", "-c", "echo -n 'config not found' > /tmp/reason; exit 1")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+	err = instance.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+	err = instance.SetTerminationMessagePath("/tmp/reason")
+	if err != nil {
+		t.Fatalf("Error setting termination message path: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(instance))
+	})
+
+	// Test logic
+
+	// the container exits right away, so the instance never becomes running
+	err = instance.StartAsync()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	var message string
+	require.Eventually(t, func() bool {
+		message, err = instance.GetTerminationMessage(ctx)
+		return err == nil
+	}, 2*time.Minute, time.Second, "the container should terminate")
+	require.Equal(t, "config not found", message)
+}
@@ -0,0 +1,64 @@
+package basic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/k8s"
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestWaitForDeletion(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	instance, err := knuu.NewInstance("deleted")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = instance.SetImage("docker.io/alpine:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	err = instance.SetCommand("sleep", "infinity")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+	err = instance.AddFileBytes([]byte("hello"), "/data/hello", "0:0")
+	if err != nil {
+		t.Fatalf("Error adding file: %v", err)
+	}
+	err = instance.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(instance))
+	})
+
+	// Test logic
+
+	err = instance.Start()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	require.NoError(t, instance.Destroy())
+	require.NoError(t, instance.WaitForDeletion(ctx))
+
+	k8sClient, err := k8s.New(ctx, knuu.Scope())
+	require.NoError(t, err)
+	exists, err := k8sClient.PodsExist(ctx, instance.Labels())
+	require.NoError(t, err)
+	require.False(t, exists, "pod should be deleted")
+
+	// everything is gone already, so waiting again returns right away
+	require.NoError(t, instance.WaitForDeletion(ctx))
+}
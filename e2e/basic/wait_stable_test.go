@@ -0,0 +1,57 @@
+package basic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/knuu"
+)
+
+func TestWaitStable(t *testing.T) {
+	t.Parallel()
+	// Setup
+
+	instance, err := knuu.NewInstance("crashing")
+	if err != nil {
+		t.Fatalf("Error creating instance '%v':", err)
+	}
+	err = instance.SetImage("docker.io/alpine:latest")
+	if err != nil {
+		t.Fatalf("Error setting image: %v", err)
+	}
+	err = instance.AddVolume("/data", "10Mi")
+	if err != nil {
+		t.Fatalf("Error adding volume: %v", err)
+	}
+	// The counter lives on the volume, so the app crashes twice before it keeps running
+	err = instance.SetCommand("sh", "-c", "c=$(cat /data/c 2>/dev/null || echo 0); echo $((c+1)) > /data/c; [ $c -ge 2 ] || exit 1; sleep infinity")
+	if err != nil {
+		t.Fatalf("Error setting command: %v", err)
+	}
+	err = instance.Commit()
+	if err != nil {
+		t.Fatalf("Error committing instance: %v", err)
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, knuu.BatchDestroy(instance))
+	})
+
+	// Test logic
+
+	err = instance.StartWithoutWait()
+	if err != nil {
+		t.Fatalf("Error starting instance: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.ErrorIs(t, instance.WaitStable(ctx, 30*time.Second), knuu.ErrInstanceNotStable)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+	require.NoError(t, instance.WaitStable(ctx, 15*time.Second))
+}
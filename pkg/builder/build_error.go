@@ -0,0 +1,82 @@
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// BuildErrorLogLines is the number of log lines kept in a BuildError
+const BuildErrorLogLines = 20
+
+var (
+	// logPrefixRegex matches the level and time prefix of the builder logs, e.g. INFO[0001]
+	logPrefixRegex = regexp.MustCompile(`^[A-Z]+\[\d+\]\s*`)
+	// exitStatusRegex matches the exit status of a failed command
+	exitStatusRegex = regexp.MustCompile(`exit (?:status|code):? (\d+)`)
+)
+
+// dockerfileInstructions are the instructions that are logged by the builder when they are executed
+var dockerfileInstructions = map[string]bool{
+	"ADD": true, "ARG": true, "CMD": true, "COPY": true, "ENTRYPOINT": true, "ENV": true, "EXPOSE": true,
+	"FROM": true, "HEALTHCHECK": true, "LABEL": true, "ONBUILD": true, "RUN": true, "SHELL": true,
+	"STOPSIGNAL": true, "USER": true, "VOLUME": true, "WORKDIR": true,
+}
+
+// BuildError describes a failed build in a machine-readable way, e.g. for CI annotations
+type BuildError struct {
+	Image       string   `json:"image"`                 // Destination of the image that failed to build
+	Instruction string   `json:"instruction,omitempty"` // Last Dockerfile instruction executed before the failure
+	ExitCode    int      `json:"exitCode"`              // Exit code of the failed instruction, or of the builder if unknown
+	Logs        []string `json:"logs"`                  // Last BuildErrorLogLines lines of the build logs
+	Err         error    `json:"-"`                     // Underlying error of the builder
+}
+
+// NewBuildError parses the logs of a failed build.
+// The exit code of the builder is used if the logs do not contain the exit code of the failed instruction.
+func NewBuildError(image, logs string, builderExitCode int, err error) *BuildError {
+	lines := strings.Split(strings.TrimRight(logs, "\n"), "\n")
+
+	be := &BuildError{
+		Image:    image,
+		ExitCode: builderExitCode,
+		Logs:     lines[max(0, len(lines)-BuildErrorLogLines):],
+		Err:      err,
+	}
+
+	for _, line := range lines {
+		msg := logPrefixRegex.ReplaceAllString(strings.TrimSpace(line), "")
+		keyword, _, _ := strings.Cut(msg, " ")
+		if dockerfileInstructions[keyword] {
+			be.Instruction = msg
+		}
+		if m := exitStatusRegex.FindStringSubmatch(msg); m != nil {
+			if code, err := strconv.Atoi(m[1]); err == nil {
+				be.ExitCode = code
+			}
+		}
+	}
+	return be
+}
+
+func (e *BuildError) Error() string {
+	msg := "build failed"
+	if e.Err != nil {
+		msg = e.Err.Error()
+	}
+	if e.Instruction != "" {
+		return fmt.Sprintf("%s: instruction '%s' exited with code %d", msg, e.Instruction, e.ExitCode)
+	}
+	return fmt.Sprintf("%s: exit code %d", msg, e.ExitCode)
+}
+
+func (e *BuildError) Unwrap() error {
+	return e.Err
+}
+
+// JSON renders the build error as JSON
+func (e *BuildError) JSON() ([]byte, error) {
+	return json.Marshal(e)
+}
@@ -0,0 +1,47 @@
+package builder
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildErrorJSON(t *testing.T) {
+	logs := `INFO[0000] Retrieving image manifest alpine:latest
+INFO[0002] Unpacking rootfs as cmd RUN exit 3 requires it.
+INFO[0003] ENV FOO=bar
+INFO[0003] RUN exit 3
+INFO[0003] Running: [/bin/sh -c exit 3]
+error building image: error building stage: failed to execute command: waiting for process to exit: exit status 3
+`
+	errBuild := errors.New("build failed")
+	buildErr := NewBuildError("ttl.sh/test:24h", logs, 1, errBuild)
+	assert.ErrorIs(t, buildErr, errBuild)
+	assert.Equal(t, "build failed: instruction 'RUN exit 3' exited with code 3", buildErr.Error())
+
+	data, err := buildErr.JSON()
+	require.NoError(t, err)
+
+	var parsed struct {
+		Image       string   `json:"image"`
+		Instruction string   `json:"instruction"`
+		ExitCode    int      `json:"exitCode"`
+		Logs        []string `json:"logs"`
+	}
+	require.NoError(t, json.Unmarshal(data, &parsed))
+	assert.Equal(t, "ttl.sh/test:24h", parsed.Image)
+	assert.Equal(t, "RUN exit 3", parsed.Instruction)
+	assert.Equal(t, 3, parsed.ExitCode)
+	require.Len(t, parsed.Logs, 6)
+	assert.Contains(t, parsed.Logs[5], "exit status 3")
+}
+
+func TestBuildErrorWithoutInstruction(t *testing.T) {
+	buildErr := NewBuildError("ttl.sh/test:24h", "error resolving dockerfile path", 1, nil)
+	assert.Empty(t, buildErr.Instruction)
+	assert.Equal(t, 1, buildErr.ExitCode, "the exit code of the builder is used")
+	assert.Equal(t, "build failed: exit code 1", buildErr.Error())
+}
@@ -0,0 +1,68 @@
+package builder
+
+import (
+	"strings"
+)
+
+const (
+	// cacheHitPrefix is logged by kaniko for the instructions whose layer is taken from the cache
+	cacheHitPrefix = "Using caching version of cmd: "
+	// cacheMissPrefix is logged by kaniko for the instructions whose layer is not in the cache
+	cacheMissPrefix = "No cached layer found for cmd "
+)
+
+// CacheStatus tells whether the layer of an instruction was taken from the cache
+type CacheStatus string
+
+const (
+	CacheHit  CacheStatus = "hit"
+	CacheMiss CacheStatus = "miss"
+)
+
+// InstructionCache is the outcome of the cache lookup of a Dockerfile instruction
+type InstructionCache struct {
+	Instruction string      `json:"instruction"` // Instruction as logged by the builder, e.g. RUN apk add curl
+	Status      CacheStatus `json:"status"`
+}
+
+// BuildResult is the outcome of a successful build
+type BuildResult struct {
+	Logs string `json:"logs"`
+	// Cache holds the cache lookups of the instructions, in the order of the build, empty if the cache is disabled.
+	// Only the instructions that create a layer are looked up, e.g. RUN. A miss invalidates the cache of the following
+	// instructions of its stage, which are not looked up anymore, so the miss is the instruction that broke the cache.
+	Cache []InstructionCache `json:"cache,omitempty"`
+	// ExportName is the name of the image tarball exported by the build, see ExportOptions, empty if it was not exported.
+	// It is unique to the build, e.g. kaniko.Kaniko.DownloadExportedImage downloads the tarball with it.
+	ExportName string `json:"exportName,omitempty"`
+}
+
+// NewBuildResult parses the cache lookups from the logs of a build.
+func NewBuildResult(logs string) *BuildResult {
+	r := &BuildResult{Logs: logs}
+	for _, line := range strings.Split(logs, "\n") {
+		msg := logPrefixRegex.ReplaceAllString(strings.TrimSpace(line), "")
+		// logrus text format, e.g. level=info msg="..."
+		if _, quoted, found := strings.Cut(msg, `msg="`); found {
+			msg = strings.TrimSuffix(quoted, `"`)
+		}
+		switch {
+		case strings.HasPrefix(msg, cacheHitPrefix):
+			r.Cache = append(r.Cache, InstructionCache{Instruction: strings.TrimPrefix(msg, cacheHitPrefix), Status: CacheHit})
+		case strings.HasPrefix(msg, cacheMissPrefix):
+			r.Cache = append(r.Cache, InstructionCache{Instruction: strings.TrimPrefix(msg, cacheMissPrefix), Status: CacheMiss})
+		}
+	}
+	return r
+}
+
+// FirstCacheMiss returns the first instruction that missed the cache, which rebuilt it and all the following ones,
+// or nil if all the lookups hit the cache
+func (r *BuildResult) FirstCacheMiss() *InstructionCache {
+	for n := range r.Cache {
+		if r.Cache[n].Status == CacheMiss {
+			return &r.Cache[n]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package builder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBuildResultCache(t *testing.T) {
+	logs := `INFO[0000] Retrieving image manifest golang:1.22
+INFO[0001] Checking for cached layer registry.local:5000/app/cache:0f3c...
+INFO[0001] Using caching version of cmd: RUN go mod download
+INFO[0001] Checking for cached layer registry.local:5000/app/cache:9a1b...
+INFO[0001] Using caching version of cmd: COPY . .
+INFO[0002] Checking for cached layer registry.local:5000/app/cache:77de...
+INFO[0002] No cached layer found for cmd RUN go build -o /app ./cmd/app
+INFO[0002] Unpacking rootfs as cmd COPY go.mod go.sum ./ requires it.
+INFO[0005] RUN go mod download
+INFO[0005] Found cached layer, extracting to filesystem
+INFO[0006] RUN go build -o /app ./cmd/app
+INFO[0030] Pushing layer registry.local:5000/app/cache:77de... to cache now
+time="2024-01-01T00:00:31Z" level=info msg="Using caching version of cmd: RUN apk add --no-cache ca-certificates"
+`
+	result := NewBuildResult(logs)
+	assert.Equal(t, logs, result.Logs)
+	assert.Equal(t, []InstructionCache{
+		{Instruction: "RUN go mod download", Status: CacheHit},
+		{Instruction: "COPY . .", Status: CacheHit},
+		{Instruction: "RUN go build -o /app ./cmd/app", Status: CacheMiss},
+		{Instruction: "RUN apk add --no-cache ca-certificates", Status: CacheHit},
+	}, result.Cache)
+
+	miss := result.FirstCacheMiss()
+	require.NotNil(t, miss)
+	assert.Equal(t, "RUN go build -o /app ./cmd/app", miss.Instruction, "the build broke the cache chain")
+
+	// without the cache there are no lookups
+	uncached := NewBuildResult("INFO[0000] RUN go build\n")
+	assert.Empty(t, uncached.Cache)
+	assert.Nil(t, uncached.FirstCacheMiss())
+}
@@ -5,24 +5,101 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"sort"
 )
 
 type Builder interface {
 	Build(ctx context.Context, b *BuilderOptions) (logs string, err error)
+	// ImageExists checks if the image reference exists in its registry.
+	// A missing image is reported as (false, nil), auth and network problems as errors.
+	ImageExists(ctx context.Context, ref string) (bool, error)
 }
 
 type BuilderOptions struct {
 	ImageName    string
 	BuildContext string
 	Args         []string
+	BuildArgs    map[string]string // Values of the build arguments declared with ARG in the Dockerfile
 	Destination  string
 	Cache        *CacheOptions
+	Verbosity    Verbosity      // Log level of the builder, defaults to VerbosityInfo
+	Export       *ExportOptions // Export the built image as a tarball, nil disables exporting
+	// Reproducible strips timestamps from the image, so identical inputs yield identical images.
+	// It is supported by kaniko only and can slow down the build.
+	Reproducible bool
+	// Squash builds the image with a single layer on top of the base image instead of one layer per instruction,
+	// which makes the image smaller when instructions remove or overwrite files of earlier ones, e.g. for shipped images.
+	// The layers cannot be cached nor shared with other images anymore, so rebuilds and pulls of similar images are slower.
+	// For multi-stage builds only the final stage is squashed, as the build stages are not part of the image:
+	// with the cache enabled and a directory build context, the build stages are built first without squashing,
+	// so their layers are still cached. This needs the stage before the final one to be named, e.g. `FROM golang AS build`.
+	// It is supported by kaniko only.
+	Squash bool
+	// ExtraArgs are passed as is to the builder, for flags that are not supported by the options above,
+	// e.g. `--snapshot-mode=redo` for kaniko. This is an advanced escape hatch: the flags are not validated
+	// and may break with builder updates. Flags managed by the options above are rejected.
+	ExtraArgs []string
+	// InsecureRegistries are the registry hosts, e.g. registry.local:5000, that are used without verifying
+	// their TLS certificate, falling back to plain HTTP. This is meant for dev registries with self-signed
+	// certificates only, as it allows anyone on the network path to tamper with the pushed and pulled images.
+	// It only affects the builder: the nodes pulling the image for the instances must trust the registry
+	// in their container runtime configuration, as kubernetes has no per-pod setting for it.
+	InsecureRegistries []string
+	// CacheMounts are the targets of the cache mounts of the RUN instructions, e.g. `/root/.cache/go-build`.
+	// Cache mounts need BuildKit, so the builders without it reject them instead of silently building without the cache.
+	CacheMounts []string
+}
+
+// ExportOptions configures exporting the built image as a tarball,
+// which can be loaded with `docker load` e.g. for air-gapped environments.
+// The tarball contains all the layers of the image, so it is as large as the image itself
+// and must fit in the storage used by the builder.
+type ExportOptions struct {
+	NoPush bool // Only export the image without pushing it to the destination
+}
+
+// BuildArgList returns the build args as NAME=VALUE, sorted by name
+func (b *BuilderOptions) BuildArgList() []string {
+	names := make([]string, 0, len(b.BuildArgs))
+	for name := range b.BuildArgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	args := make([]string, 0, len(names))
+	for _, name := range names {
+		args = append(args, name+"="+b.BuildArgs[name])
+	}
+	return args
+}
+
+// Verbosity is the log level used by the builder
+type Verbosity string
+
+const (
+	VerbosityTrace Verbosity = "trace"
+	VerbosityDebug Verbosity = "debug"
+	VerbosityInfo  Verbosity = "info"
+	VerbosityWarn  Verbosity = "warn"
+)
+
+// IsValid returns true if the verbosity is one of the supported levels
+func (v Verbosity) IsValid() bool {
+	switch v {
+	case VerbosityTrace, VerbosityDebug, VerbosityInfo, VerbosityWarn:
+		return true
+	}
+	return false
 }
 
 type CacheOptions struct {
 	Enabled bool
-	Dir     string
-	Repo    string
+	// Dir is a local directory the layers are cached in, which persists across the builds on the same machine,
+	// e.g. for a single machine CI without a registry to cache the layers in.
+	// The docker builder exports the layer cache of BuildKit to it and imports it in the next builds.
+	// Kaniko mounts it from the node its pod runs on, where it keeps the base images, while the layers are cached in Repo.
+	Dir  string
+	Repo string
 }
 
 func (c *CacheOptions) Default(buildContext string) (*CacheOptions, error) {
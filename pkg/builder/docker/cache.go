@@ -0,0 +1,88 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// cacheBuilderName is the buildx builder of the builds with a local cache,
+	// as the docker driver of the default builder cannot export the cache
+	cacheBuilderName = "knuu-cache"
+	// cacheIndexFile is written by BuildKit once the cache is exported to a directory
+	cacheIndexFile = "index.json"
+)
+
+// prepareLocalCache creates the cache directory if needed and returns the buildx args
+// importing the layers cached by the previous builds and exporting the layers of this one
+// The cache is exported to a new directory, which replaces the cache directory once the build succeeds, see commitLocalCache,
+// as BuildKit adds the new layers to an existing cache without ever removing the unused ones
+func prepareLocalCache(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, ErrCreatingCacheDir.Wrap(fmt.Errorf("dir: %s: %w", dir, err))
+	}
+	// the cache is exported next to the directory and renamed to it, so the parent directory must be writable
+	// by the user running the build, which may not be the user the cache was created by
+	probe, err := os.CreateTemp(filepath.Dir(dir), ".knuu-cache-")
+	if err != nil {
+		return nil, ErrCacheDirNotWritable.Wrap(fmt.Errorf("dir: %s: %w", dir, err))
+	}
+	probe.Close()
+	if err := os.Remove(probe.Name()); err != nil {
+		return nil, ErrCacheDirNotWritable.Wrap(fmt.Errorf("dir: %s: %w", dir, err))
+	}
+	if err := os.RemoveAll(newCacheDir(dir)); err != nil {
+		return nil, ErrCacheDirNotWritable.Wrap(fmt.Errorf("dir: %s: %w", dir, err))
+	}
+
+	cmd := exec.Command("docker", "buildx", "inspect", cacheBuilderName)
+	if _, err := runCommand(cmd); err != nil {
+		cmd = exec.Command("docker", "buildx", "create", "--name", cacheBuilderName, "--driver", "docker-container")
+		if _, err := runCommand(cmd); err != nil {
+			return nil, ErrFailedToCreateBuilder.Wrap(err)
+		}
+		logrus.Debugf("created buildx builder %s for the local cache", cacheBuilderName)
+	}
+
+	args := []string{"--builder", cacheBuilderName}
+	if _, err := os.Stat(filepath.Join(dir, cacheIndexFile)); err == nil {
+		args = append(args, "--cache-from", "type=local,src="+dir)
+	} else {
+		logrus.Debugf("local cache %s is empty, building without it", dir)
+	}
+	args = append(args, "--cache-to", "type=local,mode=max,dest="+newCacheDir(dir))
+	return args, nil
+}
+
+// commitLocalCache replaces the cache directory with the cache exported by the successful build,
+// or only deletes the exported cache if the build failed, keeping the cache of the previous builds
+func commitLocalCache(dir string, buildErr error) error {
+	exported := newCacheDir(dir)
+	if buildErr != nil {
+		if err := os.RemoveAll(exported); err != nil {
+			logrus.Warnf("failed to remove the cache %s exported by the failed build: %v", exported, err)
+		}
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(exported, cacheIndexFile)); err != nil {
+		return ErrUpdatingCacheDir.Wrap(fmt.Errorf("dir: %s: %w", dir, err))
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return ErrUpdatingCacheDir.Wrap(fmt.Errorf("dir: %s: %w", dir, err))
+	}
+	if err := os.Rename(exported, dir); err != nil {
+		return ErrUpdatingCacheDir.Wrap(fmt.Errorf("dir: %s: %w", dir, err))
+	}
+	logrus.Debugf("updated local cache %s", dir)
+	return nil
+}
+
+// newCacheDir returns the directory the cache of a build is exported to, next to the cache directory
+// so that it can be renamed to it
+func newCacheDir(dir string) string {
+	return filepath.Clean(dir) + ".new"
+}
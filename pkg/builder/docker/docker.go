@@ -9,12 +9,11 @@ import (
 	"strings"
 
 	"github.com/celestiaorg/knuu/pkg/builder"
+	"github.com/celestiaorg/knuu/pkg/registry"
 	"github.com/sirupsen/logrus"
 	"k8s.io/client-go/kubernetes"
 )
 
-const ()
-
 type Docker struct {
 	K8sClientset kubernetes.Interface
 	K8sNamespace string
@@ -26,6 +25,19 @@ func (d *Docker) Build(_ context.Context, b *builder.BuilderOptions) (logs strin
 	if builder.IsGitContext(b.BuildContext) {
 		return "", ErrGitContextNotSupported
 	}
+	if b.Export != nil {
+		return "", ErrExportNotSupported
+	}
+	if len(b.InsecureRegistries) != 0 {
+		// docker reads the insecure registries from the daemon configuration only
+		return "", ErrInsecureRegistriesNotSupported
+	}
+	if b.Squash {
+		// buildx has no squash, the legacy `docker build --squash` is an experimental feature of the daemon
+		return "", ErrSquashNotSupported
+	}
+	// cache mounts are supported, as buildx builds with BuildKit and keeps the caches in the builder instance
+	// the layers are cached in the directory of the cache options if it is set, see prepareLocalCache
 
 	// Check if there is an existing builder instance
 	cmd := exec.Command("docker", "buildx", "ls")
@@ -49,8 +61,26 @@ func (d *Docker) Build(_ context.Context, b *builder.BuilderOptions) (logs strin
 	buildContext := builder.GetDirFromBuildContext(b.BuildContext)
 
 	// Since in docker the image name and destination must be the same, we just use the destination as the image name
-	cmd = exec.Command("docker", "buildx", "build", "--load", "--platform", "linux/amd64", "-t", b.Destination, buildContext)
+	args := []string{"buildx", "build", "--load", "--platform", "linux/amd64", "-t", b.Destination}
+	for _, arg := range b.BuildArgList() {
+		args = append(args, "--build-arg", arg)
+	}
+	cacheDir := ""
+	if b.Cache != nil && b.Cache.Enabled && b.Cache.Dir != "" {
+		cacheDir = b.Cache.Dir
+		cacheArgs, err := prepareLocalCache(cacheDir)
+		if err != nil {
+			return "", err
+		}
+		args = append(args, cacheArgs...)
+	}
+	cmd = exec.Command("docker", append(args, buildContext)...)
 	cmdLogs, err := runCommand(cmd)
+	if cacheDir != "" {
+		if err := commitLocalCache(cacheDir, err); err != nil {
+			return "", err
+		}
+	}
 	if err != nil {
 		return "", ErrFailedToBuildImage.Wrap(err)
 	}
@@ -74,6 +104,11 @@ func (d *Docker) Build(_ context.Context, b *builder.BuilderOptions) (logs strin
 	return logs, nil
 }
 
+// ImageExists checks if the image exists in the registry it is pushed to
+func (d *Docker) ImageExists(ctx context.Context, ref string) (bool, error) {
+	return registry.ImageExists(ctx, ref)
+}
+
 func runCommand(cmd *exec.Cmd) (logs string, err error) {
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
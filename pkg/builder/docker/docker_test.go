@@ -0,0 +1,121 @@
+package docker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/builder"
+)
+
+// fakeDocker is a docker CLI logging its args, whose builds export a cache to the directory of `--cache-to`
+const fakeDocker = `#!/bin/sh
+echo "$@" >> "$DOCKER_LOG"
+case "$1 $2" in
+"buildx ls") echo "default * docker"; exit 0 ;;
+"buildx inspect") [ -f "$DOCKER_STATE/builder" ] || exit 1; exit 0 ;;
+"buildx create") touch "$DOCKER_STATE/builder"; exit 0 ;;
+"buildx build")
+	[ -f "$DOCKER_STATE/fail" ] && { echo "build failed" >&2; exit 1; }
+	for arg in "$@"; do
+		case "$arg" in
+		type=local,mode=max,dest=*)
+			dest="${arg#type=local,mode=max,dest=}"
+			mkdir -p "$dest"
+			echo '{}' > "$dest/index.json"
+			echo "$DOCKER_BUILD" > "$dest/blob" ;;
+		esac
+	done
+	exit 0 ;;
+esac
+exit 0
+`
+
+// newFakeDocker puts the fake docker CLI first in the PATH and returns the file it logs its calls to
+func newFakeDocker(t *testing.T) (state, log string) {
+	bin, state := t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "docker"), []byte(fakeDocker), 0o755))
+	log = filepath.Join(state, "calls.log")
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("DOCKER_LOG", log)
+	t.Setenv("DOCKER_STATE", state)
+	return state, log
+}
+
+// buildCalls returns the args of the builds logged by the fake docker CLI
+func buildCalls(t *testing.T, log string) []string {
+	content, err := os.ReadFile(log)
+	require.NoError(t, err)
+	var builds []string
+	for _, call := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		if strings.HasPrefix(call, "buildx build") {
+			builds = append(builds, call)
+		}
+	}
+	return builds
+}
+
+func TestBuildLocalCache(t *testing.T) {
+	state, log := newFakeDocker(t)
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	d := &Docker{}
+	build := func(name string) error {
+		t.Setenv("DOCKER_BUILD", name)
+		_, err := d.Build(context.Background(), &builder.BuilderOptions{
+			BuildContext: builder.DirContext{Path: t.TempDir()}.BuildContext(),
+			Destination:  "registry.example.com/app:" + name,
+			Cache:        &builder.CacheOptions{Enabled: true, Dir: cacheDir},
+		})
+		return err
+	}
+
+	// the first build populates the cache, there is nothing to import yet
+	require.NoError(t, build("first"))
+	builds := buildCalls(t, log)
+	require.Len(t, builds, 1)
+	assert.Contains(t, builds[0], "--builder "+cacheBuilderName, "the default builder cannot export the cache")
+	assert.Contains(t, builds[0], "--cache-to type=local,mode=max,dest="+cacheDir+".new")
+	assert.NotContains(t, builds[0], "--cache-from")
+	require.FileExists(t, filepath.Join(cacheDir, cacheIndexFile))
+	assert.NoDirExists(t, cacheDir+".new")
+
+	// the second build reuses the cache, which is replaced by the cache of the build
+	require.NoError(t, build("second"))
+	builds = buildCalls(t, log)
+	require.Len(t, builds, 2)
+	assert.Contains(t, builds[1], "--cache-from type=local,src="+cacheDir)
+	blob, err := os.ReadFile(filepath.Join(cacheDir, "blob"))
+	require.NoError(t, err)
+	assert.Equal(t, "second\n", string(blob))
+
+	// a failed build keeps the cache of the previous build
+	require.NoError(t, os.WriteFile(filepath.Join(state, "fail"), nil, 0o644))
+	assert.ErrorIs(t, build("third"), ErrFailedToBuildImage)
+	blob, err = os.ReadFile(filepath.Join(cacheDir, "blob"))
+	require.NoError(t, err)
+	assert.Equal(t, "second\n", string(blob))
+	assert.NoDirExists(t, cacheDir+".new")
+}
+
+func TestBuildLocalCacheNotWritable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can write to any directory")
+	}
+	newFakeDocker(t)
+	parent := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(parent, "cache"), 0o755))
+	require.NoError(t, os.Chmod(parent, 0o555))
+	t.Cleanup(func() { _ = os.Chmod(parent, 0o755) })
+
+	_, err := (&Docker{}).Build(context.Background(), &builder.BuilderOptions{
+		BuildContext: builder.DirContext{Path: t.TempDir()}.BuildContext(),
+		Destination:  "registry.example.com/app:v1",
+		Cache:        &builder.CacheOptions{Enabled: true, Dir: filepath.Join(parent, "cache")},
+	})
+	assert.ErrorIs(t, err, ErrCacheDirNotWritable)
+}
@@ -27,11 +27,17 @@ func (e *Error) Wrap(err error) error {
 }
 
 var (
-	ErrFailedToListBuildxBuilders = &Error{Code: "FailedToListBuildxBuilders", Message: "failed to list buildx builders"}
-	ErrRunCommandFailed           = &Error{Code: "RunCommandFailed", Message: "failed to run command"}
-	ErrFailedToCreateBuilder      = &Error{Code: "FailedToCreateBuilder", Message: "failed to create buildx builder"}
-	ErrFailedToBuildImage         = &Error{Code: "FailedToBuildImage", Message: "failed to build image"}
-	ErrFailedToPushImage          = &Error{Code: "FailedToPushImage", Message: "failed to push image"}
-	ErrFailedToRemoveContextDir   = &Error{Code: "FailedToRemoveContextDir", Message: "failed to remove context directory"}
-	ErrGitContextNotSupported     = &Error{Code: "GitContextNotSupported", Message: "git context is not supported in the docker builder"}
+	ErrFailedToListBuildxBuilders     = &Error{Code: "FailedToListBuildxBuilders", Message: "failed to list buildx builders"}
+	ErrRunCommandFailed               = &Error{Code: "RunCommandFailed", Message: "failed to run command"}
+	ErrFailedToCreateBuilder          = &Error{Code: "FailedToCreateBuilder", Message: "failed to create buildx builder"}
+	ErrFailedToBuildImage             = &Error{Code: "FailedToBuildImage", Message: "failed to build image"}
+	ErrFailedToPushImage              = &Error{Code: "FailedToPushImage", Message: "failed to push image"}
+	ErrFailedToRemoveContextDir       = &Error{Code: "FailedToRemoveContextDir", Message: "failed to remove context directory"}
+	ErrGitContextNotSupported         = &Error{Code: "GitContextNotSupported", Message: "git context is not supported in the docker builder"}
+	ErrExportNotSupported             = &Error{Code: "ExportNotSupported", Message: "exporting the image as a tarball is not supported in the docker builder"}
+	ErrInsecureRegistriesNotSupported = &Error{Code: "InsecureRegistriesNotSupported", Message: "insecure registries must be configured in the docker daemon, they are not supported in the docker builder"}
+	ErrSquashNotSupported             = &Error{Code: "SquashNotSupported", Message: "squashing the image layers is not supported in the docker builder"}
+	ErrCreatingCacheDir               = &Error{Code: "CreatingCacheDir", Message: "failed to create the local cache directory"}
+	ErrCacheDirNotWritable            = &Error{Code: "CacheDirNotWritable", Message: "the local cache directory is not writable by the user running the build"}
+	ErrUpdatingCacheDir               = &Error{Code: "UpdatingCacheDir", Message: "failed to update the local cache directory with the cache of the build"}
 )
@@ -0,0 +1,70 @@
+package builder
+
+import (
+	"context"
+	"sync"
+)
+
+// FakeBuilder is an in-memory Builder for unit tests, which builds nothing.
+// It records the options of each build and returns the canned logs and error,
+// so the code using a builder can be tested without a cluster nor a docker daemon.
+type FakeBuilder struct {
+	Logs     string          // Logs returned by Build
+	BuildErr error           // Error returned by Build, the build is recorded anyway
+	Images   map[string]bool // Image references reported as existing by ImageExists
+	// ExistsErr is returned by ImageExists, e.g. to simulate an unreachable registry
+	ExistsErr error
+
+	mu     sync.Mutex
+	builds []BuilderOptions
+}
+
+var _ Builder = &FakeBuilder{}
+
+// Build records the options and returns the canned logs and error.
+// A successful build adds the destination to the existing images.
+func (f *FakeBuilder) Build(_ context.Context, b *BuilderOptions) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.builds = append(f.builds, *b)
+	if f.BuildErr != nil {
+		return f.Logs, f.BuildErr
+	}
+	if f.Images == nil {
+		f.Images = make(map[string]bool)
+	}
+	f.Images[b.Destination] = true
+	return f.Logs, nil
+}
+
+// ImageExists reports whether the image reference was set in Images or built by the fake
+func (f *FakeBuilder) ImageExists(_ context.Context, ref string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.ExistsErr != nil {
+		return false, f.ExistsErr
+	}
+	return f.Images[ref], nil
+}
+
+// Builds returns the options of the builds, in the order they were called
+func (f *FakeBuilder) Builds() []BuilderOptions {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]BuilderOptions{}, f.builds...)
+}
+
+// LastBuild returns the options of the last build, or nil if Build was not called
+func (f *FakeBuilder) LastBuild() *BuilderOptions {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.builds) == 0 {
+		return nil
+	}
+	last := f.builds[len(f.builds)-1]
+	return &last
+}
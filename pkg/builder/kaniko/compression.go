@@ -0,0 +1,31 @@
+package kaniko
+
+// Compression is the compression of the archive of the build context uploaded to Minio
+type Compression string
+
+const (
+	// CompressionGzip is read by kaniko directly
+	CompressionGzip Compression = "gzip"
+	// CompressionZstd compresses faster and smaller than gzip, which shortens the upload of large build contexts,
+	// the archive is decompressed by the init container of the build job
+	CompressionZstd Compression = "zstd"
+
+	DefaultCompression = CompressionGzip
+)
+
+// IsValid returns true if the compression is one of the supported ones
+func (c Compression) IsValid() bool {
+	switch c {
+	case CompressionGzip, CompressionZstd:
+		return true
+	}
+	return false
+}
+
+// extension returns the file extension of the archives compressed with the compression
+func (c Compression) extension() string {
+	if c == CompressionZstd {
+		return ".zst"
+	}
+	return ".gz"
+}
@@ -0,0 +1,139 @@
+package kaniko
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	dockerConfigVolName = "docker-config"
+	// kaniko reads the credentials of the registries from the docker config in its home
+	dockerConfigDir  = "/kaniko/.docker"
+	dockerConfigFile = "config.json"
+)
+
+// dockerConfig is the part of a docker config with the credentials of the registries
+type dockerConfig struct {
+	Auths       map[string]dockerAuth `json:"auths"`
+	CredsStore  string                `json:"credsStore,omitempty"`
+	CredHelpers map[string]string     `json:"credHelpers,omitempty"`
+}
+
+// dockerAuth holds the credentials of a registry, it must never be logged
+type dockerAuth struct {
+	Auth          string `json:"auth,omitempty"`
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+	RegistryToken string `json:"registrytoken,omitempty"`
+}
+
+// HostDockerConfigPath returns the path of the docker config of the host, like the docker CLI:
+// config.json in the DOCKER_CONFIG directory if it is set, ~/.docker/config.json otherwise
+func HostDockerConfigPath() (string, error) {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, dockerConfigFile), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", ErrReadingDockerConfig.Wrap(err)
+	}
+	return filepath.Join(home, ".docker", dockerConfigFile), nil
+}
+
+// dockerConfigSecretName returns the name of the secret with the docker config of the build job
+func dockerConfigSecretName(jobName string) string {
+	return jobName + "-docker-config"
+}
+
+// mountHostDockerConfig creates a secret with the credentials of the docker config of the host
+// and mounts it as the docker config of the kaniko container, so it can push to the same registries as the host
+// Only the credentials are copied: the credential helpers of the host, e.g. the desktop keychain,
+// are not available in the kaniko container, so the registries they serve are skipped with a warning
+func (k *Kaniko) mountHostDockerConfig(ctx context.Context, jobName string, job *batchv1.Job) (*batchv1.Job, error) {
+	path, err := HostDockerConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, ErrReadingDockerConfig.Wrap(err)
+	}
+	var config dockerConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		// the error of the parser may quote the content of the file, so it is not wrapped
+		return nil, ErrParsingDockerConfig.Wrap(fmt.Errorf("path: %s", path))
+	}
+
+	registries := make([]string, 0, len(config.Auths))
+	auths := make(map[string]dockerAuth, len(config.Auths))
+	for registry, auth := range config.Auths {
+		if auth == (dockerAuth{}) {
+			// the credentials are stored by a credential helper
+			continue
+		}
+		auths[registry] = auth
+		registries = append(registries, registry)
+	}
+	sort.Strings(registries)
+	if config.CredsStore != "" || len(config.CredHelpers) != 0 {
+		logrus.Warnf("The credential helpers of the docker config %s are not available to kaniko, "+
+			"only the credentials of the registries %v are mounted", path, registries)
+	}
+	if len(auths) == 0 {
+		return nil, ErrNoRegistryCredentials.Wrap(fmt.Errorf("path: %s", path))
+	}
+
+	content, err := json.Marshal(dockerConfig{Auths: auths})
+	if err != nil {
+		return nil, ErrParsingDockerConfig.Wrap(fmt.Errorf("path: %s", path))
+	}
+	secretName := dockerConfigSecretName(jobName)
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName,
+			Namespace: k.K8sNamespace,
+		},
+		Type: v1.SecretTypeOpaque,
+		Data: map[string][]byte{dockerConfigFile: content},
+	}
+	if _, err := k.K8sClientset.CoreV1().Secrets(k.K8sNamespace).Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+		return nil, ErrCreatingDockerConfigSecret.Wrap(err)
+	}
+	logrus.Debugf("Created secret %s with the credentials of the registries %v from %s", secretName, registries, path)
+
+	job.Spec.Template.Spec.Volumes = append(job.Spec.Template.Spec.Volumes, v1.Volume{
+		Name: dockerConfigVolName,
+		VolumeSource: v1.VolumeSource{
+			Secret: &v1.SecretVolumeSource{SecretName: secretName},
+		},
+	})
+	job.Spec.Template.Spec.Containers[0].VolumeMounts = append(job.Spec.Template.Spec.Containers[0].VolumeMounts, v1.VolumeMount{
+		Name:      dockerConfigVolName,
+		MountPath: dockerConfigDir,
+		ReadOnly:  true,
+	})
+	return job, nil
+}
+
+// deleteDockerConfigSecret deletes the secret with the docker config of the build job, if it was created
+func (k *Kaniko) deleteDockerConfigSecret(ctx context.Context, jobName string) error {
+	if !k.HostDockerConfig {
+		return nil
+	}
+	err := k.K8sClientset.CoreV1().Secrets(k.K8sNamespace).Delete(ctx, dockerConfigSecretName(jobName), metav1.DeleteOptions{})
+	if err != nil && !apierrs.IsNotFound(err) {
+		return ErrDeletingDockerConfigSecret.Wrap(err)
+	}
+	return nil
+}
@@ -50,4 +50,15 @@ var (
 	ErrMinioDeploymentFailed            = &Error{Code: "MinioDeploymentFailed", Message: "Minio deployment failed"}
 	ErrDeletingMinioContent             = &Error{Code: "DeletingMinioContent", Message: "error deleting Minio content"}
 	ErrParsingQuantity                  = &Error{Code: "ParsingQuantity", Message: "error parsing quantity"}
+	ErrInvalidVerbosity                 = &Error{Code: "InvalidVerbosity", Message: "invalid verbosity, must be one of trace, debug, info or warn"}
+	ErrExportingImage                   = &Error{Code: "ExportingImage", Message: "error exporting image"}
+	ErrNoExportedImage                  = &Error{Code: "NoExportedImage", Message: "no exported image, build with export options and pass the export name of the build result"}
+	ErrManagedExtraArg                  = &Error{Code: "ManagedExtraArg", Message: "extra arg is managed by the builder options"}
+	ErrCacheMountsNotSupported          = &Error{Code: "CacheMountsNotSupported", Message: "cache mounts in RUN instructions are not supported by kaniko, use the docker builder"}
+	ErrInvalidCompression               = &Error{Code: "InvalidCompression", Message: "invalid build context compression, must be one of gzip or zstd"}
+	ErrReadingDockerConfig              = &Error{Code: "ReadingDockerConfig", Message: "error reading the docker config of the host"}
+	ErrParsingDockerConfig              = &Error{Code: "ParsingDockerConfig", Message: "error parsing the docker config of the host"}
+	ErrNoRegistryCredentials            = &Error{Code: "NoRegistryCredentials", Message: "the docker config of the host has no registry credentials usable by kaniko"}
+	ErrCreatingDockerConfigSecret       = &Error{Code: "CreatingDockerConfigSecret", Message: "error creating the secret with the docker config"}
+	ErrDeletingDockerConfigSecret       = &Error{Code: "DeletingDockerConfigSecret", Message: "error deleting the secret with the docker config"}
 )
@@ -6,10 +6,15 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"io"
+	"strings"
+	"time"
 
 	"github.com/celestiaorg/knuu/pkg/builder"
 	"github.com/celestiaorg/knuu/pkg/minio"
 	"github.com/celestiaorg/knuu/pkg/names"
+	"github.com/celestiaorg/knuu/pkg/registry"
+	"github.com/sirupsen/logrus"
 	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -24,9 +29,28 @@ const (
 
 	DefaultParallelism  = int32(1)
 	DefaultBackoffLimit = int32(5)
+	DefaultVerbosity    = builder.VerbosityInfo
+	// DefaultJobTTL leaves the time to read the logs of a finished build job before Kubernetes deletes it
+	DefaultJobTTL = 10 * time.Minute
 
 	MinioBucketName  = "kaniko"
 	EphemeralStorage = "10Gi"
+
+	exportContainerName = "export-container"
+	exportDir           = "/export"
+	exportVolName       = "export"
+	exportTarPath       = exportDir + "/image.tar"
+	// curlImage downloads the build context and uploads the exported image, it is pinned so that the builds
+	// do not depend on what the latest tag points to
+	curlImage = "curlimages/curl:8.10.1"
+	// zstdImage downloads and extracts the zstd archives of the build context, the curl image has no zstd
+	// and the base image of arch linux ships curl, zstd and tar without installing packages on every build
+	zstdImage = "archlinux:base-20241006.0.269705"
+
+	workspaceDir     = "/workspace"
+	workspaceVolName = "workspace"
+
+	cacheDirVolName = "cache-dir"
 )
 
 type Kaniko struct {
@@ -34,45 +58,84 @@ type Kaniko struct {
 	K8sNamespace string
 	Minio        *minio.Minio // Minio service to store the build context if it's a directory
 	ContentName  string       // Name of the content pushed to Minio
+	// Compression of the archive of the build context pushed to Minio, defaults to CompressionGzip
+	Compression Compression
+	// HostDockerConfig mounts the registry credentials of the docker config of the host, see HostDockerConfigPath,
+	// into the kaniko pod, e.g. to push from CI to the registries the runner is logged in to
+	HostDockerConfig bool
+	// JobTTL is the time after which Kubernetes deletes a finished build job along with its pod, defaults to DefaultJobTTL
+	// The jobs are deleted once built anyway, the TTL cleans up the jobs left over, e.g. by an interrupted test run
+	// A negative TTL keeps the jobs until they are deleted explicitly
+	JobTTL time.Duration
 }
 
 var _ builder.Builder = &Kaniko{}
 
 func (k *Kaniko) Build(ctx context.Context, b *builder.BuilderOptions) (logs string, err error) {
+	result, err := k.BuildWithResult(ctx, b)
+	if result == nil {
+		return "", err
+	}
+	return result.Logs, err
+}
+
+// BuildWithResult builds the image like Build, and reports which instructions were taken from the cache,
+// e.g. to find the instruction that broke the cache when a build is slower than expected, see builder.BuildResult.
+// The result is also returned if the build fails, as long as the logs of kaniko could be read.
+func (k *Kaniko) BuildWithResult(ctx context.Context, b *builder.BuilderOptions) (_ *builder.BuildResult, err error) {
 	job, err := k.prepareJob(ctx, b)
 	if err != nil {
-		return "", ErrPreparingJob.Wrap(err)
+		return nil, ErrPreparingJob.Wrap(err)
 	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		// the credentials of the registries must not outlive a failed build, even if the context is done
+		if err := k.deleteDockerConfigSecret(context.WithoutCancel(ctx), job.Name); err != nil {
+			logrus.Warnf("Failed to delete the docker config of job %s: %v", job.Name, err)
+		}
+	}()
 
 	cJob, err := k.K8sClientset.BatchV1().Jobs(k.K8sNamespace).Create(ctx, job, metav1.CreateOptions{})
 	if err != nil {
-		return "", ErrCreatingJob.Wrap(err)
+		return nil, ErrCreatingJob.Wrap(err)
 	}
 
 	kJob, err := k.waitForJobCompletion(ctx, cJob)
 	if err != nil {
-		return "", ErrWaitingJobCompletion.Wrap(err)
+		return nil, ErrWaitingJobCompletion.Wrap(err)
 	}
 
 	pod, err := k.firstPodFromJob(ctx, kJob)
 	if err != nil {
-		return "", ErrGettingPodFromJob.Wrap(err)
+		return nil, ErrGettingPodFromJob.Wrap(err)
 	}
 
-	logs, err = k.containerLogs(ctx, pod)
+	logs, err := k.containerLogs(ctx, pod)
 	if err != nil {
-		return "", ErrGettingContainerLogs.Wrap(err)
+		return nil, ErrGettingContainerLogs.Wrap(err)
 	}
 
 	if err := k.cleanup(ctx, kJob); err != nil {
-		return "", ErrCleaningUp.Wrap(err)
+		return nil, ErrCleaningUp.Wrap(err)
 	}
 
 	if kJob.Status.Succeeded == 0 {
-		return logs, ErrBuildFailed
+		return builder.NewBuildResult(logs), builder.NewBuildError(b.Destination, logs, kanikoExitCode(pod), ErrBuildFailed)
 	}
 
-	return logs, nil
+	result := builder.NewBuildResult(logs)
+	if b.Export != nil {
+		result.ExportName = exportTarName(job.Name)
+	}
+	return result, nil
+}
+
+// ImageExists checks if the image exists in the registry it is pushed to.
+// The registry is queried from where knuu runs, so it must be reachable from outside the cluster.
+func (k *Kaniko) ImageExists(ctx context.Context, ref string) (bool, error) {
+	return registry.ImageExists(ctx, ref)
 }
 
 func (k *Kaniko) waitForJobCompletion(ctx context.Context, job *batchv1.Job) (*batchv1.Job, error) {
@@ -126,8 +189,16 @@ func (k *Kaniko) containerLogs(ctx context.Context, pod *v1.Pod) (string, error)
 		return "", ErrNoContainersFound.Wrap(fmt.Errorf("pod: %s", pod.Name))
 	}
 
+	// When exporting, kaniko runs as an init container, so the logs are read by name
+	containerName := pod.Spec.Containers[0].Name
+	for _, c := range pod.Spec.InitContainers {
+		if c.Name == kanikoContainerName {
+			containerName = c.Name
+		}
+	}
+
 	logOptions := v1.PodLogOptions{
-		Container: pod.Spec.Containers[0].Name,
+		Container: containerName,
 	}
 
 	req := k.K8sClientset.CoreV1().Pods(k.K8sNamespace).GetLogs(pod.Name, &logOptions)
@@ -139,6 +210,19 @@ func (k *Kaniko) containerLogs(ctx context.Context, pod *v1.Pod) (string, error)
 	return string(logs), nil
 }
 
+// kanikoExitCode returns the exit code of the kaniko container, or 0 if it did not terminate
+func kanikoExitCode(pod *v1.Pod) int {
+	// When exporting, kaniko runs as an init container
+	for _, statuses := range [][]v1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for _, status := range statuses {
+			if status.Name == kanikoContainerName && status.State.Terminated != nil {
+				return int(status.State.Terminated.ExitCode)
+			}
+		}
+	}
+	return 0
+}
+
 func (k *Kaniko) cleanup(ctx context.Context, job *batchv1.Job) error {
 	err := k.K8sClientset.BatchV1().Jobs(k.K8sNamespace).
 		Delete(ctx, job.Name, metav1.DeleteOptions{
@@ -157,6 +241,10 @@ func (k *Kaniko) cleanup(ctx context.Context, job *batchv1.Job) error {
 		return ErrDeletingPods.Wrap(err)
 	}
 
+	if err := k.deleteDockerConfigSecret(ctx, job.Name); err != nil {
+		return err
+	}
+
 	// Delete the content pushed to Minio
 	if k.ContentName != "" {
 		if err := k.Minio.DeleteFromMinio(ctx, k.ContentName, MinioBucketName); err != nil {
@@ -167,6 +255,48 @@ func (k *Kaniko) cleanup(ctx context.Context, job *batchv1.Job) error {
 	return nil
 }
 
+// managedFlags are the kaniko flags set from the builder options, which cannot be passed as extra args
+var managedFlags = map[string]bool{
+	"context":                  true,
+	"destination":              true,
+	"verbosity":                true,
+	"cache":                    true,
+	"cache-dir":                true,
+	"cache-repo":               true,
+	"build-arg":                true,
+	"reproducible":             true,
+	"single-snapshot":          true,
+	"tar-path":                 true,
+	"no-push":                  true,
+	"insecure-registry":        true,
+	"skip-tls-verify-registry": true,
+}
+
+// validateExtraArgs checks that the extra args do not override flags managed by the builder options
+func validateExtraArgs(args []string) error {
+	for _, arg := range args {
+		flag, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if strings.HasPrefix(arg, "-") && managedFlags[flag] {
+			return ErrManagedExtraArg.Wrap(fmt.Errorf("flag: --%s", flag))
+		}
+	}
+	return nil
+}
+
+// jobTTLSeconds returns the TTL of the finished build jobs in seconds, rounded up so a short TTL does not delete
+// the job before its logs are read, or nil if the jobs are kept
+func (k *Kaniko) jobTTLSeconds() *int32 {
+	ttl := k.JobTTL
+	if ttl == 0 {
+		ttl = DefaultJobTTL
+	}
+	if ttl < 0 {
+		return nil
+	}
+	seconds := int32((ttl + time.Second - 1) / time.Second)
+	return &seconds
+}
+
 func (k *Kaniko) prepareJob(ctx context.Context, b *builder.BuilderOptions) (*batchv1.Job, error) {
 	jobName, err := names.NewRandomK8(kanikoJobNamePrefix)
 	if err != nil {
@@ -178,6 +308,28 @@ func (k *Kaniko) prepareJob(ctx context.Context, b *builder.BuilderOptions) (*ba
 		return nil, ErrParsingQuantity.Wrap(err)
 	}
 
+	verbosity := b.Verbosity
+	if verbosity == "" {
+		verbosity = DefaultVerbosity
+	}
+	if !verbosity.IsValid() {
+		return nil, ErrInvalidVerbosity.Wrap(fmt.Errorf("verbosity: %s", verbosity))
+	}
+	if len(b.CacheMounts) != 0 {
+		// kaniko ignores the --mount flag of RUN, so the dependencies would be installed from scratch on every build
+		return nil, ErrCacheMountsNotSupported.Wrap(fmt.Errorf("cache mounts: %v", b.CacheMounts))
+	}
+	compression := k.Compression
+	if compression == "" {
+		compression = DefaultCompression
+	}
+	if !compression.IsValid() {
+		return nil, ErrInvalidCompression.Wrap(fmt.Errorf("compression: %s", compression))
+	}
+	if err := validateExtraArgs(b.ExtraArgs); err != nil {
+		return nil, err
+	}
+
 	parallelism := DefaultParallelism
 	backoffLimit := DefaultBackoffLimit
 	job := &batchv1.Job{
@@ -185,8 +337,9 @@ func (k *Kaniko) prepareJob(ctx context.Context, b *builder.BuilderOptions) (*ba
 			Name: jobName,
 		},
 		Spec: batchv1.JobSpec{
-			Parallelism:  &parallelism,  // Set parallelism to 1 to ensure only one Pod
-			BackoffLimit: &backoffLimit, // Retry the Job at most 5 times
+			Parallelism:             &parallelism,  // Set parallelism to 1 to ensure only one Pod
+			BackoffLimit:            &backoffLimit, // Retry the Job at most 5 times
+			TTLSecondsAfterFinished: k.jobTTLSeconds(),
 			Template: v1.PodTemplateSpec{
 				Spec: v1.PodSpec{
 					Containers: []v1.Container{
@@ -200,7 +353,7 @@ func (k *Kaniko) prepareJob(ctx context.Context, b *builder.BuilderOptions) (*ba
 
 								// TODO: we might need to add some options to get the auth token for the registry
 								"--destination=" + b.Destination,
-								// "--verbosity=debug", // log level
+								"--verbosity=" + string(verbosity), // log level
 							},
 							Resources: v1.ResourceRequirements{
 								Requests: v1.ResourceList{
@@ -216,7 +369,7 @@ func (k *Kaniko) prepareJob(ctx context.Context, b *builder.BuilderOptions) (*ba
 	}
 
 	if builder.IsDirContext(b.BuildContext) {
-		job, err = k.mountDir(ctx, b.BuildContext, job)
+		job, err = k.mountDir(ctx, b.BuildContext, compression, job)
 		if err != nil {
 			return nil, ErrMountingDir.Wrap(err)
 		}
@@ -227,6 +380,7 @@ func (k *Kaniko) prepareJob(ctx context.Context, b *builder.BuilderOptions) (*ba
 		cacheArgs := []string{"--cache=true"}
 		if b.Cache.Dir != "" {
 			cacheArgs = append(cacheArgs, "--cache-dir="+b.Cache.Dir)
+			job = mountCacheDir(job, b.Cache.Dir)
 		}
 		if b.Cache.Repo != "" {
 			cacheArgs = append(cacheArgs, "--cache-repo="+b.Cache.Repo)
@@ -234,26 +388,158 @@ func (k *Kaniko) prepareJob(ctx context.Context, b *builder.BuilderOptions) (*ba
 		job.Spec.Template.Spec.Containers[0].Args = append(job.Spec.Template.Spec.Containers[0].Args, cacheArgs...)
 	}
 
+	for _, arg := range b.BuildArgList() {
+		job.Spec.Template.Spec.Containers[0].Args = append(job.Spec.Template.Spec.Containers[0].Args, "--build-arg="+arg)
+	}
+
+	if b.Reproducible {
+		job.Spec.Template.Spec.Containers[0].Args = append(job.Spec.Template.Spec.Containers[0].Args, "--reproducible")
+	}
+
+	for _, host := range b.InsecureRegistries {
+		job.Spec.Template.Spec.Containers[0].Args = append(job.Spec.Template.Spec.Containers[0].Args,
+			"--insecure-registry="+host, "--skip-tls-verify-registry="+host)
+	}
+
 	// Add extra args
 	job.Spec.Template.Spec.Containers[0].Args = append(job.Spec.Template.Spec.Containers[0].Args, b.Args...)
 
+	if len(b.ExtraArgs) != 0 {
+		logrus.Warnf("Passing unsupported extra args to kaniko: %v", b.ExtraArgs)
+		job.Spec.Template.Spec.Containers[0].Args = append(job.Spec.Template.Spec.Containers[0].Args, b.ExtraArgs...)
+	}
+
+	if b.Squash {
+		// kaniko takes a single snapshot of the filesystem at the end of each stage,
+		// added last so the cache warmup of multi-stage builds runs with all the other args
+		job = addSquash(job, b)
+	}
+
+	if b.Export != nil {
+		job, err = k.exportImage(ctx, jobName, b.Export, job)
+		if err != nil {
+			return nil, ErrExportingImage.Wrap(err)
+		}
+	}
+
+	// the secret with the credentials is created last, so that it is not left behind if the job cannot be prepared
+	if k.HostDockerConfig {
+		job, err = k.mountHostDockerConfig(ctx, jobName, job)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return job, nil
 }
 
+// mountCacheDir mounts the cache directory from the node, so the base images cached by kaniko persist across the builds
+// running on the same node. The directory is created if it does not exist, owned by root like the kaniko container
+func mountCacheDir(job *batchv1.Job, dir string) *batchv1.Job {
+	hostPathType := v1.HostPathDirectoryOrCreate
+	job.Spec.Template.Spec.Volumes = append(job.Spec.Template.Spec.Volumes, v1.Volume{
+		Name: cacheDirVolName,
+		VolumeSource: v1.VolumeSource{
+			HostPath: &v1.HostPathVolumeSource{Path: dir, Type: &hostPathType},
+		},
+	})
+	job.Spec.Template.Spec.Containers[0].VolumeMounts = append(job.Spec.Template.Spec.Containers[0].VolumeMounts, v1.VolumeMount{
+		Name:      cacheDirVolName,
+		MountPath: dir,
+	})
+	return job
+}
+
+// exportImage configures the job to export the built image as a tarball to Minio
+func (k *Kaniko) exportImage(ctx context.Context, jobName string, opts *builder.ExportOptions, job *batchv1.Job) (*batchv1.Job, error) {
+	if k.Minio == nil {
+		return nil, ErrMinioNotConfigured
+	}
+
+	if err := k.Minio.DeployMinio(ctx); err != nil {
+		return nil, ErrMinioDeploymentFailed.Wrap(err)
+	}
+
+	uploadURL, err := k.Minio.GetMinioUploadURL(ctx, exportTarName(jobName), MinioBucketName)
+	if err != nil {
+		return nil, err
+	}
+	return addExportContainer(job, opts, uploadURL), nil
+}
+
+// exportTarName returns the name in Minio of the image tarball exported by the build job,
+// which is unique to the build so that concurrent builds do not overwrite each other's export
+func exportTarName(jobName string) string {
+	return jobName + ".tar"
+}
+
+// addExportContainer makes kaniko write the image to a shared volume as an init container,
+// then the export container uploads the tarball to the given URL
+func addExportContainer(job *batchv1.Job, opts *builder.ExportOptions, uploadURL string) *batchv1.Job {
+	spec := &job.Spec.Template.Spec
+
+	kaniko := spec.Containers[0]
+	kaniko.Args = append(kaniko.Args, "--tar-path="+exportTarPath)
+	if opts.NoPush {
+		kaniko.Args = append(kaniko.Args, "--no-push")
+	}
+	kaniko.VolumeMounts = append(kaniko.VolumeMounts, v1.VolumeMount{
+		Name:      exportVolName,
+		MountPath: exportDir,
+	})
+	spec.InitContainers = append(spec.InitContainers, kaniko)
+
+	spec.Containers = []v1.Container{
+		{
+			Name:    exportContainerName,
+			Image:   curlImage,
+			Command: []string{"/bin/sh", "-c"},
+			Args: []string{
+				fmt.Sprintf("curl -sSf -T %s '%s'", exportTarPath, uploadURL),
+			},
+			VolumeMounts: []v1.VolumeMount{
+				{
+					Name:      exportVolName,
+					MountPath: exportDir,
+				},
+			},
+		},
+	}
+
+	spec.Volumes = append(spec.Volumes, v1.Volume{
+		Name: exportVolName,
+		VolumeSource: v1.VolumeSource{
+			EmptyDir: &v1.EmptyDirVolumeSource{},
+		},
+	})
+
+	return job
+}
+
+// DownloadExportedImage writes the image tarball exported by a build to the given writer, given the export name
+// of the result of the build, see builder.BuildResult. The tarball can be loaded with `docker load`
+func (k *Kaniko) DownloadExportedImage(ctx context.Context, exportName string, w io.Writer) error {
+	if exportName == "" {
+		return ErrNoExportedImage
+	}
+	if k.Minio == nil {
+		return ErrMinioNotConfigured
+	}
+	return k.Minio.PullFromMinio(ctx, w, exportName, MinioBucketName)
+}
+
 // mountDir mounts the build context directory to the Kaniko container
 // Since we cannot really mount a local directory to a k8s Pod,
-// we create a tar.gz archive of the directory and upload it to Minio
+// we create a compressed tar archive of the directory and upload it to Minio
 // then we download it from the init container into a shared volume which is also mounted
-// to the Kaniko container
-// As kaniko also supports directly tar.gz archives, no need to extract it,
-// we just need to set the context to tar://<path-to-archive>
-func (k *Kaniko) mountDir(ctx context.Context, bCtx string, job *batchv1.Job) (*batchv1.Job, error) {
+// to the Kaniko container, see addContextDownload
+func (k *Kaniko) mountDir(ctx context.Context, bCtx string, compression Compression, job *batchv1.Job) (*batchv1.Job, error) {
 	if k.Minio == nil {
 		return nil, ErrMinioNotConfigured
 	}
 
-	// Create the tar.gz archive
-	archiveData, err := createTarGz(builder.GetDirFromBuildContext(bCtx))
+	// Create the compressed archive
+	archiveData, err := createArchive(builder.GetDirFromBuildContext(bCtx), compression)
 	if err != nil {
 		return nil, err
 	}
@@ -276,19 +562,24 @@ func (k *Kaniko) mountDir(ctx context.Context, bCtx string, job *batchv1.Job) (*
 		return nil, err
 	}
 
-	const (
-		workspaceDir     = "/workspace"
-		workspaceVolName = "workspace"
-		archiveFilePath  = workspaceDir + "/archive.tar.gz"
-	)
+	return addContextDownload(job, s3URL, compression), nil
+}
+
+// addContextDownload configures the job to download the archive of the build context from the given URL
+// in an init container, into a volume shared with the Kaniko container
+// As kaniko supports tar.gz archives directly, a gzip archive is not extracted, the context is set to tar://<path-to-archive>
+// Other archives are decompressed and extracted by the init container, the context is set to dir://<path-to-directory>
+func addContextDownload(job *batchv1.Job, url string, compression Compression) *batchv1.Job {
+	const contextDir = workspaceDir + "/context"
+	archiveFilePath := workspaceDir + "/archive.tar" + compression.extension()
 
-	// Configure the init container to download the tar.gz archive first
+	// Configure the init container to download the archive first
 	initContainer := v1.Container{
 		Name:    "download-container",
-		Image:   "curlimages/curl:latest",
+		Image:   curlImage,
 		Command: []string{"/bin/sh", "-c"},
 		Args: []string{
-			fmt.Sprintf("curl -L -o %s '%s'", archiveFilePath, s3URL),
+			fmt.Sprintf("curl -L -o %s '%s'", archiveFilePath, url),
 		},
 		VolumeMounts: []v1.VolumeMount{
 			{
@@ -297,6 +588,15 @@ func (k *Kaniko) mountDir(ctx context.Context, bCtx string, job *batchv1.Job) (*
 			},
 		},
 	}
+	buildContext := "tar://" + archiveFilePath
+	if compression == CompressionZstd {
+		initContainer.Image = zstdImage
+		initContainer.Args = []string{
+			fmt.Sprintf("curl -fL -o %s '%s' && mkdir -p %s && zstd -dc %s | tar -x -C %s",
+				archiveFilePath, url, contextDir, archiveFilePath, contextDir),
+		}
+		buildContext = "dir://" + contextDir
+	}
 	job.Spec.Template.Spec.InitContainers = append(job.Spec.Template.Spec.InitContainers, initContainer)
 
 	job.Spec.Template.Spec.Volumes = append(job.Spec.Template.Spec.Volumes, v1.Volume{
@@ -310,8 +610,8 @@ func (k *Kaniko) mountDir(ctx context.Context, bCtx string, job *batchv1.Job) (*
 		MountPath: workspaceDir,
 	})
 
-	// Replace the context with the tar.gz archive
-	job.Spec.Template.Spec.Containers[0].Args = append(job.Spec.Template.Spec.Containers[0].Args, "--context=tar://"+archiveFilePath)
+	// Replace the context with the downloaded one
+	job.Spec.Template.Spec.Containers[0].Args = append(job.Spec.Template.Spec.Containers[0].Args, "--context="+buildContext)
 
-	return job, nil
+	return job
 }
@@ -2,6 +2,12 @@ package kaniko
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -11,8 +17,11 @@ import (
 	"github.com/stretchr/testify/require"
 	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 const (
@@ -93,6 +102,57 @@ func TestKanikoBuilder(t *testing.T) {
 
 }
 
+func TestKanikoBuildFailedJob(t *testing.T) {
+	k8sCS := fake.NewSimpleClientset()
+	kb := &Kaniko{
+		K8sClientset: k8sCS,
+		K8sNamespace: k8sNamespace,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var (
+		err error
+		wg  = &sync.WaitGroup{}
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err = kb.Build(ctx, &builder.BuilderOptions{
+			ImageName:    "test-image",
+			BuildContext: "git://github.com/mojtaba-esk/sample-docker",
+			Destination:  "registry.example.com/test-image:latest",
+		})
+	}()
+
+	// Simulate the failure of the Job after a short delay
+	time.Sleep(2 * time.Second)
+	jobs, listErr := k8sCS.BatchV1().Jobs(k8sNamespace).List(ctx, metav1.ListOptions{})
+	require.NoError(t, listErr)
+	for _, j := range jobs.Items {
+		pod := createPodFromJob(&j)
+		pod.Status.ContainerStatuses = []v1.ContainerStatus{{
+			Name:  kanikoContainerName,
+			State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1}},
+		}}
+		_, createErr := k8sCS.CoreV1().Pods(k8sNamespace).Create(ctx, pod, metav1.CreateOptions{})
+		require.NoError(t, createErr)
+
+		j.Status.Failed = 1
+		_, updateErr := k8sCS.BatchV1().Jobs(k8sNamespace).Update(ctx, &j, metav1.UpdateOptions{})
+		require.NoError(t, updateErr)
+	}
+
+	wg.Wait()
+
+	assert.ErrorIs(t, err, ErrBuildFailed)
+	var buildErr *builder.BuildError
+	require.True(t, errors.As(err, &buildErr), "a failed build should return a BuildError")
+	assert.Equal(t, "registry.example.com/test-image:latest", buildErr.Image)
+	assert.Equal(t, 1, buildErr.ExitCode)
+	assert.NotEmpty(t, buildErr.Logs)
+}
+
 func completeAllJobInFakeClientset(t *testing.T, clientset *fake.Clientset, namespace string) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -160,3 +220,547 @@ func TestGetDefaultCacheOptions(t *testing.T) {
 		})
 	}
 }
+
+func TestPrepareJobVerbosity(t *testing.T) {
+	t.Parallel()
+
+	kb := &Kaniko{
+		K8sClientset: fake.NewSimpleClientset(),
+		K8sNamespace: k8sNamespace,
+	}
+
+	tt := []struct {
+		name          string
+		verbosity     builder.Verbosity
+		expectedArg   string
+		expectedError bool
+	}{
+		{"Default", "", "--verbosity=info", false},
+		{"Debug", builder.VerbosityDebug, "--verbosity=debug", false},
+		{"Trace", builder.VerbosityTrace, "--verbosity=trace", false},
+		{"Invalid", "loud", "", true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			job, err := kb.prepareJob(context.Background(), &builder.BuilderOptions{
+				ImageName:    "test-image",
+				BuildContext: "git://example.com/repo",
+				Destination:  "registry.example.com/test-image:latest",
+				Verbosity:    tc.verbosity,
+			})
+
+			if tc.expectedError {
+				assert.ErrorIs(t, err, ErrInvalidVerbosity)
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, job.Spec.Template.Spec.Containers[0].Args, tc.expectedArg)
+		})
+	}
+}
+
+func TestPrepareJobTTL(t *testing.T) {
+	t.Parallel()
+
+	opts := &builder.BuilderOptions{
+		ImageName:    "test-image",
+		BuildContext: "git://example.com/repo",
+		Destination:  "registry.example.com/test-image:latest",
+	}
+	ttl := func(jobTTL time.Duration) *int32 {
+		kb := &Kaniko{
+			K8sClientset: fake.NewSimpleClientset(),
+			K8sNamespace: k8sNamespace,
+			JobTTL:       jobTTL,
+		}
+		job, err := kb.prepareJob(context.Background(), opts)
+		require.NoError(t, err)
+		return job.Spec.TTLSecondsAfterFinished
+	}
+
+	expected := int32(DefaultJobTTL / time.Second)
+	assert.Equal(t, &expected, ttl(0), "the jobs should be reaped after the default TTL")
+	expected = 90
+	assert.Equal(t, &expected, ttl(90*time.Second))
+	expected = 1
+	assert.Equal(t, &expected, ttl(100*time.Millisecond), "the TTL should be rounded up to keep the job")
+	assert.Nil(t, ttl(-1), "a negative TTL should keep the jobs")
+}
+
+func TestPrepareJobExport(t *testing.T) {
+	t.Parallel()
+
+	buildOptions := &builder.BuilderOptions{
+		ImageName:    "test-image",
+		BuildContext: "git://example.com/repo",
+		Destination:  "registry.example.com/test-image:latest",
+		Export:       &builder.ExportOptions{NoPush: true},
+	}
+
+	t.Run("MinioNotConfigured", func(t *testing.T) {
+		kb := &Kaniko{
+			K8sClientset: fake.NewSimpleClientset(),
+			K8sNamespace: k8sNamespace,
+		}
+		_, err := kb.prepareJob(context.Background(), buildOptions)
+		assert.ErrorIs(t, err, ErrExportingImage)
+		assert.ErrorContains(t, err, ErrMinioNotConfigured.Error())
+	})
+
+	t.Run("ExportContainer", func(t *testing.T) {
+		kb := &Kaniko{
+			K8sClientset: fake.NewSimpleClientset(),
+			K8sNamespace: k8sNamespace,
+		}
+		opts := *buildOptions
+		opts.Export = nil
+		job, err := kb.prepareJob(context.Background(), &opts)
+		require.NoError(t, err)
+
+		job = addExportContainer(job, buildOptions.Export, "http://minio/upload")
+		spec := job.Spec.Template.Spec
+
+		require.Len(t, spec.InitContainers, 1)
+		assert.Equal(t, kanikoContainerName, spec.InitContainers[0].Name)
+		assert.Contains(t, spec.InitContainers[0].Args, "--tar-path="+exportTarPath)
+		assert.Contains(t, spec.InitContainers[0].Args, "--no-push")
+
+		require.Len(t, spec.Containers, 1)
+		assert.Equal(t, exportContainerName, spec.Containers[0].Name)
+		assert.Contains(t, spec.Containers[0].Args[0], "http://minio/upload")
+		assert.Len(t, spec.Volumes, 1)
+		assert.Equal(t, curlImage, spec.Containers[0].Image)
+	})
+
+	t.Run("ExportName", func(t *testing.T) {
+		// each build exports its own tarball, so concurrent builds with the same builder keep their exports apart
+		kb := &Kaniko{
+			K8sClientset: fake.NewSimpleClientset(),
+			K8sNamespace: k8sNamespace,
+		}
+		opts := *buildOptions
+		opts.Export = nil
+		first, err := kb.prepareJob(context.Background(), &opts)
+		require.NoError(t, err)
+		second, err := kb.prepareJob(context.Background(), &opts)
+		require.NoError(t, err)
+		assert.NotEqual(t, exportTarName(first.Name), exportTarName(second.Name))
+	})
+}
+
+func TestPrepareJobReproducible(t *testing.T) {
+	t.Parallel()
+
+	kb := &Kaniko{
+		K8sClientset: fake.NewSimpleClientset(),
+		K8sNamespace: k8sNamespace,
+	}
+
+	for _, reproducible := range []bool{true, false} {
+		job, err := kb.prepareJob(context.Background(), &builder.BuilderOptions{
+			ImageName:    "test-image",
+			BuildContext: "git://example.com/repo",
+			Destination:  "registry.example.com/test-image:latest",
+			Reproducible: reproducible,
+		})
+		require.NoError(t, err)
+
+		args := job.Spec.Template.Spec.Containers[0].Args
+		if reproducible {
+			assert.Contains(t, args, "--reproducible")
+		} else {
+			assert.NotContains(t, args, "--reproducible")
+		}
+	}
+}
+
+func TestPrepareJobSquash(t *testing.T) {
+	t.Parallel()
+
+	kb := &Kaniko{
+		K8sClientset: fake.NewSimpleClientset(),
+		K8sNamespace: k8sNamespace,
+	}
+
+	for _, squash := range []bool{true, false} {
+		job, err := kb.prepareJob(context.Background(), &builder.BuilderOptions{
+			ImageName:    "test-image",
+			BuildContext: "git://example.com/repo",
+			Destination:  "registry.example.com/test-image:latest",
+			Squash:       squash,
+		})
+		require.NoError(t, err)
+
+		args := job.Spec.Template.Spec.Containers[0].Args
+		if squash {
+			assert.Contains(t, args, "--single-snapshot")
+		} else {
+			assert.NotContains(t, args, "--single-snapshot")
+		}
+	}
+}
+
+func TestAddSquashMultiStage(t *testing.T) {
+	t.Parallel()
+
+	newJob := func() *batchv1.Job {
+		return &batchv1.Job{Spec: batchv1.JobSpec{Template: v1.PodTemplateSpec{Spec: v1.PodSpec{
+			Containers: []v1.Container{{Name: kanikoContainerName, Args: []string{"--context=dir:///workspace/context", "--cache=true"}}},
+		}}}}
+	}
+	contextWith := func(t *testing.T, dockerfile string) string {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte(dockerfile), 0644))
+		return builder.DirContext{Path: dir}.BuildContext()
+	}
+	multiStage := "FROM golang:1.22 AS deps\nRUN go mod download\nFROM --platform=linux/amd64 golang:1.22 as build\nRUN go build ./...\n" +
+		"FROM alpine:3.20\nCOPY --from=build /app /app\n"
+
+	t.Run("MultiStage", func(t *testing.T) {
+		job := addSquash(newJob(), &builder.BuilderOptions{
+			BuildContext: contextWith(t, multiStage),
+			Cache:        &builder.CacheOptions{Enabled: true},
+			Squash:       true,
+		})
+
+		// the final image is squashed
+		spec := job.Spec.Template.Spec
+		assert.Contains(t, spec.Containers[0].Args, "--single-snapshot")
+
+		// the build stages are built first without squashing, so their layers are cached
+		require.Len(t, spec.InitContainers, 1)
+		warmup := spec.InitContainers[0]
+		assert.Equal(t, cacheWarmupContainerName, warmup.Name)
+		assert.Equal(t, []string{"--context=dir:///workspace/context", "--cache=true", "--target=build", "--no-push"}, warmup.Args)
+	})
+
+	tt := []struct {
+		name       string
+		dockerfile string
+		cache      *builder.CacheOptions
+	}{
+		{"SingleStage", "FROM alpine:3.20\nRUN apk add curl\n", &builder.CacheOptions{Enabled: true}},
+		{"UnnamedBuildStage", "FROM golang:1.22\nRUN go build\nFROM alpine:3.20\nCOPY --from=0 /app /app\n", &builder.CacheOptions{Enabled: true}},
+		{"NoCache", multiStage, nil},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			job := addSquash(newJob(), &builder.BuilderOptions{BuildContext: contextWith(t, tc.dockerfile), Cache: tc.cache, Squash: true})
+			assert.Empty(t, job.Spec.Template.Spec.InitContainers)
+			assert.Contains(t, job.Spec.Template.Spec.Containers[0].Args, "--single-snapshot")
+		})
+	}
+}
+
+// TestSquashLayers builds an image with and without squashing in a real cluster and compares their layers.
+// It runs only when KNUU_TEST_REGISTRY is set to a plain HTTP registry reachable from the cluster and from the tests,
+// using the cluster of the kubeconfig and the namespace KNUU_TEST_NAMESPACE, or default.
+func TestSquashLayers(t *testing.T) {
+	reg := os.Getenv("KNUU_TEST_REGISTRY")
+	if reg == "" {
+		t.Skip("KNUU_TEST_REGISTRY is not set")
+	}
+	namespace := os.Getenv("KNUU_TEST_NAMESPACE")
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	config, err := clientcmd.BuildConfigFromFlags("", filepath.Join(os.Getenv("HOME"), ".kube", "config"))
+	require.NoError(t, err)
+	kb := &Kaniko{
+		K8sClientset: kubernetes.NewForConfigOrDie(config),
+		K8sNamespace: namespace,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	layers := make(map[bool]int)
+	for _, squash := range []bool{false, true} {
+		tag := fmt.Sprintf("squash-%t", squash)
+		_, err := kb.Build(ctx, &builder.BuilderOptions{
+			ImageName:          "squash-test",
+			BuildContext:       "git://github.com/mojtaba-esk/sample-docker",
+			Destination:        reg + "/squash-test:" + tag,
+			Squash:             squash,
+			InsecureRegistries: []string{reg},
+		})
+		require.NoError(t, err)
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+reg+"/v2/squash-test/manifests/"+tag, nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", "application/vnd.docker.distribution.manifest.v2+json, application/vnd.oci.image.manifest.v1+json")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var manifest struct {
+			Layers []json.RawMessage `json:"layers"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&manifest))
+		layers[squash] = len(manifest.Layers)
+	}
+	assert.Less(t, layers[true], layers[false], "the squashed image should have fewer layers")
+}
+
+func TestPrepareJobExtraArgs(t *testing.T) {
+	t.Parallel()
+
+	kb := &Kaniko{
+		K8sClientset: fake.NewSimpleClientset(),
+		K8sNamespace: k8sNamespace,
+	}
+
+	job, err := kb.prepareJob(context.Background(), &builder.BuilderOptions{
+		ImageName:    "test-image",
+		BuildContext: "git://example.com/repo",
+		Destination:  "registry.example.com/test-image:latest",
+		ExtraArgs:    []string{"--snapshot-mode=redo", "--push-retry=3"},
+	})
+	require.NoError(t, err)
+
+	args := job.Spec.Template.Spec.Containers[0].Args
+	assert.Equal(t, []string{"--snapshot-mode=redo", "--push-retry=3"}, args[len(args)-2:])
+
+	for _, arg := range []string{"--destination=other", "--cache", "-verbosity=trace", "--single-snapshot"} {
+		_, err = kb.prepareJob(context.Background(), &builder.BuilderOptions{
+			ImageName:    "test-image",
+			BuildContext: "git://example.com/repo",
+			Destination:  "registry.example.com/test-image:latest",
+			ExtraArgs:    []string{arg},
+		})
+		assert.ErrorIs(t, err, ErrManagedExtraArg, arg)
+	}
+}
+
+func TestPrepareJobInsecureRegistries(t *testing.T) {
+	t.Parallel()
+
+	kb := &Kaniko{
+		K8sClientset: fake.NewSimpleClientset(),
+		K8sNamespace: k8sNamespace,
+	}
+
+	job, err := kb.prepareJob(context.Background(), &builder.BuilderOptions{
+		ImageName:          "test-image",
+		BuildContext:       "git://example.com/repo",
+		Destination:        "registry.local:5000/test-image:latest",
+		InsecureRegistries: []string{"registry.local:5000"},
+	})
+	require.NoError(t, err)
+
+	args := job.Spec.Template.Spec.Containers[0].Args
+	assert.Contains(t, args, "--insecure-registry=registry.local:5000")
+	assert.Contains(t, args, "--skip-tls-verify-registry=registry.local:5000")
+}
+
+func TestPrepareJobCacheMounts(t *testing.T) {
+	t.Parallel()
+
+	kb := &Kaniko{
+		K8sClientset: fake.NewSimpleClientset(),
+		K8sNamespace: k8sNamespace,
+	}
+
+	_, err := kb.prepareJob(context.Background(), &builder.BuilderOptions{
+		ImageName:    "test-image",
+		BuildContext: "git://example.com/repo",
+		Destination:  "registry.example.com/test-image:latest",
+		CacheMounts:  []string{"/root/.cache/go-build"},
+	})
+	assert.ErrorIs(t, err, ErrCacheMountsNotSupported)
+}
+
+func TestPrepareJobCacheDir(t *testing.T) {
+	t.Parallel()
+
+	kb := &Kaniko{
+		K8sClientset: fake.NewSimpleClientset(),
+		K8sNamespace: k8sNamespace,
+	}
+
+	job, err := kb.prepareJob(context.Background(), &builder.BuilderOptions{
+		ImageName:    "test-image",
+		BuildContext: "git://example.com/repo",
+		Destination:  "registry.example.com/test-image:latest",
+		Cache:        &builder.CacheOptions{Enabled: true, Dir: "/var/cache/knuu"},
+	})
+	require.NoError(t, err)
+
+	spec := job.Spec.Template.Spec
+	assert.Contains(t, spec.Containers[0].Args, "--cache-dir=/var/cache/knuu")
+	hostPathType := v1.HostPathDirectoryOrCreate
+	assert.Contains(t, spec.Volumes, v1.Volume{
+		Name: cacheDirVolName,
+		VolumeSource: v1.VolumeSource{
+			HostPath: &v1.HostPathVolumeSource{Path: "/var/cache/knuu", Type: &hostPathType},
+		},
+	}, "the cache directory should persist on the node")
+	assert.Contains(t, spec.Containers[0].VolumeMounts, v1.VolumeMount{Name: cacheDirVolName, MountPath: "/var/cache/knuu"})
+}
+
+func TestPrepareJobCompression(t *testing.T) {
+	t.Parallel()
+
+	buildOptions := &builder.BuilderOptions{
+		ImageName:    "test-image",
+		BuildContext: "git://example.com/repo",
+		Destination:  "registry.example.com/test-image:latest",
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		kb := &Kaniko{
+			K8sClientset: fake.NewSimpleClientset(),
+			K8sNamespace: k8sNamespace,
+			Compression:  "bzip2",
+		}
+		_, err := kb.prepareJob(context.Background(), buildOptions)
+		assert.ErrorIs(t, err, ErrInvalidCompression)
+	})
+
+	tt := []struct {
+		compression     Compression
+		expectedContext string
+		expectedImage   string
+		expectedArchive string
+	}{
+		{CompressionGzip, "--context=tar:///workspace/archive.tar.gz", curlImage, "/workspace/archive.tar.gz"},
+		{CompressionZstd, "--context=dir:///workspace/context", zstdImage, "/workspace/archive.tar.zst"},
+	}
+	for _, tc := range tt {
+		t.Run(string(tc.compression), func(t *testing.T) {
+			kb := &Kaniko{
+				K8sClientset: fake.NewSimpleClientset(),
+				K8sNamespace: k8sNamespace,
+				Compression:  tc.compression,
+			}
+			job, err := kb.prepareJob(context.Background(), buildOptions)
+			require.NoError(t, err)
+
+			job = addContextDownload(job, "http://minio/context", tc.compression)
+			spec := job.Spec.Template.Spec
+			require.Len(t, spec.InitContainers, 1)
+			assert.Equal(t, tc.expectedImage, spec.InitContainers[0].Image)
+			assert.Contains(t, spec.InitContainers[0].Args[0], "http://minio/context")
+			assert.Contains(t, spec.InitContainers[0].Args[0], "-o "+tc.expectedArchive+" ")
+			args := spec.Containers[0].Args
+			assert.Equal(t, tc.expectedContext, args[len(args)-1], "the downloaded context should replace the given one")
+			assert.Equal(t, workspaceVolName, spec.Volumes[0].Name)
+		})
+	}
+}
+
+func TestPrepareJobHostDockerConfig(t *testing.T) {
+	configDir := t.TempDir()
+	hostConfig := `{"auths":{"registry.example.com":{"auth":"dXNlcjpzZWNyZXQ="},"ghcr.io":{}},` +
+		`"credsStore":"desktop","proxies":{"default":{"httpProxy":"http://proxy:3128"}}}`
+	require.NoError(t, os.WriteFile(filepath.Join(configDir, "config.json"), []byte(hostConfig), 0600))
+	t.Setenv("DOCKER_CONFIG", configDir)
+
+	k8sCS := fake.NewSimpleClientset()
+	kb := &Kaniko{
+		K8sClientset:     k8sCS,
+		K8sNamespace:     k8sNamespace,
+		HostDockerConfig: true,
+	}
+
+	job, err := kb.prepareJob(context.Background(), &builder.BuilderOptions{
+		ImageName:    "test-image",
+		BuildContext: "git://example.com/repo",
+		Destination:  "registry.example.com/test-image:latest",
+	})
+	require.NoError(t, err)
+
+	secretName := job.Name + "-docker-config"
+	secret, err := k8sCS.CoreV1().Secrets(k8sNamespace).Get(context.Background(), secretName, metav1.GetOptions{})
+	require.NoError(t, err, "the secret should be created from the host config")
+	assert.JSONEq(t, `{"auths":{"registry.example.com":{"auth":"dXNlcjpzZWNyZXQ="}}}`, string(secret.Data["config.json"]),
+		"only the credentials should be copied, without the credential helpers")
+
+	spec := job.Spec.Template.Spec
+	require.Len(t, spec.Volumes, 1)
+	assert.Equal(t, secretName, spec.Volumes[0].Secret.SecretName)
+	assert.Contains(t, spec.Containers[0].VolumeMounts, v1.VolumeMount{
+		Name:      "docker-config",
+		MountPath: "/kaniko/.docker",
+		ReadOnly:  true,
+	})
+
+	_, err = k8sCS.BatchV1().Jobs(k8sNamespace).Create(context.Background(), job, metav1.CreateOptions{})
+	require.NoError(t, err)
+	require.NoError(t, kb.cleanup(context.Background(), job))
+	_, err = k8sCS.CoreV1().Secrets(k8sNamespace).Get(context.Background(), secretName, metav1.GetOptions{})
+	assert.True(t, apierrs.IsNotFound(err), "the secret should be deleted with the job")
+
+	// a config without usable credentials fails the build instead of failing the push
+	require.NoError(t, os.WriteFile(filepath.Join(configDir, "config.json"), []byte(`{"credsStore":"desktop"}`), 0600))
+	_, err = kb.prepareJob(context.Background(), &builder.BuilderOptions{
+		ImageName:    "test-image",
+		BuildContext: "git://example.com/repo",
+		Destination:  "registry.example.com/test-image:latest",
+	})
+	assert.ErrorIs(t, err, ErrNoRegistryCredentials)
+
+	// the content of an invalid config is not part of the error
+	require.NoError(t, os.WriteFile(filepath.Join(configDir, "config.json"), []byte(`{"auths":secret}`), 0600))
+	_, err = kb.prepareJob(context.Background(), &builder.BuilderOptions{
+		ImageName:    "test-image",
+		BuildContext: "git://example.com/repo",
+		Destination:  "registry.example.com/test-image:latest",
+	})
+	assert.ErrorIs(t, err, ErrParsingDockerConfig)
+	assert.NotContains(t, err.Error(), "secret")
+}
+
+func TestBuildDeletesHostDockerConfigOnFailure(t *testing.T) {
+	configDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(configDir, "config.json"),
+		[]byte(`{"auths":{"registry.example.com":{"auth":"dXNlcjpzZWNyZXQ="}}}`), 0600))
+	t.Setenv("DOCKER_CONFIG", configDir)
+
+	k8sCS := fake.NewSimpleClientset()
+	kb := &Kaniko{
+		K8sClientset:     k8sCS,
+		K8sNamespace:     k8sNamespace,
+		HostDockerConfig: true,
+	}
+	assertNoSecrets := func(msg string) {
+		t.Helper()
+		secrets, err := k8sCS.CoreV1().Secrets(k8sNamespace).List(context.Background(), metav1.ListOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, secrets.Items, msg)
+	}
+
+	// the secret is not created if the job cannot be prepared
+	_, err := kb.BuildWithResult(context.Background(), &builder.BuilderOptions{
+		ImageName:    "test-image",
+		BuildContext: "git://example.com/repo",
+		Destination:  "registry.example.com/test-image:latest",
+		ExtraArgs:    []string{"--destination=other"},
+	})
+	assert.ErrorIs(t, err, ErrPreparingJob)
+	assert.ErrorContains(t, err, "flag: --destination")
+	kb.Compression = "bzip2"
+	_, err = kb.BuildWithResult(context.Background(), &builder.BuilderOptions{
+		ImageName:    "test-image",
+		BuildContext: "git://example.com/repo",
+		Destination:  "registry.example.com/test-image:latest",
+	})
+	assert.ErrorIs(t, err, ErrPreparingJob)
+	assert.ErrorContains(t, err, "compression: bzip2")
+	assertNoSecrets("no secret should be created for a job that cannot be prepared")
+	kb.Compression = ""
+
+	// the secret is deleted if the build does not complete
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = kb.BuildWithResult(ctx, &builder.BuilderOptions{
+		ImageName:    "test-image",
+		BuildContext: "git://example.com/repo",
+		Destination:  "registry.example.com/test-image:latest",
+	})
+	assert.ErrorIs(t, err, ErrWaitingJobCompletion)
+	assertNoSecrets("the secret should be deleted when the build fails")
+}
@@ -0,0 +1,69 @@
+package kaniko
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+
+	"github.com/celestiaorg/knuu/pkg/builder"
+)
+
+const (
+	cacheWarmupContainerName = "cache-warmup-container"
+	singleSnapshotArg        = "--single-snapshot"
+)
+
+// addSquash configures the job to squash the layers of the final stage of the image.
+// Kaniko applies --single-snapshot to all the stages, so the RUN instructions of the build stages are not cached anymore.
+// To keep their cache, a multi-stage build is first built up to its last build stage by an init container
+// without squashing, which pushes the layers of the build stages to the cache. The squashed build then reuses them,
+// so only the final stage is built again.
+func addSquash(job *batchv1.Job, b *builder.BuilderOptions) *batchv1.Job {
+	kaniko := &job.Spec.Template.Spec.Containers[0]
+	if b.Cache != nil && b.Cache.Enabled && builder.IsDirContext(b.BuildContext) {
+		if target := lastBuildStage(builder.GetDirFromBuildContext(b.BuildContext)); target != "" {
+			warmup := *kaniko.DeepCopy()
+			warmup.Name = cacheWarmupContainerName
+			warmup.Args = slices.DeleteFunc(warmup.Args, func(arg string) bool { return arg == singleSnapshotArg })
+			warmup.Args = append(warmup.Args, "--target="+target, "--no-push")
+			job.Spec.Template.Spec.InitContainers = append(job.Spec.Template.Spec.InitContainers, warmup)
+		}
+	}
+	kaniko.Args = append(kaniko.Args, singleSnapshotArg)
+	return job
+}
+
+// lastBuildStage returns the name of the stage before the final one in the Dockerfile of the build context,
+// or an empty string if the Dockerfile has a single stage or the stage has no name, as kaniko targets stages by name
+func lastBuildStage(contextDir string) string {
+	content, err := os.ReadFile(filepath.Join(contextDir, "Dockerfile"))
+	if err != nil {
+		return ""
+	}
+
+	var stages []string
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || !strings.EqualFold(fields[0], "FROM") {
+			continue
+		}
+		// skip the flags, e.g. --platform
+		fields = slices.DeleteFunc(fields[1:], func(field string) bool { return strings.HasPrefix(field, "--") })
+		name := ""
+		if len(fields) == 3 && strings.EqualFold(fields[1], "AS") {
+			name = fields[2]
+		}
+		stages = append(stages, name)
+	}
+	if len(stages) < 2 {
+		return ""
+	}
+	if stages[len(stages)-2] == "" {
+		logrus.Warnf("The build stages are squashed without cache, name the stage before the final one with `FROM <image> AS <name>` to cache them")
+	}
+	return stages[len(stages)-2]
+}
@@ -7,13 +7,31 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
 )
 
 func createTarGz(srcDir string) ([]byte, error) {
-	var buffer bytes.Buffer
+	return createArchive(srcDir, CompressionGzip)
+}
 
-	gzipWriter := gzip.NewWriter(&buffer)
-	tarWriter := tar.NewWriter(gzipWriter)
+// createArchive creates a tar archive of the directory, compressed with the given compression
+func createArchive(srcDir string, compression Compression) ([]byte, error) {
+	var (
+		buffer     bytes.Buffer
+		compressor io.WriteCloser
+	)
+	switch compression {
+	case CompressionZstd:
+		zstdWriter, err := zstd.NewWriter(&buffer)
+		if err != nil {
+			return nil, err
+		}
+		compressor = zstdWriter
+	default:
+		compressor = gzip.NewWriter(&buffer)
+	}
+	tarWriter := tar.NewWriter(compressor)
 
 	err := filepath.Walk(srcDir, func(filePath string, fileInfo os.FileInfo, err error) error {
 		if err != nil {
@@ -25,7 +43,16 @@ func createTarGz(srcDir string) ([]byte, error) {
 			return err
 		}
 
-		header, err := tar.FileInfoHeader(fileInfo, "")
+		// symlinks are archived as links, without their content
+		link := ""
+		if fileInfo.Mode()&os.ModeSymlink != 0 {
+			link, err = os.Readlink(filePath)
+			if err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(fileInfo, link)
 		if err != nil {
 			return err
 		}
@@ -36,7 +63,7 @@ func createTarGz(srcDir string) ([]byte, error) {
 			return err
 		}
 
-		if !fileInfo.IsDir() {
+		if fileInfo.Mode().IsRegular() {
 			file, err := os.Open(filePath)
 			if err != nil {
 				return err
@@ -60,7 +87,7 @@ func createTarGz(srcDir string) ([]byte, error) {
 		return nil, err
 	}
 
-	if err := gzipWriter.Close(); err != nil {
+	if err := compressor.Close(); err != nil {
 		return nil, err
 	}
 
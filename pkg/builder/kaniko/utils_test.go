@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -63,3 +64,75 @@ func TestCreateTarGz(t *testing.T) {
 		assert.EqualValues(t, expectedContent, actualContent, "Content mismatch for file: %s", expectedFilePath)
 	}
 }
+
+func TestCreateTarGzSymlink(t *testing.T) {
+	testDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(testDir, "file.txt"), []byte("content"), 0644))
+	require.NoError(t, os.Symlink("file.txt", filepath.Join(testDir, "link")))
+	require.NoError(t, os.Symlink("/does/not/exist", filepath.Join(testDir, "dangling")))
+
+	archiveBytes, err := createTarGz(testDir)
+	require.NoError(t, err, "createTarGz failed")
+
+	gzipReader, err := gzip.NewReader(bytes.NewBuffer(archiveBytes))
+	require.NoError(t, err, "gzip.NewReader failed")
+	defer gzipReader.Close()
+
+	links := map[string]string{}
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err, "tarReader.Next failed")
+		if header.Typeflag == tar.TypeSymlink {
+			assert.Zero(t, header.Size, "symlink %s should have no content", header.Name)
+			links[header.Name] = header.Linkname
+		}
+	}
+	assert.Equal(t, map[string]string{"link": "file.txt", "dangling": "/does/not/exist"}, links)
+}
+
+func TestCreateArchiveCompression(t *testing.T) {
+	testDir := t.TempDir()
+	content := bytes.Repeat([]byte("genesis "), 64*1024)
+	require.NoError(t, os.WriteFile(filepath.Join(testDir, "genesis.json"), content, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(testDir, "Dockerfile"), []byte("FROM alpine:3.20\nCOPY genesis.json /"), 0644))
+
+	tt := []struct {
+		compression Compression
+		magic       []byte
+		decompress  func(io.Reader) (io.Reader, error)
+	}{
+		{CompressionGzip, []byte{0x1f, 0x8b}, func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{CompressionZstd, []byte{0x28, 0xb5, 0x2f, 0xfd}, func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) }},
+	}
+	for _, tc := range tt {
+		t.Run(string(tc.compression), func(t *testing.T) {
+			archiveBytes, err := createArchive(testDir, tc.compression)
+			require.NoError(t, err)
+			assert.True(t, bytes.HasPrefix(archiveBytes, tc.magic), "the archive should be compressed with %s", tc.compression)
+			assert.Less(t, len(archiveBytes), len(content)/10)
+
+			// the extracted context holds the files to build the image from
+			reader, err := tc.decompress(bytes.NewReader(archiveBytes))
+			require.NoError(t, err)
+			files := map[string][]byte{}
+			tarReader := tar.NewReader(reader)
+			for {
+				header, err := tarReader.Next()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				if header.Typeflag == tar.TypeReg {
+					files[header.Name], err = io.ReadAll(tarReader)
+					require.NoError(t, err)
+				}
+			}
+			assert.Equal(t, content, files["genesis.json"])
+			assert.Equal(t, "FROM alpine:3.20\nCOPY genesis.json /", string(files["Dockerfile"]))
+		})
+	}
+}
@@ -5,12 +5,18 @@ import (
 	"archive/tar"
 	"bytes"
 	"context"
+	"crypto"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
@@ -19,10 +25,21 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/celestiaorg/knuu/pkg/builder"
+	"github.com/celestiaorg/knuu/pkg/registry"
 )
 
 const (
 	DefaultTimeout = 2 * time.Minute
+
+	// copyTimeout bounds the time to wait for a file to be copied out of a container
+	copyTimeout        = 30 * time.Second
+	copyInitialBackoff = 100 * time.Millisecond
+	copyMaxBackoff     = 2 * time.Second
+	// cleanupTimeout bounds the time to stop and remove the container a file was read from
+	cleanupTimeout = 30 * time.Second
+
+	// imageUserTimeout bounds the time to read the user of the base image from its registry
+	imageUserTimeout = 30 * time.Second
 )
 
 // BuilderFactory is responsible for creating new instances of buildah.Builder
@@ -30,17 +47,38 @@ type BuilderFactory struct {
 	imageNameFrom          string
 	imageNameTo            string
 	imageBuilder           builder.Builder
-	cli                    *client.Client
+	cli                    ContainerRuntime
+	dockerClient           io.Closer // client created by NewBuilderFactory, nil once closed
 	dockerFileInstructions []string
+	preFromInstructions    []string
+	buildArgs              map[string]string
 	buildContext           string
+	ownsBuildContext       bool // the build context was created by the factory, so it can be removed
+	keepBuildContext       bool // the build context is kept after a push, see SetKeepBuildContext
+	noCache                bool
+	insecureRegistries     []string
+	cacheMounts            []string
+	customDockerfile       bool   // the instructions were replaced with SetDockerfileContent
+	user                   string // user set with SetUser, empty if the image runs as the user of the base image
+	reorderForCache        bool
+	baseImageKey           crypto.PublicKey // key the signature of the base image is verified with, nil to skip the verification
+	baseImageVerified      bool
+	imageCache             ImageCache // cache the built images are restored from, nil to always build them
+	cacheOptions           *builder.CacheOptions
 }
 
 // NewBuilderFactory creates a new instance of BuilderFactory.
+// The factory holds a Docker client, so call Close once done with it, e.g. `defer f.Close()`,
+// to release its connections when many factories are created.
 func NewBuilderFactory(imageName, buildContext string, imageBuilder builder.Builder) (*BuilderFactory, error) {
+	// pull the base image from the registry mirror, if any
+	imageName = registry.Rewrite(imageName)
 	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
 		return nil, ErrCreatingDockerClient.Wrap(err)
 	}
+	_, err = os.Stat(buildContext)
+	ownsBuildContext := os.IsNotExist(err)
 	err = os.MkdirAll(buildContext, 0755)
 	if err != nil {
 		return nil, ErrFailedToCreateContextDir.Wrap(err)
@@ -48,12 +86,21 @@ func NewBuilderFactory(imageName, buildContext string, imageBuilder builder.Buil
 	return &BuilderFactory{
 		imageNameFrom:          imageName,
 		cli:                    cli,
+		dockerClient:           cli,
 		dockerFileInstructions: []string{"FROM " + imageName},
+		preFromInstructions:    make([]string, 0),
+		buildArgs:              make(map[string]string),
 		buildContext:           buildContext,
+		ownsBuildContext:       ownsBuildContext,
 		imageBuilder:           imageBuilder,
 	}, nil
 }
 
+// BuildContext returns the directory of the build context, which the paths given to AddToBuilder are relative to.
+func (f *BuilderFactory) BuildContext() string {
+	return f.buildContext
+}
+
 // ImageNameFrom returns the name of the image from which the builder is created.
 func (f *BuilderFactory) ImageNameFrom() string {
 	return f.imageNameFrom
@@ -67,6 +114,58 @@ func (f *BuilderFactory) ExecuteCmdInBuilder(command []string) (string, error) {
 	return "", nil
 }
 
+// RunWithCacheMount runs the provided command in the builder with a cache mounted at the given path,
+// e.g. `/root/.cache/go-build` or `/root/.npm`. The content of the cache is kept by the builder across builds
+// but is not part of the image, which speeds up repeated dependency installs.
+// Cache mounts need BuildKit: the docker builder supports them, while the kaniko builder fails the build.
+func (f *BuilderFactory) RunWithCacheMount(command []string, cachePath string) error {
+	if !path.IsAbs(cachePath) {
+		return ErrCacheMountPathNotAbsolute.WithParams(cachePath)
+	}
+	cachePath = path.Clean(cachePath)
+	f.dockerFileInstructions = append(f.dockerFileInstructions,
+		"RUN --mount=type=cache,target="+cachePath+" "+strings.Join(command, " "))
+	if !slices.Contains(f.cacheMounts, cachePath) {
+		f.cacheMounts = append(f.cacheMounts, cachePath)
+	}
+	return nil
+}
+
+// checkedCmdRegex matches the line printed by a command run with ExecuteCmdInBuilderChecked that exited with another code
+var checkedCmdRegex = regexp.MustCompile(`knuu-check: expected exit code (\d+), got (\d+): (.*)`)
+
+// ExecuteCmdInBuilderChecked runs the provided command in the builder, like ExecuteCmdInBuilder,
+// and asserts that it exits with the expected code, e.g. 1 to check that a binary rejects an invalid config.
+// Commands are run when the image is built, so a mismatch fails PushBuilderImage with ErrUnexpectedExitCode,
+// including the command and the actual exit code, instead of a generic build failure.
+// A command that exits with the expected code does not fail the build, even if the code is nonzero.
+func (f *BuilderFactory) ExecuteCmdInBuilderChecked(command []string, expectExit int) error {
+	if expectExit < 0 || expectExit > 255 {
+		return ErrInvalidExpectedExitCode.WithParams(expectExit)
+	}
+	cmd := strings.Join(command, " ")
+	quoted := "'" + strings.ReplaceAll(cmd, "'", `'\''`) + "'"
+	f.dockerFileInstructions = append(f.dockerFileInstructions, fmt.Sprintf(
+		`RUN %s; status=$?; if [ "$status" -ne %d ]; then echo "knuu-check: expected exit code %d, got $status: "%s >&2; exit 1; fi`,
+		cmd, expectExit, expectExit, quoted))
+	return nil
+}
+
+// checkedCmdError returns the error of a command run with ExecuteCmdInBuilderChecked that failed the build,
+// or the build error itself if no command failed the check
+func checkedCmdError(err error) error {
+	output := err.Error()
+	var buildErr *builder.BuildError
+	if errors.As(err, &buildErr) {
+		output += "\n" + strings.Join(buildErr.Logs, "\n")
+	}
+	m := checkedCmdRegex.FindStringSubmatch(output)
+	if m == nil {
+		return err
+	}
+	return ErrUnexpectedExitCode.WithParams(m[3], m[2], m[1]).Wrap(err)
+}
+
 // AddToBuilder adds a file from the source path to the destination path in the image, with the specified ownership.
 func (f *BuilderFactory) AddToBuilder(srcPath, destPath, chown string) error {
 	f.dockerFileInstructions = append(f.dockerFileInstructions, "ADD --chown="+chown+" "+srcPath+" "+destPath)
@@ -76,6 +175,13 @@ func (f *BuilderFactory) AddToBuilder(srcPath, destPath, chown string) error {
 // ReadFileFromBuilder reads a file from the given builder's mount point.
 // It returns the file's content or any error encountered.
 func (f *BuilderFactory) ReadFileFromBuilder(filePath string) ([]byte, error) {
+	return f.ReadFileFromBuilderWithContext(context.Background(), filePath)
+}
+
+// ReadFileFromBuilderWithContext reads a file from the given builder's mount point.
+// The context bounds the creation and start of the container and the copy of the file.
+// The container is stopped and removed even if the context is canceled.
+func (f *BuilderFactory) ReadFileFromBuilderWithContext(ctx context.Context, filePath string) ([]byte, error) {
 	if f.imageNameTo == "" {
 		return nil, ErrNoImageNameProvided
 	}
@@ -84,7 +190,7 @@ func (f *BuilderFactory) ReadFileFromBuilder(filePath string) ([]byte, error) {
 		Cmd:   []string{"tail", "-f", "/dev/null"}, // This keeps the container running
 	}
 	resp, err := f.cli.ContainerCreate(
-		context.Background(),
+		ctx,
 		containerConfig,
 		nil,
 		nil,
@@ -96,28 +202,32 @@ func (f *BuilderFactory) ReadFileFromBuilder(filePath string) ([]byte, error) {
 	}
 
 	defer func() {
+		// the context of the read may be done, so the cleanup gets its own
+		ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+		defer cancel()
+
 		// Stop the container
 		timeout := int(time.Duration(10) * time.Second)
 		stopOptions := container.StopOptions{
 			Timeout: &timeout,
 		}
 
-		if err := f.cli.ContainerStop(context.Background(), resp.ID, stopOptions); err != nil {
+		if err := f.cli.ContainerStop(ctx, resp.ID, stopOptions); err != nil {
 			logrus.Warn(ErrFailedToStopContainer.Wrap(err))
 		}
 
 		// Remove the container
-		if err := f.cli.ContainerRemove(context.Background(), resp.ID, container.RemoveOptions{}); err != nil {
+		if err := f.cli.ContainerRemove(ctx, resp.ID, container.RemoveOptions{}); err != nil {
 			logrus.Warn(ErrFailedToRemoveContainer.Wrap(err))
 		}
 	}()
 
-	if err := f.cli.ContainerStart(context.Background(), resp.ID, container.StartOptions{}); err != nil {
+	if err := f.cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
 		return nil, ErrFailedToStartContainer.Wrap(err)
 	}
 
 	// Now you can copy the file
-	reader, _, err := f.cli.CopyFromContainer(context.Background(), resp.ID, filePath)
+	reader, err := f.copyFromContainer(ctx, resp.ID, filePath)
 	if err != nil {
 		return nil, ErrFailedToCopyFileFromContainer.Wrap(err)
 	}
@@ -147,33 +257,362 @@ func (f *BuilderFactory) ReadFileFromBuilder(filePath string) ([]byte, error) {
 	return nil, ErrFileNotFoundInTar
 }
 
+// copyFromContainer copies the given path out of the container.
+// A freshly started container may not be ready yet on slow nodes,
+// so the copy is retried with an exponential backoff until it succeeds, copyTimeout is reached or the context is done.
+func (f *BuilderFactory) copyFromContainer(ctx context.Context, containerID, path string) (io.ReadCloser, error) {
+	ctx, cancel := context.WithTimeout(ctx, copyTimeout)
+	defer cancel()
+
+	backoff := copyInitialBackoff
+	for {
+		info, err := f.cli.ContainerInspect(ctx, containerID)
+		if err == nil && info.State != nil && !info.State.Running {
+			err = fmt.Errorf("container %s is %s", containerID, info.State.Status)
+		}
+		if err == nil {
+			var reader io.ReadCloser
+			reader, _, err = f.cli.CopyFromContainer(ctx, containerID, path)
+			if err == nil {
+				return reader, nil
+			}
+		}
+		logrus.Debugf("Failed to copy %s from container %s, retrying in %s: %v", path, containerID, backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return nil, ErrTimeoutCopyingFromContainer.WithParams(path).Wrap(err)
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, copyMaxBackoff)
+	}
+}
+
 // SetEnvVar sets the value of an environment variable in the builder.
+// Values containing spaces, equals signs, quotes or backslashes are quoted, so they are set verbatim,
+// except for the references to other variables like $PATH, which are expanded by the builder as in any ENV instruction.
 func (f *BuilderFactory) SetEnvVar(name, value string) error {
-	f.dockerFileInstructions = append(f.dockerFileInstructions, "ENV "+name+"="+value)
+	assignment, err := envAssignment(name, value)
+	if err != nil {
+		return err
+	}
+	f.dockerFileInstructions = append(f.dockerFileInstructions, "ENV "+assignment)
+	return nil
+}
+
+// SetEnvVars sets the values of the environment variables in the builder with a single ENV instruction,
+// so they are added in a single layer. The variables are sorted by name to keep the image hash stable,
+// and their values are quoted like with SetEnvVar.
+func (f *BuilderFactory) SetEnvVars(vars map[string]string) error {
+	if len(vars) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	assignments := make([]string, 0, len(names))
+	for _, name := range names {
+		assignment, err := envAssignment(name, vars[name])
+		if err != nil {
+			return err
+		}
+		assignments = append(assignments, assignment)
+	}
+	f.dockerFileInstructions = append(f.dockerFileInstructions, "ENV "+strings.Join(assignments, " "))
 	return nil
 }
 
+// envAssignment returns the name=value assignment of an ENV instruction, quoting the value if the Dockerfile parser
+// would otherwise split it or interpret its quotes and backslashes. Simple values are kept as is to keep the image hash.
+func envAssignment(name, value string) (string, error) {
+	if name == "" || strings.ContainsAny(name, "= \t\r\n\"'\\$") {
+		return "", ErrInvalidEnvVarName.WithParams(name)
+	}
+	// a Dockerfile instruction ends at the end of the line, even in a quoted value
+	if strings.ContainsAny(value, "\r\n") {
+		return "", ErrInvalidEnvVarValue.WithParams(name)
+	}
+	if !strings.ContainsAny(value, " \t=\"'\\") {
+		return name + "=" + value, nil
+	}
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+	return name + `="` + value + `"`, nil
+}
+
 // SetUser sets the user in the builder.
 func (f *BuilderFactory) SetUser(user string) error {
 	f.dockerFileInstructions = append(f.dockerFileInstructions, "USER "+user)
+	f.user = user
+	return nil
+}
+
+// RunAsUser runs the provided command in the builder as the given user, e.g. root for a step installing packages
+// in an image running as a non-root user, and switches back to the prior user afterwards,
+// so the image does not run as that user unintentionally.
+// The prior user is the last one set with SetUser, otherwise the user of the base image, read from its registry.
+func (f *BuilderFactory) RunAsUser(user string, command []string) error {
+	if user == "" {
+		return ErrUserRequired
+	}
+	prior, err := f.currentUser()
+	if err != nil {
+		return ErrRunningAsUser.WithParams(user).Wrap(err)
+	}
+	f.dockerFileInstructions = append(f.dockerFileInstructions,
+		"USER "+user,
+		"RUN "+strings.Join(command, " "),
+		"USER "+prior,
+	)
+	return nil
+}
+
+// currentUser returns the user the image runs as at this point of the Dockerfile
+func (f *BuilderFactory) currentUser() (string, error) {
+	if f.user != "" {
+		return f.user, nil
+	}
+	// the base image of a provided Dockerfile is not known
+	if f.customDockerfile {
+		return "", ErrUnknownUser.WithParams("of the provided Dockerfile")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), imageUserTimeout)
+	defer cancel()
+	user, err := registry.ImageUser(ctx, f.imageNameFrom)
+	if err != nil {
+		return "", ErrUnknownUser.WithParams(f.imageNameFrom).Wrap(err)
+	}
+	// images without a USER instruction run as root
+	if user == "" {
+		user = "root"
+	}
+	f.user = user
+	return user, nil
+}
+
+// AddArgBeforeFrom adds an ARG instruction before the FROM instruction.
+// This allows to parameterize the base image itself, e.g. `ARG BASE=alpine` with the image `${BASE}`.
+// An empty default value declares the argument without a default.
+func (f *BuilderFactory) AddArgBeforeFrom(name, defaultValue string) error {
+	instruction := "ARG " + name
+	if defaultValue != "" {
+		instruction += "=" + defaultValue
+	}
+	f.preFromInstructions = append(f.preFromInstructions, instruction)
+	return nil
+}
+
+// AddOnBuild adds an ONBUILD instruction, which is triggered when the image is used as a base image.
+func (f *BuilderFactory) AddOnBuild(instruction string) error {
+	f.dockerFileInstructions = append(f.dockerFileInstructions, "ONBUILD "+instruction)
+	return nil
+}
+
+// SetBuildArg sets the value of a build argument declared with ARG.
+func (f *BuilderFactory) SetBuildArg(name, value string) {
+	f.buildArgs[name] = value
+}
+
+// SetDockerfileContent replaces the instructions recorded so far with a hand-written Dockerfile, e.g. a multi-stage one,
+// which is written to the build context and built with the builder of the factory like the generated ones.
+// Instructions added afterwards, e.g. by setting environment variables in the instance, are appended to it,
+// so they apply to its last stage. The Dockerfile must start with a FROM or an ARG instruction,
+// after the comments and parser directives like `# syntax=...`. Its content is part of the image hash.
+func (f *BuilderFactory) SetDockerfileContent(content string) error {
+	first, keyword := "", ""
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			first, keyword = line, strings.Fields(line)[0]
+			break
+		}
+	}
+	if !strings.EqualFold(keyword, "FROM") && !strings.EqualFold(keyword, "ARG") {
+		return ErrInvalidDockerfileContent.WithParams(first)
+	}
+	f.preFromInstructions = make([]string, 0)
+	f.dockerFileInstructions = []string{strings.TrimRight(content, "\n")}
+	f.customDockerfile = true
+	f.user = ""
+	return nil
+}
+
+// dockerFile returns the content of the Dockerfile, with the instructions that must come before FROM first.
+func (f *BuilderFactory) dockerFile() string {
+	instructions := f.dockerFileInstructions
+	if f.reorderForCache {
+		instructions = reorderForCache(instructions)
+	}
+	instructions = append(append([]string{}, f.preFromInstructions...), instructions...)
+	return strings.Join(instructions, "\n")
+}
+
+// SetReorderForCache enables moving the ENV and LABEL instructions before the ADD and COPY instructions,
+// so that changing the added files, e.g. a folder added after the instance is committed, does not invalidate
+// the layers of the metadata, and images differing only in their files share more layers in the build cache.
+// The instructions are reordered when the Dockerfile is generated, deterministically, with the following rules:
+//   - only ENV and LABEL instructions are moved, before the ADD and COPY instructions directly preceding them
+//   - the moved instructions keep their relative order, as do the ADD and COPY instructions
+//   - no instruction is moved across any other instruction, e.g. RUN, USER or WORKDIR, as it may depend on it
+//   - no instruction is moved across an ADD or COPY referencing a variable, as moving an ENV changes its expansion
+//
+// The reordered Dockerfile is the one hashed by GenerateImageHash.
+func (f *BuilderFactory) SetReorderForCache(reorder bool) {
+	f.reorderForCache = reorder
+}
+
+// reorderForCache returns the instructions reordered according to the rules of SetReorderForCache
+func reorderForCache(instructions []string) []string {
+	reordered := make([]string, 0, len(instructions))
+	var metadata, files []string
+	flush := func() {
+		reordered = append(append(reordered, metadata...), files...)
+		metadata, files = nil, nil
+	}
+	for _, instruction := range instructions {
+		keyword := ""
+		if fields := strings.Fields(instruction); len(fields) > 0 {
+			keyword = strings.ToUpper(fields[0])
+		}
+		switch {
+		case keyword == "ENV" || keyword == "LABEL":
+			metadata = append(metadata, instruction)
+		case (keyword == "ADD" || keyword == "COPY") && !strings.Contains(instruction, "$"):
+			files = append(files, instruction)
+		default:
+			flush()
+			reordered = append(reordered, instruction)
+		}
+	}
+	flush()
+	return reordered
+}
+
+// SetNoCache disables the registry lookup that skips building an image that already exists,
+// and the restore of the image from the image cache, see SetImageCache. Built images are still stored in the cache.
+func (f *BuilderFactory) SetNoCache(noCache bool) {
+	f.noCache = noCache
+}
+
+// SetCacheOptions sets the cache of the layers of the builds, e.g. a local directory persisting them across builds,
+// see builder.CacheOptions. Nil disables the cache, which is the default.
+func (f *BuilderFactory) SetCacheOptions(opts *builder.CacheOptions) {
+	f.cacheOptions = opts
+}
+
+// SetKeepBuildContext keeps the build context after a successful push, which removes it by default,
+// e.g. for a factory shared by several instances that add files to it and push it again. It is still removed by Cleanup.
+func (f *BuilderFactory) SetKeepBuildContext(keep bool) {
+	f.keepBuildContext = keep
+}
+
+// SetInsecureRegistries sets the registry hosts that are used by the builder without verifying their TLS certificate.
+// See builder.BuilderOptions.InsecureRegistries for the security implications.
+func (f *BuilderFactory) SetInsecureRegistries(hosts []string) {
+	f.insecureRegistries = hosts
+}
+
+// SetBaseImagePublicKey requires the base image to be signed with cosign by the private key of the PEM encoded public key,
+// e.g. the cosign.pub file generated by `cosign generate-key-pair`, or the output of `cosign public-key --key <kms-uri>`.
+// The signature is verified with VerifyBaseImage before the image is built, which fails if the base image is unsigned
+// or signed with another key. Once verified, the FROM instruction is pinned to the digest of the base image,
+// so the image is built from the verified image even if its tag is moved afterwards.
+// Only key based signatures are supported, see registry.VerifySignature.
+func (f *BuilderFactory) SetBaseImagePublicKey(pemKey []byte) error {
+	key, err := registry.ParsePublicKey(pemKey)
+	if err != nil {
+		return err
+	}
+	f.baseImageKey = key
+	f.baseImageVerified = false
+	return nil
+}
+
+// VerifyBaseImage verifies the signature of the base image with the key set with SetBaseImagePublicKey, if any,
+// and pins the base image to its verified digest. It is called by PushBuilderImage, and does nothing once the base image is verified.
+// Hand-written Dockerfiles set with SetDockerfileContent are not pinned, only the image the factory was created from is verified.
+func (f *BuilderFactory) VerifyBaseImage(ctx context.Context) error {
+	if f.baseImageKey == nil || f.baseImageVerified {
+		return nil
+	}
+	digest, err := registry.VerifySignature(ctx, f.imageNameFrom, f.baseImageKey)
+	if err != nil {
+		return ErrVerifyingBaseImage.WithParams(f.imageNameFrom).Wrap(err)
+	}
+
+	ref, err := registry.ParseReference(f.imageNameFrom)
+	if err != nil {
+		return ErrVerifyingBaseImage.WithParams(f.imageNameFrom).Wrap(err)
+	}
+	ref.Tag, ref.Digest = "", digest
+	if !f.customDockerfile && f.dockerFileInstructions[0] == "FROM "+f.imageNameFrom {
+		f.dockerFileInstructions[0] = "FROM " + ref.String()
+	}
+	logrus.Debugf("Verified the signature of base image %s, pinned to %s", f.imageNameFrom, ref.String())
+	f.imageNameFrom = ref.String()
+	f.baseImageVerified = true
 	return nil
 }
 
 // Changed returns true if the builder has been modified, false otherwise.
 func (f *BuilderFactory) Changed() bool {
-	return len(f.dockerFileInstructions) > 1
+	return f.customDockerfile || len(f.dockerFileInstructions) > 1 || len(f.preFromInstructions) > 0
 }
 
 // PushBuilderImage pushes the image from the given builder to a registry.
 // The image is identified by the provided name.
-func (f *BuilderFactory) PushBuilderImage(imageName string) error {
+func (f *BuilderFactory) PushBuilderImage(imageName string) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+
+	if err := f.VerifyBaseImage(ctx); err != nil {
+		return err
+	}
 	if !f.Changed() {
 		logrus.Debugf("No changes made to image %s, skipping push", f.imageNameFrom)
 		return nil
 	}
 
+	imageName = registry.Rewrite(imageName)
 	f.imageNameTo = imageName
 
+	// the build context is not needed anymore once the image is pushed, whether it was built, already in the
+	// registry or restored from the image cache, unless it is pushed again
+	defer func() {
+		if err != nil || f.keepBuildContext {
+			return
+		}
+		if err := f.Cleanup(); err != nil {
+			logrus.Warnf("Failed to clean up build context %s: %v", f.buildContext, err)
+		}
+	}()
+
+	if !f.noCache {
+		exists, err := f.imageBuilder.ImageExists(ctx, imageName)
+		if err != nil {
+			logrus.Warnf("Cannot check if image %s exists in the registry, building it: %v", imageName, err)
+		}
+		if exists {
+			logrus.Debugf("Image %s already exists in the registry, skipping build", imageName)
+			return nil
+		}
+	}
+
+	imageHash := ""
+	if f.imageCache != nil {
+		hash, err := f.GenerateImageHash()
+		if err != nil {
+			logrus.Warnf("Cannot hash image %s for the image cache, building it without the cache: %v", imageName, err)
+		}
+		imageHash = hash
+		if imageHash != "" && !f.noCache && f.restoreFromImageCache(ctx, imageHash, imageName) {
+			return nil
+		}
+	}
+
 	dockerFilePath := filepath.Join(f.buildContext, "Dockerfile")
 	// create path if it does not exist
 	if _, err := os.Stat(f.buildContext); os.IsNotExist(err) {
@@ -182,18 +621,18 @@ func (f *BuilderFactory) PushBuilderImage(imageName string) error {
 			return ErrFailedToCreateContextDir.Wrap(err)
 		}
 	}
-	dockerFile := strings.Join(f.dockerFileInstructions, "\n")
-	err := os.WriteFile(dockerFilePath, []byte(dockerFile), 0644)
-	if err != nil {
+	if err := os.WriteFile(dockerFilePath, []byte(f.dockerFile()), 0644); err != nil {
 		return ErrFailedToWriteDockerfile.Wrap(err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
-	defer cancel()
 	logs, err := f.imageBuilder.Build(ctx, &builder.BuilderOptions{
-		ImageName:    f.imageNameTo,
-		Destination:  f.imageNameTo, // in docker the image name and destination are the same
-		BuildContext: builder.DirContext{Path: f.buildContext}.BuildContext(),
+		ImageName:          f.imageNameTo,
+		Destination:        f.imageNameTo, // in docker the image name and destination are the same
+		BuildContext:       builder.DirContext{Path: f.buildContext}.BuildContext(),
+		BuildArgs:          f.buildArgs,
+		InsecureRegistries: f.insecureRegistries,
+		CacheMounts:        f.cacheMounts,
+		Cache:              f.cacheOptions,
 	})
 
 	qStatus := logrus.TextFormatter{}.DisableQuote
@@ -204,8 +643,46 @@ func (f *BuilderFactory) PushBuilderImage(imageName string) error {
 	logrus.SetFormatter(&logrus.TextFormatter{
 		DisableQuote: qStatus,
 	})
+	if err != nil {
+		return checkedCmdError(err)
+	}
 
-	return err
+	if imageHash != "" {
+		f.storeInImageCache(ctx, imageHash, imageName)
+	}
+	return nil
+}
+
+// Cleanup removes the build context directory if it was created by the factory.
+// Directories provided by the user are left untouched, and it is safe to call it multiple times.
+func (f *BuilderFactory) Cleanup() error {
+	if !f.ownsBuildContext {
+		return nil
+	}
+	if err := os.RemoveAll(f.buildContext); err != nil {
+		return ErrRemovingBuildContext.WithParams(f.buildContext).Wrap(err)
+	}
+	logrus.Debugf("Removed build context %s", f.buildContext)
+	return nil
+}
+
+// Close closes the Docker client created by NewBuilderFactory and removes the build context, see Cleanup.
+// A runtime set with SetContainerRuntime is left open, as it belongs to the caller.
+// It is safe to call it multiple times, the factory must not be used to read files afterwards.
+func (f *BuilderFactory) Close() error {
+	var closeErr error
+	if f.dockerClient != nil {
+		if err := f.dockerClient.Close(); err != nil {
+			closeErr = ErrClosingDockerClient.Wrap(err)
+		}
+		// a client that failed to close is not retried
+		f.dockerClient = nil
+		logrus.Debugf("Closed docker client of builder factory for %s", f.imageNameFrom)
+	}
+	if err := f.Cleanup(); err != nil {
+		return err
+	}
+	return closeErr
 }
 
 // BuildImageFromGitRepo builds an image from the given git repository and
@@ -216,6 +693,7 @@ func (f *BuilderFactory) BuildImageFromGitRepo(ctx context.Context, gitCtx build
 		return ErrFailedToGetBuildContext.Wrap(err)
 	}
 
+	imageName = registry.Rewrite(imageName)
 	f.imageNameTo = imageName
 
 	cOpts := &builder.CacheOptions{}
@@ -262,17 +740,43 @@ func (f *BuilderFactory) GenerateImageHash() (string, error) {
 	hasher := sha256.New()
 
 	// Hash Dockerfile content
-	dockerFileContent := strings.Join(f.dockerFileInstructions, "\n")
-	_, err := hasher.Write([]byte(dockerFileContent))
+	_, err := hasher.Write([]byte(f.dockerFile()))
 	if err != nil {
 		return "", ErrHashingDockerfile.Wrap(err)
 	}
 
+	// Hash the build args as they change the resulting image
+	buildArgNames := make([]string, 0, len(f.buildArgs))
+	for name := range f.buildArgs {
+		buildArgNames = append(buildArgNames, name)
+	}
+	sort.Strings(buildArgNames)
+	for _, name := range buildArgNames {
+		if _, err := hasher.Write([]byte(name + "=" + f.buildArgs[name] + "\n")); err != nil {
+			return "", ErrHashingDockerfile.Wrap(err)
+		}
+	}
+
 	// Hash contents of all files in the build context
 	err = filepath.Walk(f.buildContext, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		if path == filepath.Join(f.buildContext, "Dockerfile") {
+			// the Dockerfile written by PushBuilderImage is the content hashed above
+			return nil
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			// hash the target of the symlink, as it is copied as is
+			target, err := os.Readlink(path)
+			if err != nil {
+				return ErrReadingFile.WithParams(path).Wrap(err)
+			}
+			if _, err := hasher.Write([]byte("symlink:" + target)); err != nil {
+				return ErrHashingFile.WithParams(path).Wrap(err)
+			}
+			return nil
+		}
 		if !info.IsDir() {
 			fileContent, err := os.ReadFile(path)
 			if err != nil {
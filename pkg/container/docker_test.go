@@ -0,0 +1,704 @@
+package container
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/builder"
+	"github.com/celestiaorg/knuu/pkg/registry"
+)
+
+// builtDockerfile returns the Dockerfile of the last build of the fake builder
+func builtDockerfile(t *testing.T, fb *builder.FakeBuilder) string {
+	t.Helper()
+	require.NotEmpty(t, fb.Builds(), "no image was built")
+	dockerFile, err := os.ReadFile(filepath.Join(builder.GetDirFromBuildContext(fb.LastBuild().BuildContext), "Dockerfile"))
+	require.NoError(t, err)
+	return string(dockerFile)
+}
+
+func TestPushBuilderImageSkipsExistingImage(t *testing.T) {
+	fb := &builder.FakeBuilder{}
+	host := "registry.local:5000"
+
+	newFactory := func() *BuilderFactory {
+		f, err := NewBuilderFactory("alpine:latest", t.TempDir(), fb)
+		require.NoError(t, err)
+		require.NoError(t, f.SetEnvVar("FOO", "bar"))
+		return f
+	}
+
+	imageName := func(f *BuilderFactory) string {
+		hash, err := f.GenerateImageHash()
+		require.NoError(t, err)
+		return fmt.Sprintf("%s/%s:24h", host, hash)
+	}
+
+	first := newFactory()
+	require.NoError(t, first.PushBuilderImage(imageName(first)))
+	assert.Equal(t, 1, len(fb.Builds()))
+
+	second := newFactory()
+	require.NoError(t, second.PushBuilderImage(imageName(second)))
+	assert.Equal(t, 1, len(fb.Builds()), "identical image should not be built again")
+
+	noCache := newFactory()
+	noCache.SetNoCache(true)
+	require.NoError(t, noCache.PushBuilderImage(imageName(noCache)))
+	assert.Equal(t, 2, len(fb.Builds()), "no cache should always build")
+}
+
+func TestPushBuilderImageRegistryUnreachable(t *testing.T) {
+	fb := &builder.FakeBuilder{ExistsErr: errors.New("registry unreachable")}
+	f, err := NewBuilderFactory("alpine:latest", t.TempDir(), fb)
+	require.NoError(t, err)
+	require.NoError(t, f.SetEnvVar("FOO", "bar"))
+
+	require.NoError(t, f.PushBuilderImage("registry.local:5000/test:24h"))
+	assert.Equal(t, 1, len(fb.Builds()), "should fall back to building when the registry is unreachable")
+}
+
+func TestPushBuilderImageArgBeforeFrom(t *testing.T) {
+	fb := &builder.FakeBuilder{}
+	f, err := NewBuilderFactory("${BASE}", t.TempDir(), fb)
+	require.NoError(t, err)
+
+	require.NoError(t, f.AddArgBeforeFrom("BASE", "alpine:latest"))
+	require.NoError(t, f.AddOnBuild("RUN echo triggered"))
+	f.SetBuildArg("BASE", "alpine:3.20")
+	require.True(t, f.Changed())
+
+	hash, err := f.GenerateImageHash()
+	require.NoError(t, err)
+	f.SetBuildArg("BASE", "alpine:3.19")
+	otherHash, err := f.GenerateImageHash()
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, otherHash, "build args should change the image hash")
+
+	host := "registry.local:5000"
+	require.NoError(t, f.PushBuilderImage(host+"/test:24h"))
+
+	assert.Equal(t, "ARG BASE=alpine:latest\nFROM ${BASE}\nONBUILD RUN echo triggered", builtDockerfile(t, fb))
+	assert.Equal(t, []string{"BASE=alpine:3.19"}, fb.LastBuild().BuildArgList())
+}
+
+// slowDaemon mocks a docker daemon whose containers take a while to start
+type slowDaemon struct {
+	mu              sync.Mutex
+	startingInspect int // number of inspects reporting the container as not running yet
+	failingCopies   int // number of copies failing after the container is running
+	copies          int
+	content         []byte
+}
+
+func (d *slowDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/containers/create"):
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"Id":"slow"}`)
+	case strings.HasSuffix(r.URL.Path, "/json"):
+		status := "running"
+		if d.startingInspect > 0 {
+			d.startingInspect--
+			status = "created"
+		}
+		fmt.Fprintf(w, `{"Id":"slow","State":{"Status":%q,"Running":%t}}`, status, status == "running")
+	case strings.HasSuffix(r.URL.Path, "/archive"):
+		d.copies++
+		if d.failingCopies > 0 {
+			d.failingCopies--
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `{"message":"container is not ready"}`)
+			return
+		}
+		stat, _ := json.Marshal(map[string]interface{}{"name": "file", "size": len(d.content)})
+		w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString(stat))
+		tw := tar.NewWriter(w)
+		_ = tw.WriteHeader(&tar.Header{Name: "file", Mode: 0644, Size: int64(len(d.content)), Typeflag: tar.TypeReg})
+		_, _ = tw.Write(d.content)
+		_ = tw.Close()
+	default:
+		// start, stop and remove
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestReadFileFromBuilderRetriesSlowContainer(t *testing.T) {
+	daemon := &slowDaemon{startingInspect: 2, failingCopies: 2, content: []byte("hello")}
+	server := httptest.NewServer(daemon)
+	defer server.Close()
+
+	cli, err := client.NewClientWithOpts(
+		client.WithHost("tcp://"+strings.TrimPrefix(server.URL, "http://")),
+		client.WithHTTPClient(server.Client()),
+		client.WithVersion("1.45"),
+	)
+	require.NoError(t, err)
+
+	f := &BuilderFactory{imageNameTo: "test:latest", cli: cli}
+	data, err := f.ReadFileFromBuilder("/file")
+	require.NoError(t, err)
+	assert.Equal(t, daemon.content, data)
+	assert.Equal(t, 3, daemon.copies, "the copy should be retried until it succeeds")
+}
+
+func TestCleanupBuildContext(t *testing.T) {
+	host := "registry.local:5000"
+
+	buildContext := filepath.Join(t.TempDir(), "build")
+	f, err := NewBuilderFactory("alpine:latest", buildContext, &builder.FakeBuilder{})
+	require.NoError(t, err)
+	require.DirExists(t, buildContext)
+
+	require.NoError(t, f.Cleanup())
+	assert.NoDirExists(t, buildContext)
+	require.NoError(t, f.Cleanup(), "cleaning up twice should not fail")
+
+	// the build context is recreated for the build and removed after a successful push
+	require.NoError(t, f.SetEnvVar("FOO", "bar"))
+	require.NoError(t, f.PushBuilderImage(host+"/cleanup:24h"))
+	assert.NoDirExists(t, buildContext)
+
+	// it is removed as well when the image is already in the registry and the build is skipped
+	fb := &builder.FakeBuilder{Images: map[string]bool{host + "/cleanup-exists:24h": true}}
+	f, err = NewBuilderFactory("alpine:latest", buildContext, fb)
+	require.NoError(t, err)
+	require.NoError(t, f.SetEnvVar("FOO", "bar"))
+	require.NoError(t, f.PushBuilderImage(host+"/cleanup-exists:24h"))
+	assert.Empty(t, fb.Builds())
+	assert.NoDirExists(t, buildContext)
+
+	// a kept build context is only removed by Cleanup, and the Dockerfile written to it does not change the hash
+	f.SetKeepBuildContext(true)
+	require.NoError(t, os.MkdirAll(buildContext, 0o755))
+	hash, err := f.GenerateImageHash()
+	require.NoError(t, err)
+	require.NoError(t, f.PushBuilderImage(host+"/cleanup-kept:24h"))
+	assert.FileExists(t, filepath.Join(f.BuildContext(), "Dockerfile"))
+	pushedHash, err := f.GenerateImageHash()
+	require.NoError(t, err)
+	assert.Equal(t, hash, pushedHash)
+	require.NoError(t, f.Cleanup())
+	assert.NoDirExists(t, buildContext)
+
+	userDir := t.TempDir()
+	f, err = NewBuilderFactory("alpine:latest", userDir, &builder.FakeBuilder{})
+	require.NoError(t, err)
+	require.NoError(t, f.Cleanup())
+	assert.DirExists(t, userDir, "directories not created by the factory must not be removed")
+}
+
+// countingCloser counts the times it is closed
+type countingCloser struct {
+	closes int
+}
+
+func (c *countingCloser) Close() error {
+	c.closes++
+	return nil
+}
+
+func TestCloseBuilderFactory(t *testing.T) {
+	buildContext := filepath.Join(t.TempDir(), "build")
+	f, err := NewBuilderFactory("alpine:latest", buildContext, nil)
+	require.NoError(t, err)
+	require.NotNil(t, f.dockerClient, "the factory should own the docker client it created")
+
+	// the docker client is closed for real, then replaced to count the closes
+	require.NoError(t, f.dockerClient.Close())
+	closer := &countingCloser{}
+	f.dockerClient = closer
+
+	require.NoError(t, f.Close())
+	assert.Equal(t, 1, closer.closes)
+	assert.Nil(t, f.dockerClient, "the client should be released")
+	assert.NoDirExists(t, buildContext, "the build context created by the factory should be removed")
+
+	require.NoError(t, f.Close(), "closing twice should not fail")
+	assert.Equal(t, 1, closer.closes, "the client should be closed only once")
+}
+
+func TestPushBuilderImageInsecureRegistries(t *testing.T) {
+	host := "registry.local:5000"
+
+	fb := &builder.FakeBuilder{}
+	f, err := NewBuilderFactory("alpine:latest", t.TempDir(), fb)
+	require.NoError(t, err)
+	require.NoError(t, f.SetEnvVar("FOO", "bar"))
+	f.SetInsecureRegistries([]string{host})
+
+	require.NoError(t, f.PushBuilderImage(host+"/insecure:24h"))
+	assert.Equal(t, []string{host}, fb.LastBuild().InsecureRegistries)
+}
+
+func TestRegistryMirror(t *testing.T) {
+	host := "registry.local:5000"
+
+	require.NoError(t, registry.SetMirror(host))
+	t.Cleanup(func() { require.NoError(t, registry.SetMirror("")) })
+
+	fb := &builder.FakeBuilder{}
+	f, err := NewBuilderFactory("alpine:latest", t.TempDir(), fb)
+	require.NoError(t, err)
+	assert.Equal(t, host+"/library/alpine:latest", f.ImageNameFrom())
+	require.NoError(t, f.SetEnvVar("FOO", "bar"))
+
+	require.NoError(t, f.PushBuilderImage("ttl.sh/mirror:24h"))
+	assert.Equal(t, 1, len(fb.Builds()))
+	assert.True(t, strings.HasPrefix(builtDockerfile(t, fb), "FROM "+host+"/library/alpine:latest\n"), builtDockerfile(t, fb))
+	assert.Equal(t, host+"/mirror:24h", f.imageNameTo, "the image should be pushed to the mirror")
+	assert.Equal(t, host+"/mirror:24h", fb.LastBuild().Destination)
+}
+
+func TestRunWithCacheMount(t *testing.T) {
+	host := "registry.local:5000"
+
+	fb := &builder.FakeBuilder{}
+	f, err := NewBuilderFactory("golang:1.22", t.TempDir(), fb)
+	require.NoError(t, err)
+	require.NoError(t, f.RunWithCacheMount([]string{"go", "mod", "download"}, "/root/go/pkg/mod/"))
+	require.NoError(t, f.RunWithCacheMount([]string{"go", "build", "./..."}, "/root/.cache/go-build"))
+	require.NoError(t, f.RunWithCacheMount([]string{"go", "build", "./cmd/..."}, "/root/.cache/go-build"))
+	assert.ErrorIs(t, f.RunWithCacheMount([]string{"npm", "ci"}, "root/.npm"), ErrCacheMountPathNotAbsolute)
+
+	require.NoError(t, f.PushBuilderImage(host+"/cache-mount:24h"))
+	assert.Equal(t, "FROM golang:1.22\n"+
+		"RUN --mount=type=cache,target=/root/go/pkg/mod go mod download\n"+
+		"RUN --mount=type=cache,target=/root/.cache/go-build go build ./...\n"+
+		"RUN --mount=type=cache,target=/root/.cache/go-build go build ./cmd/...", builtDockerfile(t, fb))
+	assert.Equal(t, []string{"/root/go/pkg/mod", "/root/.cache/go-build"}, fb.LastBuild().CacheMounts)
+}
+
+// TestRunWithCacheMountBuildKit checks that the cache is kept across builds, it needs docker with buildx
+func TestRunWithCacheMountBuildKit(t *testing.T) {
+	if err := exec.Command("docker", "buildx", "version").Run(); err != nil {
+		t.Skip("docker buildx is not available:", err)
+	}
+
+	runs := func() int {
+		buildContext := t.TempDir()
+		f, err := NewBuilderFactory("alpine:3.20", buildContext, nil)
+		require.NoError(t, err)
+		require.NoError(t, f.RunWithCacheMount([]string{"sh", "-c", `'echo run >> /cache/runs && echo "runs=$(wc -l < /cache/runs)"'`}, "/cache"))
+		require.NoError(t, os.WriteFile(filepath.Join(buildContext, "Dockerfile"), []byte(f.dockerFile()), 0644))
+
+		// the cache mount is not part of the layer cache, so the RUN instruction needs to be executed again
+		out, err := exec.Command("docker", "buildx", "build", "--no-cache", "--progress=plain", buildContext).CombinedOutput()
+		require.NoError(t, err, string(out))
+		match := regexp.MustCompile(`runs=(\d+)`).FindStringSubmatch(string(out))
+		require.NotNil(t, match, string(out))
+		var n int
+		_, err = fmt.Sscan(match[1], &n)
+		require.NoError(t, err)
+		return n
+	}
+
+	first := runs()
+	assert.Equal(t, first+1, runs(), "the cache should persist across builds")
+}
+
+// shellBuilder runs the RUN instructions of the Dockerfile with the local shell, failing like the docker builder
+// or, if kaniko is set, like the kaniko builder
+type shellBuilder struct {
+	kaniko bool
+}
+
+func (b *shellBuilder) Build(_ context.Context, opts *builder.BuilderOptions) (string, error) {
+	dockerFile, err := os.ReadFile(filepath.Join(builder.GetDirFromBuildContext(opts.BuildContext), "Dockerfile"))
+	if err != nil {
+		return "", err
+	}
+	var logs string
+	for _, line := range strings.Split(string(dockerFile), "\n") {
+		script, ok := strings.CutPrefix(line, "RUN ")
+		if !ok {
+			continue
+		}
+		out, err := exec.Command("sh", "-c", script).CombinedOutput()
+		logs += line + "\n" + string(out)
+		if err == nil {
+			continue
+		}
+		if b.kaniko {
+			return logs, builder.NewBuildError(opts.Destination, logs, 1, fmt.Errorf("build failed"))
+		}
+		return "", fmt.Errorf("failed to build image: %w\nstdout: \nstderr: %s", err, logs)
+	}
+	return logs, nil
+}
+
+func (b *shellBuilder) ImageExists(context.Context, string) (bool, error) {
+	return false, nil
+}
+
+func TestExecuteCmdInBuilderChecked(t *testing.T) {
+	for _, kaniko := range []bool{false, true} {
+		t.Run(fmt.Sprintf("Kaniko=%t", kaniko), func(t *testing.T) {
+			f, err := NewBuilderFactory("alpine:3.20", t.TempDir(), &shellBuilder{kaniko: kaniko})
+			require.NoError(t, err)
+			require.NoError(t, f.ExecuteCmdInBuilderChecked([]string{"sh", "-c", "'exit 2'"}, 2))
+			require.NoError(t, f.ExecuteCmdInBuilderChecked([]string{"echo", "'it''s fine'"}, 0))
+			require.NoError(t, f.ExecuteCmdInBuilderChecked([]string{"sh", "-c", "'exit 3'"}, 0))
+			assert.ErrorIs(t, f.ExecuteCmdInBuilderChecked([]string{"true"}, 256), ErrInvalidExpectedExitCode)
+
+			err = f.PushBuilderImage("registry.local:5000/checked:24h")
+			require.ErrorIs(t, err, ErrUnexpectedExitCode)
+			assert.Equal(t, "command 'sh -c 'exit 3'' exited with code 3 while building the image, expected 0", strings.SplitN(err.Error(), ": ", 2)[0])
+		})
+	}
+}
+
+func TestSetDockerfileContent(t *testing.T) {
+	host := "registry.local:5000"
+
+	const dockerFile = "# syntax=docker/dockerfile:1\n" +
+		"ARG GO_VERSION=1.22\n" +
+		"FROM golang:${GO_VERSION} AS build\n" +
+		"WORKDIR /src\n" +
+		"COPY . .\n" +
+		"RUN go build -o /app ./cmd/app\n" +
+		"\n" +
+		"FROM alpine:3.20\n" +
+		"COPY --from=build /app /usr/local/bin/app\n" +
+		"ENTRYPOINT [\"app\"]\n"
+
+	fb := &builder.FakeBuilder{}
+	f, err := NewBuilderFactory("alpine:latest", t.TempDir(), fb)
+	require.NoError(t, err)
+	require.NoError(t, f.SetEnvVar("IGNORED", "true"))
+	hashBefore, err := f.GenerateImageHash()
+	require.NoError(t, err)
+
+	require.NoError(t, f.SetDockerfileContent(dockerFile))
+	assert.True(t, f.Changed())
+	hash, err := f.GenerateImageHash()
+	require.NoError(t, err)
+	assert.NotEqual(t, hashBefore, hash, "the provided Dockerfile should be hashed")
+
+	require.NoError(t, f.PushBuilderImage(fmt.Sprintf("%s/%s:24h", host, hash)))
+	assert.Equal(t, 1, len(fb.Builds()))
+	assert.Equal(t, strings.TrimRight(dockerFile, "\n"), builtDockerfile(t, fb), "the generated instructions should be replaced")
+
+	// instructions added afterwards apply to the last stage
+	require.NoError(t, f.SetEnvVar("FOO", "bar"))
+	newHash, err := f.GenerateImageHash()
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, newHash)
+	require.NoError(t, f.PushBuilderImage(fmt.Sprintf("%s/%s:24h", host, newHash)))
+	assert.Equal(t, strings.TrimRight(dockerFile, "\n")+"\nENV FOO=bar", builtDockerfile(t, fb))
+
+	for _, invalid := range []string{"", "# only a comment\n", "RUN echo hello\nFROM alpine:3.20"} {
+		assert.ErrorIs(t, f.SetDockerfileContent(invalid), ErrInvalidDockerfileContent, invalid)
+	}
+	require.NoError(t, f.SetDockerfileContent("from alpine:3.20\n"), "instructions are case-insensitive")
+}
+
+func TestSetEnvVarQuoting(t *testing.T) {
+	vars := map[string]string{
+		"SIMPLE":    "bar",
+		"SPACES":    "hello  world",
+		"EQUALS":    "a=b=c",
+		"DOUBLE":    `say "hi"`,
+		"SINGLE":    "it's",
+		"BACKSLASH": `C:\dir\`,
+		"EMPTY":     "",
+		"PATH":      "/app/bin:$PATH",
+	}
+
+	f, err := NewBuilderFactory("alpine:latest", t.TempDir(), &builder.FakeBuilder{})
+	require.NoError(t, err)
+	require.NoError(t, f.SetEnvVar("SIMPLE", "bar"))
+	require.NoError(t, f.SetEnvVar("DOUBLE", `say "hi"`))
+	require.NoError(t, f.SetEnvVars(vars))
+	require.NoError(t, f.SetEnvVars(nil))
+	// the first instruction is the FROM of the base image
+	require.Len(t, f.dockerFileInstructions, 4, "the variables of SetEnvVars should be set with a single instruction")
+	assert.Equal(t, "ENV SIMPLE=bar", f.dockerFileInstructions[1], "simple values should not be quoted to keep the image hash")
+	assert.Equal(t, `ENV DOUBLE="say \"hi\""`, f.dockerFileInstructions[2])
+	assert.Equal(t, `ENV BACKSLASH="C:\\dir\\" DOUBLE="say \"hi\"" EMPTY= EQUALS="a=b=c" PATH=/app/bin:$PATH `+
+		`SIMPLE=bar SINGLE="it's" SPACES="hello  world"`, f.dockerFileInstructions[3])
+
+	// the values are parsed back verbatim, the variables are expanded by the builder
+	assert.Equal(t, vars, parseEnvInstruction(t, f.dockerFileInstructions[3]))
+
+	assert.ErrorIs(t, f.SetEnvVar("", "value"), ErrInvalidEnvVarName)
+	assert.ErrorIs(t, f.SetEnvVar("A B", "value"), ErrInvalidEnvVarName)
+	assert.ErrorIs(t, f.SetEnvVars(map[string]string{"A=B": "value"}), ErrInvalidEnvVarName)
+	assert.ErrorIs(t, f.SetEnvVar("MULTILINE", "first\nsecond"), ErrInvalidEnvVarValue)
+	assert.Len(t, f.dockerFileInstructions, 4, "invalid variables should not be added")
+}
+
+// parseEnvInstruction parses the variables of an ENV instruction like the Dockerfile parser does,
+// splitting the words on unquoted whitespace and removing the quotes and the escaping backslashes
+func parseEnvInstruction(t *testing.T, instruction string) map[string]string {
+	t.Helper()
+	args, ok := strings.CutPrefix(instruction, "ENV ")
+	require.True(t, ok, instruction)
+
+	var (
+		words []string
+		word  strings.Builder
+		quote rune
+	)
+	runes := []rune(args)
+	for idx := 0; idx < len(runes); idx++ {
+		r := runes[idx]
+		switch {
+		case r == '\\' && quote != '\'':
+			idx++
+			require.Less(t, idx, len(runes), "dangling backslash in %s", instruction)
+			word.WriteRune(runes[idx])
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		case quote == 0 && r == ' ':
+			words = append(words, word.String())
+			word.Reset()
+		default:
+			word.WriteRune(r)
+		}
+	}
+	require.Zero(t, quote, "unterminated quote in %s", instruction)
+	words = append(words, word.String())
+
+	vars := make(map[string]string, len(words))
+	for _, w := range words {
+		name, value, ok := strings.Cut(w, "=")
+		require.True(t, ok, w)
+		vars[name] = value
+	}
+	return vars
+}
+
+func TestRunAsUser(t *testing.T) {
+	host := "registry.local:5000"
+
+	fb := &builder.FakeBuilder{}
+	f, err := NewBuilderFactory("alpine:3.20", t.TempDir(), fb)
+	require.NoError(t, err)
+	require.NoError(t, f.SetUser("1000"))
+	require.NoError(t, f.RunAsUser("root", []string{"apk", "add", "curl"}))
+	require.NoError(t, f.ExecuteCmdInBuilderChecked([]string{"curl", "--version"}, 0))
+	assert.ErrorIs(t, f.RunAsUser("", []string{"true"}), ErrUserRequired)
+
+	require.NoError(t, f.PushBuilderImage(host+"/run-as-user:24h"))
+	lines := strings.Split(builtDockerfile(t, fb), "\n")
+	assert.Equal(t, []string{"USER 1000", "USER root", "RUN apk add curl", "USER 1000"}, lines[1:5])
+	assert.Equal(t, "USER 1000", lastUser(lines), "the image should run as the prior user")
+
+	t.Run("BaseImageUser", func(t *testing.T) {
+		// the base image runs as a non-root user, which is read from its config
+		base := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/v2/base/manifests/latest":
+				w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+				fmt.Fprint(w, `{"schemaVersion":2,"config":{"digest":"sha256:config"}}`)
+			case "/v2/base/blobs/sha256:config":
+				fmt.Fprint(w, `{"config":{"User":"app"}}`)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer base.Close()
+
+		f, err := NewBuilderFactory(strings.TrimPrefix(base.URL, "http://")+"/base:latest", t.TempDir(), fb)
+		require.NoError(t, err)
+		require.NoError(t, f.RunAsUser("root", []string{"apk", "add", "curl"}))
+		require.NoError(t, f.PushBuilderImage(host+"/run-as-base-user:24h"))
+		assert.Equal(t, "USER app", lastUser(strings.Split(builtDockerfile(t, fb), "\n")))
+
+		f, err = NewBuilderFactory(strings.TrimPrefix(base.URL, "http://")+"/missing:latest", t.TempDir(), fb)
+		require.NoError(t, err)
+		assert.ErrorIs(t, f.RunAsUser("root", []string{"true"}), ErrRunningAsUser)
+		require.NoError(t, f.SetUser("nobody"))
+		require.NoError(t, f.RunAsUser("root", []string{"true"}), "the user set with SetUser is restored")
+
+		require.NoError(t, f.SetDockerfileContent("FROM alpine:3.20\nUSER nobody"))
+		assert.ErrorContains(t, f.RunAsUser("root", []string{"true"}), "set it with SetUser first")
+	})
+}
+
+// lastUser returns the last USER instruction of the Dockerfile lines
+func lastUser(lines []string) string {
+	for n := len(lines) - 1; n >= 0; n-- {
+		if strings.HasPrefix(lines[n], "USER ") {
+			return lines[n]
+		}
+	}
+	return ""
+}
+
+func TestSetReorderForCache(t *testing.T) {
+	host := "registry.local:5000"
+
+	// build returns the Dockerfile of an image adding a file with the given content after its metadata was set
+	build := func(content string) string {
+		fb := &builder.FakeBuilder{}
+		buildContext := t.TempDir()
+		f, err := NewBuilderFactory("alpine:latest", buildContext, fb)
+		require.NoError(t, err)
+		f.SetReorderForCache(true)
+
+		_, err = f.ExecuteCmdInBuilder([]string{"apk", "add", "curl"})
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(buildContext, "config.toml"), []byte(content), 0644))
+		require.NoError(t, f.AddToBuilder("config.toml", "/etc/app/config.toml", "0:0"))
+		require.NoError(t, f.SetEnvVar("FOO", "bar"))
+		require.NoError(t, f.AddToBuilder("$HOME/data", "/data", "0:0"))
+		require.NoError(t, f.SetEnvVar("HOME", "/root"))
+		require.NoError(t, f.SetUser("app"))
+		require.NoError(t, f.SetEnvVar("BAZ", "qux"))
+
+		hash, err := f.GenerateImageHash()
+		require.NoError(t, err)
+		require.NoError(t, f.PushBuilderImage(fmt.Sprintf("%s/%s:24h", host, hash)))
+		require.Equal(t, 1, len(fb.Builds()))
+		return builtDockerfile(t, fb)
+	}
+
+	dockerFile := build("version = 1")
+	assert.Equal(t, "FROM alpine:latest\n"+
+		"RUN apk add curl\n"+
+		"ENV FOO=bar\n"+
+		"ADD --chown=0:0 config.toml /etc/app/config.toml\n"+
+		"ADD --chown=0:0 $HOME/data /data\n"+
+		"ENV HOME=/root\n"+
+		"USER app\n"+
+		"ENV BAZ=qux", dockerFile, "ENV must not cross RUN, USER or an ADD referencing a variable")
+
+	// only the layers after the changed file are rebuilt, the ones before it are reused from the cache
+	otherDockerFile := build("version = 2")
+	assert.Equal(t, dockerFile, otherDockerFile, "the reordering must be deterministic")
+	lines := strings.Split(dockerFile, "\n")
+	firstAdd := slices.IndexFunc(lines, func(line string) bool { return strings.HasPrefix(line, "ADD ") })
+	assert.Equal(t, []string{"FROM alpine:latest", "RUN apk add curl", "ENV FOO=bar"}, lines[:firstAdd],
+		"the metadata should be part of the cached layers before the added file")
+}
+
+func TestBuildImageFromGitRepo(t *testing.T) {
+	fb := &builder.FakeBuilder{Logs: "built"}
+	f, err := NewBuilderFactory("alpine:latest", t.TempDir(), fb)
+	require.NoError(t, err)
+
+	gitCtx := builder.GitContext{Repo: "https://github.com/celestiaorg/knuu.git", Branch: "main"}
+	require.NoError(t, f.BuildImageFromGitRepo(context.Background(), gitCtx, "registry.local:5000/knuu:24h"))
+
+	require.Len(t, fb.Builds(), 1)
+	opts := fb.LastBuild()
+	assert.Equal(t, "registry.local:5000/knuu:24h", opts.ImageName)
+	assert.Equal(t, "registry.local:5000/knuu:24h", opts.Destination)
+	assert.Equal(t, "git://github.com/celestiaorg/knuu#refs/heads/main", opts.BuildContext)
+	require.NotNil(t, opts.Cache)
+	assert.True(t, opts.Cache.Enabled)
+	wantCache, err := (&builder.CacheOptions{}).Default(opts.BuildContext)
+	require.NoError(t, err)
+	assert.Equal(t, wantCache, opts.Cache, "the cache repo should be derived from the build context")
+
+	exists, err := fb.ImageExists(context.Background(), "registry.local:5000/knuu:24h")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// the error of the builder is returned as is
+	fb.BuildErr = errors.New("clone failed")
+	err = f.BuildImageFromGitRepo(context.Background(), gitCtx, "registry.local:5000/knuu:24h")
+	assert.ErrorIs(t, err, fb.BuildErr)
+	assert.Len(t, fb.Builds(), 2)
+}
+
+func TestSetBaseImagePublicKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	// the signed image has a cosign signature of its manifest digest, the unsigned one has none
+	manifest := `{"schemaVersion":2,"config":{"digest":"sha256:config"}}`
+	sum := sha256.Sum256([]byte(manifest))
+	digest := fmt.Sprintf("sha256:%x", sum)
+	payload := fmt.Sprintf(`{"critical":{"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"}}`, digest)
+	payloadSum := sha256.Sum256([]byte(payload))
+	signature, err := ecdsa.SignASN1(rand.Reader, key, payloadSum[:])
+	require.NoError(t, err)
+	payloadDigest := fmt.Sprintf("sha256:%x", payloadSum)
+
+	base := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/signed/manifests/latest", "/v2/unsigned/manifests/latest":
+			w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+			w.Header().Set("Docker-Content-Digest", digest)
+			fmt.Fprint(w, manifest)
+		case "/v2/signed/manifests/" + strings.Replace(digest, ":", "-", 1) + ".sig":
+			w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+			fmt.Fprintf(w, `{"schemaVersion":2,"layers":[{"mediaType":"application/vnd.dev.cosign.simplesigning.v1+json",`+
+				`"digest":%q,"annotations":{"dev.cosignproject.cosign/signature":%q}}]}`,
+				payloadDigest, base64.StdEncoding.EncodeToString(signature))
+		case "/v2/signed/blobs/" + payloadDigest:
+			fmt.Fprint(w, payload)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer base.Close()
+	host := strings.TrimPrefix(base.URL, "http://")
+
+	t.Run("Signed", func(t *testing.T) {
+		fb := &builder.FakeBuilder{}
+		f, err := NewBuilderFactory(host+"/signed:latest", t.TempDir(), fb)
+		require.NoError(t, err)
+		require.NoError(t, f.SetBaseImagePublicKey(pemKey))
+		require.NoError(t, f.SetEnvVar("FOO", "bar"))
+
+		require.NoError(t, f.PushBuilderImage("ttl.sh/signed-base:24h"))
+		require.Len(t, fb.Builds(), 1)
+		assert.Equal(t, host+"/signed@"+digest, f.ImageNameFrom(), "the base image is pinned to its verified digest")
+		assert.True(t, strings.HasPrefix(f.dockerFile(), "FROM "+host+"/signed@"+digest+"\n"))
+	})
+
+	t.Run("Unsigned", func(t *testing.T) {
+		fb := &builder.FakeBuilder{}
+		f, err := NewBuilderFactory(host+"/unsigned:latest", t.TempDir(), fb)
+		require.NoError(t, err)
+		require.NoError(t, f.SetBaseImagePublicKey(pemKey))
+		require.NoError(t, f.SetEnvVar("FOO", "bar"))
+
+		err = f.PushBuilderImage("ttl.sh/unsigned-base:24h")
+		assert.ErrorIs(t, err, ErrVerifyingBaseImage)
+		assert.ErrorContains(t, err, "has no cosign signature")
+		assert.Empty(t, fb.Builds(), "the image must not be built from an unverified base image")
+	})
+
+	t.Run("InvalidKey", func(t *testing.T) {
+		f, err := NewBuilderFactory(host+"/signed:latest", t.TempDir(), &builder.FakeBuilder{})
+		require.NoError(t, err)
+		assert.ErrorIs(t, f.SetBaseImagePublicKey([]byte("not a key")), registry.ErrParsingPublicKey)
+	})
+}
@@ -52,4 +52,18 @@ var (
 	ErrReadingFile                    = &Error{Code: "ReadingFile", Message: "error reading file: %s"}
 	ErrHashingFile                    = &Error{Code: "HashingFile", Message: "error hashing file %s"}
 	ErrHashingBuildContext            = &Error{Code: "HashingBuildContext", Message: "error hashing build context"}
+	ErrTimeoutCopyingFromContainer    = &Error{Code: "TimeoutCopyingFromContainer", Message: "timed out copying %s from container"}
+	ErrRemovingBuildContext           = &Error{Code: "RemovingBuildContext", Message: "failed to remove build context %s"}
+	ErrCacheMountPathNotAbsolute      = &Error{Code: "CacheMountPathNotAbsolute", Message: "cache mount path %s must be absolute"}
+	ErrInvalidExpectedExitCode        = &Error{Code: "InvalidExpectedExitCode", Message: "invalid expected exit code %d, it must be between 0 and 255"}
+	ErrUnexpectedExitCode             = &Error{Code: "UnexpectedExitCode", Message: "command '%s' exited with code %s while building the image, expected %s"}
+	ErrInvalidDockerfileContent       = &Error{Code: "InvalidDockerfileContent", Message: "the Dockerfile must start with a FROM or ARG instruction, got '%s'"}
+	ErrUserRequired                   = &Error{Code: "UserRequired", Message: "a user is required to run the command as"}
+	ErrRunningAsUser                  = &Error{Code: "RunningAsUser", Message: "error running the command as user '%s'"}
+	ErrUnknownUser                    = &Error{Code: "UnknownUser", Message: "the user of the image %s is unknown, set it with SetUser first"}
+	ErrVerifyingBaseImage             = &Error{Code: "VerifyingBaseImage", Message: "error verifying the signature of base image %s"}
+	ErrClosingDockerClient            = &Error{Code: "ClosingDockerClient", Message: "failed to close docker client"}
+	ErrInvalidEnvVarName              = &Error{Code: "InvalidEnvVarName", Message: "invalid environment variable name '%s'"}
+	ErrInvalidEnvVarValue             = &Error{Code: "InvalidEnvVarValue", Message: "the value of environment variable %s must not contain line breaks"}
+	ErrInvalidStopSignal              = &Error{Code: "InvalidStopSignal", Message: "invalid stop signal '%s', it must be a signal name like SIGQUIT or a number between 1 and 64"}
 )
@@ -0,0 +1,71 @@
+package container
+
+import (
+	"context"
+	"io"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/celestiaorg/knuu/pkg/registry"
+)
+
+// ImageCache stores the archives of the built images outside of the registry, keyed by the hash of the image,
+// see GenerateImageHash. Images in temporary registries like ttl.sh expire, and each CI runner builds them again:
+// with a cache shared by the runners, e.g. a Minio bucket, an image is built once and restored by the others.
+type ImageCache interface {
+	// Get writes the archive stored under the key to w, and fails if there is none
+	Get(ctx context.Context, key string, w io.Writer) error
+	// Put stores the archive read from r under the key, replacing any previous one
+	Put(ctx context.Context, key string, r io.Reader) error
+}
+
+// SetImageCache sets the cache the built images are restored from instead of building them, nil disables it.
+// Before building, the image is restored from the cache if it has an archive for the hash of the image,
+// and after building, the archive of the image is stored in it.
+// A missing or corrupted archive falls back to building the image, and so does a cache that cannot be reached.
+func (f *BuilderFactory) SetImageCache(cache ImageCache) {
+	f.imageCache = cache
+}
+
+// restoreFromImageCache pushes the image archived under the hash to imageName,
+// and returns false if it cannot be restored, so it has to be built
+func (f *BuilderFactory) restoreFromImageCache(ctx context.Context, imageHash, imageName string) bool {
+	pr, pw := io.Pipe()
+	getErr := make(chan error, 1)
+	go func() {
+		err := f.imageCache.Get(ctx, imageHash, pw)
+		pw.CloseWithError(err)
+		getErr <- err
+	}()
+	digest, err := registry.LoadImage(ctx, pr, imageName, registry.Auth{})
+	// stop the download if the archive was not read until its end
+	pr.Close()
+	if cacheErr := <-getErr; cacheErr != nil && err != nil {
+		// the archive is missing or could not be downloaded, which matters more than the truncated archive it left
+		err = cacheErr
+	}
+	if err != nil {
+		logrus.Warnf("Cannot restore image %s from the image cache, building it: %v", imageName, err)
+		return false
+	}
+	logrus.Debugf("Restored image %s with digest %s from the image cache", imageName, digest)
+	return true
+}
+
+// storeInImageCache archives the image pushed to imageName under the hash,
+// a failure is only logged as the image was built anyway
+func (f *BuilderFactory) storeInImageCache(ctx context.Context, imageHash, imageName string) {
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := registry.SaveImage(ctx, imageName, pw)
+		pw.CloseWithError(err)
+	}()
+	err := f.imageCache.Put(ctx, imageHash, pr)
+	// stop the save if the cache did not read the archive until its end
+	pr.CloseWithError(err)
+	if err != nil {
+		logrus.Warnf("Cannot store image %s in the image cache: %v", imageName, err)
+		return
+	}
+	logrus.Debugf("Stored image %s in the image cache", imageName)
+}
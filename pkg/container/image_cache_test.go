@@ -0,0 +1,180 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/builder"
+	"github.com/celestiaorg/knuu/pkg/registry"
+)
+
+// storingRegistry stores the manifests and blobs pushed to it, by path
+type storingRegistry struct {
+	mu      sync.Mutex
+	content map[string][]byte
+	types   map[string]string
+}
+
+func (s *storingRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/blobs/uploads/"):
+		w.Header().Set("Location", r.URL.Path+"1")
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		path := r.URL.Path
+		if digest := r.URL.Query().Get("digest"); digest != "" {
+			path = strings.TrimSuffix(path, "/uploads/1") + "/" + digest
+		} else {
+			s.types[path] = r.Header.Get("Content-Type")
+		}
+		s.content[path] = data
+		w.WriteHeader(http.StatusCreated)
+	default:
+		data, ok := s.content[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", s.types[r.URL.Path])
+		_, _ = w.Write(data)
+	}
+}
+
+// push stores an image with a config and a layer under the tag
+func (s *storingRegistry) push(repo, tag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	digests := make([]string, 0, 2)
+	for _, blob := range []string{`{"architecture":"amd64"}`, "layer of " + tag} {
+		sum := sha256.Sum256([]byte(blob))
+		digest := "sha256:" + hex.EncodeToString(sum[:])
+		s.content[fmt.Sprintf("/v2/%s/blobs/%s", repo, digest)] = []byte(blob)
+		digests = append(digests, digest)
+	}
+	path := fmt.Sprintf("/v2/%s/manifests/%s", repo, tag)
+	s.content[path] = []byte(fmt.Sprintf(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json",`+
+		`"config":{"digest":%q},"layers":[{"digest":%q}]}`, digests[0], digests[1]))
+	s.types[path] = "application/vnd.oci.image.manifest.v1+json"
+}
+
+// expire removes all the images, like a temporary registry after their TTL
+func (s *storingRegistry) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.content = make(map[string][]byte)
+	s.types = make(map[string]string)
+}
+
+// pushingBuilder counts the builds and pushes an image to the storing registry
+type pushingBuilder struct {
+	registry *storingRegistry
+	builds   int
+}
+
+func (b *pushingBuilder) Build(_ context.Context, opts *builder.BuilderOptions) (string, error) {
+	b.builds++
+	name := opts.Destination[strings.Index(opts.Destination, "/")+1:]
+	repo, tag, _ := strings.Cut(name, ":")
+	b.registry.push(repo, tag)
+	return "", nil
+}
+
+func (b *pushingBuilder) ImageExists(ctx context.Context, ref string) (bool, error) {
+	return registry.ImageExists(ctx, ref)
+}
+
+// memoryImageCache is an ImageCache keeping the archives in memory
+type memoryImageCache struct {
+	mu       sync.Mutex
+	archives map[string][]byte
+}
+
+func (c *memoryImageCache) Get(_ context.Context, key string, w io.Writer) error {
+	c.mu.Lock()
+	archive, ok := c.archives[key]
+	c.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no archive for %s", key)
+	}
+	_, err := w.Write(archive)
+	return err
+}
+
+func (c *memoryImageCache) Put(_ context.Context, key string, r io.Reader) error {
+	archive, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.archives[key] = archive
+	return nil
+}
+
+func TestPushBuilderImageImageCache(t *testing.T) {
+	reg := &storingRegistry{content: map[string][]byte{}, types: map[string]string{}}
+	server := httptest.NewServer(reg)
+	defer server.Close()
+
+	pb := &pushingBuilder{registry: reg}
+	cache := &memoryImageCache{archives: map[string][]byte{}}
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	// each run is a new runner, with an empty registry as the images of the previous runs expired
+	run := func() (string, string) {
+		reg.expire()
+		buildContext := filepath.Join(t.TempDir(), "build")
+		f, err := NewBuilderFactory("alpine:latest", buildContext, pb)
+		require.NoError(t, err)
+		require.NoError(t, f.SetEnvVar("FOO", "bar"))
+		f.SetImageCache(cache)
+		hash, err := f.GenerateImageHash()
+		require.NoError(t, err)
+		imageName := fmt.Sprintf("%s/%s:24h", host, hash)
+		require.NoError(t, f.PushBuilderImage(imageName))
+		assert.NoDirExists(t, buildContext, "the build context should be removed once the image is pushed")
+		return hash, imageName
+	}
+
+	hash, imageName := run()
+	assert.Equal(t, 1, pb.builds)
+	require.Contains(t, cache.archives, hash, "the built image should be stored under its hash")
+
+	_, restored := run()
+	assert.Equal(t, 1, pb.builds, "the image should be restored from the cache without building it")
+	exists, err := registry.ImageExists(context.Background(), restored)
+	require.NoError(t, err)
+	assert.True(t, exists, "the restored image should be pushed to the registry")
+	assert.Equal(t, imageName, restored)
+
+	cache.archives[hash] = bytes.Replace(cache.archives[hash], []byte("layer of 24h"), []byte("layer of 42h"), 1)
+	run()
+	assert.Equal(t, 2, pb.builds, "a corrupted archive should fall back to building the image")
+
+	delete(cache.archives, hash)
+	run()
+	assert.Equal(t, 3, pb.builds, "a missing archive should fall back to building the image")
+	assert.Contains(t, cache.archives, hash, "the rebuilt image should be stored again")
+}
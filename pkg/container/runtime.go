@@ -0,0 +1,40 @@
+package container
+
+import (
+	"context"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// ContainerRuntime runs the containers used to read files from the built images, see ReadFileFromBuilder.
+// The methods follow the Docker client, which is the default runtime; daemons with a Docker compatible API,
+// like podman or a remote Docker, can be used with a client connected to them, see SetContainerRuntime.
+type ContainerRuntime interface {
+	ContainerCreate(
+		ctx context.Context,
+		config *container.Config,
+		hostConfig *container.HostConfig,
+		networkingConfig *network.NetworkingConfig,
+		platform *ocispec.Platform,
+		containerName string,
+	) (container.CreateResponse, error)
+	ContainerStart(ctx context.Context, containerID string, options container.StartOptions) error
+	ContainerStop(ctx context.Context, containerID string, options container.StopOptions) error
+	ContainerRemove(ctx context.Context, containerID string, options container.RemoveOptions) error
+	ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error)
+	CopyFromContainer(ctx context.Context, containerID, srcPath string) (io.ReadCloser, types.ContainerPathStat, error)
+}
+
+// the Docker client is the default runtime
+var _ ContainerRuntime = (*client.Client)(nil)
+
+// SetContainerRuntime replaces the runtime used to read files from the built images, e.g. with a Docker client
+// connected to a podman socket, or with a fake in tests.
+func (f *BuilderFactory) SetContainerRuntime(runtime ContainerRuntime) {
+	f.cli = runtime
+}
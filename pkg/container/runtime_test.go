@@ -0,0 +1,138 @@
+package container
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRuntime serves the archive of a copy from a running container without a daemon
+type fakeRuntime struct {
+	archive []byte
+	image   string
+	calls   []string
+	// slowStart blocks the start of the container until its context is done
+	slowStart bool
+	// cleanupErrs are the errors of the contexts of the stop and remove of the container when they are called
+	cleanupErrs []error
+}
+
+func (r *fakeRuntime) ContainerCreate(
+	_ context.Context,
+	config *container.Config,
+	_ *container.HostConfig,
+	_ *network.NetworkingConfig,
+	_ *ocispec.Platform,
+	_ string,
+) (container.CreateResponse, error) {
+	r.image = config.Image
+	r.calls = append(r.calls, "create")
+	return container.CreateResponse{ID: "fake"}, nil
+}
+
+func (r *fakeRuntime) ContainerStart(ctx context.Context, _ string, _ container.StartOptions) error {
+	r.calls = append(r.calls, "start")
+	if r.slowStart {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (r *fakeRuntime) ContainerStop(ctx context.Context, _ string, _ container.StopOptions) error {
+	r.calls = append(r.calls, "stop")
+	r.cleanupErrs = append(r.cleanupErrs, ctx.Err())
+	return nil
+}
+
+func (r *fakeRuntime) ContainerRemove(ctx context.Context, _ string, _ container.RemoveOptions) error {
+	r.calls = append(r.calls, "remove")
+	r.cleanupErrs = append(r.cleanupErrs, ctx.Err())
+	return nil
+}
+
+func (r *fakeRuntime) ContainerInspect(_ context.Context, id string) (types.ContainerJSON, error) {
+	return types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{
+		ID:    id,
+		State: &types.ContainerState{Status: "running", Running: true},
+	}}, nil
+}
+
+func (r *fakeRuntime) CopyFromContainer(context.Context, string, string) (io.ReadCloser, types.ContainerPathStat, error) {
+	r.calls = append(r.calls, "copy")
+	return io.NopCloser(bytes.NewReader(r.archive)), types.ContainerPathStat{}, nil
+}
+
+// tarArchive returns an archive with the given entries, directories when their content is nil
+func tarArchive(t *testing.T, entries ...struct {
+	name    string
+	content []byte
+}) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: 0755, Typeflag: tar.TypeDir}
+		if entry.content != nil {
+			header = &tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(entry.content)), Typeflag: tar.TypeReg}
+		}
+		require.NoError(t, tw.WriteHeader(header))
+		_, err := tw.Write(entry.content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+func TestReadFileFromBuilderWithFakeRuntime(t *testing.T) {
+	type entry = struct {
+		name    string
+		content []byte
+	}
+
+	f := &BuilderFactory{}
+	_, err := f.ReadFileFromBuilder("/etc/app/config.toml")
+	assert.ErrorIs(t, err, ErrNoImageNameProvided)
+
+	runtime := &fakeRuntime{archive: tarArchive(t, entry{name: "app/"}, entry{name: "app/config.toml", content: []byte("port = 8080")})}
+	f = &BuilderFactory{imageNameTo: "registry.local/app:24h"}
+	f.SetContainerRuntime(runtime)
+	data, err := f.ReadFileFromBuilder("/etc/app/config.toml")
+	require.NoError(t, err)
+	assert.Equal(t, "port = 8080", string(data), "the first regular file of the archive is read")
+	assert.Equal(t, "registry.local/app:24h", runtime.image)
+	assert.Equal(t, []string{"create", "start", "copy", "stop", "remove"}, runtime.calls, "the container is removed after the copy")
+
+	runtime.archive = tarArchive(t, entry{name: "app/"})
+	_, err = f.ReadFileFromBuilder("/etc/app")
+	assert.ErrorIs(t, err, ErrFileNotFoundInTar)
+
+	runtime.archive = []byte("not a tar archive, but long enough to be read as a header of one")
+	_, err = f.ReadFileFromBuilder("/etc/app/config.toml")
+	assert.ErrorIs(t, err, ErrFailedToReadFromTar)
+}
+
+func TestReadFileFromBuilderWithContextCanceled(t *testing.T) {
+	runtime := &fakeRuntime{slowStart: true}
+	f := &BuilderFactory{imageNameTo: "registry.local/app:24h"}
+	f.SetContainerRuntime(runtime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := f.ReadFileFromBuilderWithContext(ctx, "/etc/app/config.toml")
+	assert.ErrorIs(t, err, ErrFailedToStartContainer)
+	assert.Less(t, time.Since(start), time.Second, "the read should return once the context is done")
+	assert.Equal(t, []string{"create", "start", "stop", "remove"}, runtime.calls, "the container is removed after the cancellation")
+	assert.Equal(t, []error{nil, nil}, runtime.cleanupErrs, "the cleanup should not use the canceled context")
+}
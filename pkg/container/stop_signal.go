@@ -0,0 +1,59 @@
+package container
+
+import (
+	"strconv"
+	"strings"
+)
+
+// maxSignal is the highest signal number of Linux, SIGRTMAX
+const maxSignal = 64
+
+// signalNames are the names of the Linux signals without their SIG prefix, as accepted by the container runtimes
+var signalNames = map[string]bool{
+	"ABRT": true, "ALRM": true, "BUS": true, "CHLD": true, "CLD": true, "CONT": true, "FPE": true, "HUP": true,
+	"ILL": true, "INT": true, "IO": true, "IOT": true, "KILL": true, "PIPE": true, "POLL": true, "PROF": true,
+	"PWR": true, "QUIT": true, "SEGV": true, "STKFLT": true, "STOP": true, "SYS": true, "TERM": true, "TRAP": true,
+	"TSTP": true, "TTIN": true, "TTOU": true, "URG": true, "USR1": true, "USR2": true, "VTALRM": true, "WINCH": true,
+	"XCPU": true, "XFSZ": true, "RTMIN": true, "RTMAX": true,
+}
+
+// SetStopSignal sets the signal sent to the containers of the image to stop them, e.g. SIGQUIT for an app
+// shutting down gracefully on it instead of SIGTERM. The signal is a name, with or without the SIG prefix, or a number.
+// The container is killed once the termination grace period of the pod expires, whatever the signal.
+func (f *BuilderFactory) SetStopSignal(signal string) error {
+	if err := validateStopSignal(signal); err != nil {
+		return err
+	}
+	f.dockerFileInstructions = append(f.dockerFileInstructions, "STOPSIGNAL "+signal)
+	return nil
+}
+
+// validateStopSignal returns an error if the signal is neither a Linux signal name, including the real-time signals
+// relative to SIGRTMIN and SIGRTMAX like SIGRTMIN+3, nor a signal number
+func validateStopSignal(signal string) error {
+	if n, err := strconv.Atoi(signal); err == nil {
+		if n < 1 || n > maxSignal {
+			return ErrInvalidStopSignal.WithParams(signal)
+		}
+		return nil
+	}
+
+	name := strings.TrimPrefix(strings.ToUpper(signal), "SIG")
+	// the 30 real-time signals are named from both ends, SIGRTMIN+1 to SIGRTMIN+15 and SIGRTMAX-14 to SIGRTMAX-1
+	if base, offset, ok := strings.Cut(name, "+"); ok {
+		if n, err := strconv.Atoi(offset); base != "RTMIN" || err != nil || n < 1 || n > 15 {
+			return ErrInvalidStopSignal.WithParams(signal)
+		}
+		return nil
+	}
+	if base, offset, ok := strings.Cut(name, "-"); ok {
+		if n, err := strconv.Atoi(offset); base != "RTMAX" || err != nil || n < 1 || n > 14 {
+			return ErrInvalidStopSignal.WithParams(signal)
+		}
+		return nil
+	}
+	if !signalNames[name] {
+		return ErrInvalidStopSignal.WithParams(signal)
+	}
+	return nil
+}
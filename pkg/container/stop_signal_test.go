@@ -0,0 +1,36 @@
+package container
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/builder"
+)
+
+func TestSetStopSignal(t *testing.T) {
+	host := "registry.local:5000"
+
+	fb := &builder.FakeBuilder{}
+	f, err := NewBuilderFactory("alpine:latest", t.TempDir(), fb)
+	require.NoError(t, err)
+	hashBefore, err := f.GenerateImageHash()
+	require.NoError(t, err)
+
+	require.NoError(t, f.SetStopSignal("SIGQUIT"))
+	hash, err := f.GenerateImageHash()
+	require.NoError(t, err)
+	assert.NotEqual(t, hashBefore, hash, "the stop signal is part of the image")
+	require.NoError(t, f.PushBuilderImage(fmt.Sprintf("%s/%s:24h", host, hash)))
+	assert.Equal(t, "FROM alpine:latest\nSTOPSIGNAL SIGQUIT", builtDockerfile(t, fb))
+
+	for _, valid := range []string{"SIGTERM", "quit", "sigint", "USR1", "9", "64", "SIGRTMIN+3", "SIGRTMAX-1", "RTMIN"} {
+		assert.NoError(t, validateStopSignal(valid), valid)
+	}
+	for _, invalid := range []string{"", "SIG", "SIGFOO", "0", "65", "-1", "SIGRTMIN+16", "SIGRTMAX-15", "SIGTERM+1", "SIGQUIT now"} {
+		assert.ErrorIs(t, f.SetStopSignal(invalid), ErrInvalidStopSignal, invalid)
+	}
+	assert.Len(t, f.dockerFileInstructions, 2, "invalid signals should not be added")
+}
@@ -64,6 +64,9 @@ var (
 	ErrParsingMemoryRequest            = &Error{Code: "ErrorParsingMemoryRequest", Message: "failed to parse memory request quantity '%s'"}
 	ErrParsingMemoryLimit              = &Error{Code: "ErrorParsingMemoryLimit", Message: "failed to parse memory limit quantity '%s'"}
 	ErrParsingCPURequest               = &Error{Code: "ErrorParsingCPURequest", Message: "failed to parse CPU request quantity '%s'"}
+	ErrParsingEphemeralStorageRequest  = &Error{Code: "ErrorParsingEphemeralStorageRequest", Message: "failed to parse ephemeral storage request quantity '%s'"}
+	ErrParsingTmpfsSizeLimit           = &Error{Code: "ErrorParsingTmpfsSizeLimit", Message: "failed to parse tmpfs size limit quantity '%s'"}
+	ErrParsingEphemeralStorageLimit    = &Error{Code: "ErrorParsingEphemeralStorageLimit", Message: "failed to parse ephemeral storage limit quantity '%s'"}
 	ErrBuildingContainerVolumes        = &Error{Code: "ErrorBuildingContainerVolumes", Message: "failed to build container volumes"}
 	ErrBuildingResources               = &Error{Code: "ErrorBuildingResources", Message: "failed to build resources"}
 	ErrBuildingInitContainerVolumes    = &Error{Code: "ErrorBuildingInitContainerVolumes", Message: "failed to build init container volumes"}
@@ -125,4 +128,41 @@ var (
 	ErrGetEndpoint                     = &Error{Code: "GetEndpoint", Message: "failed to get endpoint for service %s"}
 	ErrUpdateEndpoint                  = &Error{Code: "UpdateEndpoint", Message: "failed to update endpoint for service %s"}
 	ErrCheckingServiceReady            = &Error{Code: "CheckingServiceReady", Message: "failed to check if service %s is ready"}
+	ErrMetricsServerNotInstalled       = &Error{Code: "MetricsServerNotInstalled", Message: "metrics API is not available, make sure metrics-server is installed in the cluster"}
+	ErrGettingPodMetrics               = &Error{Code: "GettingPodMetrics", Message: "failed to get metrics for pod %s"}
+	ErrParsingPodMetrics               = &Error{Code: "ParsingPodMetrics", Message: "failed to parse metrics for pod %s"}
+	ErrEphemeralContainersNotSupported = &Error{Code: "EphemeralContainersNotSupported", Message: "ephemeral containers are not supported or disabled in the cluster"}
+	ErrAddingEphemeralContainer        = &Error{Code: "AddingEphemeralContainer", Message: "failed to add ephemeral container %s to pod %s"}
+	ErrEphemeralContainerTerminated    = &Error{Code: "EphemeralContainerTerminated", Message: "ephemeral container %s terminated: %s"}
+	ErrWaitingForEphemeralContainer    = &Error{Code: "WaitingForEphemeralContainer", Message: "error waiting for ephemeral container %s to be running"}
+	ErrGettingServiceAccount           = &Error{Code: "GettingServiceAccount", Message: "failed to get service account %s"}
+	ErrGettingPriorityClass            = &Error{Code: "GettingPriorityClass", Message: "failed to get priority class %s"}
+	ErrListingPods                     = &Error{Code: "ListingPods", Message: "failed to list pods with selector %s"}
+	ErrGettingPersistentVolumeClaim    = &Error{Code: "GettingPersistentVolumeClaim", Message: "failed to get persistent volume claim %s"}
+	ErrSettingTerminalRawMode          = &Error{Code: "SettingTerminalRawMode", Message: "failed to put the terminal into raw mode"}
+	ErrStreamingPodLogs                = &Error{Code: "StreamingPodLogs", Message: "failed to stream logs of container %s in pod %s"}
+	ErrListingStorageClasses           = &Error{Code: "ListingStorageClasses", Message: "failed to list storage classes"}
+	ErrNoDefaultStorageClass           = &Error{Code: "NoDefaultStorageClass", Message: "the cluster has no default storage class"}
+	ErrStorageClassNotReadWriteMany    = &Error{Code: "StorageClassNotReadWriteMany", Message: "the default storage class %s with provisioner %s is not known to support ReadWriteMany volumes"}
+	ErrGettingServerVersion            = &Error{Code: "GettingServerVersion", Message: "failed to get the version of the API server"}
+	ErrParsingServerVersion            = &Error{Code: "ParsingServerVersion", Message: "failed to parse the version %s of the API server"}
+	ErrCopyingToPod                    = &Error{Code: "CopyingToPod", Message: "error copying files to '%s' in pod '%s'"}
+	ErrGettingRuntimeClass             = &Error{Code: "GettingRuntimeClass", Message: "failed to get runtime class %s"}
+	ErrGettingPodLogs                  = &Error{Code: "GettingPodLogs", Message: "failed to get logs of container %s in pod %s"}
+	ErrListingPodEvents                = &Error{Code: "ListingPodEvents", Message: "failed to list events of pod %s"}
+	ErrPreparingCronJob                = &Error{Code: "PreparingCronJob", Message: "failed to prepare cron job %s"}
+	ErrCreatingCronJob                 = &Error{Code: "CreatingCronJob", Message: "failed to create cron job %s"}
+	ErrGettingCronJob                  = &Error{Code: "GettingCronJob", Message: "failed to get cron job %s"}
+	ErrDeletingCronJob                 = &Error{Code: "DeletingCronJob", Message: "failed to delete cron job %s"}
+	ErrTriggeringCronJob               = &Error{Code: "TriggeringCronJob", Message: "failed to create a job from cron job %s"}
+	ErrGettingJob                      = &Error{Code: "GettingJob", Message: "failed to get job %s"}
+	ErrInvalidCronSchedule             = &Error{Code: "InvalidCronSchedule", Message: "invalid cron schedule '%s': %s"}
+	ErrInvalidCronField                = &Error{Code: "InvalidCronField", Message: "invalid %s '%s'"}
+	ErrDecodingManifest                = &Error{Code: "DecodingManifest", Message: "failed to decode document %d of the manifest"}
+	ErrInvalidManifestObject           = &Error{Code: "InvalidManifestObject", Message: "document %d of the manifest must have an apiVersion, a kind and a name"}
+	ErrEmptyManifest                   = &Error{Code: "EmptyManifest", Message: "the manifest has no objects"}
+	ErrFindingManifestKind             = &Error{Code: "FindingManifestKind", Message: "kind %s is not served by the API server in %s"}
+	ErrApplyingManifestObject          = &Error{Code: "ApplyingManifestObject", Message: "failed to apply %s of the manifest"}
+	ErrGettingManifestResource         = &Error{Code: "GettingManifestResource", Message: "failed to get %s"}
+	ErrDeletingManifestResource        = &Error{Code: "DeletingManifestResource", Message: "failed to delete %s"}
 )
@@ -29,8 +29,9 @@ const (
 )
 
 type Client struct {
+	config          *rest.Config
 	clientset       *kubernetes.Clientset
-	discoveryClient *discovery.DiscoveryClient
+	discoveryClient discovery.DiscoveryInterface
 	dynamicClient   dynamic.Interface
 	namespace       string
 }
@@ -57,7 +58,7 @@ func New(ctx context.Context, namespace string) (*Client, error) {
 	if err != nil {
 		return nil, ErrCreatingDynamicClient.Wrap(err)
 	}
-	kc := &Client{clientset: cs, discoveryClient: dc, dynamicClient: dC}
+	kc := &Client{config: config, clientset: cs, discoveryClient: dc, dynamicClient: dC}
 
 	namespace = SanitizeName(namespace)
 	kc.namespace = namespace
@@ -77,6 +78,11 @@ func (c *Client) Clientset() *kubernetes.Clientset {
 	return c.clientset
 }
 
+// RESTConfig returns a copy of the config used to connect to the cluster
+func (c *Client) RESTConfig() *rest.Config {
+	return rest.CopyConfig(c.config)
+}
+
 func (c *Client) DynamicClient() dynamic.Interface {
 	return c.dynamicClient
 }
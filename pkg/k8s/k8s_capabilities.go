@@ -0,0 +1,50 @@
+package k8s
+
+import (
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+)
+
+const (
+	// ephemeralContainersSubresource is served by the clusters supporting ephemeral containers
+	ephemeralContainersSubresource = "pods/ephemeralcontainers"
+
+	// sysctlsMinVersion is the version where the sysctls of the pods became GA
+	sysctlsMinVersion = "v1.21.0"
+)
+
+// Capabilities are the features of the cluster that some functionality of knuu depends on
+type Capabilities struct {
+	ServerVersion       string // git version of the API server, e.g. v1.28.2
+	EphemeralContainers bool   // whether the pods/ephemeralcontainers subresource is served
+	Sysctls             bool   // whether the API server supports the sysctls of the pods
+	MetricsServer       bool   // whether the metrics API is served, see MetricsServerAvailable
+}
+
+// ProbeCapabilities discovers the version and the APIs of the cluster to tell which features are available
+func (c *Client) ProbeCapabilities() (*Capabilities, error) {
+	info, err := c.discoveryClient.ServerVersion()
+	if err != nil {
+		return nil, ErrGettingServerVersion.Wrap(err)
+	}
+	version, err := utilversion.ParseGeneric(info.GitVersion)
+	if err != nil {
+		return nil, ErrParsingServerVersion.WithParams(info.GitVersion).Wrap(err)
+	}
+
+	resourceList, err := c.discoveryClient.ServerResourcesForGroupVersion("v1")
+	if err != nil {
+		return nil, ErrGettingResourceList.WithParams("v1").Wrap(err)
+	}
+
+	capabilities := &Capabilities{
+		ServerVersion: info.GitVersion,
+		Sysctls:       version.AtLeast(utilversion.MustParseGeneric(sysctlsMinVersion)),
+		MetricsServer: c.MetricsServerAvailable(),
+	}
+	for _, resource := range resourceList.APIResources {
+		if resource.Name == ephemeralContainersSubresource {
+			capabilities.EphemeralContainers = true
+		}
+	}
+	return capabilities, nil
+}
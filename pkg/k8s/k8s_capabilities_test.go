@@ -0,0 +1,68 @@
+package k8s
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func newFakeDiscovery(gitVersion string, resources ...*metav1.APIResourceList) *fakediscovery.FakeDiscovery {
+	return &fakediscovery.FakeDiscovery{
+		Fake:               &clienttesting.Fake{Resources: resources},
+		FakedServerVersion: &version.Info{GitVersion: gitVersion},
+	}
+}
+
+func TestProbeCapabilities(t *testing.T) {
+	core := func(resources ...string) *metav1.APIResourceList {
+		list := &metav1.APIResourceList{GroupVersion: "v1"}
+		for _, name := range resources {
+			list.APIResources = append(list.APIResources, metav1.APIResource{Name: name})
+		}
+		return list
+	}
+	metrics := &metav1.APIResourceList{
+		GroupVersion: podMetricsGVR.GroupVersion().String(),
+		APIResources: []metav1.APIResource{{Name: podMetricsGVR.Resource}},
+	}
+
+	t.Run("Available", func(t *testing.T) {
+		c := &Client{discoveryClient: newFakeDiscovery("v1.28.2-gke.1000", core("pods", ephemeralContainersSubresource), metrics)}
+		capabilities, err := c.ProbeCapabilities()
+		require.NoError(t, err)
+		assert.Equal(t, &Capabilities{
+			ServerVersion:       "v1.28.2-gke.1000",
+			EphemeralContainers: true,
+			Sysctls:             true,
+			MetricsServer:       true,
+		}, capabilities)
+	})
+
+	t.Run("Unavailable", func(t *testing.T) {
+		c := &Client{discoveryClient: newFakeDiscovery("v1.20.15", core("pods"))}
+		capabilities, err := c.ProbeCapabilities()
+		require.NoError(t, err)
+		assert.Equal(t, &Capabilities{ServerVersion: "v1.20.15"}, capabilities)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		c := &Client{discoveryClient: newFakeDiscovery("unknown", core("pods"))}
+		_, err := c.ProbeCapabilities()
+		assert.ErrorIs(t, err, ErrParsingServerVersion)
+
+		fake := newFakeDiscovery("v1.28.2", core("pods"))
+		fake.PrependReactor("get", "version", func(clienttesting.Action) (bool, runtime.Object, error) {
+			return true, nil, fmt.Errorf("connection refused")
+		})
+		c = &Client{discoveryClient: fake}
+		_, err = c.ProbeCapabilities()
+		assert.ErrorIs(t, err, ErrGettingServerVersion)
+	})
+}
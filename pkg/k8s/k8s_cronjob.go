@@ -0,0 +1,233 @@
+package k8s
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// manualJobAnnotation marks the jobs created from a cron job outside of its schedule, like kubectl create job --from does
+const manualJobAnnotation = "cronjob.kubernetes.io/instantiate"
+
+type CronJobConfig struct {
+	Name         string            // Name of the CronJob
+	Namespace    string            // Namespace of the CronJob
+	Labels       map[string]string // Labels to apply to the CronJob and its jobs, key/value represents the name/value of the label
+	Schedule     string            // Schedule of the CronJob in the cron format, see ValidateCronSchedule
+	BackoffLimit *int32            // BackoffLimit is the number of retries of a failing job, nil keeps the default of Kubernetes
+	PodConfig    PodConfig         // PodConfig represents the pod configuration of the jobs
+}
+
+// CreateCronJob creates a new cron job in the namespace that k8s is initialized with
+func (c *Client) CreateCronJob(ctx context.Context, cjConfig CronJobConfig, init bool) (*batchv1.CronJob, error) {
+	cjConfig.Namespace = c.namespace
+	cj, err := prepareCronJob(cjConfig, init)
+	if err != nil {
+		return nil, ErrPreparingCronJob.WithParams(cjConfig.Name).Wrap(err)
+	}
+
+	created, err := c.clientset.BatchV1().CronJobs(c.namespace).Create(ctx, cj, metav1.CreateOptions{})
+	if err != nil {
+		return nil, ErrCreatingCronJob.WithParams(cjConfig.Name).Wrap(err)
+	}
+	logrus.Debugf("CronJob %s created in namespace %s", cjConfig.Name, c.namespace)
+	return created, nil
+}
+
+func (c *Client) CronJobExists(ctx context.Context, name string) (bool, error) {
+	_, err := c.clientset.BatchV1().CronJobs(c.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if apierrs.IsNotFound(err) {
+			return false, nil
+		}
+		return false, ErrGettingCronJob.WithParams(name).Wrap(err)
+	}
+	return true, nil
+}
+
+// DeleteCronJob deletes the cron job along with its jobs and their pods, skipping a cron job that is already deleted
+func (c *Client) DeleteCronJob(ctx context.Context, name string) error {
+	propagation := metav1.DeletePropagationBackground
+	err := c.clientset.BatchV1().CronJobs(c.namespace).Delete(ctx, name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && !apierrs.IsNotFound(err) {
+		return ErrDeletingCronJob.WithParams(name).Wrap(err)
+	}
+	logrus.Debugf("CronJob %s deleted in namespace %s", name, c.namespace)
+	return nil
+}
+
+// TriggerCronJob creates a job from the template of the cron job to run it outside of its schedule,
+// and returns the job, which is owned by the cron job so it is deleted along with it
+func (c *Client) TriggerCronJob(ctx context.Context, name string) (*batchv1.Job, error) {
+	cj, err := c.clientset.BatchV1().CronJobs(c.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, ErrGettingCronJob.WithParams(name).Wrap(err)
+	}
+
+	annotations := map[string]string{manualJobAnnotation: "manual"}
+	for key, value := range cj.Spec.JobTemplate.Annotations {
+		annotations[key] = value
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:    c.namespace,
+			GenerateName: name + "-manual-",
+			Labels:       cj.Spec.JobTemplate.Labels,
+			Annotations:  annotations,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(cj, batchv1.SchemeGroupVersion.WithKind("CronJob")),
+			},
+		},
+		Spec: cj.Spec.JobTemplate.Spec,
+	}
+
+	created, err := c.clientset.BatchV1().Jobs(c.namespace).Create(ctx, job, metav1.CreateOptions{})
+	if err != nil {
+		return nil, ErrTriggeringCronJob.WithParams(name).Wrap(err)
+	}
+	logrus.Debugf("Job %s of CronJob %s created in namespace %s", created.Name, name, c.namespace)
+	return created, nil
+}
+
+func (c *Client) GetJob(ctx context.Context, name string) (*batchv1.Job, error) {
+	job, err := c.clientset.BatchV1().Jobs(c.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, ErrGettingJob.WithParams(name).Wrap(err)
+	}
+	return job, nil
+}
+
+func prepareCronJob(cjConfig CronJobConfig, init bool) (*batchv1.CronJob, error) {
+	if err := ValidateCronSchedule(cjConfig.Schedule); err != nil {
+		return nil, err
+	}
+	podSpec, err := preparePodSpec(cjConfig.PodConfig, init)
+	if err != nil {
+		return nil, ErrPreparingPodSpec.Wrap(err)
+	}
+	// the pods of a job must not be restarted once their command exits, the job retries them on failure
+	podSpec.RestartPolicy = v1.RestartPolicyNever
+
+	cj := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: cjConfig.Namespace,
+			Name:      cjConfig.Name,
+			Labels:    cjConfig.Labels,
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule: cjConfig.Schedule,
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: cjConfig.Labels,
+				},
+				Spec: batchv1.JobSpec{
+					BackoffLimit: cjConfig.BackoffLimit,
+					Template: v1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels:      cjConfig.Labels,
+							Annotations: cjConfig.PodConfig.Annotations,
+						},
+						Spec: podSpec,
+					},
+				},
+			},
+		},
+	}
+
+	logrus.Debugf("Prepared CronJob %s in namespace %s", cjConfig.Name, cjConfig.Namespace)
+	return cj, nil
+}
+
+// cronField is a field of a cron schedule with the range of its values and the names that can be used for them
+type cronField struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{name: "day of week", min: 0, max: 6, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// cronMacros are the predefined schedules accepted by Kubernetes
+var cronMacros = map[string]bool{
+	"@yearly":   true,
+	"@annually": true,
+	"@monthly":  true,
+	"@weekly":   true,
+	"@daily":    true,
+	"@midnight": true,
+	"@hourly":   true,
+}
+
+// ValidateCronSchedule returns an error if the schedule is not accepted by the cron jobs of Kubernetes, i.e. it is
+// neither one of the macros like @daily nor made of the 5 fields minute, hour, day of month, month and day of week,
+// each of them a list of values, ranges like 1-5 and steps like */15 or 1-30/5, or * for any value
+// The time zone is set on the cron job rather than in the schedule, so TZ= prefixes are rejected as well
+func ValidateCronSchedule(schedule string) error {
+	if cronMacros[strings.ToLower(schedule)] {
+		return nil
+	}
+	fields := strings.Fields(schedule)
+	if len(fields) != len(cronFields) {
+		return ErrInvalidCronSchedule.WithParams(schedule, "it must have 5 fields")
+	}
+	for idx, field := range fields {
+		if err := cronFields[idx].validate(field); err != nil {
+			return ErrInvalidCronSchedule.WithParams(schedule, err.Error())
+		}
+	}
+	return nil
+}
+
+func (f cronField) validate(field string) error {
+	for _, item := range strings.Split(field, ",") {
+		values, step, hasStep := strings.Cut(item, "/")
+		if hasStep {
+			n, err := strconv.Atoi(step)
+			if err != nil || n <= 0 {
+				return ErrInvalidCronField.WithParams(f.name, item)
+			}
+		}
+		if values == "*" || (values == "?" && (f.name == "day of month" || f.name == "day of week")) {
+			continue
+		}
+		from, to, isRange := strings.Cut(values, "-")
+		start, ok := f.value(from)
+		if !ok {
+			return ErrInvalidCronField.WithParams(f.name, item)
+		}
+		if !isRange {
+			continue
+		}
+		end, ok := f.value(to)
+		if !ok || end < start {
+			return ErrInvalidCronField.WithParams(f.name, item)
+		}
+	}
+	return nil
+}
+
+// value returns the value of a number or a name in the field, and false if it is out of its range
+func (f cronField) value(s string) (int, bool) {
+	for idx, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + idx, true
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < f.min || n > f.max {
+		return 0, false
+	}
+	return n, true
+}
@@ -0,0 +1,40 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCronSchedule(t *testing.T) {
+	for _, schedule := range []string{
+		"* * * * *",
+		"*/15 * * * *",
+		"0 9-17/2 * * 1-5",
+		"0,30 0 1,15 JAN-jun sun",
+		"5/10 * ? * ?",
+		"@daily",
+		"@Hourly",
+	} {
+		assert.NoError(t, ValidateCronSchedule(schedule), schedule)
+	}
+
+	for _, schedule := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 7",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"? * * * *",
+		"* * * foo *",
+		"@every 1h",
+		"TZ=UTC 0 * * * *",
+	} {
+		assert.ErrorIs(t, ValidateCronSchedule(schedule), ErrInvalidCronSchedule, schedule)
+	}
+}
@@ -0,0 +1,72 @@
+package k8s
+
+import (
+	"context"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AddEphemeralContainer adds an ephemeral container to a running pod and waits until it is running.
+// The ephemeral container shares the process namespace of the target container.
+func (c *Client) AddEphemeralContainer(
+	ctx context.Context,
+	podName,
+	targetContainer,
+	name,
+	image string,
+	command []string,
+) error {
+	pod, err := c.getPod(ctx, podName)
+	if err != nil {
+		return ErrGettingPod.WithParams(podName).Wrap(err)
+	}
+
+	pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, v1.EphemeralContainer{
+		EphemeralContainerCommon: v1.EphemeralContainerCommon{
+			Name:    name,
+			Image:   image,
+			Command: command,
+			Stdin:   true, // keeps the container running if the command is a shell
+		},
+		TargetContainerName: targetContainer,
+	})
+
+	_, err = c.clientset.CoreV1().Pods(c.namespace).UpdateEphemeralContainers(ctx, podName, pod, metav1.UpdateOptions{})
+	if err != nil {
+		if apierrs.IsNotFound(err) || apierrs.IsMethodNotSupported(err) {
+			return ErrEphemeralContainersNotSupported.Wrap(err)
+		}
+		return ErrAddingEphemeralContainer.WithParams(name, podName).Wrap(err)
+	}
+
+	return c.waitForEphemeralContainer(ctx, podName, name)
+}
+
+func (c *Client) waitForEphemeralContainer(ctx context.Context, podName, name string) error {
+	for {
+		pod, err := c.getPod(ctx, podName)
+		if err == nil {
+			for _, status := range pod.Status.EphemeralContainerStatuses {
+				if status.Name != name {
+					continue
+				}
+				if status.State.Running != nil {
+					return nil
+				}
+				if status.State.Terminated != nil {
+					return ErrEphemeralContainerTerminated.WithParams(name, status.State.Terminated.Reason)
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ErrWaitingForEphemeralContainer.WithParams(name).Wrap(ctx.Err())
+		case <-time.After(waitRetry):
+			// Retry after some seconds
+		}
+	}
+}
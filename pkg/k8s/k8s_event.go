@@ -0,0 +1,36 @@
+package k8s
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+// ListPodEvents returns the events of a pod, e.g. scheduling failures, image pulls, and probe failures, oldest first
+func (c *Client) ListPodEvents(ctx context.Context, podName string) ([]v1.Event, error) {
+	selector := fields.Set{"involvedObject.kind": "Pod", "involvedObject.name": podName}.AsSelector().String()
+	events, err := c.clientset.CoreV1().Events(c.namespace).List(ctx, metav1.ListOptions{FieldSelector: selector})
+	if err != nil {
+		return nil, ErrListingPodEvents.WithParams(podName).Wrap(err)
+	}
+	sort.SliceStable(events.Items, func(a, b int) bool {
+		return EventTime(events.Items[a]).Before(EventTime(events.Items[b]))
+	})
+	return events.Items, nil
+}
+
+// EventTime returns the last time the event occurred, falling back to its creation for the events without timestamps
+func EventTime(event v1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
@@ -0,0 +1,159 @@
+package k8s
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+
+	"golang.org/x/term"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// terminalResizeInterval is the interval to check the local terminal for size changes
+const terminalResizeInterval = 250 * time.Millisecond
+
+// ExecStreams are the streams attached to an interactive command
+type ExecStreams struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer // Ignored when TTY is set, as the terminal merges stderr into stdout
+	// TTY allocates a terminal for the command, like `kubectl exec -it`.
+	// If Stdin is a local terminal it is put into raw mode and its size is propagated to the command.
+	TTY bool
+}
+
+// RunInteractiveCommandInPod runs a command in a container within a pod,
+// proxying the given streams until the command exits or the context is done.
+func (c *Client) RunInteractiveCommandInPod(
+	ctx context.Context,
+	podName,
+	containerName string,
+	cmd []string,
+	streams ExecStreams,
+) error {
+	_, err := c.getPod(ctx, podName)
+	if err != nil {
+		return ErrGettingPod.WithParams(podName).Wrap(err)
+	}
+
+	req := c.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(podName).
+		Namespace(c.namespace).
+		SubResource("exec").
+		VersionedParams(&v1.PodExecOptions{
+			Command:   cmd,
+			Container: containerName,
+			Stdin:     streams.Stdin != nil,
+			Stdout:    streams.Stdout != nil,
+			Stderr:    streams.Stderr != nil && !streams.TTY,
+			TTY:       streams.TTY,
+		}, scheme.ParameterCodec)
+
+	k8sConfig, err := getClusterConfig()
+	if err != nil {
+		return ErrGettingK8sConfig.Wrap(err)
+	}
+	exec, err := remotecommand.NewSPDYExecutor(k8sConfig, "POST", req.URL())
+	if err != nil {
+		return ErrCreatingExecutor.Wrap(err)
+	}
+
+	opts := remotecommand.StreamOptions{
+		Stdin:  streams.Stdin,
+		Stdout: streams.Stdout,
+		Tty:    streams.TTY,
+	}
+	if !streams.TTY {
+		opts.Stderr = streams.Stderr
+	}
+
+	if fd, ok := terminalFd(streams.Stdin); ok && streams.TTY {
+		oldState, err := term.MakeRaw(fd)
+		if err != nil {
+			return ErrSettingTerminalRawMode.Wrap(err)
+		}
+		defer term.Restore(fd, oldState)
+
+		sizeQueue := newTerminalSizeQueue(ctx, fd)
+		defer sizeQueue.stop()
+		opts.TerminalSizeQueue = sizeQueue
+	}
+
+	if err := exec.StreamWithContext(ctx, opts); err != nil {
+		return ErrExecutingCommand.Wrap(err)
+	}
+	return nil
+}
+
+// terminalFd returns the file descriptor of the reader if it is a terminal
+func terminalFd(r io.Reader) (int, bool) {
+	f, ok := r.(*os.File)
+	if !ok {
+		return 0, false
+	}
+	fd := int(f.Fd())
+	return fd, term.IsTerminal(fd)
+}
+
+// terminalSizeQueue reports the size of a local terminal whenever it changes.
+// The size is polled, as resize signals are not available on every platform.
+type terminalSizeQueue struct {
+	sizes  chan remotecommand.TerminalSize
+	cancel context.CancelFunc
+}
+
+var _ remotecommand.TerminalSizeQueue = &terminalSizeQueue{}
+
+func newTerminalSizeQueue(ctx context.Context, fd int) *terminalSizeQueue {
+	ctx, cancel := context.WithCancel(ctx)
+	q := &terminalSizeQueue{
+		sizes:  make(chan remotecommand.TerminalSize, 1),
+		cancel: cancel,
+	}
+
+	go func() {
+		defer close(q.sizes)
+		ticker := time.NewTicker(terminalResizeInterval)
+		defer ticker.Stop()
+
+		var last remotecommand.TerminalSize
+		for {
+			width, height, err := term.GetSize(fd)
+			if err == nil {
+				size := remotecommand.TerminalSize{Width: uint16(width), Height: uint16(height)}
+				if size != last {
+					last = size
+					select {
+					case q.sizes <- size:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return q
+}
+
+// Next returns the next terminal size, or nil once the queue is stopped
+func (q *terminalSizeQueue) Next() *remotecommand.TerminalSize {
+	size, ok := <-q.sizes
+	if !ok {
+		return nil
+	}
+	return &size
+}
+
+func (q *terminalSizeQueue) stop() {
+	q.cancel()
+}
@@ -0,0 +1,165 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
+)
+
+// manifestFieldManager owns the fields of the objects applied from manifests
+const manifestFieldManager = "knuu"
+
+// ManifestResource is a resource applied from a manifest, see ApplyManifest
+type ManifestResource struct {
+	Resource  schema.GroupVersionResource // Resource is the API resource of the object, e.g. v1 configmaps
+	Kind      string                      // Kind of the object, e.g. ConfigMap
+	Namespace string                      // Namespace of the object, empty for the cluster scoped resources
+	Name      string                      // Name of the object
+}
+
+// String returns the kind and the name of the resource, e.g. ConfigMap/settings
+func (r ManifestResource) String() string {
+	return r.Kind + "/" + r.Name
+}
+
+// DecodeManifest returns the objects of a YAML or JSON manifest, which may hold several documents separated by ---
+// The empty documents are skipped, every other document must have an apiVersion, a kind and a name
+func DecodeManifest(manifest []byte) ([]*unstructured.Unstructured, error) {
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(manifest), 4096)
+	var objects []*unstructured.Unstructured
+	for document := 1; ; document++ {
+		var object map[string]interface{}
+		if err := decoder.Decode(&object); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, ErrDecodingManifest.WithParams(document).Wrap(err)
+		}
+		if len(object) == 0 {
+			continue
+		}
+		u := &unstructured.Unstructured{Object: object}
+		if u.GetAPIVersion() == "" || u.GetKind() == "" || u.GetName() == "" {
+			return nil, ErrInvalidManifestObject.WithParams(document)
+		}
+		objects = append(objects, u)
+	}
+	if len(objects) == 0 {
+		return nil, ErrEmptyManifest
+	}
+	return objects, nil
+}
+
+// ApplyManifest applies the objects of the manifest with server-side apply, so applying them again updates them,
+// and adds the labels to them unless they set them already
+// The namespaced objects without a namespace are applied in the namespace of the client
+// The resources applied before an object fails to apply are returned along with the error, so they can be deleted
+func (c *Client) ApplyManifest(ctx context.Context, manifest []byte, labels map[string]string) ([]ManifestResource, error) {
+	objects, err := DecodeManifest(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make([]ManifestResource, 0, len(objects))
+	for _, object := range objects {
+		resource, err := c.applyObject(ctx, object, labels)
+		if err != nil {
+			return applied, err
+		}
+		applied = append(applied, resource)
+	}
+	return applied, nil
+}
+
+func (c *Client) applyObject(ctx context.Context, object *unstructured.Unstructured, labels map[string]string) (ManifestResource, error) {
+	gvk := object.GroupVersionKind()
+	apiResource, err := c.apiResourceFor(gvk)
+	if err != nil {
+		return ManifestResource{}, err
+	}
+	resource := ManifestResource{
+		Resource: gvk.GroupVersion().WithResource(apiResource.Name),
+		Kind:     gvk.Kind,
+		Name:     object.GetName(),
+	}
+	if apiResource.Namespaced {
+		if object.GetNamespace() == "" {
+			object.SetNamespace(c.namespace)
+		}
+		resource.Namespace = object.GetNamespace()
+	}
+
+	objectLabels := object.GetLabels()
+	if objectLabels == nil {
+		objectLabels = make(map[string]string, len(labels))
+	}
+	for key, value := range labels {
+		if _, ok := objectLabels[key]; !ok {
+			objectLabels[key] = value
+		}
+	}
+	object.SetLabels(objectLabels)
+
+	_, err = c.manifestResourceClient(resource).Apply(ctx, resource.Name, object, metav1.ApplyOptions{FieldManager: manifestFieldManager, Force: true})
+	if err != nil {
+		return ManifestResource{}, ErrApplyingManifestObject.WithParams(resource.String()).Wrap(err)
+	}
+	logrus.Debugf("Applied %s in namespace %s", resource, resource.Namespace)
+	return resource, nil
+}
+
+// apiResourceFor returns the API resource serving the kind, e.g. configmaps for the kind ConfigMap of v1
+func (c *Client) apiResourceFor(gvk schema.GroupVersionKind) (metav1.APIResource, error) {
+	groupVersion := gvk.GroupVersion().String()
+	resourceList, err := c.discoveryClient.ServerResourcesForGroupVersion(groupVersion)
+	if err != nil {
+		return metav1.APIResource{}, ErrFindingManifestKind.WithParams(gvk.Kind, groupVersion).Wrap(err)
+	}
+	for _, apiResource := range resourceList.APIResources {
+		// the subresources, e.g. pods/status, have the kind of their resource
+		if apiResource.Kind == gvk.Kind && !strings.Contains(apiResource.Name, "/") {
+			return apiResource, nil
+		}
+	}
+	return metav1.APIResource{}, ErrFindingManifestKind.WithParams(gvk.Kind, groupVersion)
+}
+
+func (c *Client) manifestResourceClient(resource ManifestResource) dynamic.ResourceInterface {
+	if resource.Namespace == "" {
+		return c.dynamicClient.Resource(resource.Resource)
+	}
+	return c.dynamicClient.Resource(resource.Resource).Namespace(resource.Namespace)
+}
+
+func (c *Client) ManifestResourceExists(ctx context.Context, resource ManifestResource) (bool, error) {
+	_, err := c.manifestResourceClient(resource).Get(ctx, resource.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrs.IsNotFound(err) {
+			return false, nil
+		}
+		return false, ErrGettingManifestResource.WithParams(resource.String()).Wrap(err)
+	}
+	return true, nil
+}
+
+// DeleteManifestResource deletes a resource applied from a manifest along with its dependents,
+// skipping a resource that is already deleted
+func (c *Client) DeleteManifestResource(ctx context.Context, resource ManifestResource) error {
+	propagation := metav1.DeletePropagationBackground
+	err := c.manifestResourceClient(resource).Delete(ctx, resource.Name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && !apierrs.IsNotFound(err) {
+		return ErrDeletingManifestResource.WithParams(resource.String()).Wrap(err)
+	}
+	logrus.Debugf("Deleted %s in namespace %s", resource, resource.Namespace)
+	return nil
+}
@@ -0,0 +1,37 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeManifest(t *testing.T) {
+	objects, err := DecodeManifest([]byte(`---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  level: debug
+---
+# only a comment
+---
+{"apiVersion": "example.com/v1", "kind": "Widget", "metadata": {"name": "widget", "namespace": "other"}, "spec": {"size": 3}}
+`))
+	require.NoError(t, err)
+	require.Len(t, objects, 2, "the empty documents should be skipped")
+	assert.Equal(t, "ConfigMap", objects[0].GetKind())
+	assert.Equal(t, "settings", objects[0].GetName())
+	assert.Equal(t, map[string]interface{}{"level": "debug"}, objects[0].Object["data"])
+	assert.Equal(t, "example.com/v1", objects[1].GetAPIVersion())
+	assert.Equal(t, "other", objects[1].GetNamespace())
+
+	_, err = DecodeManifest([]byte("kind: ConfigMap\nmetadata:\n  name: settings\n"))
+	assert.ErrorIs(t, err, ErrInvalidManifestObject)
+	_, err = DecodeManifest([]byte("apiVersion: v1\nkind: [ConfigMap\n"))
+	assert.ErrorIs(t, err, ErrDecodingManifest)
+	_, err = DecodeManifest([]byte("---\n---\n"))
+	assert.ErrorIs(t, err, ErrEmptyManifest)
+}
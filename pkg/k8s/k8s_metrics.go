@@ -0,0 +1,62 @@
+package k8s
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// podMetricsGVR is the resource served by metrics-server for pod metrics
+var podMetricsGVR = schema.GroupVersionResource{
+	Group:    "metrics.k8s.io",
+	Version:  "v1beta1",
+	Resource: "pods",
+}
+
+// ContainerMetrics is the resource usage of a single container
+type ContainerMetrics struct {
+	Name  string          `json:"name"`
+	Usage v1.ResourceList `json:"usage"`
+}
+
+// PodMetrics is the resource usage of a pod as reported by metrics-server
+type PodMetrics struct {
+	Timestamp  metav1.Time        `json:"timestamp"`
+	Window     metav1.Duration    `json:"window"`
+	Containers []ContainerMetrics `json:"containers"`
+}
+
+// MetricsServerAvailable returns true if the metrics API is served in the cluster
+func (c *Client) MetricsServerAvailable() bool {
+	resourceList, err := c.discoveryClient.ServerResourcesForGroupVersion(podMetricsGVR.GroupVersion().String())
+	if err != nil {
+		return false
+	}
+	for _, resource := range resourceList.APIResources {
+		if resource.Name == podMetricsGVR.Resource {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPodMetrics returns the current resource usage of the given pod
+func (c *Client) GetPodMetrics(ctx context.Context, name string) (*PodMetrics, error) {
+	if !c.MetricsServerAvailable() {
+		return nil, ErrMetricsServerNotInstalled
+	}
+
+	obj, err := c.dynamicClient.Resource(podMetricsGVR).Namespace(c.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, ErrGettingPodMetrics.WithParams(name).Wrap(err)
+	}
+
+	metrics := &PodMetrics{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, metrics); err != nil {
+		return nil, ErrParsingPodMetrics.WithParams(name).Wrap(err)
+	}
+	return metrics, nil
+}
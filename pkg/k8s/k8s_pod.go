@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -31,31 +32,50 @@ const (
 )
 
 type ContainerConfig struct {
-	Name            string              // Name to assign to the Container
-	Image           string              // Name of the container image to use for the container
-	Command         []string            // Command to run in the container
-	Args            []string            // Arguments to pass to the command in the container
-	Env             map[string]string   // Environment variables to set in the container
-	Volumes         []*Volume           // Volumes to mount in the Pod
-	MemoryRequest   string              // Memory request for the container
-	MemoryLimit     string              // Memory limit for the container
-	CPURequest      string              // CPU request for the container
-	LivenessProbe   *v1.Probe           // Liveness probe for the container
-	ReadinessProbe  *v1.Probe           // Readiness probe for the container
-	StartupProbe    *v1.Probe           // Startup probe for the container
-	Files           []*File             // Files to add to the Pod
-	SecurityContext *v1.SecurityContext // Security context for the container
+	Name                    string                      // Name to assign to the Container
+	Image                   string                      // Name of the container image to use for the container
+	Command                 []string                    // Command to run in the container
+	Args                    []string                    // Arguments to pass to the command in the container
+	Env                     map[string]string           // Environment variables to set in the container
+	EnvValueFrom            map[string]*v1.EnvVarSource // Environment variables sourced from the pod or container, e.g. with a fieldRef
+	EnvFrom                 []v1.EnvFromSource          // Sources to import all the keys of as environment variables, e.g. ConfigMaps
+	Volumes                 []*Volume                   // Volumes to mount in the Pod
+	SharedVolumes           []*SharedVolume             // Volumes shared with other Pods, each one with its own claim
+	TmpfsMounts             []*TmpfsMount               // Memory-backed volumes, writable even with a read-only root filesystem
+	MemoryRequest           string                      // Memory request for the container
+	MemoryLimit             string                      // Memory limit for the container
+	CPURequest              string                      // CPU request for the container
+	EphemeralStorageRequest string                      // Ephemeral storage request for the container, not set if empty
+	EphemeralStorageLimit   string                      // Ephemeral storage limit for the container, not set if empty
+	LivenessProbe           *v1.Probe                   // Liveness probe for the container
+	ReadinessProbe          *v1.Probe                   // Readiness probe for the container
+	StartupProbe            *v1.Probe                   // Startup probe for the container
+	Files                   []*File                     // Files to add to the Pod
+	SecurityContext         *v1.SecurityContext         // Security context for the container
+	WorkingDir              string                      // Working directory of the container, empty uses the WORKDIR of the image
+	TermMsgPath             string                      // Path of the termination message file, empty uses /dev/termination-log
+	TermMsgPolicy           v1.TerminationMessagePolicy // Policy of the termination message, empty uses the file only
+	PostStartCommand        []string                    // Command executed in the container right after it is created, none if empty
 }
 
 type PodConfig struct {
-	Namespace          string            // Kubernetes namespace of the Pod
-	Name               string            // Name to assign to the Pod
-	Labels             map[string]string // Labels to apply to the Pod
-	ServiceAccountName string            // ServiceAccount to assign to Pod
-	FsGroup            int64             // FSGroup to apply to the Pod
-	ContainerConfig    ContainerConfig   // ContainerConfig for the Pod
-	SidecarConfigs     []ContainerConfig // SideCarConfigs for the Pod
-	Annotations        map[string]string // Annotations to apply to the Pod
+	Namespace                    string                        // Kubernetes namespace of the Pod
+	Name                         string                        // Name to assign to the Pod
+	Labels                       map[string]string             // Labels to apply to the Pod
+	ServiceAccountName           string                        // ServiceAccount to assign to Pod
+	FsGroup                      int64                         // FSGroup to apply to the Pod, not set if 0
+	ContainerConfig              ContainerConfig               // ContainerConfig for the Pod
+	SidecarConfigs               []ContainerConfig             // SideCarConfigs for the Pod
+	Annotations                  map[string]string             // Annotations to apply to the Pod
+	Sysctls                      []v1.Sysctl                   // Sysctls to set in the Pod
+	PriorityClassName            string                        // PriorityClass to assign to the Pod
+	RuntimeClassName             string                        // RuntimeClass to run the containers of the Pod with, e.g. gvisor, empty for the default runtime
+	AutomountServiceAccountToken *bool                         // Whether to mount the ServiceAccount token, nil uses the setting of the ServiceAccount
+	ShareProcessNamespace        bool                          // Whether the containers of the Pod share a single process namespace
+	ReadinessGates               []string                      // Condition types that must be True, in addition to the readiness of the containers, for the Pod to be ready
+	ActiveDeadlineSeconds        *int64                        // Duration the Pod may run before it is terminated, nil for no deadline
+	TopologySpreadConstraints    []v1.TopologySpreadConstraint // Constraints on how the Pods are spread across the topology domains
+	NodeSelector                 map[string]string             // Labels a node must have for the Pod to be scheduled on it, e.g. kubernetes.io/arch
 }
 
 type Volume struct {
@@ -64,6 +84,18 @@ type Volume struct {
 	Owner int64
 }
 
+// SharedVolume is a volume backed by a ReadWriteMany claim, which can be mounted by several Pods
+type SharedVolume struct {
+	ClaimName string
+	Path      string
+}
+
+// TmpfsMount is a memory-backed emptyDir volume mounted in a single container
+type TmpfsMount struct {
+	Path      string
+	SizeLimit string // Maximum size of the content, empty for no limit other than the memory limit of the container
+}
+
 type File struct {
 	Source string
 	Dest   string
@@ -155,12 +187,72 @@ func (c *Client) IsPodRunning(ctx context.Context, name string) (bool, error) {
 	return true, nil
 }
 
+// PodsExist returns true if at least one pod with the given labels exists, including terminating pods.
+func (c *Client) PodsExist(ctx context.Context, labels map[string]string) (bool, error) {
+	selector := metav1.FormatLabelSelector(&metav1.LabelSelector{MatchLabels: labels})
+	pods, err := c.clientset.CoreV1().Pods(c.namespace).List(ctx, metav1.ListOptions{LabelSelector: selector, Limit: 1})
+	if err != nil {
+		return false, ErrListingPods.WithParams(selector).Wrap(err)
+	}
+
+	return len(pods.Items) != 0, nil
+}
+
+// StreamPodLogs follows the logs of a container within a pod, the stream ends when the container terminates.
+func (c *Client) StreamPodLogs(ctx context.Context, podName, containerName string) (io.ReadCloser, error) {
+	req := c.clientset.CoreV1().Pods(c.namespace).GetLogs(podName, &v1.PodLogOptions{
+		Container: containerName,
+		Follow:    true,
+	})
+	stream, err := req.Stream(ctx)
+	if err != nil {
+		return nil, ErrStreamingPodLogs.WithParams(containerName, podName).Wrap(err)
+	}
+	return stream, nil
+}
+
+// GetPodLogs returns the logs of a container within a pod, including the logs of its previous run if it restarted,
+// so the output of a crashed container is kept.
+func (c *Client) GetPodLogs(ctx context.Context, podName, containerName string) ([]byte, error) {
+	logs, err := c.clientset.CoreV1().Pods(c.namespace).GetLogs(podName, &v1.PodLogOptions{Container: containerName}).DoRaw(ctx)
+	if err != nil {
+		return nil, ErrGettingPodLogs.WithParams(containerName, podName).Wrap(err)
+	}
+	previous, err := c.clientset.CoreV1().Pods(c.namespace).GetLogs(podName, &v1.PodLogOptions{Container: containerName, Previous: true}).DoRaw(ctx)
+	if err != nil {
+		// there is no previous run if the container did not restart
+		return logs, nil
+	}
+	return append(append(previous, []byte("--- container restarted ---\n")...), logs...), nil
+}
+
 // RunCommandInPod runs a command in a container within a pod with a context.
 func (c *Client) RunCommandInPod(
 	ctx context.Context,
 	podName,
 	containerName string,
 	cmd []string,
+) (string, error) {
+	return c.runCommandInPod(ctx, podName, containerName, cmd, nil)
+}
+
+// CopyToPod extracts the tar archive into the directory of a container within a pod, like `kubectl cp`.
+// The container must have a tar binary; the owner of the files in the archive is kept if the container runs as root.
+func (c *Client) CopyToPod(ctx context.Context, podName, containerName, destDir string, archive io.Reader) error {
+	cmd := []string{"tar", "-x", "-m", "-C", destDir, "-f", "-"}
+	if _, err := c.runCommandInPod(ctx, podName, containerName, cmd, archive); err != nil {
+		return ErrCopyingToPod.WithParams(destDir, podName).Wrap(err)
+	}
+	return nil
+}
+
+// runCommandInPod runs a command in a container within a pod, with the given stdin if it is not nil.
+func (c *Client) runCommandInPod(
+	ctx context.Context,
+	podName,
+	containerName string,
+	cmd []string,
+	stdin io.Reader,
 ) (string, error) {
 	_, err := c.getPod(ctx, podName)
 	if err != nil {
@@ -175,7 +267,7 @@ func (c *Client) RunCommandInPod(
 		VersionedParams(&v1.PodExecOptions{
 			Command:   cmd,
 			Container: containerName,
-			Stdin:     false,
+			Stdin:     stdin != nil,
 			Stdout:    true,
 			Stderr:    true,
 			TTY:       false,
@@ -194,6 +286,7 @@ func (c *Client) RunCommandInPod(
 	// Execute the command and capture the output and error streams
 	var stdout, stderr bytes.Buffer
 	err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:  stdin,
 		Stdout: &stdout,
 		Stderr: &stderr,
 		Tty:    false,
@@ -315,16 +408,32 @@ func (c *Client) getPod(ctx context.Context, name string) (*v1.Pod, error) {
 	return pod, nil
 }
 
-// buildEnv builds an environment variable configuration for a Pod based on the given map of key-value pairs.
-func buildEnv(envMap map[string]string) []v1.EnvVar {
-	envVars := make([]v1.EnvVar, 0, len(envMap))
-	for key, val := range envMap {
-		envVar := v1.EnvVar{Name: key, Value: val}
-		envVars = append(envVars, envVar)
+// buildEnv builds an environment variable configuration for a Pod based on the given map of key-value pairs
+// and the variables sourced from the pod or container.
+// The sourced variables come first, so the literal values can reference them with $(VAR_NAME).
+func buildEnv(envMap map[string]string, valueFrom map[string]*v1.EnvVarSource) []v1.EnvVar {
+	envVars := make([]v1.EnvVar, 0, len(valueFrom)+len(envMap))
+	for _, key := range sortedKeys(valueFrom) {
+		envVars = append(envVars, v1.EnvVar{Name: key, ValueFrom: valueFrom[key]})
+	}
+	for _, key := range sortedKeys(envMap) {
+		if _, ok := valueFrom[key]; ok {
+			continue
+		}
+		envVars = append(envVars, v1.EnvVar{Name: key, Value: envMap[key]})
 	}
 	return envVars
 }
 
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // buildPodVolumes generates a volume configuration for a pod based on the given name.
 // If the volumes amount is zero, returns an empty slice.
 func buildPodVolumes(name string, volumesAmount, filesAmount int) ([]v1.Volume, error) {
@@ -370,6 +479,54 @@ func buildPodVolumes(name string, volumesAmount, filesAmount int) ([]v1.Volume,
 	return podVolumes, nil
 }
 
+// buildSharedPodVolumes generates the volumes of the claims shared by the containers, once per claim
+func buildSharedPodVolumes(configs []ContainerConfig) []v1.Volume {
+	var podVolumes []v1.Volume
+	seen := make(map[string]bool)
+	for _, config := range configs {
+		for _, volume := range config.SharedVolumes {
+			if seen[volume.ClaimName] {
+				continue
+			}
+			seen[volume.ClaimName] = true
+			podVolumes = append(podVolumes, v1.Volume{
+				Name: volume.ClaimName,
+				VolumeSource: v1.VolumeSource{
+					PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+						ClaimName: volume.ClaimName,
+					},
+				},
+			})
+		}
+	}
+	return podVolumes
+}
+
+// tmpfsVolumeName returns the name of the volume of the n-th tmpfs mount of a container
+func tmpfsVolumeName(name string, n int) string {
+	return fmt.Sprintf("%s-tmpfs-%d", name, n)
+}
+
+// buildTmpfsPodVolumes generates the memory-backed volumes of the tmpfs mounts of a container
+func buildTmpfsPodVolumes(name string, mounts []*TmpfsMount) ([]v1.Volume, error) {
+	var podVolumes []v1.Volume
+	for n, mount := range mounts {
+		emptyDir := &v1.EmptyDirVolumeSource{Medium: v1.StorageMediumMemory}
+		if mount.SizeLimit != "" {
+			sizeLimit, err := resource.ParseQuantity(mount.SizeLimit)
+			if err != nil {
+				return nil, ErrParsingTmpfsSizeLimit.WithParams(mount.SizeLimit).Wrap(err)
+			}
+			emptyDir.SizeLimit = &sizeLimit
+		}
+		podVolumes = append(podVolumes, v1.Volume{
+			Name:         tmpfsVolumeName(name, n),
+			VolumeSource: v1.VolumeSource{EmptyDir: emptyDir},
+		})
+	}
+	return podVolumes, nil
+}
+
 // buildContainerVolumes generates a volume mount configuration for a container based on the given name and volumes.
 func buildContainerVolumes(name string, volumes []*Volume) ([]v1.VolumeMount, error) {
 	var containerVolumes []v1.VolumeMount
@@ -502,10 +659,29 @@ func buildResources(memoryRequest string, memoryLimit string, cpuRequest string)
 	return resources, nil
 }
 
+// addEphemeralStorage adds the ephemeral storage request and limit to the resources of a container, if they are set
+func addEphemeralStorage(resources *v1.ResourceRequirements, request, limit string) error {
+	if request != "" {
+		quantity, err := resource.ParseQuantity(request)
+		if err != nil {
+			return ErrParsingEphemeralStorageRequest.WithParams(request).Wrap(err)
+		}
+		resources.Requests[v1.ResourceEphemeralStorage] = quantity
+	}
+	if limit != "" {
+		quantity, err := resource.ParseQuantity(limit)
+		if err != nil {
+			return ErrParsingEphemeralStorageLimit.WithParams(limit).Wrap(err)
+		}
+		resources.Limits[v1.ResourceEphemeralStorage] = quantity
+	}
+	return nil
+}
+
 // prepareContainer creates a v1.Container from a given ContainerConfig.
 func prepareContainer(config ContainerConfig) (v1.Container, error) {
 	// Build environment variables from the given map
-	podEnv := buildEnv(config.Env)
+	podEnv := buildEnv(config.Env, config.EnvValueFrom)
 
 	// Build container volumes from the given map
 	containerVolumes, err := buildContainerVolumes(config.Name, config.Volumes)
@@ -513,10 +689,33 @@ func prepareContainer(config ContainerConfig) (v1.Container, error) {
 		return v1.Container{}, ErrBuildingContainerVolumes.Wrap(err)
 	}
 
+	for _, volume := range config.SharedVolumes {
+		containerVolumes = append(containerVolumes, v1.VolumeMount{
+			Name:      volume.ClaimName,
+			MountPath: volume.Path,
+		})
+	}
+	for n, mount := range config.TmpfsMounts {
+		containerVolumes = append(containerVolumes, v1.VolumeMount{
+			Name:      tmpfsVolumeName(config.Name, n),
+			MountPath: mount.Path,
+		})
+	}
+
 	resources, err := buildResources(config.MemoryRequest, config.MemoryLimit, config.CPURequest)
 	if err != nil {
 		return v1.Container{}, ErrBuildingResources.Wrap(err)
 	}
+	if err := addEphemeralStorage(&resources, config.EphemeralStorageRequest, config.EphemeralStorageLimit); err != nil {
+		return v1.Container{}, ErrBuildingResources.Wrap(err)
+	}
+
+	var lifecycle *v1.Lifecycle
+	if len(config.PostStartCommand) > 0 {
+		lifecycle = &v1.Lifecycle{
+			PostStart: &v1.LifecycleHandler{Exec: &v1.ExecAction{Command: config.PostStartCommand}},
+		}
+	}
 
 	return v1.Container{
 		Name:            config.Name,
@@ -524,12 +723,18 @@ func prepareContainer(config ContainerConfig) (v1.Container, error) {
 		Command:         config.Command,
 		Args:            config.Args,
 		Env:             podEnv,
+		EnvFrom:         config.EnvFrom,
 		VolumeMounts:    containerVolumes,
 		Resources:       resources,
 		LivenessProbe:   config.LivenessProbe,
 		ReadinessProbe:  config.ReadinessProbe,
 		StartupProbe:    config.StartupProbe,
 		SecurityContext: config.SecurityContext,
+		WorkingDir:      config.WorkingDir,
+		Lifecycle:       lifecycle,
+		// kubernetes defaults the empty values
+		TerminationMessagePath:   config.TermMsgPath,
+		TerminationMessagePolicy: config.TermMsgPolicy,
 	}, nil
 }
 
@@ -569,8 +774,12 @@ func preparePodVolumes(config ContainerConfig) ([]v1.Volume, error) {
 	if err != nil {
 		return nil, ErrBuildingPodVolumes.Wrap(err)
 	}
+	tmpfsVolumes, err := buildTmpfsPodVolumes(config.Name, config.TmpfsMounts)
+	if err != nil {
+		return nil, ErrBuildingPodVolumes.Wrap(err)
+	}
 
-	return podVolumes, nil
+	return append(podVolumes, tmpfsVolumes...), nil
 }
 
 func preparePodSpec(spec PodConfig, init bool) (v1.PodSpec, error) {
@@ -578,7 +787,11 @@ func preparePodSpec(spec PodConfig, init bool) (v1.PodSpec, error) {
 
 	// Prepare security context
 	securityContext := v1.PodSecurityContext{
-		FSGroup: &spec.FsGroup,
+		Sysctls: spec.Sysctls,
+	}
+	// a fsGroup of 0 would make the volumes writable by the root group instead of leaving them untouched
+	if spec.FsGroup != 0 {
+		securityContext.FSGroup = &spec.FsGroup
 	}
 
 	// Prepare main container
@@ -600,11 +813,25 @@ func preparePodSpec(spec PodConfig, init bool) (v1.PodSpec, error) {
 	}
 
 	podSpec := v1.PodSpec{
-		ServiceAccountName: spec.ServiceAccountName,
-		SecurityContext:    &securityContext,
-		InitContainers:     initContainers,
-		Containers:         []v1.Container{mainContainer},
-		Volumes:            podVolumes,
+		ServiceAccountName:           spec.ServiceAccountName,
+		PriorityClassName:            spec.PriorityClassName,
+		AutomountServiceAccountToken: spec.AutomountServiceAccountToken,
+		SecurityContext:              &securityContext,
+		InitContainers:               initContainers,
+		Containers:                   []v1.Container{mainContainer},
+		Volumes:                      podVolumes,
+		ActiveDeadlineSeconds:        spec.ActiveDeadlineSeconds,
+		TopologySpreadConstraints:    spec.TopologySpreadConstraints,
+		NodeSelector:                 spec.NodeSelector,
+	}
+	if spec.ShareProcessNamespace {
+		podSpec.ShareProcessNamespace = &spec.ShareProcessNamespace
+	}
+	if spec.RuntimeClassName != "" {
+		podSpec.RuntimeClassName = &spec.RuntimeClassName
+	}
+	for _, gate := range spec.ReadinessGates {
+		podSpec.ReadinessGates = append(podSpec.ReadinessGates, v1.PodReadinessGate{ConditionType: v1.PodConditionType(gate)})
 	}
 
 	// Prepare sidecar containers and append to the pod spec
@@ -622,6 +849,7 @@ func preparePodSpec(spec PodConfig, init bool) (v1.PodSpec, error) {
 		podSpec.Containers = append(podSpec.Containers, sidecar)
 		podSpec.Volumes = append(podSpec.Volumes, sidecarVolumes...)
 	}
+	podSpec.Volumes = append(podSpec.Volumes, buildSharedPodVolumes(append([]ContainerConfig{spec.ContainerConfig}, spec.SidecarConfigs...))...)
 
 	return podSpec, nil
 }
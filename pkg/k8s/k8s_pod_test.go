@@ -0,0 +1,227 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testPodConfig() PodConfig {
+	return PodConfig{
+		Namespace: "test-namespace",
+		Name:      "test-pod",
+		ContainerConfig: ContainerConfig{
+			Name:  "test-container",
+			Image: "docker.io/alpine:latest",
+		},
+	}
+}
+
+func TestPreparePodSpecSysctls(t *testing.T) {
+	config := testPodConfig()
+	config.Sysctls = []v1.Sysctl{{Name: "net.ipv4.tcp_syncookies", Value: "1"}}
+
+	spec, err := preparePodSpec(config, false)
+	require.NoError(t, err)
+
+	require.NotNil(t, spec.SecurityContext)
+	assert.Equal(t, config.Sysctls, spec.SecurityContext.Sysctls)
+}
+
+func TestPreparePodSpecFSGroup(t *testing.T) {
+	config := testPodConfig()
+
+	spec, err := preparePodSpec(config, false)
+	require.NoError(t, err)
+	assert.Nil(t, spec.SecurityContext.FSGroup, "the volumes should be left untouched without a fsGroup")
+
+	config.FsGroup = 1000
+	spec, err = preparePodSpec(config, false)
+	require.NoError(t, err)
+	require.NotNil(t, spec.SecurityContext.FSGroup)
+	assert.Equal(t, int64(1000), *spec.SecurityContext.FSGroup)
+}
+
+func TestPreparePodSpecPriorityClass(t *testing.T) {
+	config := testPodConfig()
+	config.PriorityClassName = "high-priority"
+
+	spec, err := preparePodSpec(config, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, "high-priority", spec.PriorityClassName)
+}
+
+func TestPreparePodSpecRuntimeClass(t *testing.T) {
+	config := testPodConfig()
+	spec, err := preparePodSpec(config, false)
+	require.NoError(t, err)
+	assert.Nil(t, spec.RuntimeClassName, "the default runtime is used")
+
+	config.RuntimeClassName = "gvisor"
+	spec, err = preparePodSpec(config, false)
+	require.NoError(t, err)
+	require.NotNil(t, spec.RuntimeClassName)
+	assert.Equal(t, "gvisor", *spec.RuntimeClassName)
+}
+
+func TestPreparePodSpecEnvValueFrom(t *testing.T) {
+	config := testPodConfig()
+	podName := &v1.EnvVarSource{FieldRef: &v1.ObjectFieldSelector{FieldPath: "metadata.name"}}
+	config.ContainerConfig.Env = map[string]string{"GREETING": "hello $(POD_NAME)", "POD_NAME": "ignored"}
+	config.ContainerConfig.EnvValueFrom = map[string]*v1.EnvVarSource{"POD_NAME": podName}
+
+	spec, err := preparePodSpec(config, false)
+	require.NoError(t, err)
+
+	require.Len(t, spec.Containers, 1)
+	assert.Equal(t, []v1.EnvVar{
+		{Name: "POD_NAME", ValueFrom: podName},
+		{Name: "GREETING", Value: "hello $(POD_NAME)"},
+	}, spec.Containers[0].Env)
+}
+
+func TestPreparePodSpecAutomountServiceAccountToken(t *testing.T) {
+	config := testPodConfig()
+	spec, err := preparePodSpec(config, false)
+	require.NoError(t, err)
+	assert.Nil(t, spec.AutomountServiceAccountToken, "the setting of the service account should be used by default")
+
+	automount := false
+	config.AutomountServiceAccountToken = &automount
+	spec, err = preparePodSpec(config, false)
+	require.NoError(t, err)
+	require.NotNil(t, spec.AutomountServiceAccountToken)
+	assert.False(t, *spec.AutomountServiceAccountToken)
+}
+
+func TestPreparePodSpecShareProcessNamespace(t *testing.T) {
+	config := testPodConfig()
+	spec, err := preparePodSpec(config, false)
+	require.NoError(t, err)
+	assert.Nil(t, spec.ShareProcessNamespace)
+
+	config.ShareProcessNamespace = true
+	spec, err = preparePodSpec(config, false)
+	require.NoError(t, err)
+	require.NotNil(t, spec.ShareProcessNamespace)
+	assert.True(t, *spec.ShareProcessNamespace)
+}
+
+func TestPreparePodSpecReadinessGates(t *testing.T) {
+	config := testPodConfig()
+	config.ReadinessGates = []string{"example.com/seeded"}
+	spec, err := preparePodSpec(config, false)
+	require.NoError(t, err)
+	assert.Equal(t, []v1.PodReadinessGate{{ConditionType: "example.com/seeded"}}, spec.ReadinessGates)
+}
+
+func TestPreparePodSpecTopologySpreadConstraints(t *testing.T) {
+	config := testPodConfig()
+	config.TopologySpreadConstraints = []v1.TopologySpreadConstraint{{MaxSkew: 1, TopologyKey: "kubernetes.io/hostname", WhenUnsatisfiable: v1.DoNotSchedule}}
+	spec, err := preparePodSpec(config, false)
+	require.NoError(t, err)
+	assert.Equal(t, config.TopologySpreadConstraints, spec.TopologySpreadConstraints)
+}
+
+func TestPreparePodSpecSharedVolumes(t *testing.T) {
+	shared := &SharedVolume{ClaimName: "shared-volume-1234", Path: "/shared"}
+	config := testPodConfig()
+	config.ContainerConfig.SharedVolumes = []*SharedVolume{shared}
+	config.SidecarConfigs = []ContainerConfig{{
+		Name:          "test-sidecar",
+		Image:         "docker.io/alpine:latest",
+		SharedVolumes: []*SharedVolume{shared},
+	}}
+
+	spec, err := preparePodSpec(config, false)
+	require.NoError(t, err)
+
+	// the claim is mounted by both containers but added once to the pod
+	require.Len(t, spec.Volumes, 1)
+	assert.Equal(t, "shared-volume-1234", spec.Volumes[0].Name)
+	require.NotNil(t, spec.Volumes[0].PersistentVolumeClaim)
+	assert.Equal(t, "shared-volume-1234", spec.Volumes[0].PersistentVolumeClaim.ClaimName)
+	for _, container := range spec.Containers {
+		assert.Equal(t, []v1.VolumeMount{{Name: "shared-volume-1234", MountPath: "/shared"}}, container.VolumeMounts, container.Name)
+	}
+}
+
+func TestDefaultStorageClassReadWriteMany(t *testing.T) {
+	classes := []storagev1.StorageClass{
+		{ObjectMeta: metav1.ObjectMeta{Name: "nfs"}, Provisioner: "nfs.csi.k8s.io"},
+		{
+			ObjectMeta:  metav1.ObjectMeta{Name: "standard", Annotations: map[string]string{"storageclass.kubernetes.io/is-default-class": "true"}},
+			Provisioner: "rancher.io/local-path",
+		},
+	}
+
+	class := defaultStorageClass(classes)
+	require.NotNil(t, class)
+	assert.Equal(t, "standard", class.Name)
+	assert.False(t, supportsReadWriteMany(class.Provisioner))
+	assert.True(t, supportsReadWriteMany(classes[0].Provisioner))
+	assert.True(t, supportsReadWriteMany("efs.csi.aws.com"))
+	assert.Nil(t, defaultStorageClass(classes[:1]))
+}
+
+func TestPreparePodSpecEphemeralStorage(t *testing.T) {
+	config := testPodConfig()
+	spec, err := preparePodSpec(config, false)
+	require.NoError(t, err)
+	assert.NotContains(t, spec.Containers[0].Resources.Requests, v1.ResourceEphemeralStorage, "ephemeral storage is not set by default")
+	assert.NotContains(t, spec.Containers[0].Resources.Limits, v1.ResourceEphemeralStorage)
+
+	config.ContainerConfig.EphemeralStorageRequest = "1Gi"
+	config.ContainerConfig.EphemeralStorageLimit = "4Gi"
+	spec, err = preparePodSpec(config, false)
+	require.NoError(t, err)
+	resources := spec.Containers[0].Resources
+	assert.Equal(t, resource.MustParse("1Gi"), resources.Requests[v1.ResourceEphemeralStorage])
+	assert.Equal(t, resource.MustParse("4Gi"), resources.Limits[v1.ResourceEphemeralStorage])
+
+	config.ContainerConfig.EphemeralStorageLimit = "lots"
+	_, err = preparePodSpec(config, false)
+	assert.ErrorContains(t, err, ErrParsingEphemeralStorageLimit.WithParams("lots").Error())
+}
+
+func TestPreparePodSpecTmpfsMounts(t *testing.T) {
+	config := testPodConfig()
+	config.ContainerConfig.TmpfsMounts = []*TmpfsMount{{Path: "/tmp", SizeLimit: "64Mi"}, {Path: "/run"}}
+	spec, err := preparePodSpec(config, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, []v1.VolumeMount{
+		{Name: "test-container-tmpfs-0", MountPath: "/tmp"},
+		{Name: "test-container-tmpfs-1", MountPath: "/run"},
+	}, spec.Containers[0].VolumeMounts)
+	sizeLimit := resource.MustParse("64Mi")
+	assert.Equal(t, []v1.Volume{
+		{Name: "test-container-tmpfs-0", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{Medium: v1.StorageMediumMemory, SizeLimit: &sizeLimit}}},
+		{Name: "test-container-tmpfs-1", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{Medium: v1.StorageMediumMemory}}},
+	}, spec.Volumes)
+
+	config.ContainerConfig.TmpfsMounts[1].SizeLimit = "huge"
+	_, err = preparePodSpec(config, false)
+	assert.ErrorContains(t, err, ErrParsingTmpfsSizeLimit.WithParams("huge").Error())
+}
+
+func TestPreparePodSpecPostStartCommand(t *testing.T) {
+	config := testPodConfig()
+
+	spec, err := preparePodSpec(config, false)
+	require.NoError(t, err)
+	assert.Nil(t, spec.Containers[0].Lifecycle, "no hook should be set without a command")
+
+	config.ContainerConfig.PostStartCommand = []string{"touch", "/tmp/started"}
+	spec, err = preparePodSpec(config, false)
+	require.NoError(t, err)
+	require.NotNil(t, spec.Containers[0].Lifecycle)
+	require.NotNil(t, spec.Containers[0].Lifecycle.PostStart)
+	assert.Equal(t, config.ContainerConfig.PostStartCommand, spec.Containers[0].Lifecycle.PostStart.Exec.Command)
+}
@@ -0,0 +1,20 @@
+package k8s
+
+import (
+	"context"
+
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PriorityClassExists checks if the given cluster wide PriorityClass exists
+func (c *Client) PriorityClassExists(ctx context.Context, name string) (bool, error) {
+	_, err := c.clientset.SchedulingV1().PriorityClasses().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if apierrs.IsNotFound(err) {
+			return false, nil
+		}
+		return false, ErrGettingPriorityClass.WithParams(name).Wrap(err)
+	}
+	return true, nil
+}
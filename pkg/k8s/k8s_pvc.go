@@ -5,6 +5,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -15,6 +16,28 @@ func (c *Client) CreatePersistentVolumeClaim(
 	name string,
 	labels map[string]string,
 	size resource.Quantity,
+) error {
+	return c.createPersistentVolumeClaim(ctx, name, labels, size, v1.ReadWriteOnce)
+}
+
+// CreateSharedPersistentVolumeClaim deploys a ReadWriteMany PersistentVolumeClaim,
+// which can be mounted by several pods at the same time, possibly on different nodes.
+// The default StorageClass must support it, see ValidateReadWriteManyStorageClass.
+func (c *Client) CreateSharedPersistentVolumeClaim(
+	ctx context.Context,
+	name string,
+	labels map[string]string,
+	size resource.Quantity,
+) error {
+	return c.createPersistentVolumeClaim(ctx, name, labels, size, v1.ReadWriteMany)
+}
+
+func (c *Client) createPersistentVolumeClaim(
+	ctx context.Context,
+	name string,
+	labels map[string]string,
+	size resource.Quantity,
+	accessMode v1.PersistentVolumeAccessMode,
 ) error {
 	pvc := &v1.PersistentVolumeClaim{
 		ObjectMeta: metav1.ObjectMeta{
@@ -24,7 +47,7 @@ func (c *Client) CreatePersistentVolumeClaim(
 		},
 		Spec: v1.PersistentVolumeClaimSpec{
 			AccessModes: []v1.PersistentVolumeAccessMode{
-				v1.ReadWriteOnce,
+				accessMode,
 			},
 			Resources: v1.ResourceRequirements{
 				Requests: v1.ResourceList{
@@ -57,6 +80,17 @@ func (c *Client) DeletePersistentVolumeClaim(ctx context.Context, name string) e
 	return nil
 }
 
+func (c *Client) PersistentVolumeClaimExists(ctx context.Context, name string) (bool, error) {
+	_, err := c.getPersistentVolumeClaim(ctx, name)
+	if err != nil {
+		if apierrs.IsNotFound(err) {
+			return false, nil
+		}
+		return false, ErrGettingPersistentVolumeClaim.WithParams(name).Wrap(err)
+	}
+	return true, nil
+}
+
 func (c *Client) getPersistentVolumeClaim(ctx context.Context, name string) (*v1.PersistentVolumeClaim, error) {
 	return c.clientset.CoreV1().PersistentVolumeClaims(c.namespace).Get(ctx, name, metav1.GetOptions{})
 }
@@ -91,6 +91,20 @@ func (c *Client) IsReplicaSetRunning(ctx context.Context, name string) (bool, er
 	return rs.Status.ReadyReplicas == *rs.Spec.Replicas, nil
 }
 
+// GetReplicaSetReplicas returns the number of ready pods of the ReplicaSet and the number of pods it should run
+// A pod is ready once all its containers pass their readiness probes
+func (c *Client) GetReplicaSetReplicas(ctx context.Context, name string) (ready, desired int32, err error) {
+	rs, err := c.getReplicaSet(ctx, name)
+	if err != nil {
+		return 0, 0, ErrGettingReplicaSet.WithParams(name).Wrap(err)
+	}
+	desired = 1
+	if rs.Spec.Replicas != nil {
+		desired = *rs.Spec.Replicas
+	}
+	return rs.Status.ReadyReplicas, desired, nil
+}
+
 func (c *Client) DeleteReplicaSetWithGracePeriod(ctx context.Context, name string, gracePeriodSeconds *int64) error {
 	exists, err := c.ReplicaSetExists(ctx, name)
 	if err != nil {
@@ -133,6 +147,20 @@ func (c *Client) GetFirstPodFromReplicaSet(ctx context.Context, name string) (*v
 	return c.getPod(ctx, pods.Items[0].Name)
 }
 
+// ListPodsFromReplicaSet returns all the pods of the ReplicaSet, including the terminated ones it replaced
+func (c *Client) ListPodsFromReplicaSet(ctx context.Context, name string) ([]v1.Pod, error) {
+	rs, err := c.getReplicaSet(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	selector := metav1.FormatLabelSelector(rs.Spec.Selector)
+	pods, err := c.clientset.CoreV1().Pods(c.namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, ErrListingPodsForReplicaSet.WithParams(name).Wrap(err)
+	}
+	return pods.Items, nil
+}
+
 func (c *Client) getReplicaSet(ctx context.Context, name string) (*appv1.ReplicaSet, error) {
 	rs, err := c.clientset.AppsV1().ReplicaSets(c.namespace).Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
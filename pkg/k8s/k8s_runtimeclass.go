@@ -0,0 +1,20 @@
+package k8s
+
+import (
+	"context"
+
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RuntimeClassExists checks if the given cluster wide RuntimeClass exists
+func (c *Client) RuntimeClassExists(ctx context.Context, name string) (bool, error) {
+	_, err := c.clientset.NodeV1().RuntimeClasses().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if apierrs.IsNotFound(err) {
+			return false, nil
+		}
+		return false, ErrGettingRuntimeClass.WithParams(name).Wrap(err)
+	}
+	return true, nil
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
@@ -20,6 +21,17 @@ func (c *Client) GetService(ctx context.Context, name string) (*v1.Service, erro
 	return svc, nil
 }
 
+func (c *Client) ServiceExists(ctx context.Context, name string) (bool, error) {
+	_, err := c.clientset.CoreV1().Services(c.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if apierrs.IsNotFound(err) {
+			return false, nil
+		}
+		return false, ErrGettingService.WithParams(name).Wrap(err)
+	}
+	return true, nil
+}
+
 func (c *Client) CreateService(
 	ctx context.Context,
 	name string,
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	v1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -23,3 +24,14 @@ func (c *Client) CreateServiceAccount(ctx context.Context, name string, labels m
 func (c *Client) DeleteServiceAccount(ctx context.Context, name string) error {
 	return c.clientset.CoreV1().ServiceAccounts(c.namespace).Delete(ctx, name, metav1.DeleteOptions{})
 }
+
+func (c *Client) ServiceAccountExists(ctx context.Context, name string) (bool, error) {
+	_, err := c.clientset.CoreV1().ServiceAccounts(c.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if apierrs.IsNotFound(err) {
+			return false, nil
+		}
+		return false, ErrGettingServiceAccount.WithParams(name).Wrap(err)
+	}
+	return true, nil
+}
@@ -0,0 +1,69 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// defaultStorageClassAnnotations mark the StorageClass used by the claims without a StorageClass
+var defaultStorageClassAnnotations = []string{
+	"storageclass.kubernetes.io/is-default-class",
+	"storageclass.beta.kubernetes.io/is-default-class",
+}
+
+// readWriteManyProvisioners are the provisioners known to support ReadWriteMany volumes.
+// The hostpath provisioners of single node clusters are included, as all the pods share the node.
+var readWriteManyProvisioners = []string{
+	"nfs",
+	"cephfs",
+	"efs.csi.aws.com",
+	"file.csi.azure.com",
+	"kubernetes.io/azure-file",
+	"filestore.csi.storage.gke.io",
+	"driver.longhorn.io",
+	"k8s.io/minikube-hostpath",
+	"docker.io/hostpath",
+}
+
+// ValidateReadWriteManyStorageClass checks that the default StorageClass of the cluster supports ReadWriteMany volumes.
+// Kubernetes does not expose the access modes supported by a provisioner, so the provisioner must be a known one,
+// otherwise the claims would stay pending forever.
+func (c *Client) ValidateReadWriteManyStorageClass(ctx context.Context) error {
+	classes, err := c.clientset.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return ErrListingStorageClasses.Wrap(err)
+	}
+	class := defaultStorageClass(classes.Items)
+	if class == nil {
+		return ErrNoDefaultStorageClass
+	}
+	if !supportsReadWriteMany(class.Provisioner) {
+		return ErrStorageClassNotReadWriteMany.WithParams(class.Name, class.Provisioner)
+	}
+	return nil
+}
+
+// defaultStorageClass returns the default StorageClass, or nil if there is none
+func defaultStorageClass(classes []storagev1.StorageClass) *storagev1.StorageClass {
+	for n := range classes {
+		for _, annotation := range defaultStorageClassAnnotations {
+			if classes[n].Annotations[annotation] == "true" {
+				return &classes[n]
+			}
+		}
+	}
+	return nil
+}
+
+// supportsReadWriteMany returns true if the provisioner is known to support ReadWriteMany volumes
+func supportsReadWriteMany(provisioner string) bool {
+	for _, known := range readWriteManyProvisioners {
+		if strings.Contains(provisioner, known) {
+			return true
+		}
+	}
+	return false
+}
@@ -103,7 +103,7 @@ var (
 	ErrCreatingPoolNotAllowed                    = &Error{Code: "CreatingPoolNotAllowed", Message: "creating a pool is only allowed in state 'Committed' or 'Destroyed'. Current state is '%s'"}
 	ErrGeneratingK8sName                         = &Error{Code: "GeneratingK8sName", Message: "error generating k8s name for instance '%s'"}
 	ErrEnablingBitTwister                        = &Error{Code: "EnablingBitTwister", Message: "enabling BitTwister is not allowed in state 'Started'"}
-	ErrSettingImageNotAllowed                    = &Error{Code: "SettingImageNotAllowed", Message: "setting image is only allowed in state 'None' and 'Started'. Current state is '%s'"}
+	ErrSettingImageNotAllowed                    = &Error{Code: "SettingImageNotAllowed", Message: "setting image is only allowed in state 'None', 'Preparing', 'Committed' and 'Started'. Current state is '%s'"}
 	ErrCreatingBuilder                           = &Error{Code: "CreatingBuilder", Message: "error creating builder"}
 	ErrSettingImageNotAllowedForSidecarsStarted  = &Error{Code: "SettingImageNotAllowedForSidecarsStarted", Message: "setting image is not allowed for sidecars when in state 'Started'"}
 	ErrSettingGitRepo                            = &Error{Code: "SettingGitRepo", Message: "setting git repo is only allowed in state 'None'. Current state is '%s'"}
@@ -196,7 +196,7 @@ var (
 	ErrWaitingForInstanceStoppedNotAllowed       = &Error{Code: "WaitingForInstanceStoppedNotAllowed", Message: "waiting for instance is only allowed in state 'Stopped'. Current state is '%s"}
 	ErrCheckingIfInstanceStopped                 = &Error{Code: "CheckingIfInstanceStopped", Message: "error checking if instance '%s' is running"}
 	ErrStoppingNotAllowed                        = &Error{Code: "StoppingNotAllowed", Message: "stopping is only allowed in state 'Started'. Current state is '%s"}
-	ErrDestroyingNotAllowed                      = &Error{Code: "DestroyingNotAllowed", Message: "destroying is only allowed in state 'Started' or 'Destroyed'. Current state is '%s"}
+	ErrDestroyingNotAllowed                      = &Error{Code: "DestroyingNotAllowed", Message: "destroying is not allowed in state '%s'"}
 	ErrDestroyingPod                             = &Error{Code: "DestroyingPod", Message: "error destroying pod for instance '%s'"}
 	ErrDestroyingResourcesForInstance            = &Error{Code: "DestroyingResourcesForInstance", Message: "error destroying resources for instance '%s'"}
 	ErrDestroyingResourcesForSidecars            = &Error{Code: "DestroyingResourcesForSidecars", Message: "error destroying resources for sidecars of instance '%s'"}
@@ -230,4 +230,207 @@ var (
 	ErrAddingToProxy                             = &Error{Code: "AddingToTraefikProxy", Message: "error adding '%s' to traefik proxy for service '%s'"}
 	ErrCannotGetTraefikEndpoint                  = &Error{Code: "CannotGetTraefikEndpoint", Message: "cannot get traefik endpoint"}
 	ErrGettingProxyURL                           = &Error{Code: "GettingProxyURL", Message: "error getting proxy URL for service '%s'"}
+	ErrSettingNoCacheNotAllowed                  = &Error{Code: "SettingNoCacheNotAllowed", Message: "setting no cache is only allowed in state 'Preparing'. Current state is '%s'"}
+	ErrEmptyImageHash                            = &Error{Code: "EmptyImageHash", Message: "image hash is empty"}
+	ErrDroppingCapabilityNotAllowed              = &Error{Code: "DroppingCapabilityNotAllowed", Message: "dropping capability is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrInvalidCapability                         = &Error{Code: "InvalidCapability", Message: "invalid capability '%s', expected the upper case name of a linux capability without the 'CAP_' prefix, e.g. 'NET_ADMIN'"}
+	ErrSettingFolderLimitsNotAllowed             = &Error{Code: "SettingFolderLimitsNotAllowed", Message: "setting folder limits is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrInvalidFolderLimits                       = &Error{Code: "InvalidFolderLimits", Message: "invalid folder limits: max size '%d' and max files '%d' must not be negative"}
+	ErrFolderTooLarge                            = &Error{Code: "FolderTooLarge", Message: "folder '%s' exceeds the maximum size of %d bytes, use SetFolderLimits to change the limit"}
+	ErrFolderHasTooManyFiles                     = &Error{Code: "FolderHasTooManyFiles", Message: "folder '%s' exceeds the maximum number of %d files, use SetFolderLimits to change the limit"}
+	ErrApplyingInstanceOption                    = &Error{Code: "ApplyingInstanceOption", Message: "error applying option to instance '%s'"}
+	ErrRemovingBuildDir                          = &Error{Code: "RemovingBuildDir", Message: "error removing build directory '%s'"}
+	ErrClosingBuilderFactory                     = &Error{Code: "ClosingBuilderFactory", Message: "error closing the builder factory of instance '%s'"}
+	ErrGettingMetricsNotAllowed                  = &Error{Code: "GettingMetricsNotAllowed", Message: "getting metrics is only allowed in state 'Started'. Current state is '%s'"}
+	ErrInstanceNotStable                         = &Error{Code: "InstanceNotStable", Message: "instance '%s' did not run for %s without restarting, observed %d restarts"}
+	ErrSettingSysctlNotAllowed                   = &Error{Code: "SettingSysctlNotAllowed", Message: "setting sysctl is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrInvalidSysctl                             = &Error{Code: "InvalidSysctl", Message: "invalid sysctl name '%s', expected a name like 'net.core.somaxconn'"}
+	ErrAddingDebugContainerNotAllowed            = &Error{Code: "AddingDebugContainerNotAllowed", Message: "adding debug container is only allowed in state 'Started'. Current state is '%s'"}
+	ErrAddingDebugContainerToSidecar             = &Error{Code: "AddingDebugContainerToSidecar", Message: "adding debug container is not allowed for sidecar '%s', add it to the parent instance instead"}
+	ErrGeneratingDebugContainerName              = &Error{Code: "GeneratingDebugContainerName", Message: "error generating the name of the debug container for instance '%s'"}
+	ErrAddingDebugContainer                      = &Error{Code: "AddingDebugContainer", Message: "error adding debug container with image '%s' to instance '%s'"}
+	ErrExecutingCommandInDebugContainer          = &Error{Code: "ExecutingCommandInDebugContainer", Message: "error executing command '%s' in debug container '%s'"}
+	ErrSettingServiceAccountNotAllowed           = &Error{Code: "SettingServiceAccountNotAllowed", Message: "setting service account is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrServiceAccountNameEmpty                   = &Error{Code: "ServiceAccountNameEmpty", Message: "service account name cannot be empty"}
+	ErrCheckingServiceAccountExists              = &Error{Code: "CheckingServiceAccountExists", Message: "error checking if service account '%s' exists"}
+	ErrServiceAccountNotFound                    = &Error{Code: "ServiceAccountNotFound", Message: "service account '%s' does not exist"}
+	ErrSettingPriorityClassNotAllowed            = &Error{Code: "SettingPriorityClassNotAllowed", Message: "setting priority class is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrPriorityClassNameEmpty                    = &Error{Code: "PriorityClassNameEmpty", Message: "priority class name cannot be empty"}
+	ErrCheckingPriorityClassExists               = &Error{Code: "CheckingPriorityClassExists", Message: "error checking if priority class '%s' exists"}
+	ErrPriorityClassNotFound                     = &Error{Code: "PriorityClassNotFound", Message: "priority class '%s' does not exist"}
+	ErrWaitingForDeletionNotAllowed              = &Error{Code: "WaitingForDeletionNotAllowed", Message: "waiting for deletion is only allowed in state 'Destroyed'. Current state is '%s'"}
+	ErrCheckingRemainingResources                = &Error{Code: "CheckingRemainingResources", Message: "error checking the remaining resources of instance '%s'"}
+	ErrWaitingForDeletionTimeout                 = &Error{Code: "WaitingForDeletionTimeout", Message: "timeout while waiting for instance '%s' to be deleted, remaining resources: %v"}
+	ErrExecutingInteractiveCommandNotAllowed     = &Error{Code: "ExecutingInteractiveCommandNotAllowed", Message: "executing an interactive command is only allowed in state 'Started'. Current state is '%s'"}
+	ErrEnvNameEmpty                              = &Error{Code: "EnvNameEmpty", Message: "environment variable name cannot be empty"}
+	ErrInvalidEnvFieldPath                       = &Error{Code: "InvalidEnvFieldPath", Message: "invalid field path '%s', expected a pod field like 'metadata.name' or 'status.podIP'"}
+	ErrInvalidEnvResourceField                   = &Error{Code: "InvalidEnvResourceField", Message: "invalid resource '%s', expected a resource like 'limits.memory' or 'requests.cpu'"}
+	ErrInvalidEnvResourceDivisor                 = &Error{Code: "InvalidEnvResourceDivisor", Message: "invalid divisor '%s'"}
+	ErrSettingAutomountTokenNotAllowed           = &Error{Code: "SettingAutomountTokenNotAllowed", Message: "setting automount service account token is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrSettingInsecureRegistriesNotAllowed       = &Error{Code: "SettingInsecureRegistriesNotAllowed", Message: "setting insecure registries is only allowed in state 'Preparing'. Current state is '%s'"}
+	ErrInvalidRegistryHost                       = &Error{Code: "InvalidRegistryHost", Message: "invalid registry host '%s', expected a host like 'registry.local:5000'"}
+	ErrGettingPodNameNotAllowed                  = &Error{Code: "GettingPodNameNotAllowed", Message: "getting the pod name is only allowed in state 'Started'. Current state is '%s'"}
+	ErrSettingSymlinkPolicyNotAllowed            = &Error{Code: "SettingSymlinkPolicyNotAllowed", Message: "setting the symlink policy is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrInvalidSymlinkPolicy                      = &Error{Code: "InvalidSymlinkPolicy", Message: "invalid symlink policy '%d'"}
+	ErrSymlinkNotAllowed                         = &Error{Code: "SymlinkNotAllowed", Message: "symlink '%s' is not allowed by the symlink policy"}
+	ErrSymlinkLoop                               = &Error{Code: "SymlinkLoop", Message: "symlink loop detected at '%s'"}
+	ErrResolvingSymlink                          = &Error{Code: "ResolvingSymlink", Message: "error resolving symlink '%s'"}
+	ErrCopyingSymlinkNotAllowed                  = &Error{Code: "CopyingSymlinkNotAllowed", Message: "copying symlink '%s' is only allowed in state 'Preparing', use SymlinkFollow in state '%s'"}
+	ErrSettingLogBufferSizeNotAllowed            = &Error{Code: "SettingLogBufferSizeNotAllowed", Message: "setting the log buffer size is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrInvalidLogBufferSize                      = &Error{Code: "InvalidLogBufferSize", Message: "invalid log buffer size %d, it must not be negative"}
+	ErrSettingWorkingDirNotAllowed               = &Error{Code: "SettingWorkingDirNotAllowed", Message: "setting the working directory is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrWorkingDirNotAbsolute                     = &Error{Code: "WorkingDirNotAbsolute", Message: "working directory '%s' must be an absolute path"}
+	ErrInvalidRegistryMirror                     = &Error{Code: "InvalidRegistryMirror", Message: "invalid registry mirror set in KNUU_REGISTRY_MIRROR"}
+	ErrSettingShareProcessNamespaceNotAllowed    = &Error{Code: "SettingShareProcessNamespaceNotAllowed", Message: "setting the process namespace sharing is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrWaitingForPortNotAllowed                  = &Error{Code: "WaitingForPortNotAllowed", Message: "waiting for a port is only allowed in state 'Started'. Current state is '%s'"}
+	ErrWaitingForPortTimeout                     = &Error{Code: "WaitingForPortTimeout", Message: "timeout waiting for port %d to be open in instance '%s'"}
+	ErrAddingFiles                               = &Error{Code: "AddingFiles", Message: "error adding file %d '%s' to instance '%s'"}
+	ErrInvalidConfigMapName                      = &Error{Code: "InvalidConfigMapName", Message: "invalid configmap name '%s': %s"}
+	ErrInvalidConfigMapKey                       = &Error{Code: "InvalidConfigMapKey", Message: "invalid configmap key '%s': %s"}
+	ErrEnvFromConfigMapAlreadyAdded              = &Error{Code: "EnvFromConfigMapAlreadyAdded", Message: "environment variables from configmap '%s' are already added"}
+	ErrEnvConfigMapAlreadyExists                 = &Error{Code: "EnvConfigMapAlreadyExists", Message: "env configmap '%s' already exists in the instance"}
+	ErrDeployingEnvConfigMapsForInstance         = &Error{Code: "DeployingEnvConfigMapsForInstance", Message: "error deploying env configmaps for instance '%s'"}
+	ErrDestroyingEnvConfigMapsForInstance        = &Error{Code: "DestroyingEnvConfigMapsForInstance", Message: "error destroying env configmaps for instance '%s'"}
+	ErrGettingSpecNotAllowed                     = &Error{Code: "GettingSpecNotAllowed", Message: "getting the spec is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrUnmarshalingSpec                          = &Error{Code: "UnmarshalingSpec", Message: "error unmarshaling instance spec"}
+	ErrSpecImageEmpty                            = &Error{Code: "SpecImageEmpty", Message: "image of instance spec '%s' is empty"}
+	ErrApplyingSpec                              = &Error{Code: "ApplyingSpec", Message: "error applying spec to instance '%s'"}
+	ErrGettingContainerExitCodeNotAllowed        = &Error{Code: "GettingContainerExitCodeNotAllowed", Message: "getting the container exit code is only allowed in state 'Started'. Current state is '%s'"}
+	ErrContainerNotTerminated                    = &Error{Code: "ContainerNotTerminated", Message: "container of instance '%s' has not terminated yet"}
+	ErrSettingOperationTimeoutNotAllowed         = &Error{Code: "SettingOperationTimeoutNotAllowed", Message: "setting the operation timeout is only allowed in state 'Preparing', 'Committed', 'Started' or 'Stopped'. Current state is '%s'"}
+	ErrInvalidOperationTimeout                   = &Error{Code: "InvalidOperationTimeout", Message: "invalid operation timeout '%s', it must not be negative"}
+	ErrBatchDestroyFailed                        = &Error{Code: "BatchDestroyFailed", Message: "error destroying instances '%s'"}
+	ErrSharingVolumeNotAllowed                   = &Error{Code: "SharingVolumeNotAllowed", Message: "sharing a volume is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrSharingVolumeWithInvalidInstance          = &Error{Code: "SharingVolumeWithInvalidInstance", Message: "instance '%s' can only share a volume with another instance"}
+	ErrSharedVolumePathNotAbsolute               = &Error{Code: "SharedVolumePathNotAbsolute", Message: "shared volume path '%s' must be absolute"}
+	ErrSharedVolumePathInUse                     = &Error{Code: "SharedVolumePathInUse", Message: "path '%s' is used by different shared volumes in instances '%s' and '%s'"}
+	ErrSharedVolumeNotSupported                  = &Error{Code: "SharedVolumeNotSupported", Message: "shared volumes are not supported by the cluster"}
+	ErrDeployingSharedVolumesForInstance         = &Error{Code: "DeployingSharedVolumesForInstance", Message: "error deploying shared volumes for instance '%s'"}
+	ErrDestroyingSharedVolumesForInstance        = &Error{Code: "DestroyingSharedVolumesForInstance", Message: "error destroying shared volumes for instance '%s'"}
+	ErrSettingPollIntervalNotAllowed             = &Error{Code: "SettingPollIntervalNotAllowed", Message: "setting the poll interval is only allowed in state 'Preparing', 'Committed', 'Started' or 'Stopped'. Current state is '%s'"}
+	ErrInvalidPollInterval                       = &Error{Code: "InvalidPollInterval", Message: "invalid poll interval '%s', it must not be negative"}
+	ErrSettingTerminationMessageNotAllowed       = &Error{Code: "SettingTerminationMessageNotAllowed", Message: "setting the termination message is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrTerminationMessagePathNotAbsolute         = &Error{Code: "TerminationMessagePathNotAbsolute", Message: "termination message path '%s' must be absolute"}
+	ErrInvalidTerminationMessagePolicy           = &Error{Code: "InvalidTerminationMessagePolicy", Message: "invalid termination message policy '%s', must be 'File' or 'FallbackToLogsOnError'"}
+	ErrGettingTerminationMessageNotAllowed       = &Error{Code: "GettingTerminationMessageNotAllowed", Message: "getting the termination message is only allowed in state 'Started'. Current state is '%s'"}
+	ErrFetchingPprofProfileNotAllowed            = &Error{Code: "FetchingPprofProfileNotAllowed", Message: "fetching a pprof profile is only allowed in state 'Started'. Current state is '%s'"}
+	ErrInvalidPprofProfile                       = &Error{Code: "InvalidPprofProfile", Message: "invalid pprof profile '%s', must be one of cpu, heap, allocs, goroutine, block, mutex or threadcreate"}
+	ErrInvalidPprofDuration                      = &Error{Code: "InvalidPprofDuration", Message: "invalid duration '%s' for pprof profile '%s', the cpu profile needs at least 1s"}
+	ErrFetchingPprofProfile                      = &Error{Code: "FetchingPprofProfile", Message: "error fetching pprof profile '%s' from instance '%s'"}
+	ErrUnexpectedPprofStatus                     = &Error{Code: "UnexpectedPprofStatus", Message: "unexpected status %d from %s: %s"}
+	ErrSettingGracefulImagePullNotAllowed        = &Error{Code: "SettingGracefulImagePullNotAllowed", Message: "setting graceful image pull is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrInvalidGracefulImagePull                  = &Error{Code: "InvalidGracefulImagePull", Message: "invalid graceful image pull with %d retries and a delay of '%s', they must not be negative"}
+	ErrImagePullRateLimited                      = &Error{Code: "ImagePullRateLimited", Message: "image pull of instance '%s' is still rate limited after %d retries"}
+	ErrRetryingImagePull                         = &Error{Code: "RetryingImagePull", Message: "error recreating the pod of instance '%s' to retry the image pull"}
+	ErrRegisteringHookNotAllowed                 = &Error{Code: "RegisteringHookNotAllowed", Message: "registering a hook is only allowed in state 'Preparing', 'Committed', 'Started' or 'Stopped'. Current state is '%s'"}
+	ErrHookIsNil                                 = &Error{Code: "HookIsNil", Message: "hook of instance '%s' is nil"}
+	ErrRunningStartHook                          = &Error{Code: "RunningStartHook", Message: "error running start hook of instance '%s'"}
+	ErrRunningReadyHook                          = &Error{Code: "RunningReadyHook", Message: "error running ready hook of instance '%s'"}
+	ErrRunningDestroyHooks                       = &Error{Code: "RunningDestroyHooks", Message: "%d destroy hooks of instance '%s' failed"}
+	ErrPromotingImageNotAllowed                  = &Error{Code: "PromotingImageNotAllowed", Message: "promoting the image is only allowed in state 'Committed', 'Started' or 'Stopped'. Current state is '%s'"}
+	ErrPromotingImage                            = &Error{Code: "PromotingImage", Message: "error promoting image '%s' of instance '%s' to '%s'"}
+	ErrGeneratingUniqueName                      = &Error{Code: "GeneratingUniqueName", Message: "error generating a unique name for instance '%s' after %d attempts"}
+	ErrUsingBuilderNotAllowed                    = &Error{Code: "UsingBuilderNotAllowed", Message: "using a builder is only allowed in state 'None' or 'Preparing'. Current state is '%s'"}
+	ErrBuilderFactoryIsNil                       = &Error{Code: "BuilderFactoryIsNil", Message: "builder factory of instance '%s' is nil"}
+	ErrBuildingImageFromBuilder                  = &Error{Code: "BuildingImageFromBuilder", Message: "error building the image of instance '%s' from its builder"}
+	ErrAddingReadinessGateNotAllowed             = &Error{Code: "AddingReadinessGateNotAllowed", Message: "adding a readiness gate is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrInvalidReadinessGate                      = &Error{Code: "InvalidReadinessGate", Message: "invalid readiness gate '%s': %s"}
+	ErrCheckingReadinessGates                    = &Error{Code: "CheckingReadinessGates", Message: "error checking the readiness gates of instance '%s'"}
+	ErrSettingActiveDeadlineNotAllowed           = &Error{Code: "SettingActiveDeadlineNotAllowed", Message: "setting the active deadline is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrInvalidActiveDeadline                     = &Error{Code: "InvalidActiveDeadline", Message: "invalid active deadline '%s', it must be positive"}
+	ErrWaitingForCompletionNotAllowed            = &Error{Code: "WaitingForCompletionNotAllowed", Message: "waiting for the instance to complete is only allowed in state 'Started'. Current state is '%s'"}
+	ErrWaitingForCompletionTimeout               = &Error{Code: "WaitingForCompletionTimeout", Message: "timeout while waiting for instance '%s' to complete"}
+	ErrInstanceDeadlineExceeded                  = &Error{Code: "InstanceDeadlineExceeded", Message: "instance '%s' was terminated after its active deadline of '%s': %s"}
+	ErrInstanceFailed                            = &Error{Code: "InstanceFailed", Message: "instance '%s' failed with reason '%s': %s"}
+	ErrGettingContainerStatusesNotAllowed        = &Error{Code: "GettingContainerStatusesNotAllowed", Message: "getting the container statuses is only allowed in state 'Started'. Current state is '%s'"}
+	ErrKnuuNotInitialized                        = &Error{Code: "KnuuNotInitialized", Message: "knuu is not initialized"}
+	ErrRunningPreflight                          = &Error{Code: "RunningPreflight", Message: "error running the preflight checks of the cluster"}
+	ErrFeatureNotAvailable                       = &Error{Code: "FeatureNotAvailable", Message: "feature '%s' is not available in the cluster: %s"}
+	ErrSettingFSGroupNotAllowed                  = &Error{Code: "SettingFSGroupNotAllowed", Message: "setting fsGroup is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrInvalidFSGroup                            = &Error{Code: "InvalidFSGroup", Message: "invalid fsGroup %d, it must be a positive group ID"}
+	ErrInvalidPortName                           = &Error{Code: "InvalidPortName", Message: "invalid port name '%s': %s"}
+	ErrPortNameAlreadyRegistered                 = &Error{Code: "PortNameAlreadyRegistered", Message: "port name '%s' is already registered"}
+	ErrInvalidPortProtocol                       = &Error{Code: "InvalidPortProtocol", Message: "invalid port protocol '%s', expected 'tcp' or 'udp'"}
+	ErrPortNameNotRegistered                     = &Error{Code: "PortNameNotRegistered", Message: "port name '%s' is not registered in instance '%s'"}
+	ErrInvalidRetryAttempts                      = &Error{Code: "InvalidRetryAttempts", Message: "invalid number of attempts %d, it must be at least 1"}
+	ErrInvalidRetryBackoff                       = &Error{Code: "InvalidRetryBackoff", Message: "invalid backoff '%s', it must not be negative"}
+	ErrExecutingCommandWithRetry                 = &Error{Code: "ExecutingCommandWithRetry", Message: "command '%s' in instance '%s' failed after %d attempts"}
+	ErrInvalidStartupProbe                       = &Error{Code: "InvalidStartupProbe", Message: "invalid startup probe, %s"}
+	ErrInspectingImageNotAllowed                 = &Error{Code: "InspectingImageNotAllowed", Message: "inspecting the image is only allowed in state 'Committed', 'Started' or 'Stopped'. Current state is '%s'"}
+	ErrInspectingBuiltImage                      = &Error{Code: "InspectingBuiltImage", Message: "error inspecting image '%s' of instance '%s'"}
+	ErrAddingTopologySpreadConstraintNotAllowed  = &Error{Code: "AddingTopologySpreadConstraintNotAllowed", Message: "adding a topology spread constraint is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrInvalidTopologySpreadMaxSkew              = &Error{Code: "InvalidTopologySpreadMaxSkew", Message: "invalid max skew '%d' of the topology spread constraint, it must be at least 1"}
+	ErrTopologyKeyRequired                       = &Error{Code: "TopologyKeyRequired", Message: "topology key of the topology spread constraint is required"}
+	ErrInvalidWhenUnsatisfiable                  = &Error{Code: "InvalidWhenUnsatisfiable", Message: "invalid value '%s' of whenUnsatisfiable, it must be 'DoNotSchedule' or 'ScheduleAnyway'"}
+	ErrSettingReorderForCacheNotAllowed          = &Error{Code: "SettingReorderForCacheNotAllowed", Message: "setting reorder for cache is only allowed in state 'Preparing'. Current state is '%s'"}
+	ErrSettingEphemeralStorageNotAllowed         = &Error{Code: "SettingEphemeralStorageNotAllowed", Message: "setting ephemeral storage is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrInvalidEphemeralStorage                   = &Error{Code: "InvalidEphemeralStorage", Message: "invalid ephemeral storage quantity '%s'"}
+	ErrEphemeralStorageRequestExceedsLimit       = &Error{Code: "EphemeralStorageRequestExceedsLimit", Message: "ephemeral storage request '%s' exceeds the limit '%s'"}
+	ErrSettingReadOnlyRootFilesystemNotAllowed   = &Error{Code: "SettingReadOnlyRootFilesystemNotAllowed", Message: "setting the read-only root filesystem is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrAddingTmpfsMountNotAllowed                = &Error{Code: "AddingTmpfsMountNotAllowed", Message: "adding a tmpfs mount is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrInvalidTmpfsPath                          = &Error{Code: "InvalidTmpfsPath", Message: "invalid tmpfs path '%s', it must be an absolute path other than '/'"}
+	ErrTmpfsPathAlreadyMounted                   = &Error{Code: "TmpfsPathAlreadyMounted", Message: "path '%s' is already mounted"}
+	ErrInvalidTmpfsSizeLimit                     = &Error{Code: "InvalidTmpfsSizeLimit", Message: "invalid tmpfs size limit '%s', it must be a positive quantity"}
+	ErrProbeNotHTTP                              = &Error{Code: "ProbeNotHTTP", Message: "the %s probe of instance '%s' must be set to an HTTP probe before setting its headers"}
+	ErrInvalidProbeHeader                        = &Error{Code: "InvalidProbeHeader", Message: "invalid probe header name '%s': %s"}
+	ErrSyncingFolderNotAllowed                   = &Error{Code: "SyncingFolderNotAllowed", Message: "syncing a folder is only allowed in state 'Preparing', 'Committed' or 'Started'. Current state is '%s'"}
+	ErrSyncDestinationNotAbsolute                = &Error{Code: "SyncDestinationNotAbsolute", Message: "destination '%s' of the synced folder must be an absolute path"}
+	ErrSyncingFolder                             = &Error{Code: "SyncingFolder", Message: "error syncing folder '%s' to instance '%s'"}
+	ErrSettingBaseImagePublicKeyNotAllowed       = &Error{Code: "SettingBaseImagePublicKeyNotAllowed", Message: "setting the base image public key is only allowed in state 'Preparing'. Current state is '%s'"}
+	ErrSettingBaseImagePublicKey                 = &Error{Code: "SettingBaseImagePublicKey", Message: "error setting the base image public key for instance '%s'"}
+	ErrVerifyingBaseImage                        = &Error{Code: "VerifyingBaseImage", Message: "error verifying the base image of instance '%s'"}
+	ErrSettingRuntimeClassNotAllowed             = &Error{Code: "SettingRuntimeClassNotAllowed", Message: "setting runtime class is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrRuntimeClassNameEmpty                     = &Error{Code: "RuntimeClassNameEmpty", Message: "runtime class name cannot be empty"}
+	ErrCheckingRuntimeClassExists                = &Error{Code: "CheckingRuntimeClassExists", Message: "error checking if runtime class '%s' exists"}
+	ErrRuntimeClassNotFound                      = &Error{Code: "RuntimeClassNotFound", Message: "runtime class '%s' does not exist, it must be created by the cluster admin with the handler of the runtime, e.g. runsc for gVisor"}
+	ErrCreatingLogsDir                           = &Error{Code: "CreatingLogsDir", Message: "error creating the directory '%s' to collect the logs in"}
+	ErrCollectingLogs                            = &Error{Code: "CollectingLogs", Message: "error collecting the logs of instances '%s'"}
+	ErrCollectingLogsNotAllowed                  = &Error{Code: "CollectingLogsNotAllowed", Message: "collecting logs is only allowed in state 'Started'. Current state is '%s'"}
+	ErrInvalidReadyReplicas                      = &Error{Code: "InvalidReadyReplicas", Message: "invalid number of ready replicas %d, it must be at least 1"}
+	ErrWaitingForReplicas                        = &Error{Code: "WaitingForReplicas", Message: "timed out waiting for %d ready replicas of instance '%s', %d are ready"}
+	ErrReadyReplicasExceedDesired                = &Error{Code: "ReadyReplicasExceedDesired", Message: "cannot wait for %d ready replicas of instance '%s', it runs %d replicas"}
+	ErrSettingReplicasNotAllowed                 = &Error{Code: "SettingReplicasNotAllowed", Message: "setting replicas is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrInvalidReplicas                           = &Error{Code: "InvalidReplicas", Message: "invalid number of replicas %d, it must be at least 1"}
+	ErrRolloutRestartNotAllowed                  = &Error{Code: "RolloutRestartNotAllowed", Message: "rollout restart is only allowed in state 'Started'. Current state is '%s'"}
+	ErrRollingOutRestart                         = &Error{Code: "RollingOutRestart", Message: "error restarting the pods of instance '%s', %d of %d pods were restarted"}
+	ErrSettingServiceMeshNotAllowed              = &Error{Code: "SettingServiceMeshNotAllowed", Message: "setting the service mesh is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrInvalidServiceMesh                        = &Error{Code: "InvalidServiceMesh", Message: "invalid service mesh '%s', it must be 'istio' or 'linkerd'"}
+	ErrEnablingMeshInjectionNotAllowed           = &Error{Code: "EnablingMeshInjectionNotAllowed", Message: "enabling the service mesh injection is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrInvalidImageCache                         = &Error{Code: "InvalidImageCache", Message: "invalid KNUU_IMAGE_CACHE, available [minio], value used: %s"}
+	ErrSettingBackoffLimitNotAllowed             = &Error{Code: "SettingBackoffLimitNotAllowed", Message: "setting the backoff limit is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrInvalidBackoffLimit                       = &Error{Code: "InvalidBackoffLimit", Message: "invalid backoff limit %d, it must not be negative"}
+	ErrCheckingBackoffLimit                      = &Error{Code: "CheckingBackoffLimit", Message: "error checking the backoff limit of instance '%s'"}
+	ErrBackoffLimitExceeded                      = &Error{Code: "BackoffLimitExceeded", Message: "instance '%s' exceeded its backoff limit of %d after %d failures, last failure: %s"}
+	ErrSettingStableImageTagNotAllowed           = &Error{Code: "SettingStableImageTagNotAllowed", Message: "setting the stable image tag is only allowed in state 'Preparing'. Current state is '%s'"}
+	ErrInvalidStableImageTag                     = &Error{Code: "InvalidStableImageTag", Message: "invalid stable image tag '%s', it must be an image reference with a tag"}
+	ErrTaggingStableImage                        = &Error{Code: "TaggingStableImage", Message: "error tagging stable image '%s' of instance '%s'"}
+	ErrSettingCronScheduleNotAllowed             = &Error{Code: "SettingCronScheduleNotAllowed", Message: "setting the cron schedule is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrInvalidCronSchedule                       = &Error{Code: "InvalidCronSchedule", Message: "invalid cron schedule '%s'"}
+	ErrDeployingCronJob                          = &Error{Code: "DeployingCronJob", Message: "error deploying cron job of instance '%s'"}
+	ErrTriggeringCronJobNotAllowed               = &Error{Code: "TriggeringCronJobNotAllowed", Message: "triggering the cron job is only allowed in state 'Started'. Current state is '%s'"}
+	ErrWaitingForJobNotAllowed                   = &Error{Code: "WaitingForJobNotAllowed", Message: "waiting for a job is only allowed in state 'Started'. Current state is '%s'"}
+	ErrInstanceNotCronJob                        = &Error{Code: "InstanceNotCronJob", Message: "instance '%s' is not run by a cron job, see SetCronSchedule"}
+	ErrTriggeringCronJob                         = &Error{Code: "TriggeringCronJob", Message: "error triggering the cron job of instance '%s'"}
+	ErrWaitingForJob                             = &Error{Code: "WaitingForJob", Message: "error waiting for job '%s' of instance '%s' to complete"}
+	ErrJobFailed                                 = &Error{Code: "JobFailed", Message: "job '%s' of instance '%s' failed: %s"}
+	ErrSettingPostStartCommandNotAllowed         = &Error{Code: "SettingPostStartCommandNotAllowed", Message: "setting the post start command is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrEmptyPostStartCommand                     = &Error{Code: "EmptyPostStartCommand", Message: "the post start command of instance '%s' must not be empty"}
+	ErrGettingPodIPsNotAllowed                   = &Error{Code: "GettingPodIPsNotAllowed", Message: "getting the pod IPs is only allowed in state 'Started'. Current state is '%s'"}
+	ErrPodHasNoIP                                = &Error{Code: "PodHasNoIP", Message: "pod '%s' of instance '%s' has no IP assigned yet"}
+	ErrNoIPv6Address                             = &Error{Code: "NoIPv6Address", Message: "instance '%s' has no IPv6 address, its pod IPs are %v"}
+	ErrSettingImageArchitectureNotAllowed        = &Error{Code: "SettingImageArchitectureNotAllowed", Message: "setting the image architecture is only allowed in state 'Preparing' or 'Committed'. Current state is '%s'"}
+	ErrInvalidImageArchitecture                  = &Error{Code: "InvalidImageArchitecture", Message: "invalid image architecture '%s', it must be an architecture with an optional variant, e.g. arm64 or arm/v7"}
+	ErrPinningImageArchitecture                  = &Error{Code: "PinningImageArchitecture", Message: "error reading the platforms of image '%s' of instance '%s'"}
+	ErrImageArchitectureNotFound                 = &Error{Code: "ImageArchitectureNotFound", Message: "image '%s' is not built for architecture '%s', its platforms are %v"}
+	ErrSettingStopSignalNotAllowed               = &Error{Code: "SettingStopSignalNotAllowed", Message: "setting stop signal is only allowed in state 'Preparing'. Current state is '%s'"}
+	ErrSettingStopSignal                         = &Error{Code: "SettingStopSignal", Message: "error setting stop signal '%s' for instance '%s'"}
+	ErrApplyingManifestNotAllowed                = &Error{Code: "ApplyingManifestNotAllowed", Message: "applying a manifest is only allowed in state 'Preparing', 'Committed', 'Started' or 'Stopped'. Current state is '%s'"}
+	ErrApplyingManifest                          = &Error{Code: "ApplyingManifest", Message: "error applying manifest for instance '%s'"}
+	ErrDestroyingManifestResources               = &Error{Code: "DestroyingManifestResources", Message: "error destroying the resources applied with ApplyManifest for instance '%s'"}
+	ErrCannotParseBuilderJobTTL                  = &Error{Code: "CannotParseBuilderJobTTL", Message: "cannot parse KNUU_BUILDER_JOB_TTL"}
+	ErrSettingProgressDeadlineNotAllowed         = &Error{Code: "SettingProgressDeadlineNotAllowed", Message: "setting progress deadline is only allowed in state 'Preparing', 'Committed', 'Started' or 'Stopped'. Current state is '%s'"}
+	ErrInvalidProgressDeadline                   = &Error{Code: "InvalidProgressDeadline", Message: "invalid progress deadline '%s', it must not be negative"}
+	ErrProgressDeadlineExceeded                  = &Error{Code: "ProgressDeadlineExceeded", Message: "instance '%s' made no progress for %s, %d of %d replicas are ready: %s"}
+	ErrSettingBuildCacheDirNotAllowed            = &Error{Code: "SettingBuildCacheDirNotAllowed", Message: "setting build cache dir is only allowed in state 'Preparing'. Current state is '%s'"}
+	ErrBuildCacheDirNotAbsolute                  = &Error{Code: "BuildCacheDirNotAbsolute", Message: "build cache dir '%s' must be an absolute path"}
 )
@@ -4,8 +4,12 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"maps"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -13,7 +17,9 @@ import (
 	appv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	"github.com/sirupsen/logrus"
 
@@ -61,6 +67,13 @@ type ObsyConfig struct {
 	prometheusRemoteWriteExporterEndpoint string
 }
 
+const (
+	// DefaultMaxFolderSize is the default maximum total size in bytes of a folder added with AddFolder
+	DefaultMaxFolderSize = int64(1 << 30) // 1GiB
+	// DefaultMaxFolderFiles is the default maximum number of files in a folder added with AddFolder
+	DefaultMaxFolderFiles = 10000
+)
+
 // SecurityContext represents the security settings for a container
 type SecurityContext struct {
 	// Privileged indicates whether the container should be run in privileged mode
@@ -68,6 +81,12 @@ type SecurityContext struct {
 
 	// CapabilitiesAdd is the list of capabilities to add to the container
 	capabilitiesAdd []string
+
+	// CapabilitiesDrop is the list of capabilities to drop from the container
+	capabilitiesDrop []string
+
+	// ReadOnlyRootFilesystem indicates whether the root filesystem of the container is mounted read-only
+	readOnlyRootFilesystem bool
 }
 
 // Instance represents a instance
@@ -82,13 +101,17 @@ type Instance struct {
 	kubernetesReplicaSet *appv1.ReplicaSet
 	portsTCP             []int
 	portsUDP             []int
+	namedPorts           map[string]int
 	command              []string
 	args                 []string
 	env                  map[string]string
+	envValueFrom         map[string]*v1.EnvVarSource
 	volumes              []*k8s.Volume
 	memoryRequest        string
 	memoryLimit          string
 	cpuRequest           string
+	ephemeralRequest     string
+	ephemeralLimit       string
 	policyRules          []rbacv1.PolicyRule
 	livenessProbe        *v1.Probe
 	readinessProbe       *v1.Probe
@@ -101,10 +124,57 @@ type Instance struct {
 	obsyConfig           *ObsyConfig
 	securityContext      *SecurityContext
 	BitTwister           *btConfig
+	maxFolderSize        int64
+	maxFolderFiles       int
+	sysctls              []v1.Sysctl
+	serviceAccount       string
+	priorityClass        string
+	runtimeClass         string
+	automountToken       *bool
+	symlinkPolicy        SymlinkPolicy
+	logBufferSize        int
+	logBuffer            *logRingBuffer
+	workingDir           string
+	sharePidNamespace    bool
+	envFromConfigMaps    []string
+	envConfigMaps        map[string]map[string]string
+	imageEnv             map[string]string
+	specFiles            []FileSpec
+	specFolders          []FileSpec
+	opTimeout            time.Duration
+	sharedVolumes        []*sharedVolume
+	pollInterval         time.Duration
+	termMsgPath          string
+	termMsgPolicy        v1.TerminationMessagePolicy
+	pullRetries          int
+	pullRetryDelay       time.Duration
+	startHooks           []Hook
+	readyHooks           []Hook
+	destroyHooks         []Hook
+	readyHooksDone       bool
+	externalBuilder      bool
+	readinessGates       []string
+	activeDeadline       time.Duration
+	topologySpread       []v1.TopologySpreadConstraint
+	tmpfsMounts          []*k8s.TmpfsMount
+	syncedFolders        map[string]syncedFolder // folders synced with SyncFolder, by destination
+	serviceMesh          ServiceMesh
+	meshInjection        *bool  // whether the service mesh injects its sidecar, nil for the setting of the namespace
+	backoffLimit         *int   // attempts after the first failure before the instance gives up, nil to retry forever
+	stableImage          string // stable tag the image is pushed to and reused from, see SetStableImageTag
+	cronSchedule         string // schedule of the cron job the instance is run by instead of a replica set, see SetCronSchedule
+	postStartCommand     []string
+	imageArch            string // architecture the image is pinned to, with an optional variant, e.g. arm/v7
+	serviceStale         bool   // the service is resolved again by GetIP, as it may have been recreated while the instance was stopped
+	// resources applied with ApplyManifest, deleted along with the instance
+	manifestResources []k8s.ManifestResource
+	progressDeadline  time.Duration // time without a new ready pod after which WaitForReplicas fails, 0 to wait for the context
+	replicas          int32         // number of pods of the replica set, see SetReplicas
 }
 
 // NewInstance creates a new instance of the Instance struct
-func NewInstance(name string) (*Instance, error) {
+// The instance can be configured with the given options, e.g. WithImage
+func NewInstance(name string, opts ...InstanceOption) (*Instance, error) {
 	// Generate a UUID for this instance
 
 	k8sName, err := generateK8sName(name)
@@ -128,12 +198,13 @@ func NewInstance(name string) (*Instance, error) {
 		prometheusRemoteWriteExporterEndpoint: "",
 	}
 	securityContext := &SecurityContext{
-		privileged:      false,
-		capabilitiesAdd: make([]string, 0),
+		privileged:       false,
+		capabilitiesAdd:  make([]string, 0),
+		capabilitiesDrop: make([]string, 0),
 	}
 
 	// Create the instance
-	return &Instance{
+	i := &Instance{
 		name:            name,
 		k8sName:         k8sName,
 		imageName:       "",
@@ -141,9 +212,15 @@ func NewInstance(name string) (*Instance, error) {
 		instanceType:    BasicInstance,
 		portsTCP:        make([]int, 0),
 		portsUDP:        make([]int, 0),
+		namedPorts:      make(map[string]int),
 		command:         make([]string, 0),
 		args:            make([]string, 0),
 		env:             make(map[string]string),
+		envValueFrom:    make(map[string]*v1.EnvVarSource),
+		envConfigMaps:   make(map[string]map[string]string),
+		imageEnv:        make(map[string]string),
+		syncedFolders:   make(map[string]syncedFolder),
+		replicas:        1,
 		volumes:         make([]*k8s.Volume, 0),
 		memoryRequest:   "",
 		memoryLimit:     "",
@@ -159,7 +236,17 @@ func NewInstance(name string) (*Instance, error) {
 		obsyConfig:      obsyConfig,
 		securityContext: securityContext,
 		BitTwister:      getBitTwisterDefaultConfig(),
-	}, nil
+		maxFolderSize:   DefaultMaxFolderSize,
+		maxFolderFiles:  DefaultMaxFolderFiles,
+		sysctls:         make([]v1.Sysctl, 0),
+	}
+
+	for _, opt := range opts {
+		if err := opt(i); err != nil {
+			return nil, ErrApplyingInstanceOption.WithParams(name).Wrap(err)
+		}
+	}
+	return i, nil
 }
 
 func (i *Instance) EnableBitTwister() error {
@@ -180,27 +267,85 @@ func (i *Instance) Name() string {
 	return i.name
 }
 
+// GetUniqueName returns the name of the instance in Kubernetes, which is the name given to NewInstance
+// with a random suffix, e.g. `web1-1a2b3c4d`. It is unique in the run, so parallel tests can use the same name
+// for their instances without sharing their resources, and it names the replica set, service, etc. of the instance
+func (i *Instance) GetUniqueName() string {
+	return i.k8sName
+}
+
+// GetPodName returns the name of the pod running the instance, e.g. to query it with kubectl or client-go
+// The pod is recreated when e.g. the image is changed, so the name should not be cached
+// This function can only be called in the state 'Started'
+func (i *Instance) GetPodName() (string, error) {
+	if !i.IsInState(Started) {
+		return "", ErrGettingPodNameNotAllowed.WithParams(i.state.String())
+	}
+
+	instanceName := i.k8sName
+	if i.isSidecar {
+		instanceName = i.parentInstance.k8sName
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), i.operationTimeout())
+	defer cancel()
+	pod, err := k8sClient.GetFirstPodFromReplicaSet(ctx, instanceName)
+	if err != nil {
+		return "", ErrGettingPodFromReplicaSet.WithParams(i.k8sName).Wrap(err)
+	}
+	return pod.Name, nil
+}
+
+// GetNamespace returns the kubernetes namespace the instance is deployed in
+// It is empty if knuu is not initialized
+func (i *Instance) GetNamespace() string {
+	if k8sClient == nil {
+		return ""
+	}
+	return k8sClient.Namespace()
+}
+
 // SetImage sets the image of the instance.
+// When calling in state 'Preparing' or 'Committed', the base image is replaced by a new builder, which discards
+// everything set on the builder so far: the files, folders and commands added to the image, its env vars and user,
+// and the build settings, e.g. SetNoCache, SetBuildCacheDir, SetInsecureRegistries or SetStopSignal, to set again.
+// The settings of the instance itself, e.g. its command, ports or resources, are kept.
 // When calling in state 'Started', make sure to call AddVolume() before.
-// It is only allowed in the 'None' and 'Started' states.
+// It is only allowed in the 'None', 'Preparing', 'Committed' and 'Started' states.
 func (i *Instance) SetImage(image string) error {
 	// Check if setting the image is allowed in the current state
-	if !i.IsInState(None, Started) {
+	if !i.IsInState(None, Preparing, Committed, Started) {
 		return ErrSettingImageNotAllowed.WithParams(i.state.String())
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), i.operationTimeout())
 	defer cancel()
 
 	// Handle each state accordingly
 	switch i.state {
-	case None:
+	case None, Preparing, Committed:
+		if err := i.closeBuilderFactory(); err != nil {
+			return err
+		}
+		if i.state != None {
+			// Reset the image built so far, so the hash only depends on the new image
+			if err := os.RemoveAll(i.getBuildDir()); err != nil {
+				return ErrRemovingBuildDir.WithParams(i.getBuildDir()).Wrap(err)
+			}
+			i.imageName = ""
+			i.imageEnv = make(map[string]string)
+			i.specFiles = nil
+			i.specFolders = nil
+			logrus.Debugf("Reset image of instance '%s' to '%s'", i.name, image)
+		}
 		// Use the builder to build a new image
 		factory, err := container.NewBuilderFactory(image, i.getBuildDir(), ImageBuilder())
 		if err != nil {
 			return ErrCreatingBuilder.Wrap(err)
 		}
+		factory.SetImageCache(ImageCache())
 		i.builderFactory = factory
+		i.externalBuilder = false
 		i.state = Preparing
 	case Started:
 
@@ -232,16 +377,57 @@ func (i *Instance) SetGitRepo(ctx context.Context, gitContext builder.GitContext
 		return ErrGettingImageName.Wrap(err)
 	}
 
+	if err := i.closeBuilderFactory(); err != nil {
+		return err
+	}
 	factory, err := container.NewBuilderFactory(imageName, i.getBuildDir(), ImageBuilder())
 	if err != nil {
 		return ErrCreatingBuilder.Wrap(err)
 	}
 	i.builderFactory = factory
+	i.externalBuilder = false
 	i.state = Preparing
 
 	return i.builderFactory.BuildImageFromGitRepo(ctx, gitContext, imageName)
 }
 
+// UseBuilder sets the builder factory the image of the instance is built from, e.g. a factory created with
+// container.NewBuilderFactory and shared by several instances. The image is built and pushed when the instance
+// is started, unless an identical image was already pushed, and the instance runs the resulting image,
+// so the instance never runs an image whose name drifted from its builder. The settings of the instance that change
+// its image, e.g. SetEnvironmentVariable in the state 'Preparing', are applied to the factory
+// The files added with AddFile or AddFolder are copied to the build context of the factory, which is kept after
+// the image is pushed, so the other instances can push it again, see container.BuilderFactory.SetKeepBuildContext
+// The factory still belongs to the caller, who closes it with Close once the instances using it are started
+// This function can only be called in the states 'None' and 'Preparing'
+func (i *Instance) UseBuilder(f *container.BuilderFactory) error {
+	if !i.IsInState(None, Preparing) {
+		return ErrUsingBuilderNotAllowed.WithParams(i.state.String())
+	}
+	if f == nil {
+		return ErrBuilderFactoryIsNil.WithParams(i.name)
+	}
+	if i.state == Preparing {
+		// Discard the image prepared so far by the instance
+		if err := i.closeBuilderFactory(); err != nil {
+			return err
+		}
+		if err := os.RemoveAll(i.getBuildDir()); err != nil {
+			return ErrRemovingBuildDir.WithParams(i.getBuildDir()).Wrap(err)
+		}
+		i.imageEnv = make(map[string]string)
+		i.specFiles = nil
+		i.specFolders = nil
+	}
+	f.SetKeepBuildContext(true)
+	i.builderFactory = f
+	i.externalBuilder = true
+	i.imageName = ""
+	i.state = Preparing
+	logrus.Debugf("Using builder of image '%s' in instance '%s'", f.ImageNameFrom(), i.name)
+	return nil
+}
+
 // SetImageInstant sets the image of the instance without a grace period.
 // Instant means that the pod is replaced without a grace period of 1 second.
 // It is only allowed in the 'Running' state.
@@ -255,7 +441,7 @@ func (i *Instance) SetImageInstant(image string) error {
 		return ErrSettingImageNotAllowedForSidecars
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), i.operationTimeout())
 	defer cancel()
 
 	gracePeriod := int64(0)
@@ -323,7 +509,7 @@ func (i *Instance) PortForwardTCP(port int) (int, error) {
 		return -1, ErrGettingFreePort.WithParams(port)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), i.operationTimeout())
 	defer cancel()
 
 	// Forward the port
@@ -369,7 +555,7 @@ func (i *Instance) AddPortUDP(port int) error {
 // ExecuteCommand executes the given command in the instance
 // This function can only be called in the states 'Preparing' and 'Started'
 func (i *Instance) ExecuteCommand(command ...string) (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), i.operationTimeout())
 	defer cancel()
 
 	return i.ExecuteCommandWithContext(ctx, command...)
@@ -377,7 +563,8 @@ func (i *Instance) ExecuteCommand(command ...string) (string, error) {
 
 // ExecuteCommandWithContext executes the given command in the instance
 // This function can only be called in the states 'Preparing' and 'Started'
-// The context can be used to cancel the command and it is only possible in start state
+// The context can be used to cancel the command and it is only possible in start state,
+// the command is also canceled after the operation timeout of the instance, see SetOperationTimeout
 func (i *Instance) ExecuteCommandWithContext(ctx context.Context, command ...string) (string, error) {
 	if !i.IsInState(Preparing, Started) {
 		return "", ErrExecutingCommandNotAllowed.WithParams(i.state.String())
@@ -405,7 +592,7 @@ func (i *Instance) ExecuteCommandWithContext(ctx context.Context, command ...str
 		eErr = ErrExecutingCommandInInstance.WithParams(command, i.k8sName)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(ctx, i.operationTimeout())
 	defer cancel()
 
 	pod, err := k8sClient.GetFirstPodFromReplicaSet(ctx, instanceName)
@@ -432,6 +619,15 @@ func (i *Instance) checkStateForAddingFile() error {
 // AddFile adds a file to the instance
 // This function can only be called in the state 'Preparing'
 func (i *Instance) AddFile(src string, dest string, chown string) error {
+	if err := i.addFile(src, dest, chown); err != nil {
+		return err
+	}
+	i.specFiles = append(i.specFiles, FileSpec{Src: src, Dest: dest, Chown: chown})
+	return nil
+}
+
+// addFile adds a file to the instance without recording it in the spec of the instance
+func (i *Instance) addFile(src string, dest string, chown string) error {
 	if err := i.checkStateForAddingFile(); err != nil {
 		return err
 	}
@@ -447,7 +643,7 @@ func (i *Instance) AddFile(src string, dest string, chown string) error {
 	}
 
 	// copy file to build dir
-	dstPath := filepath.Join(i.getBuildDir(), dest)
+	dstPath := filepath.Join(i.getImageFilesDir(), dest)
 
 	// make sure dir exists
 	err = os.MkdirAll(filepath.Dir(dstPath), os.ModePerm)
@@ -518,7 +714,27 @@ func (i *Instance) AddFile(src string, dest string, chown string) error {
 	return nil
 }
 
-// AddFolder adds a folder to the instance
+// FileSpec describes a file to add to the instance with AddFiles
+type FileSpec struct {
+	Src   string `yaml:"src"`   // Path of the file on the host
+	Dest  string `yaml:"dest"`  // Path of the file in the instance
+	Chown string `yaml:"chown"` // Owner of the file in the instance, e.g. 0:0
+}
+
+// AddFiles adds the files to the instance in the given order, as if AddFile was called for each of them
+// It stops at the first file that cannot be added, the returned error identifies it by its index and source
+// This function can only be called in the state 'Preparing' or 'Committed'
+func (i *Instance) AddFiles(files []FileSpec) error {
+	for n, file := range files {
+		if err := i.AddFile(file.Src, file.Dest, file.Chown); err != nil {
+			return ErrAddingFiles.WithParams(n, file.Src, i.name).Wrap(err)
+		}
+	}
+	logrus.Debugf("Added %d files to instance '%s'", len(files), i.name)
+	return nil
+}
+
+// AddFolder adds a folder to the instance, the symlinks in it are handled according to SetSymlinkPolicy
 // This function can only be called in the state 'Preparing' or 'Committed'
 func (i *Instance) AddFolder(src string, dest string, chown string) error {
 	if !i.IsInState(Preparing, Committed) {
@@ -533,35 +749,37 @@ func (i *Instance) AddFolder(src string, dest string, chown string) error {
 		return ErrSrcDoesNotExistOrIsNotDirectory.WithParams(src).Wrap(err)
 	}
 
-	// iterate over the files/directories in the src
-	err = filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// create the destination path
-		relPath, err := filepath.Rel(src, path)
-		if err != nil {
-			return err
-		}
-		dstPath := filepath.Join(i.getBuildDir(), dest, relPath)
-
-		if info.IsDir() {
-			// create directory at destination path
-			return os.MkdirAll(dstPath, os.ModePerm)
-		}
-		// copy file to destination path
-		return i.AddFile(path, filepath.Join(dest, relPath), chown)
-	})
+	if err := i.validateFolderLimits(src); err != nil {
+		return err
+	}
 
+	// iterate over the files/directories in the src
+	err = i.addFolderContents(src, dest, chown, map[string]bool{})
 	if err != nil {
 		return ErrCopyingFolderToInstance.WithParams(src, i.name).Wrap(err)
 	}
+	i.specFolders = append(i.specFolders, FileSpec{Src: src, Dest: dest, Chown: chown})
 
 	logrus.Debugf("Added folder '%s' to instance '%s'", dest, i.name)
 	return nil
 }
 
+// SetFolderLimits sets the maximum total size in bytes and the maximum number of files
+// of a folder added with AddFolder, a value of 0 disables the respective limit
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetFolderLimits(maxSize int64, maxFiles int) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingFolderLimitsNotAllowed.WithParams(i.state.String())
+	}
+	if maxSize < 0 || maxFiles < 0 {
+		return ErrInvalidFolderLimits.WithParams(maxSize, maxFiles)
+	}
+	i.maxFolderSize = maxSize
+	i.maxFolderFiles = maxFiles
+	logrus.Debugf("Set folder limits to '%d' bytes and '%d' files in instance '%s'", maxSize, maxFiles, i.name)
+	return nil
+}
+
 // AddFileBytes adds a file with the given content to the instance
 // This function can only be called in the state 'Preparing'
 func (i *Instance) AddFileBytes(bytes []byte, dest string, chown string) error {
@@ -584,8 +802,8 @@ func (i *Instance) AddFileBytes(bytes []byte, dest string, chown string) error {
 		return err
 	}
 
-	// use AddFile to copy the temp file to the destination
-	return i.AddFile(tmpfile.Name(), dest, chown)
+	// use addFile to copy the temp file to the destination, the temp file is not part of the spec
+	return i.addFile(tmpfile.Name(), dest, chown)
 }
 
 // SetUser sets the user for the instance
@@ -616,6 +834,66 @@ func updateImageCacheWithHash(imageHash, imageName string) {
 	imageCache[imageHash] = imageName // Update the cache with the new hash and image name
 }
 
+// SetNoCache disables skipping the build when an identical image already exists in the registry
+// This function can only be called in the state 'Preparing'
+func (i *Instance) SetNoCache(noCache bool) error {
+	if !i.IsInState(Preparing) {
+		return ErrSettingNoCacheNotAllowed.WithParams(i.state.String())
+	}
+	i.builderFactory.SetNoCache(noCache)
+	logrus.Debugf("Set no cache to '%t' for instance '%s'", noCache, i.name)
+	return nil
+}
+
+// SetReorderForCache enables moving the environment variables before the files added to the image when it is built,
+// so that changing the files, e.g. with AddFolder after the instance is committed, reuses more cached layers
+// See container.BuilderFactory.SetReorderForCache for the reordering rules
+// This function can only be called in the state 'Preparing'
+func (i *Instance) SetReorderForCache(reorder bool) error {
+	if !i.IsInState(Preparing) {
+		return ErrSettingReorderForCacheNotAllowed.WithParams(i.state.String())
+	}
+	i.builderFactory.SetReorderForCache(reorder)
+	logrus.Debugf("Set reorder for cache to '%t' for instance '%s'", reorder, i.name)
+	return nil
+}
+
+// SetBaseImagePublicKey requires the image of the instance to be signed with cosign by the private key of the PEM encoded public key,
+// e.g. the content of the cosign.pub file generated by `cosign generate-key-pair`
+// The signature is verified when the instance is committed, which fails if the image is unsigned or signed with another key,
+// and the image is then pinned to its verified digest
+// See container.BuilderFactory.SetBaseImagePublicKey for the supported keys
+// This function can only be called in the state 'Preparing'
+func (i *Instance) SetBaseImagePublicKey(pemKey []byte) error {
+	if !i.IsInState(Preparing) {
+		return ErrSettingBaseImagePublicKeyNotAllowed.WithParams(i.state.String())
+	}
+	if err := i.builderFactory.SetBaseImagePublicKey(pemKey); err != nil {
+		return ErrSettingBaseImagePublicKey.WithParams(i.name).Wrap(err)
+	}
+	logrus.Debugf("Set base image public key for instance '%s'", i.name)
+	return nil
+}
+
+// SetInsecureRegistries sets the registry hosts, e.g. registry.local:5000, that the image builder uses
+// without verifying their TLS certificate, e.g. for dev registries with self-signed certificates
+// This must never be used with registries reached over untrusted networks, as the images can be tampered with
+// Only the builder is affected: the nodes must trust the registry in their container runtime to pull the image
+// This function can only be called in the state 'Preparing'
+func (i *Instance) SetInsecureRegistries(hosts ...string) error {
+	if !i.IsInState(Preparing) {
+		return ErrSettingInsecureRegistriesNotAllowed.WithParams(i.state.String())
+	}
+	for _, host := range hosts {
+		if host == "" || strings.ContainsAny(host, "/ ") {
+			return ErrInvalidRegistryHost.WithParams(host)
+		}
+	}
+	i.builderFactory.SetInsecureRegistries(hosts)
+	logrus.Warnf("TLS verification is disabled for registries %v in instance '%s'", hosts, i.name)
+	return nil
+}
+
 // Commit commits the instance
 // This function can only be called in the state 'Preparing'
 func (i *Instance) Commit() error {
@@ -628,37 +906,10 @@ func (i *Instance) Commit() error {
 			i.deployOrPatchService(context.TODO(), i.portsTCP, i.portsUDP)
 		}()
 	}
-	if i.builderFactory.Changed() {
-		// TODO: To speed up the process, the image name could be dependent on the hash of the image
-		imageName, err := i.getImageRegistry()
-		if err != nil {
-			return ErrGettingImageRegistry.Wrap(err)
-		}
-
-		// Generate a hash for the current image
-		imageHash, err := i.builderFactory.GenerateImageHash()
-		if err != nil {
-			return ErrGeneratingImageHash.Wrap(err)
-		}
-
-		// Check if the generated image hash already exists in the cache, otherwise, we build it.
-		cachedImageName, exists := checkImageHashInCache(imageHash)
-		if exists {
-			i.imageName = cachedImageName
-			logrus.Debugf("Using cached image for instance '%s'", i.name)
-		} else {
-			logrus.Debugf("Cannot use any cached image for instance '%s'", i.name)
-			err = i.builderFactory.PushBuilderImage(imageName)
-			if err != nil {
-				return ErrPushingImage.WithParams(i.name).Wrap(err)
-			}
-			updateImageCacheWithHash(imageHash, imageName)
-			i.imageName = imageName
-			logrus.Debugf("Pushed new image for instance '%s'", i.name)
-		}
-	} else {
-		i.imageName = i.builderFactory.ImageNameFrom()
-		logrus.Debugf("No need to build and push image for instance '%s'", i.name)
+	if i.externalBuilder {
+		logrus.Debugf("Image of instance '%s' is built from its builder when it is started", i.name)
+	} else if err := i.pushImage(); err != nil {
+		return err
 	}
 	i.state = Committed
 	logrus.Debugf("Set state of instance '%s' to '%s'", i.name, i.state.String())
@@ -719,6 +970,34 @@ func (i *Instance) SetCPU(request string) error {
 	return nil
 }
 
+// SetEphemeralStorage sets the ephemeral storage request and limit of the instance, e.g. '1Gi' and '4Gi',
+// for apps writing lots of temporary data, which are evicted when they exceed the limit of their node otherwise
+// The request is used to schedule the pod on a node with enough free local storage, an empty value is not set
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetEphemeralStorage(request, limit string) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingEphemeralStorageNotAllowed.WithParams(i.state.String())
+	}
+	quantities := make([]resource.Quantity, 0, 2)
+	for _, value := range []string{request, limit} {
+		if value == "" {
+			continue
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return ErrInvalidEphemeralStorage.WithParams(value).Wrap(err)
+		}
+		quantities = append(quantities, quantity)
+	}
+	if len(quantities) == 2 && quantities[0].Cmp(quantities[1]) > 0 {
+		return ErrEphemeralStorageRequestExceedsLimit.WithParams(request, limit)
+	}
+	i.ephemeralRequest = request
+	i.ephemeralLimit = limit
+	logrus.Debugf("Set ephemeral storage to '%s' and limit to '%s' in instance '%s'", request, limit, i.name)
+	return nil
+}
+
 // SetEnvironmentVariable sets the given environment variable in the instance
 // This function can only be called in the states 'Preparing' and 'Committed'
 func (i *Instance) SetEnvironmentVariable(key, value string) error {
@@ -730,21 +1009,185 @@ func (i *Instance) SetEnvironmentVariable(key, value string) error {
 		if err != nil {
 			return err
 		}
+		i.imageEnv[key] = value
 	} else if i.state == Committed {
 		i.env[key] = value
+		delete(i.envValueFrom, key)
 	}
 	logrus.Debugf("Set environment variable '%s' to '%s' in instance '%s'", key, value, i.name)
 	return nil
 }
 
-// GetIP returns the IP of the instance
+// SetEnvMap sets all the given environment variables in the instance
+// Variables that are already set are overridden, so later calls take precedence
+// In the state 'Preparing' the variables are added to the image with a single ENV instruction sorted by name,
+// to keep the image hash stable
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetEnvMap(vars map[string]string) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingEnvNotAllowed.WithParams(i.state.String())
+	}
+	if i.state == Preparing {
+		if err := i.builderFactory.SetEnvVars(vars); err != nil {
+			return err
+		}
+		for key, value := range vars {
+			i.imageEnv[key] = value
+		}
+		logrus.Debugf("Set environment variables '%v' in instance '%s'", vars, i.name)
+		return nil
+	}
+
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := i.SetEnvironmentVariable(key, vars[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetEnvFromField sets an environment variable to a field of the pod using the downward API
+// The field path is e.g. 'metadata.name', 'metadata.namespace', 'spec.nodeName' or 'status.podIP'
+// ref: https://kubernetes.io/docs/concepts/workloads/pods/downward-api/
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetEnvFromField(envName, fieldPath string) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingEnvNotAllowed.WithParams(i.state.String())
+	}
+	if envName == "" {
+		return ErrEnvNameEmpty
+	}
+	if err := validateEnvFieldPath(fieldPath); err != nil {
+		return err
+	}
+
+	i.setEnvValueFrom(envName, &v1.EnvVarSource{
+		FieldRef: &v1.ObjectFieldSelector{FieldPath: fieldPath},
+	})
+	logrus.Debugf("Set environment variable '%s' from field '%s' in instance '%s'", envName, fieldPath, i.name)
+	return nil
+}
+
+// SetEnvFromResourceField sets an environment variable to a resource request or limit of the instance
+// The resource is e.g. 'limits.memory' or 'requests.cpu', the divisor sets the unit of the value, e.g. '1Mi' or '1m'
+// An empty divisor uses the default of 1, which means cores for cpu and bytes for memory
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetEnvFromResourceField(envName, resourceName, divisor string) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingEnvNotAllowed.WithParams(i.state.String())
+	}
+	if envName == "" {
+		return ErrEnvNameEmpty
+	}
+	if !envResourceFields[resourceName] {
+		return ErrInvalidEnvResourceField.WithParams(resourceName)
+	}
+
+	selector := &v1.ResourceFieldSelector{Resource: resourceName}
+	if divisor != "" {
+		quantity, err := resource.ParseQuantity(divisor)
+		if err != nil {
+			return ErrInvalidEnvResourceDivisor.WithParams(divisor).Wrap(err)
+		}
+		selector.Divisor = quantity
+	}
+
+	i.setEnvValueFrom(envName, &v1.EnvVarSource{ResourceFieldRef: selector})
+	logrus.Debugf("Set environment variable '%s' from resource '%s' in instance '%s'", envName, resourceName, i.name)
+	return nil
+}
+
+// SetEnvFromConfigMap sets an environment variable to the value of a key of a ConfigMap
+// The ConfigMap is either created by the instance with CreateEnvConfigMap or must exist in the namespace of knuu
+// The key must exist in the ConfigMap, otherwise the container does not start
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetEnvFromConfigMap(envName, configMapName, key string) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingEnvNotAllowed.WithParams(i.state.String())
+	}
+	if envName == "" {
+		return ErrEnvNameEmpty
+	}
+	if err := validateConfigMapName(configMapName); err != nil {
+		return err
+	}
+	if err := validateConfigMapKey(key); err != nil {
+		return err
+	}
+
+	i.setEnvValueFrom(envName, &v1.EnvVarSource{
+		ConfigMapKeyRef: &v1.ConfigMapKeySelector{
+			LocalObjectReference: v1.LocalObjectReference{Name: configMapName},
+			Key:                  key,
+		},
+	})
+	logrus.Debugf("Set environment variable '%s' from key '%s' of configmap '%s' in instance '%s'", envName, key, configMapName, i.name)
+	return nil
+}
+
+// AddEnvFromConfigMap sets an environment variable for every key of a ConfigMap, named after the key
+// The ConfigMap is either created by the instance with CreateEnvConfigMap or must exist in the namespace of knuu
+// Variables set with SetEnvironmentVariable or the other SetEnvFrom functions take precedence over the keys of the ConfigMap
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) AddEnvFromConfigMap(configMapName string) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingEnvNotAllowed.WithParams(i.state.String())
+	}
+	if err := validateConfigMapName(configMapName); err != nil {
+		return err
+	}
+	if slices.Contains(i.envFromConfigMaps, configMapName) {
+		return ErrEnvFromConfigMapAlreadyAdded.WithParams(configMapName)
+	}
+
+	i.envFromConfigMaps = append(i.envFromConfigMaps, configMapName)
+	logrus.Debugf("Added environment variables from configmap '%s' in instance '%s'", configMapName, i.name)
+	return nil
+}
+
+// CreateEnvConfigMap creates a ConfigMap with the given data to be used with SetEnvFromConfigMap and AddEnvFromConfigMap
+// The ConfigMap is created when the instance is started and deleted when it is destroyed
+// The name is local to the instance: the ConfigMap in the cluster is named after the instance, so clones get their own copy
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) CreateEnvConfigMap(configMapName string, data map[string]string) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingEnvNotAllowed.WithParams(i.state.String())
+	}
+	if err := validateConfigMapName(configMapName); err != nil {
+		return err
+	}
+	if _, exists := i.envConfigMaps[configMapName]; exists {
+		return ErrEnvConfigMapAlreadyExists.WithParams(configMapName)
+	}
+	for key := range data {
+		if err := validateConfigMapKey(key); err != nil {
+			return err
+		}
+	}
+
+	i.envConfigMaps[configMapName] = maps.Clone(data)
+	logrus.Debugf("Created env configmap '%s' with %d keys in instance '%s'", configMapName, len(data), i.name)
+	return nil
+}
+
+// GetIP returns the IP of the instance, which is the cluster IP of its service, deploying the service if needed
+// The IP is cached, as the service and its IP outlive the pods of the instance: restarting the container or
+// replacing the pod does not change it. It is resolved again after the instance is stopped and started again,
+// so a service recreated in the meantime is not reported with its former address
+// Use GetPodIPs for the addresses of the pod, which change whenever the pod is replaced
 // This function can only be called in the states 'Preparing' and 'Started'
 func (i *Instance) GetIP() (string, error) {
 	// Check if i.kubernetesService already has the IP
-	if i.kubernetesService != nil && i.kubernetesService.Spec.ClusterIP != "" {
+	if i.kubernetesService != nil && i.kubernetesService.Spec.ClusterIP != "" && !i.serviceStale {
 		return i.kubernetesService.Spec.ClusterIP, nil
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), i.operationTimeout())
 	defer cancel()
 	// If not, proceed with the existing logic to deploy the service and get the IP
 	svc, err := k8sClient.GetService(ctx, i.k8sName)
@@ -767,6 +1210,7 @@ func (i *Instance) GetIP() (string, error) {
 
 	// Update i.kubernetesService for future reference
 	i.kubernetesService = svc
+	i.serviceStale = false
 
 	return ip, nil
 }
@@ -778,17 +1222,17 @@ func (i *Instance) GetFileBytes(file string) ([]byte, error) {
 		return nil, ErrGettingFileNotAllowed.WithParams(i.state.String())
 	}
 
+	ctx, cancel := context.WithTimeout(context.Background(), i.operationTimeout())
+	defer cancel()
+
 	if i.state != Started {
-		bytes, err := i.builderFactory.ReadFileFromBuilder(file)
+		bytes, err := i.builderFactory.ReadFileFromBuilderWithContext(ctx, file)
 		if err != nil {
 			return nil, ErrGettingFile.WithParams(file, i.name).Wrap(err)
 		}
 		return bytes, nil
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-
 	rc, err := i.ReadFileFromRunningInstance(ctx, file)
 	if err != nil {
 		return nil, ErrReadingFile.WithParams(file, i.name).Wrap(err)
@@ -822,6 +1266,126 @@ func (i *Instance) AddPolicyRule(rule rbacv1.PolicyRule) error {
 	return nil
 }
 
+// SetServiceAccount sets an existing service account to run the pod of the instance with
+// By default a service account is created for each instance, with the rules added by AddPolicyRule.
+// When a service account is set, the policy rules are bound to it instead and it is not deleted on Destroy.
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetServiceAccount(name string) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingServiceAccountNotAllowed.WithParams(i.state.String())
+	}
+	if name == "" {
+		return ErrServiceAccountNameEmpty
+	}
+	i.serviceAccount = name
+	logrus.Debugf("Set service account '%s' in instance '%s'", name, i.name)
+	return nil
+}
+
+// SetPriorityClass sets the PriorityClass of the pod of the instance
+// A higher priority keeps important pods from being evicted on busy clusters,
+// and when the cluster is full the scheduler may preempt (evict) pods with a lower priority to schedule it.
+// The PriorityClass must exist in the cluster, which is checked when the instance is started.
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetPriorityClass(name string) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingPriorityClassNotAllowed.WithParams(i.state.String())
+	}
+	if name == "" {
+		return ErrPriorityClassNameEmpty
+	}
+	i.priorityClass = name
+	logrus.Debugf("Set priority class '%s' in instance '%s'", name, i.name)
+	return nil
+}
+
+// SetRuntimeClass sets the RuntimeClass the containers of the instance run with, e.g. gvisor or kata,
+// to run untrusted workloads in a sandboxed runtime with a stronger isolation from the node
+// The RuntimeClass must exist in the cluster, which is checked when the instance is started.
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetRuntimeClass(name string) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingRuntimeClassNotAllowed.WithParams(i.state.String())
+	}
+	if name == "" {
+		return ErrRuntimeClassNameEmpty
+	}
+	i.runtimeClass = name
+	logrus.Debugf("Set runtime class '%s' in instance '%s'", name, i.name)
+	return nil
+}
+
+// SetAutomountServiceAccountToken sets whether the token of the service account is mounted in the pod of the instance
+// Disabling it mimics production setups where pods cannot talk to the kubernetes API
+// By default the setting of the service account is used, which mounts the token
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetAutomountServiceAccountToken(automount bool) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingAutomountTokenNotAllowed.WithParams(i.state.String())
+	}
+	i.automountToken = &automount
+	logrus.Debugf("Set automount service account token to '%t' in instance '%s'", automount, i.name)
+	return nil
+}
+
+// SetShareProcessNamespace sets whether the main container and the sidecars of the instance share a single process namespace
+// This lets sidecars see and signal the processes of the main container, e.g. for profiling
+// In a shared namespace the process with PID 1 is the pause container instead of the command of the image,
+// so applications that expect to run as PID 1 (e.g. to reap zombie processes) may misbehave,
+// and the filesystems of the containers are reachable from each other through /proc/<pid>/root
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetShareProcessNamespace(share bool) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingShareProcessNamespaceNotAllowed.WithParams(i.state.String())
+	}
+	i.sharePidNamespace = share
+	logrus.Debugf("Set share process namespace to '%t' in instance '%s'", share, i.name)
+	return nil
+}
+
+// AddReadinessGate adds a readiness gate with the condition type to the pod of the instance, e.g. `example.com/seeded`
+// The pod is only ready once the condition is set to True in its status, in addition to its containers being ready,
+// so WaitInstanceIsRunning waits for it too. Kubernetes never sets the condition, an external controller
+// (or the test itself) must set it, otherwise the instance never becomes ready
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) AddReadinessGate(conditionType string) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrAddingReadinessGateNotAllowed.WithParams(i.state.String())
+	}
+	if errs := validation.IsQualifiedName(conditionType); len(errs) != 0 {
+		return ErrInvalidReadinessGate.WithParams(conditionType, strings.Join(errs, ", "))
+	}
+	if slices.Contains(i.readinessGates, conditionType) {
+		return nil
+	}
+	i.readinessGates = append(i.readinessGates, conditionType)
+	logrus.Debugf("Added readiness gate '%s' to instance '%s'", conditionType, i.name)
+	return nil
+}
+
+// SetWorkingDir sets the working directory of the container, overriding the WORKDIR of the image without rebuilding it
+// The directory must be an absolute path, it is created by the container runtime if it does not exist
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetWorkingDir(dir string) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingWorkingDirNotAllowed.WithParams(i.state.String())
+	}
+	if !path.IsAbs(dir) {
+		return ErrWorkingDirNotAbsolute.WithParams(dir)
+	}
+	i.workingDir = path.Clean(dir)
+	logrus.Debugf("Set working directory to '%s' in instance '%s'", i.workingDir, i.name)
+	return nil
+}
+
+// ServiceAccountName returns the name of the service account the pod of the instance runs with
+func (i *Instance) ServiceAccountName() string {
+	if i.serviceAccount != "" {
+		return i.serviceAccount
+	}
+	return i.k8sName
+}
+
 // checkStateForProbe checks if the current state is allowed for setting a probe
 func (i *Instance) checkStateForProbe() error {
 	if !i.IsInState(Preparing, Committed) {
@@ -832,12 +1396,16 @@ func (i *Instance) checkStateForProbe() error {
 
 // SetLivenessProbe sets the liveness probe of the instance
 // A live probe is a probe that is used to determine if the instance is still alive, and should be restarted if not
+// The headers of an HTTP probe can be set with SetLivenessHTTPHeaders, e.g. for a secured health endpoint
 // See usage documentation: https://pkg.go.dev/k8sClient.io/api/core/v1@v0.27.3#Probe
 // This function can only be called in the states 'Preparing' and 'Committed'
 func (i *Instance) SetLivenessProbe(livenessProbe *v1.Probe) error {
 	if err := i.checkStateForProbe(); err != nil {
 		return err
 	}
+	if err := validateProbe(livenessProbe); err != nil {
+		return err
+	}
 	i.livenessProbe = livenessProbe
 	logrus.Debugf("Set liveness probe to '%s' in instance '%s'", livenessProbe, i.name)
 	return nil
@@ -845,12 +1413,16 @@ func (i *Instance) SetLivenessProbe(livenessProbe *v1.Probe) error {
 
 // SetReadinessProbe sets the readiness probe of the instance
 // A readiness probe is a probe that is used to determine if the instance is ready to receive traffic
+// The headers of an HTTP probe can be set with SetReadinessHTTPHeaders, e.g. for a secured health endpoint
 // See usage documentation: https://pkg.go.dev/k8sClient.io/api/core/v1@v0.27.3#Probe
 // This function can only be called in the states 'Preparing' and 'Committed'
 func (i *Instance) SetReadinessProbe(readinessProbe *v1.Probe) error {
 	if err := i.checkStateForProbe(); err != nil {
 		return err
 	}
+	if err := validateProbe(readinessProbe); err != nil {
+		return err
+	}
 	i.readinessProbe = readinessProbe
 	logrus.Debugf("Set readiness probe to '%s' in instance '%s'", readinessProbe, i.name)
 	return nil
@@ -858,12 +1430,17 @@ func (i *Instance) SetReadinessProbe(readinessProbe *v1.Probe) error {
 
 // SetStartupProbe sets the startup probe of the instance
 // A startup probe is a probe that is used to determine if the instance is ready to receive traffic after a startup
+// The liveness and readiness probes only apply once it succeeds, so slow-booting apps are not restarted too early
+// WaitInstanceIsRunning waits for the time the probe allows the app to start on top of its own timeout
 // See usage documentation: https://pkg.go.dev/k8sClient.io/api/core/v1@v0.27.3#Probe
 // This function can only be called in the states 'Preparing' and 'Committed'
 func (i *Instance) SetStartupProbe(startupProbe *v1.Probe) error {
 	if err := i.checkStateForProbe(); err != nil {
 		return err
 	}
+	if err := validateStartupProbe(startupProbe); err != nil {
+		return err
+	}
 	i.startupProbe = startupProbe
 	logrus.Debugf("Set startup probe to '%s' in instance '%s'", startupProbe, i.name)
 	return nil
@@ -1005,22 +1582,32 @@ func (i *Instance) SetPrivileged(privileged bool) error {
 }
 
 // AddCapability adds a capability to the instance
+// The name is validated, e.g. NET_ADMIN, ErrInvalidCapability is returned for an unknown capability
 // This function can only be called in the state 'Preparing' or 'Committed'
 func (i *Instance) AddCapability(capability string) error {
 	if !i.IsInState(Preparing, Committed) {
 		return ErrAddingCapabilityNotAllowed.WithParams(i.state.String())
 	}
+	if err := validateCapability(capability); err != nil {
+		return err
+	}
 	i.securityContext.capabilitiesAdd = append(i.securityContext.capabilitiesAdd, capability)
 	logrus.Debugf("Added capability '%s' to instance '%s'", capability, i.name)
 	return nil
 }
 
 // AddCapabilities adds multiple capabilities to the instance
+// None of them is added if one of the names is invalid, see AddCapability
 // This function can only be called in the state 'Preparing' or 'Committed'
 func (i *Instance) AddCapabilities(capabilities []string) error {
 	if !i.IsInState(Preparing, Committed) {
 		return ErrAddingCapabilitiesNotAllowed.WithParams(i.state.String())
 	}
+	for _, capability := range capabilities {
+		if err := validateCapability(capability); err != nil {
+			return err
+		}
+	}
 	for _, capability := range capabilities {
 		i.securityContext.capabilitiesAdd = append(i.securityContext.capabilitiesAdd, capability)
 		logrus.Debugf("Added capability '%s' to instance '%s'", capability, i.name)
@@ -1028,6 +1615,80 @@ func (i *Instance) AddCapabilities(capabilities []string) error {
 	return nil
 }
 
+// SetSysctl sets a namespaced kernel parameter in the pod of the instance, e.g. net.core.somaxconn
+// Safe sysctls are always allowed, while unsafe sysctls must be allowed in the kubelet of the node
+// with `--allowed-unsafe-sysctls`, otherwise the pod fails to start with 'SysctlForbidden'
+// If Preflight reported that the cluster does not support sysctls, ErrFeatureNotAvailable is returned
+// ref: https://kubernetes.io/docs/tasks/administer-cluster/sysctl-cluster/
+// This function can only be called in the state 'Preparing' or 'Committed'
+func (i *Instance) SetSysctl(name, value string) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingSysctlNotAllowed.WithParams(i.state.String())
+	}
+	if err := validateSysctl(name); err != nil {
+		return err
+	}
+	if err := checkCachedFeature(FeatureSysctls); err != nil {
+		return err
+	}
+	if !isSafeSysctl(name) {
+		logrus.Warnf("Sysctl '%s' is unsafe, it must be allowed in the kubelet for instance '%s' to start", name, i.name)
+	}
+
+	for idx, sysctl := range i.sysctls {
+		if sysctl.Name == name {
+			i.sysctls[idx].Value = value
+			logrus.Debugf("Set sysctl '%s' to '%s' in instance '%s'", name, value, i.name)
+			return nil
+		}
+	}
+	i.sysctls = append(i.sysctls, v1.Sysctl{Name: name, Value: value})
+	logrus.Debugf("Set sysctl '%s' to '%s' in instance '%s'", name, value, i.name)
+	return nil
+}
+
+// SetFSGroup sets the group owning the volumes mounted in the pod of the instance, e.g. to let an application
+// running as a non-root user write to them, as the volumes are owned by root otherwise.
+// The group is added to the supplemental groups of the containers. It must match the group of the files
+// added to the instance in the state 'Committed', which set it too
+// This function can only be called in the state 'Preparing' or 'Committed'
+func (i *Instance) SetFSGroup(gid int64) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingFSGroupNotAllowed.WithParams(i.state.String())
+	}
+	if gid <= 0 {
+		return ErrInvalidFSGroup.WithParams(gid)
+	}
+	if i.fsGroup != 0 && i.fsGroup != gid {
+		return ErrAllFilesMustHaveSameGroup
+	}
+	i.fsGroup = gid
+	logrus.Debugf("Set fsGroup to '%d' in instance '%s'", gid, i.name)
+	return nil
+}
+
+// DropCapability drops a capability from the instance
+// The name is validated like in AddCapability
+// This function can only be called in the state 'Preparing' or 'Committed'
+func (i *Instance) DropCapability(capability string) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrDroppingCapabilityNotAllowed.WithParams(i.state.String())
+	}
+	if err := validateCapability(capability); err != nil {
+		return err
+	}
+	i.securityContext.capabilitiesDrop = append(i.securityContext.capabilitiesDrop, capability)
+	logrus.Debugf("Dropped capability '%s' from instance '%s'", capability, i.name)
+	return nil
+}
+
+// DropAllCapabilities drops all capabilities from the instance
+// Capabilities added with AddCapability are still granted, which allows least-privilege setups
+// This function can only be called in the state 'Preparing' or 'Committed'
+func (i *Instance) DropAllCapabilities() error {
+	return i.DropCapability(allCapabilities)
+}
+
 // StartAsync starts the instance without waiting for it to be ready
 // This function can only be called in the state 'Committed' or 'Stopped'
 // This function will replace StartWithoutWait
@@ -1056,10 +1717,16 @@ func (i *Instance) StartWithoutWait() error {
 		return ErrStartingSidecarNotAllowed
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), i.operationTimeout())
 	defer cancel()
 
 	if i.state == Committed {
+		if i.externalBuilder {
+			if err := i.pushImage(); err != nil {
+				return ErrBuildingImageFromBuilder.WithParams(i.name).Wrap(err)
+			}
+		}
+
 		// deploy otel collector if observability is enabled
 		if i.isObservabilityEnabled() {
 			if err := i.addOtelCollectorSidecar(); err != nil {
@@ -1087,11 +1754,14 @@ func (i *Instance) StartWithoutWait() error {
 	if err != nil {
 		return ErrDeployingPodForInstance.WithParams(i.k8sName).Wrap(err)
 	}
+	// the IP of a restarted instance is not taken for granted, see GetIP
+	i.serviceStale = i.state == Stopped
 	i.state = Started
+	i.readyHooksDone = false
 	setStateForSidecars(i.sidecars, Started)
 	logrus.Debugf("Set state of instance '%s' to '%s'", i.k8sName, i.state.String())
 
-	return nil
+	return i.runStartHooks()
 }
 
 // Start starts the instance and waits for it to be ready
@@ -1109,37 +1779,154 @@ func (i *Instance) Start() error {
 	return nil
 }
 
+// GetContainerExitCode returns the exit code of the container of the instance once it has terminated,
+// e.g. to assert the success of a one-shot workload. The replica set restarts the container when it exits,
+// so the exit code of its last run is returned until it is running again
+// It returns an error if the container has not terminated yet
+// This function can only be called in the state 'Started'
+func (i *Instance) GetContainerExitCode(ctx context.Context) (int, error) {
+	if !i.IsInState(Started) {
+		return 0, ErrGettingContainerExitCodeNotAllowed.WithParams(i.state.String())
+	}
+
+	instanceName := i.k8sName
+	if i.isSidecar {
+		instanceName = i.parentInstance.k8sName
+	}
+
+	pod, err := k8sClient.GetFirstPodFromReplicaSet(ctx, instanceName)
+	if err != nil {
+		return 0, ErrGettingPodFromReplicaSet.WithParams(i.k8sName).Wrap(err)
+	}
+	code, terminated := containerExitCode(pod, i.k8sName)
+	if !terminated {
+		return 0, ErrContainerNotTerminated.WithParams(i.name)
+	}
+	return code, nil
+}
+
 // IsRunning returns true if the instance is running
+// An instance run by a cron job, see SetCronSchedule, is running once its cron job is created
 // This function can only be called in the state 'Started'
 func (i *Instance) IsRunning() (bool, error) {
 	if !i.IsInState(Started, Stopped) {
 		return false, ErrCheckingIfInstanceRunningNotAllowed.WithParams(i.state.String())
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), i.operationTimeout())
 	defer cancel()
+	if i.cronSchedule != "" {
+		// the pods of a cron job only run on schedule or when triggered
+		return k8sClient.CronJobExists(ctx, i.k8sName)
+	}
 	return k8sClient.IsReplicaSetRunning(ctx, i.k8sName)
 }
 
 // WaitInstanceIsRunning waits until the instance is running
+// It waits for 1 minute, or for the operation timeout of the instance if it is set,
+// plus the time the startup probe allows the app to start, see SetStartupProbe
+// Rate limited image pulls are retried as configured with SetGracefulImagePull
+// A container failing more often than the backoff limit, see SetBackoffLimit, is reported with ErrBackoffLimitExceeded
+// The hooks registered with OnReady are invoked once the instance is running, their first error is returned
+// The readiness gates added with AddReadinessGate must be met as well
+// The instance is checked after 250ms, then after twice the previous interval up to 5s, so the waits for fast pods
+// stay short while the slow pods do not load the API server, unless a fixed interval is set with SetPollInterval
 // This function can only be called in the state 'Started'
 func (i *Instance) WaitInstanceIsRunning() error {
 	if !i.IsInState(Started) {
 		return ErrWaitingForInstanceNotAllowed.WithParams(i.state.String())
 	}
-	timeout := time.After(1 * time.Minute)
-	tick := time.Tick(1 * time.Second)
+	waitTimeout := 1 * time.Minute
+	if i.opTimeout != 0 {
+		waitTimeout = i.opTimeout
+	}
+	waitTimeout += i.startupDuration()
+	timeout := time.After(waitTimeout)
+	backoff := i.pollBackoffOr(waitInitialPollInterval, waitMaxPollInterval)
 
+	pullAttempts := 0
 	for {
 		select {
 		case <-timeout:
 			return ErrWaitingForInstanceTimeout.WithParams(i.k8sName)
-		case <-tick:
+		case <-waitAfter(backoff.interval()):
 			running, err := i.IsRunning()
 			if err != nil {
 				return ErrCheckingIfInstanceRunning.WithParams(i.k8sName).Wrap(err)
 			}
 			if running {
+				met, err := i.readinessGatesMet()
+				if err != nil {
+					return ErrCheckingReadinessGates.WithParams(i.k8sName).Wrap(err)
+				}
+				if !met {
+					continue
+				}
+				i.startLogCapture()
+				return i.runReadyHooks()
+			}
+			retried, err := i.retryRateLimitedPull(pullAttempts)
+			if err != nil {
+				return err
+			}
+			if retried {
+				// the time spent rate limited does not count against the wait
+				pullAttempts++
+				timeout = time.After(waitTimeout)
+				continue
+			}
+			if err := i.checkBackoffLimit(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// WaitStable waits until all the containers of the instance have been running without restarting for the given duration
+// This catches crash looping apps that briefly appear to be running
+// The context bounds the total time to wait, the observed number of restarts is reported if it never stabilizes
+// This function can only be called in the state 'Started'
+func (i *Instance) WaitStable(ctx context.Context, stableFor time.Duration) error {
+	if !i.IsInState(Started) {
+		return ErrWaitingForInstanceNotAllowed.WithParams(i.state.String())
+	}
+
+	var (
+		tick         = time.NewTicker(i.pollIntervalOr(1 * time.Second))
+		podName      string
+		restarts     int32
+		stableSince  time.Time
+		lastRestarts int32 = -1
+	)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ErrInstanceNotStable.WithParams(i.k8sName, stableFor, restarts).Wrap(ctx.Err())
+		case <-tick.C:
+			pod, err := k8sClient.GetFirstPodFromReplicaSet(ctx, i.k8sName)
+			if err != nil {
+				logrus.Debugf("Error getting pod of instance '%s': %v", i.k8sName, err)
+				stableSince = time.Time{}
+				continue
+			}
+
+			running, podRestarts := podRunningAndRestarts(pod)
+			restarts = podRestarts
+			if pod.Name != podName || restarts != lastRestarts || !running {
+				podName = pod.Name
+				lastRestarts = restarts
+				stableSince = time.Time{}
+			}
+			if !running {
+				continue
+			}
+			if stableSince.IsZero() {
+				stableSince = time.Now()
+			}
+			if time.Since(stableSince) >= stableFor {
+				logrus.Debugf("Instance '%s' is stable after %d restarts", i.k8sName, restarts)
 				return nil
 			}
 		}
@@ -1156,7 +1943,7 @@ func (i *Instance) DisableNetwork() error {
 	executorSelectorMap := map[string]string{
 		"knuu.sh/type": ExecutorInstance.String(),
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), i.operationTimeout())
 	defer cancel()
 
 	err := k8sClient.CreateNetworkPolicy(ctx, i.k8sName, i.getLabels(), executorSelectorMap, executorSelectorMap)
@@ -1280,7 +2067,7 @@ func (i *Instance) EnableNetwork() error {
 		return ErrEnablingNetworkNotAllowed.WithParams(i.state.String())
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), i.operationTimeout())
 	defer cancel()
 
 	err := k8sClient.DeleteNetworkPolicy(ctx, i.k8sName)
@@ -1297,7 +2084,7 @@ func (i *Instance) NetworkIsDisabled() (bool, error) {
 		return false, ErrCheckingIfNetworkDisabledNotAllowed.WithParams(i.state.String())
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), i.operationTimeout())
 	defer cancel()
 
 	return k8sClient.NetworkPolicyExists(ctx, i.k8sName), nil
@@ -1317,6 +2104,8 @@ func (i *Instance) WaitInstanceIsStopped() error {
 		if err != nil {
 			return ErrCheckingIfInstanceStopped.WithParams(i.k8sName).Wrap(err)
 		}
+		// by default the instance is checked again right away
+		time.Sleep(i.pollInterval)
 	}
 
 	return nil
@@ -1330,7 +2119,7 @@ func (i *Instance) Stop() error {
 		return ErrStoppingNotAllowed.WithParams(i.state.String())
 
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), i.operationTimeout())
 	defer cancel()
 
 	err := i.destroyPod(ctx)
@@ -1404,7 +2193,7 @@ func (i *Instance) CustomResourceDefinitionExists(gvr *schema.GroupVersionResour
 }
 
 func (i *Instance) AddHost(port int) (err error, host string) {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), i.operationTimeout())
 	defer cancel()
 
 	prefix := fmt.Sprintf("%s-%d", i.k8sName, port)
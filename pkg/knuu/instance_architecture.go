@@ -0,0 +1,70 @@
+package knuu
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/celestiaorg/knuu/pkg/registry"
+)
+
+// imageArchRegex matches an architecture with an optional variant, e.g. arm64 or arm/v7
+var imageArchRegex = regexp.MustCompile(`^[a-z0-9_]+(/[a-z0-9]+)?$`)
+
+// SetImageArchitecture pins the instance to the given architecture of its image, e.g. arm64, or arm/v7 with a variant,
+// when the image is built for several platforms, e.g. to run it on the arm nodes of a cluster with mixed nodes
+// When the instance is started, the image is pinned to the digest of its manifest for this architecture,
+// which fails with ErrImageArchitectureNotFound if the image is not built for it, and the pod is only scheduled
+// on the nodes of this architecture. The sidecars run on the same node, so their images must support it as well
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetImageArchitecture(arch string) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingImageArchitectureNotAllowed.WithParams(i.state.String())
+	}
+	if !imageArchRegex.MatchString(arch) {
+		return ErrInvalidImageArchitecture.WithParams(arch)
+	}
+	i.imageArch = arch
+	logrus.Debugf("Set image architecture to '%s' in instance '%s'", arch, i.name)
+	return nil
+}
+
+// pinImageArchitecture replaces the image of the instance with the digest of its manifest for the architecture
+// set with SetImageArchitecture, if any
+func (i *Instance) pinImageArchitecture(ctx context.Context) error {
+	if i.imageArch == "" {
+		return nil
+	}
+
+	platforms, err := registry.ImagePlatforms(ctx, i.imageName)
+	if err != nil {
+		return ErrPinningImageArchitecture.WithParams(i.imageName, i.name).Wrap(err)
+	}
+	arch, variant, _ := strings.Cut(i.imageArch, "/")
+	available := make([]string, 0, len(platforms))
+	for _, platform := range platforms {
+		// the config of a single-platform image may omit its os
+		if (platform.OS == "linux" || platform.OS == "") && platform.Architecture == arch && (variant == "" || platform.Variant == variant) {
+			// the reference was parsed by ImagePlatforms
+			ref, _ := registry.ParseReference(i.imageName)
+			ref.Digest = platform.Digest
+			i.imageName = ref.String()
+			logrus.Debugf("Pinned image of instance '%s' to '%s' for architecture '%s'", i.name, i.imageName, i.imageArch)
+			return nil
+		}
+		available = append(available, platform.String())
+	}
+	return ErrImageArchitectureNotFound.WithParams(i.imageName, i.imageArch, available)
+}
+
+// nodeSelector returns the labels of the nodes the pod of the instance must be scheduled on, nil for any node
+func (i *Instance) nodeSelector() map[string]string {
+	if i.imageArch == "" {
+		return nil
+	}
+	arch, _, _ := strings.Cut(i.imageArch, "/")
+	return map[string]string{v1.LabelArchStable: arch}
+}
@@ -0,0 +1,85 @@
+package knuu
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestSetImageArchitecture(t *testing.T) {
+	// the registry has a multi-arch image for amd64 and arm64
+	manifests := map[string]string{}
+	addManifest := func(ref, data string) string {
+		digest := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(data)))
+		manifests[ref], manifests[digest] = data, data
+		return digest
+	}
+	digests := map[string]string{}
+	for _, arch := range []string{"amd64", "arm64"} {
+		digests[arch] = addManifest(arch, `{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json",`+
+			`"config":{"digest":"sha256:config-`+arch+`"},"layers":[]}`)
+	}
+	addManifest("multi", fmt.Sprintf(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[`+
+		`{"digest":%q,"platform":{"os":"linux","architecture":"amd64"}},{"digest":%q,"platform":{"os":"linux","architecture":"arm64"}}]}`,
+		digests["amd64"], digests["arm64"]))
+	reg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ref, ok := strings.CutPrefix(r.URL.Path, "/v2/app/manifests/"); ok && manifests[ref] != "" {
+			w.Header().Set("Docker-Content-Digest", fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(manifests[ref]))))
+			fmt.Fprint(w, manifests[ref])
+			return
+		}
+		if arch, ok := strings.CutPrefix(r.URL.Path, "/v2/app/blobs/sha256:config-"); ok {
+			fmt.Fprintf(w, `{"os":"linux","architecture":%q}`, arch)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer reg.Close()
+	host := strings.TrimPrefix(reg.URL, "http://")
+
+	var podSpecs []v1.PodSpec
+	useK8sClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if spec, ok := createdPodSpec(t, r); ok {
+			podSpecs = append(podSpecs, spec)
+		}
+		echoK8sHandler(w, r)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	i := newTestInstance(t, "arch")
+	i.imageName = host + "/app:multi"
+	assert.ErrorIs(t, i.SetImageArchitecture("ARM64!"), ErrInvalidImageArchitecture)
+	require.NoError(t, i.SetImageArchitecture("arm64"))
+	require.NoError(t, i.deployPod(ctx))
+
+	require.Len(t, podSpecs, 1)
+	assert.Equal(t, host+"/app@"+digests["arm64"], podSpecs[0].Containers[0].Image, "the image should be pinned to the arm64 manifest")
+	assert.Equal(t, map[string]string{"kubernetes.io/arch": "arm64"}, podSpecs[0].NodeSelector)
+
+	// starting the instance again keeps the pinned image
+	require.NoError(t, i.deployPod(ctx))
+	require.Len(t, podSpecs, 2)
+	assert.Equal(t, host+"/app@"+digests["arm64"], podSpecs[1].Containers[0].Image)
+
+	missing := newTestInstance(t, "arch")
+	missing.imageName = host + "/app:multi"
+	require.NoError(t, missing.SetImageArchitecture("arm/v7"))
+	err := missing.deployPod(ctx)
+	assert.ErrorIs(t, err, ErrImageArchitectureNotFound)
+	assert.ErrorContains(t, err, "[linux/amd64 linux/arm64]")
+	assert.Len(t, podSpecs, 2, "no pod should be deployed for a missing architecture")
+
+	i.state = Started
+	assert.ErrorIs(t, i.SetImageArchitecture("amd64"), ErrSettingImageArchitectureNotAllowed)
+}
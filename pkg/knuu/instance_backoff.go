@@ -0,0 +1,103 @@
+package knuu
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+)
+
+// pullFailedMessage starts the messages of the events of the failed image pulls
+const pullFailedMessage = "Failed to pull image"
+
+// SetBackoffLimit sets the number of times the container of the instance is retried after failing before the instance
+// gives up, like the backoffLimit of a Job, so a job-style instance with a failing command or a bad image fails
+// WaitInstanceIsRunning, and so Start, with ErrBackoffLimitExceeded instead of retrying until the wait times out
+// The instance is run by a replica set, which restarts the container whatever its exit code, so every restart
+// counts as a failure, and so does every failed pull of its image
+// An instance run by a cron job, see SetCronSchedule, passes the limit to its jobs instead, which give up on their own
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetBackoffLimit(n int) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingBackoffLimitNotAllowed.WithParams(i.state.String())
+	}
+	if n < 0 {
+		return ErrInvalidBackoffLimit.WithParams(n)
+	}
+	i.backoffLimit = &n
+	logrus.Debugf("Set backoff limit to '%d' in instance '%s'", n, i.name)
+	return nil
+}
+
+// checkBackoffLimit returns ErrBackoffLimitExceeded if the container of the instance failed more often than its backoff limit
+func (i *Instance) checkBackoffLimit() error {
+	if i.backoffLimit == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), i.operationTimeout())
+	defer cancel()
+	pod, err := k8sClient.GetFirstPodFromReplicaSet(ctx, i.k8sName)
+	if err != nil {
+		// the pod may not be created yet
+		return nil
+	}
+	failures, reason := containerFailures(pod, i.k8sName)
+	if pullFailure(pod, i.k8sName) {
+		events, err := k8sClient.ListPodEvents(ctx, pod.Name)
+		if err != nil {
+			return ErrCheckingBackoffLimit.WithParams(i.name).Wrap(err)
+		}
+		failures, reason = pullFailures(events)
+	}
+	if failures > *i.backoffLimit {
+		return ErrBackoffLimitExceeded.WithParams(i.name, *i.backoffLimit, failures, reason)
+	}
+	return nil
+}
+
+// containerFailures returns the number of times the container of the pod failed, with the reason of the last failure
+func containerFailures(pod *v1.Pod, containerName string) (int, string) {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != containerName {
+			continue
+		}
+		failures := int(status.RestartCount)
+		// the restart count is only incremented once the container is started again
+		terminated := lastTermination(pod, containerName)
+		if status.State.Running == nil && terminated != nil {
+			failures++
+		}
+		if terminated == nil {
+			return failures, ""
+		}
+		return failures, fmt.Sprintf("%s with exit code %d", terminated.Reason, terminated.ExitCode)
+	}
+	return 0, ""
+}
+
+// pullFailure returns true if the container of the pod waits for its image to be pulled again after failing to pull it
+func pullFailure(pod *v1.Pod, containerName string) bool {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == containerName && status.State.Waiting != nil {
+			return status.State.Waiting.Reason == "ErrImagePull" || status.State.Waiting.Reason == "ImagePullBackOff"
+		}
+	}
+	return false
+}
+
+// pullFailures returns the number of failed image pulls reported by the events of a pod, with the message of the last one
+// Kubernetes aggregates the identical events, so each event counts as many times as it was reported
+func pullFailures(events []v1.Event) (int, string) {
+	failures, message := 0, ""
+	for _, event := range events {
+		if event.Reason != "Failed" || !strings.HasPrefix(event.Message, pullFailedMessage) {
+			continue
+		}
+		failures += max(int(event.Count), 1)
+		message = event.Message
+	}
+	return failures, message
+}
@@ -0,0 +1,79 @@
+package knuu
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/k8s"
+)
+
+func TestSetBackoffLimit(t *testing.T) {
+	lockK8sClient(t)
+
+	// the command fails on every run, the pod status moves from the first failure to the back-off after the first restart
+	statuses := []string{
+		`{"name":"backoff","restartCount":0,"state":{"terminated":{"reason":"Error","exitCode":1}}}`,
+		`{"name":"backoff","restartCount":1,"state":{"running":{}},"lastState":{"terminated":{"reason":"Error","exitCode":1}}}`,
+		`{"name":"backoff","restartCount":1,"state":{"waiting":{"reason":"CrashLoopBackOff"}},"lastState":{"terminated":{"reason":"Error","exitCode":1}}}`,
+	}
+	var polls atomic.Int32
+	newClient := func(t *testing.T, pullEvents int) *k8s.Client {
+		return newTestK8sClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch {
+			case strings.HasPrefix(r.URL.Path, "/apis/apps/v1/namespaces/test/replicasets/"):
+				fmt.Fprint(w, `{"kind":"ReplicaSet","apiVersion":"apps/v1","metadata":{"name":"backoff"},`+
+					`"spec":{"replicas":1,"selector":{"matchLabels":{"app":"backoff"}}},"status":{"readyReplicas":0}}`)
+			case r.URL.Path == "/api/v1/namespaces/test/pods":
+				fmt.Fprint(w, `{"kind":"PodList","apiVersion":"v1","items":[{"metadata":{"name":"backoff-pod"}}]}`)
+			case r.URL.Path == "/api/v1/namespaces/test/pods/backoff-pod":
+				status := statuses[min(int(polls.Add(1))-1, len(statuses)-1)]
+				if pullEvents > 0 {
+					status = `{"name":"backoff","state":{"waiting":{"reason":"ImagePullBackOff"}}}`
+				}
+				fmt.Fprintf(w, `{"kind":"Pod","apiVersion":"v1","metadata":{"name":"backoff-pod"},"status":{"containerStatuses":[%s]}}`, status)
+			case r.URL.Path == "/api/v1/namespaces/test/events":
+				fmt.Fprintf(w, `{"kind":"EventList","apiVersion":"v1","items":[{"metadata":{"name":"pull"},"reason":"Failed",`+
+					`"message":"Failed to pull image \"bad:image\": not found","count":%d}]}`, pullEvents)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		})
+	}
+	newInstance := func(t *testing.T) *Instance {
+		i := newQuickTestInstance(t, "backoff")
+		i.k8sName = "backoff"
+		require.NoError(t, i.SetBackoffLimit(1))
+		i.state = Started
+		return i
+	}
+
+	t.Run("FailingCommand", func(t *testing.T) {
+		k8sClient = newClient(t, 0)
+		start := time.Now()
+		err := newInstance(t).WaitInstanceIsRunning()
+		assert.ErrorIs(t, err, ErrBackoffLimitExceeded)
+		assert.ErrorContains(t, err, "after 2 failures, last failure: Error with exit code 1")
+		assert.Less(t, time.Since(start), 3*time.Second, "the wait should fail before it times out")
+		assert.Equal(t, int32(len(statuses)), polls.Load(), "the first failure and the restart are within the limit")
+	})
+
+	t.Run("BadImage", func(t *testing.T) {
+		k8sClient = newClient(t, 2)
+		err := newInstance(t).WaitInstanceIsRunning()
+		assert.ErrorIs(t, err, ErrBackoffLimitExceeded)
+		assert.ErrorContains(t, err, `Failed to pull image "bad:image"`)
+	})
+
+	i := newTestInstance(t, "backoff")
+	assert.ErrorIs(t, i.SetBackoffLimit(-1), ErrInvalidBackoffLimit)
+	i.state = Started
+	assert.ErrorIs(t, i.SetBackoffLimit(1), ErrSettingBackoffLimitNotAllowed)
+}
@@ -0,0 +1,33 @@
+package knuu
+
+import (
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/celestiaorg/knuu/pkg/builder"
+)
+
+// SetBuildCacheDir caches the layers of the image of the instance in the given local directory, so the next builds
+// on the same machine reuse them, e.g. in a single machine CI without a registry cache
+// The docker builder exports the layer cache of BuildKit to the directory, replacing the cache of the previous build,
+// which is kept if the build fails. The directory is created if needed, its parent must be writable by the user running knuu
+// The kubernetes builder mounts the directory from the node the build runs on, where kaniko caches the base images
+// An empty directory disables the cache
+// This function can only be called in the state 'Preparing'
+func (i *Instance) SetBuildCacheDir(dir string) error {
+	if !i.IsInState(Preparing) {
+		return ErrSettingBuildCacheDirNotAllowed.WithParams(i.state.String())
+	}
+	if dir == "" {
+		i.builderFactory.SetCacheOptions(nil)
+		logrus.Debugf("Disabled build cache dir in instance '%s'", i.name)
+		return nil
+	}
+	if !filepath.IsAbs(dir) {
+		return ErrBuildCacheDirNotAbsolute.WithParams(dir)
+	}
+	i.builderFactory.SetCacheOptions(&builder.CacheOptions{Enabled: true, Dir: filepath.Clean(dir)})
+	logrus.Debugf("Set build cache dir to '%s' in instance '%s'", dir, i.name)
+	return nil
+}
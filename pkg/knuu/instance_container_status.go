@@ -0,0 +1,80 @@
+package knuu
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// ContainerState is the state of a container of an instance
+type ContainerState string
+
+const (
+	ContainerStateWaiting    ContainerState = "waiting"
+	ContainerStateRunning    ContainerState = "running"
+	ContainerStateTerminated ContainerState = "terminated"
+	// ContainerStateUnknown is used for the containers that have no status yet, e.g. while the pod is scheduled
+	ContainerStateUnknown ContainerState = "unknown"
+)
+
+// ContainerStatus is the status of a container in the pod of an instance
+type ContainerStatus struct {
+	Ready        bool
+	RestartCount int
+	State        ContainerState
+	Reason       string // Reason of the waiting or terminated state, e.g. CrashLoopBackOff
+	Message      string // Message of the waiting or terminated state
+	ExitCode     int    // Exit code of the last run of the container, if Terminated is true
+	Terminated   bool   // Whether the container has terminated at least once, so ExitCode is set
+}
+
+// GetContainerStatuses returns the status of the main container and of each sidecar of the instance,
+// keyed by their container name, which is the unique name of the instance and of each sidecar, see GetUniqueName
+// It tells e.g. which sidecar is crash looping while the main container is healthy
+// This function can only be called in the state 'Started'
+func (i *Instance) GetContainerStatuses(ctx context.Context) (map[string]ContainerStatus, error) {
+	if !i.IsInState(Started) {
+		return nil, ErrGettingContainerStatusesNotAllowed.WithParams(i.state.String())
+	}
+
+	parent := i
+	if i.isSidecar {
+		parent = i.parentInstance
+	}
+	pod, err := k8sClient.GetFirstPodFromReplicaSet(ctx, parent.k8sName)
+	if err != nil {
+		return nil, ErrGettingPodFromReplicaSet.WithParams(parent.k8sName).Wrap(err)
+	}
+
+	statuses := make(map[string]ContainerStatus, len(parent.sidecars)+1)
+	for _, instance := range append([]*Instance{parent}, parent.sidecars...) {
+		statuses[instance.k8sName] = ContainerStatus{State: ContainerStateUnknown}
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		statuses[status.Name] = containerStatus(pod, status)
+	}
+	return statuses, nil
+}
+
+// containerStatus converts the status of a container of the pod
+func containerStatus(pod *v1.Pod, status v1.ContainerStatus) ContainerStatus {
+	s := ContainerStatus{
+		Ready:        status.Ready,
+		RestartCount: int(status.RestartCount),
+		State:        ContainerStateUnknown,
+	}
+	switch {
+	case status.State.Running != nil:
+		s.State = ContainerStateRunning
+	case status.State.Waiting != nil:
+		s.State = ContainerStateWaiting
+		s.Reason = status.State.Waiting.Reason
+		s.Message = status.State.Waiting.Message
+	case status.State.Terminated != nil:
+		s.State = ContainerStateTerminated
+		s.Reason = status.State.Terminated.Reason
+		s.Message = status.State.Terminated.Message
+	}
+	s.ExitCode, s.Terminated = containerExitCode(pod, status.Name)
+	return s
+}
@@ -0,0 +1,62 @@
+package knuu
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetContainerStatuses(t *testing.T) {
+	i := newTestInstance(t, "statuses")
+	sidecar := newTestInstance(t, "statuses-sidecar")
+	sidecar.isSidecar = true
+	sidecar.parentInstance = i
+	pending := newTestInstance(t, "statuses-pending")
+	pending.isSidecar = true
+	pending.parentInstance = i
+	i.sidecars = []*Instance{sidecar, pending}
+	_, err := i.GetContainerStatuses(context.Background())
+	assert.ErrorIs(t, err, ErrGettingContainerStatusesNotAllowed)
+
+	require.NoError(t, i.SetOperationTimeout(time.Minute))
+	useK8sClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/apis/apps/v1/namespaces/test/replicasets/"):
+			fmt.Fprint(w, `{"kind":"ReplicaSet","apiVersion":"apps/v1","metadata":{"name":"statuses"},`+
+				`"spec":{"replicas":1,"selector":{"matchLabels":{"app":"statuses"}}}}`)
+		case r.URL.Path == "/api/v1/namespaces/test/pods":
+			fmt.Fprint(w, `{"kind":"PodList","apiVersion":"v1","items":[{"metadata":{"name":"statuses-pod"}}]}`)
+		case r.URL.Path == "/api/v1/namespaces/test/pods/statuses-pod":
+			// the main container is healthy while the sidecar is crash looping
+			fmt.Fprintf(w, `{"kind":"Pod","apiVersion":"v1","metadata":{"name":"statuses-pod"},"status":{"containerStatuses":[`+
+				`{"name":%q,"ready":true,"state":{"running":{}}},`+
+				`{"name":%q,"ready":false,"restartCount":4,"state":{"waiting":{"reason":"CrashLoopBackOff","message":"back-off 1m20s"}},`+
+				`"lastState":{"terminated":{"exitCode":1,"reason":"Error"}}}]}}`, i.k8sName, sidecar.k8sName)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	i.state, sidecar.state, pending.state = Started, Started, Started
+
+	statuses, err := sidecar.GetContainerStatuses(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]ContainerStatus{
+		i.k8sName: {Ready: true, State: ContainerStateRunning},
+		sidecar.k8sName: {
+			RestartCount: 4,
+			State:        ContainerStateWaiting,
+			Reason:       "CrashLoopBackOff",
+			Message:      "back-off 1m20s",
+			ExitCode:     1,
+			Terminated:   true,
+		},
+		pending.k8sName: {State: ContainerStateUnknown},
+	}, statuses)
+}
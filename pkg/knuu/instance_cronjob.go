@@ -0,0 +1,117 @@
+package knuu
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/celestiaorg/knuu/pkg/k8s"
+)
+
+// SetCronSchedule makes the instance run by a cron job with the given schedule, e.g. `*/5 * * * *` or `@hourly`,
+// instead of a replica set, for periodic workloads like backups or load generators
+// Each run is a job whose pod runs the command of the instance once, and is retried on failure up to the backoff limit,
+// see SetBackoffLimit. A run can be started outside of the schedule with TriggerCronJob and awaited with WaitForJob
+// Start only creates the cron job, and Stop and Destroy delete it along with its jobs and their pods
+// The sidecars run in the pods of the jobs as well, so they must exit for a run to complete
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetCronSchedule(schedule string) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingCronScheduleNotAllowed.WithParams(i.state.String())
+	}
+	if err := k8s.ValidateCronSchedule(schedule); err != nil {
+		return ErrInvalidCronSchedule.WithParams(schedule).Wrap(err)
+	}
+	i.cronSchedule = schedule
+	logrus.Debugf("Set cron schedule to '%s' in instance '%s'", schedule, i.name)
+	return nil
+}
+
+// TriggerCronJob starts a run of the cron job of the instance now, independently of its schedule,
+// and returns the name of the job of the run, see WaitForJob
+// This function can only be called in the state 'Started'
+func (i *Instance) TriggerCronJob(ctx context.Context) (string, error) {
+	if !i.IsInState(Started) {
+		return "", ErrTriggeringCronJobNotAllowed.WithParams(i.state.String())
+	}
+	if i.cronSchedule == "" {
+		return "", ErrInstanceNotCronJob.WithParams(i.name)
+	}
+
+	job, err := k8sClient.TriggerCronJob(ctx, i.k8sName)
+	if err != nil {
+		return "", ErrTriggeringCronJob.WithParams(i.name).Wrap(err)
+	}
+	logrus.Debugf("Triggered job '%s' of instance '%s'", job.Name, i.name)
+	return job.Name, nil
+}
+
+// WaitForJob waits until the job of the cron job of the instance with the given name completes,
+// and returns ErrJobFailed if it failed, i.e. its pods failed more often than the backoff limit
+// The context bounds the time to wait
+// This function can only be called in the state 'Started'
+func (i *Instance) WaitForJob(ctx context.Context, jobName string) error {
+	if !i.IsInState(Started) {
+		return ErrWaitingForJobNotAllowed.WithParams(i.state.String())
+	}
+	if i.cronSchedule == "" {
+		return ErrInstanceNotCronJob.WithParams(i.name)
+	}
+
+	tick := time.NewTicker(i.pollIntervalOr(1 * time.Second))
+	defer tick.Stop()
+	for {
+		job, err := k8sClient.GetJob(ctx, jobName)
+		if err != nil {
+			logrus.Debugf("Error getting job '%s' of instance '%s': %v", jobName, i.name, err)
+		}
+		if job != nil {
+			if jobCondition(job, batchv1.JobComplete) != nil {
+				logrus.Debugf("Job '%s' of instance '%s' completed", jobName, i.name)
+				return nil
+			}
+			if failed := jobCondition(job, batchv1.JobFailed); failed != nil {
+				return ErrJobFailed.WithParams(jobName, i.name, failed.Message)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ErrWaitingForJob.WithParams(jobName, i.name).Wrap(ctx.Err())
+		case <-tick.C:
+		}
+	}
+}
+
+// deployCronJob creates the cron job running the pod of the instance on its schedule
+func (i *Instance) deployCronJob(ctx context.Context, rsConfig k8s.ReplicaSetConfig) error {
+	cjConfig := k8s.CronJobConfig{
+		Name:      rsConfig.Name,
+		Labels:    rsConfig.Labels,
+		Schedule:  i.cronSchedule,
+		PodConfig: rsConfig.PodConfig,
+	}
+	if i.backoffLimit != nil {
+		limit := int32(*i.backoffLimit)
+		cjConfig.BackoffLimit = &limit
+	}
+	if _, err := k8sClient.CreateCronJob(ctx, cjConfig, true); err != nil {
+		return ErrDeployingCronJob.WithParams(i.name).Wrap(err)
+	}
+	logrus.Debugf("Started cron job '%s' with schedule '%s'", i.k8sName, i.cronSchedule)
+	return nil
+}
+
+// jobCondition returns the condition of the job of the given type if it is true, nil otherwise
+func jobCondition(job *batchv1.Job, conditionType batchv1.JobConditionType) *batchv1.JobCondition {
+	for idx := range job.Status.Conditions {
+		condition := &job.Status.Conditions[idx]
+		if condition.Type == conditionType && condition.Status == v1.ConditionTrue {
+			return condition
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package knuu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetCronSchedule(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		cronJob  map[string]interface{}
+		jobs     []map[string]interface{}
+		polls    int
+		deletion string
+	)
+	useK8sClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/apis/batch/v1/namespaces/test/cronjobs":
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&cronJob))
+			cronJob["kind"], cronJob["apiVersion"] = "CronJob", "batch/v1"
+			cronJob["metadata"].(map[string]interface{})["uid"] = "cron-uid"
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(cronJob)
+		case r.Method == http.MethodGet && r.URL.Path == "/apis/batch/v1/namespaces/test/cronjobs/cron":
+			if cronJob == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(cronJob)
+		case r.Method == http.MethodDelete && r.URL.Path == "/apis/batch/v1/namespaces/test/cronjobs/cron":
+			body, _ := io.ReadAll(r.Body)
+			deletion = string(body)
+			cronJob = nil
+			fmt.Fprint(w, `{"kind":"Status","apiVersion":"v1","status":"Success"}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/apis/batch/v1/namespaces/test/jobs":
+			var job map[string]interface{}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&job))
+			metadata := job["metadata"].(map[string]interface{})
+			metadata["name"] = fmt.Sprintf("%s%d", metadata["generateName"], len(jobs))
+			job["kind"], job["apiVersion"] = "Job", "batch/v1"
+			jobs = append(jobs, job)
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(job)
+		case r.URL.Path == "/apis/batch/v1/namespaces/test/jobs/cron-manual-0":
+			// the first job completes after a few polls
+			polls++
+			status := `{"active":1}`
+			if polls >= 3 {
+				status = `{"succeeded":1,"conditions":[{"type":"Complete","status":"True"}]}`
+			}
+			fmt.Fprintf(w, `{"kind":"Job","apiVersion":"batch/v1","metadata":{"name":"cron-manual-0"},"status":%s}`, status)
+		case r.URL.Path == "/apis/batch/v1/namespaces/test/jobs/cron-manual-1":
+			fmt.Fprint(w, `{"kind":"Job","apiVersion":"batch/v1","metadata":{"name":"cron-manual-1"},`+
+				`"status":{"failed":3,"conditions":[{"type":"Failed","status":"True","message":"Job has reached the specified backoff limit"}]}}`)
+		default:
+			echoK8sHandler(w, r)
+		}
+	})
+
+	i := newQuickTestInstance(t, "cron")
+	i.k8sName = "cron"
+	assert.ErrorIs(t, i.SetCronSchedule("*/5 * * *"), ErrInvalidCronSchedule)
+	assert.ErrorIs(t, i.SetCronSchedule("0 25 * * *"), ErrInvalidCronSchedule)
+	require.NoError(t, i.SetCronSchedule("*/5 9-17 * * mon-fri"))
+	require.NoError(t, i.SetBackoffLimit(2))
+	_, err := i.TriggerCronJob(context.Background())
+	assert.ErrorIs(t, err, ErrTriggeringCronJobNotAllowed)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, i.deployPod(ctx))
+	i.state = Started
+
+	mu.Lock()
+	require.NotNil(t, cronJob, "a cron job should be created instead of a replica set")
+	spec := cronJob["spec"].(map[string]interface{})
+	assert.Equal(t, "*/5 9-17 * * mon-fri", spec["schedule"])
+	jobSpec := spec["jobTemplate"].(map[string]interface{})["spec"].(map[string]interface{})
+	assert.EqualValues(t, 2, jobSpec["backoffLimit"])
+	podSpec := jobSpec["template"].(map[string]interface{})["spec"].(map[string]interface{})
+	assert.Equal(t, "Never", podSpec["restartPolicy"])
+	mu.Unlock()
+
+	running, err := i.IsRunning()
+	require.NoError(t, err)
+	assert.True(t, running, "the instance should be running once its cron job is created")
+
+	jobName, err := i.TriggerCronJob(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "cron-manual-0", jobName)
+	mu.Lock()
+	metadata := jobs[0]["metadata"].(map[string]interface{})
+	assert.Equal(t, "manual", metadata["annotations"].(map[string]interface{})["cronjob.kubernetes.io/instantiate"])
+	owner := metadata["ownerReferences"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "CronJob", owner["kind"])
+	assert.Equal(t, "cron-uid", owner["uid"], "the job should be deleted along with the cron job")
+	mu.Unlock()
+
+	require.NoError(t, i.WaitForJob(ctx, jobName))
+	assert.Equal(t, 3, polls, "the job should be polled until it completes")
+
+	jobName, err = i.TriggerCronJob(ctx)
+	require.NoError(t, err)
+	err = i.WaitForJob(ctx, jobName)
+	assert.ErrorIs(t, err, ErrJobFailed)
+	assert.ErrorContains(t, err, "reached the specified backoff limit")
+
+	require.NoError(t, i.destroyPod(ctx))
+	assert.Nil(t, cronJob, "the cron job should be deleted")
+	assert.Contains(t, deletion, `"propagationPolicy":"Background"`, "the jobs of the cron job should be deleted as well")
+
+	replicaSet := newTestInstance(t, "replicaset")
+	replicaSet.state = Started
+	_, err = replicaSet.TriggerCronJob(ctx)
+	assert.ErrorIs(t, err, ErrInstanceNotCronJob)
+}
@@ -0,0 +1,96 @@
+package knuu
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+)
+
+// deadlineExceededReason is the reason of the pods terminated by Kubernetes after their active deadline
+const deadlineExceededReason = "DeadlineExceeded"
+
+// SetActiveDeadline sets the duration the pod of the instance may run before Kubernetes terminates it,
+// e.g. to protect the cluster from job-style or runaway instances. It is rounded up to whole seconds
+// The instance is run by a replica set, so a new pod is started after the deadline, which runs until its own deadline
+// WaitInstanceCompleted reports the termination with ErrInstanceDeadlineExceeded
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetActiveDeadline(d time.Duration) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingActiveDeadlineNotAllowed.WithParams(i.state.String())
+	}
+	if d <= 0 {
+		return ErrInvalidActiveDeadline.WithParams(d.String())
+	}
+	i.activeDeadline = d
+	logrus.Debugf("Set active deadline to '%s' in instance '%s'", d, i.name)
+	return nil
+}
+
+// activeDeadlineSeconds returns the active deadline of the pod in seconds, or nil if it is not set
+func (i *Instance) activeDeadlineSeconds() *int64 {
+	if i.activeDeadline == 0 {
+		return nil
+	}
+	seconds := int64(math.Ceil(i.activeDeadline.Seconds()))
+	return &seconds
+}
+
+// WaitInstanceCompleted waits until the instance has completed, i.e. its main container has terminated
+// or its pod has finished. Use GetContainerExitCode to get the exit code of the container
+// A pod terminated after its active deadline, see SetActiveDeadline, is reported with ErrInstanceDeadlineExceeded
+// and a pod that failed for another reason, e.g. it was evicted, with ErrInstanceFailed
+// The context bounds the time to wait
+// This function can only be called in the state 'Started'
+func (i *Instance) WaitInstanceCompleted(ctx context.Context) error {
+	if !i.IsInState(Started) {
+		return ErrWaitingForCompletionNotAllowed.WithParams(i.state.String())
+	}
+
+	tick := time.NewTicker(i.pollIntervalOr(1 * time.Second))
+	defer tick.Stop()
+
+	for {
+		pods, err := k8sClient.ListPodsFromReplicaSet(ctx, i.k8sName)
+		if err != nil && ctx.Err() != nil {
+			return ErrWaitingForCompletionTimeout.WithParams(i.k8sName).Wrap(ctx.Err())
+		}
+		if err != nil {
+			return ErrGettingPodFromReplicaSet.WithParams(i.k8sName).Wrap(err)
+		}
+		completed, err := i.podsCompleted(pods)
+		if completed || err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ErrWaitingForCompletionTimeout.WithParams(i.k8sName).Wrap(ctx.Err())
+		case <-tick.C:
+		}
+	}
+}
+
+// podsCompleted returns true if one of the pods of the instance has completed,
+// with an error if it failed or was terminated after its active deadline
+func (i *Instance) podsCompleted(pods []v1.Pod) (bool, error) {
+	// the deadline is reported first, as the replica set replaces the terminated pod with a running one
+	for _, pod := range pods {
+		if pod.Status.Phase == v1.PodFailed && pod.Status.Reason == deadlineExceededReason {
+			return true, ErrInstanceDeadlineExceeded.WithParams(i.name, i.activeDeadline.String(), pod.Status.Message)
+		}
+	}
+	for _, pod := range pods {
+		switch {
+		case pod.Status.Phase == v1.PodFailed:
+			return true, ErrInstanceFailed.WithParams(i.name, pod.Status.Reason, pod.Status.Message)
+		case pod.Status.Phase == v1.PodSucceeded:
+			return true, nil
+		case lastTermination(&pod, i.k8sName) != nil:
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,80 @@
+package knuu
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/k8s"
+)
+
+func TestSetActiveDeadline(t *testing.T) {
+	lockK8sClient(t)
+	i := newTestInstance(t, "deadline")
+	assert.ErrorIs(t, i.SetActiveDeadline(0), ErrInvalidActiveDeadline)
+	require.NoError(t, i.SetActiveDeadline(1500*time.Millisecond))
+	require.NoError(t, i.SetPollInterval(10*time.Millisecond))
+	k8sClient = &k8s.Client{}
+	deadline := i.prepareReplicaSetConfig().PodConfig.ActiveDeadlineSeconds
+	require.NotNil(t, deadline)
+	assert.Equal(t, int64(2), *deadline, "the deadline is rounded up to seconds")
+
+	var (
+		mu   sync.Mutex
+		pods string
+	)
+	setPods := func(items ...string) {
+		mu.Lock()
+		defer mu.Unlock()
+		pods = strings.Join(items, ",")
+	}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/apis/apps/v1/namespaces/test/replicasets/"):
+			fmt.Fprint(w, `{"kind":"ReplicaSet","apiVersion":"apps/v1","metadata":{"name":"deadline"},`+
+				`"spec":{"replicas":1,"selector":{"matchLabels":{"app":"deadline"}}}}`)
+		case r.URL.Path == "/api/v1/namespaces/test/pods":
+			mu.Lock()
+			defer mu.Unlock()
+			fmt.Fprintf(w, `{"kind":"PodList","apiVersion":"v1","items":[%s]}`, pods)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+	k8sClient = newTestK8sClient(t, handler)
+	running := `{"metadata":{"name":"running"},"status":{"phase":"Running"}}`
+	i.state = Started
+
+	// the pod is terminated by Kubernetes after the deadline, while the command is still running
+	setPods(running)
+	time.AfterFunc(50*time.Millisecond, func() {
+		setPods(`{"metadata":{"name":"terminated"},"status":{"phase":"Failed","reason":"DeadlineExceeded",`+
+			`"message":"Pod was active on the node longer than the specified deadline"}}`, running)
+	})
+	err := i.WaitInstanceCompleted(context.Background())
+	assert.ErrorIs(t, err, ErrInstanceDeadlineExceeded)
+	assert.ErrorContains(t, err, "longer than the specified deadline")
+
+	setPods(`{"metadata":{"name":"evicted"},"status":{"phase":"Failed","reason":"Evicted"}}`)
+	assert.ErrorIs(t, i.WaitInstanceCompleted(context.Background()), ErrInstanceFailed)
+
+	setPods(fmt.Sprintf(`{"metadata":{"name":"exited"},"status":{"phase":"Running","containerStatuses":`+
+		`[{"name":%q,"state":{"terminated":{"exitCode":0}}}]}}`, i.k8sName))
+	assert.NoError(t, i.WaitInstanceCompleted(context.Background()))
+
+	// a new client and a slow poll, so the requests are not throttled by the rate limiter of the client
+	k8sClient = newTestK8sClient(t, handler)
+	require.NoError(t, i.SetPollInterval(time.Second))
+	setPods(running)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, i.WaitInstanceCompleted(ctx), ErrWaitingForCompletionTimeout)
+}
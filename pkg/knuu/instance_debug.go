@@ -0,0 +1,55 @@
+package knuu
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/celestiaorg/knuu/pkg/names"
+)
+
+// ExecFunc executes a command in a container and returns its output
+type ExecFunc func(ctx context.Context, command ...string) (string, error)
+
+// AddDebugContainer attaches an ephemeral container with the given image to the running pod of the instance
+// The debug container shares the process namespace of the instance, so tools like `ps` see its processes
+// The returned function executes commands in the debug container
+// This requires ephemeral containers to be enabled in the cluster (enabled by default since kubernetes 1.23),
+// which is checked with the report of Preflight
+// This function can only be called in the state 'Started'
+func (i *Instance) AddDebugContainer(ctx context.Context, image string) (ExecFunc, error) {
+	if !i.IsInState(Started) {
+		return nil, ErrAddingDebugContainerNotAllowed.WithParams(i.state.String())
+	}
+	if i.isSidecar {
+		return nil, ErrAddingDebugContainerToSidecar.WithParams(i.name)
+	}
+	if err := requireFeature(ctx, FeatureDebugContainers); err != nil {
+		return nil, err
+	}
+
+	pod, err := k8sClient.GetFirstPodFromReplicaSet(ctx, i.k8sName)
+	if err != nil {
+		return nil, ErrGettingPodFromReplicaSet.WithParams(i.k8sName).Wrap(err)
+	}
+
+	name, err := names.NewRandomK8("debug")
+	if err != nil {
+		return nil, ErrGeneratingDebugContainerName.WithParams(i.name).Wrap(err)
+	}
+
+	err = k8sClient.AddEphemeralContainer(ctx, pod.Name, i.k8sName, name, image, []string{"sh"})
+	if err != nil {
+		return nil, ErrAddingDebugContainer.WithParams(image, i.name).Wrap(err)
+	}
+	logrus.Debugf("Added debug container '%s' with image '%s' to instance '%s'", name, image, i.name)
+
+	podName := pod.Name
+	return func(ctx context.Context, command ...string) (string, error) {
+		output, err := k8sClient.RunCommandInPod(ctx, podName, name, command)
+		if err != nil {
+			return "", ErrExecutingCommandInDebugContainer.WithParams(command, name).Wrap(err)
+		}
+		return output, nil
+	}, nil
+}
@@ -2,26 +2,73 @@ package knuu
 
 import (
 	"context"
+	"errors"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
 // Destroy destroys the instance
-// This function can only be called in the state 'Started' or 'Destroyed'
+// The hooks registered with OnDestroy are invoked first, also if the instance was never started,
+// and the instance is destroyed even if they fail
+// An instance that was never started has no resources to clean up but the ones applied with ApplyManifest
+// and, once committed, the service of the ports added before Commit, so destroying it only deletes them
+// and leaves the volumes it shares with ShareVolumeWith
+// and sets its state to 'Destroyed', e.g. when a test fails while preparing its instances and destroys all of them in its cleanup
+// The build dir of the instance, holding the files added to it, is removed in all the states,
+// and the builder factories of the instance and its sidecars are closed, but the ones set with UseBuilder
 func (i *Instance) Destroy() error {
 	if i.state == Destroyed {
 		return nil
 	}
+	if i.IsInState(None, Preparing, Committed) {
+		ctx, cancel := context.WithTimeout(context.Background(), i.operationTimeout())
+		defer cancel()
 
-	if !i.IsInState(Started, Stopped, Destroyed) {
+		hooksErr := i.runDestroyHooks()
+		if i.state == Committed {
+			if err := i.destroyResources(ctx); err != nil {
+				return ErrDestroyingResourcesForInstance.WithParams(i.k8sName).Wrap(err)
+			}
+		} else {
+			if len(i.manifestResources) != 0 {
+				if err := i.destroyManifestResources(ctx); err != nil {
+					return ErrDestroyingManifestResources.WithParams(i.k8sName).Wrap(err)
+				}
+			}
+			// the instance leaves the volumes it shares, so the last started sharer deletes their claims
+			if len(i.sharedVolumes) != 0 {
+				if err := i.destroySharedVolumes(ctx); err != nil {
+					return ErrDestroyingSharedVolumesForInstance.WithParams(i.k8sName).Wrap(err)
+				}
+			}
+		}
+		for _, instance := range append([]*Instance{i}, i.sidecars...) {
+			if err := instance.closeBuilderFactory(); err != nil {
+				return err
+			}
+		}
+		if err := os.RemoveAll(i.getBuildDir()); err != nil {
+			return ErrRemovingBuildDir.WithParams(i.getBuildDir()).Wrap(err)
+		}
+		i.state = Destroyed
+		setStateForSidecars(i.sidecars, Destroyed)
+		logrus.Debugf("Instance '%s' was never started, set its state to '%s'", i.k8sName, i.state.String())
+		return hooksErr
+	}
+
+	if !i.IsInState(Started, Stopped) {
 		return ErrDestroyingNotAllowed.WithParams(i.state.String())
 	}
 
 	// TODO: receive context from the user in the breaking refactor
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), i.operationTimeout())
 	defer cancel()
 
+	hooksErr := i.runDestroyHooks()
+
 	if err := i.destroyPod(ctx); err != nil {
 		return ErrDestroyingPod.WithParams(i.k8sName).Wrap(err)
 	}
@@ -31,33 +78,231 @@ func (i *Instance) Destroy() error {
 
 	err := applyFunctionToInstances(i.sidecars, func(sidecar Instance) error {
 		logrus.Debugf("Destroying sidecar resources from '%s'", sidecar.k8sName)
-		return sidecar.destroyResources(ctx)
+		if err := sidecar.destroyResources(ctx); err != nil {
+			return err
+		}
+		return sidecar.closeBuilderFactory()
 	})
 	if err != nil {
 		return ErrDestroyingResourcesForSidecars.WithParams(i.k8sName).Wrap(err)
 	}
+	if err := i.closeBuilderFactory(); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(i.getBuildDir()); err != nil {
+		return ErrRemovingBuildDir.WithParams(i.getBuildDir()).Wrap(err)
+	}
 
 	i.state = Destroyed
 	setStateForSidecars(i.sidecars, Destroyed)
 	logrus.Debugf("Set state of instance '%s' to '%s'", i.k8sName, i.state.String())
 
-	return nil
+	return hooksErr
+}
+
+// DestroyAndWait destroys the instance and waits until its pod and resources are deleted
+// This function can only be called in the state 'Started', 'Stopped' or 'Destroyed'
+func (i *Instance) DestroyAndWait(ctx context.Context) error {
+	if err := i.Destroy(); err != nil {
+		return err
+	}
+	return i.WaitForDeletion(ctx)
+}
+
+// WaitForDeletion waits until the pod and the resources of the instance are gone from the cluster
+// Destroy only requests the deletion, so this can be used before reusing the name of the instance
+// Resources that are already gone are considered deleted
+// This function can only be called in the state 'Destroyed'
+func (i *Instance) WaitForDeletion(ctx context.Context) error {
+	if !i.IsInState(Destroyed) {
+		return ErrWaitingForDeletionNotAllowed.WithParams(i.state.String())
+	}
+
+	tick := time.NewTicker(i.pollIntervalOr(1 * time.Second))
+	defer tick.Stop()
+
+	for {
+		remaining, err := i.remainingResources(ctx)
+		if err != nil {
+			return ErrCheckingRemainingResources.WithParams(i.k8sName).Wrap(err)
+		}
+		if len(remaining) == 0 {
+			logrus.Debugf("All resources of instance '%s' are deleted", i.k8sName)
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ErrWaitingForDeletionTimeout.WithParams(i.k8sName, remaining).Wrap(ctx.Err())
+		case <-tick.C:
+		}
+	}
+}
+
+// remainingResources returns the kinds of the resources of the instance that still exist
+func (i *Instance) remainingResources(ctx context.Context) ([]string, error) {
+	var remaining []string
+
+	exists, err := k8sClient.ReplicaSetExists(ctx, i.k8sName)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		remaining = append(remaining, "replicaset")
+	}
+
+	exists, err = k8sClient.PodsExist(ctx, map[string]string{"knuu.sh/k8s-name": i.k8sName})
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		remaining = append(remaining, "pod")
+	}
+
+	if i.kubernetesService != nil {
+		exists, err = k8sClient.ServiceExists(ctx, i.k8sName)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			remaining = append(remaining, "service")
+		}
+	}
+
+	for _, instance := range append([]*Instance{i}, i.sidecars...) {
+		if len(instance.volumes) != 0 {
+			exists, err = k8sClient.PersistentVolumeClaimExists(ctx, instance.k8sName)
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				remaining = append(remaining, "persistentvolumeclaim/"+instance.k8sName)
+			}
+		}
+		for _, volume := range instance.sharedVolumes {
+			// the claim is kept while other sharers use it
+			if !volume.unused() {
+				continue
+			}
+			exists, err = k8sClient.PersistentVolumeClaimExists(ctx, volume.claimName)
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				remaining = append(remaining, "persistentvolumeclaim/"+volume.claimName)
+			}
+		}
+		for _, resource := range instance.manifestResources {
+			exists, err = k8sClient.ManifestResourceExists(ctx, resource)
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				remaining = append(remaining, strings.ToLower(resource.String()))
+			}
+		}
+		if len(instance.files) != 0 {
+			exists, err = k8sClient.ConfigMapExists(ctx, instance.k8sName)
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				remaining = append(remaining, "configmap/"+instance.k8sName)
+			}
+		}
+		for _, name := range instance.envConfigMapNames() {
+			exists, err = k8sClient.ConfigMapExists(ctx, instance.envConfigMapName(name))
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				remaining = append(remaining, "configmap/"+instance.envConfigMapName(name))
+			}
+		}
+	}
+
+	return remaining, nil
 }
 
 // BatchDestroy destroys a list of instances.
+// All the instances are destroyed even if some of them fail, the returned error lists the failed ones
+// Use BatchDestroyWithResult to get the outcome of each instance
 func BatchDestroy(instances ...*Instance) error {
-	if os.Getenv("KNUU_SKIP_CLEANUP") == "true" {
-		logrus.Info("Skipping cleanup")
+	return BatchDestroyWithResult(instances...).Err()
+}
+
+// DestroyOutcome is the outcome of destroying an instance in a batch
+type DestroyOutcome string
+
+const (
+	DestroyOutcomeDestroyed DestroyOutcome = "destroyed"
+	DestroyOutcomeFailed    DestroyOutcome = "failed"
+	// DestroyOutcomeSkipped is used for all the instances when the cleanup is skipped with KNUU_SKIP_CLEANUP
+	DestroyOutcomeSkipped DestroyOutcome = "skipped"
+)
+
+// InstanceDestroyResult is the outcome of destroying an instance in a batch
+type InstanceDestroyResult struct {
+	Name    string
+	Outcome DestroyOutcome
+	Err     error // The error of Destroy if the outcome is DestroyOutcomeFailed
+}
+
+// BatchDestroyResult holds the outcome of each instance destroyed in a batch, in the order they were given
+type BatchDestroyResult struct {
+	Results []InstanceDestroyResult
+}
+
+// Failed returns the results of the instances that could not be destroyed
+func (r *BatchDestroyResult) Failed() []InstanceDestroyResult {
+	var failed []InstanceDestroyResult
+	for _, result := range r.Results {
+		if result.Outcome == DestroyOutcomeFailed {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Err returns an error listing the instances that could not be destroyed, or nil if there are none
+func (r *BatchDestroyResult) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
 		return nil
 	}
+	names := make([]string, 0, len(failed))
+	errs := make([]error, 0, len(failed))
+	for _, result := range failed {
+		names = append(names, result.Name)
+		errs = append(errs, result.Err)
+	}
+	return ErrBatchDestroyFailed.WithParams(strings.Join(names, "', '")).Wrap(errors.Join(errs...))
+}
+
+// BatchDestroyWithResult destroys a list of instances, continuing past failures, and returns the outcome of each one
+// so a test cleanup can report which instances are left behind. Nil instances are ignored
+// When KNUU_SKIP_CLEANUP is set to true, nothing is destroyed and all the instances are reported as skipped
+func BatchDestroyWithResult(instances ...*Instance) *BatchDestroyResult {
+	skip := os.Getenv("KNUU_SKIP_CLEANUP") == "true"
+	if skip {
+		logrus.Info("Skipping cleanup")
+	}
 
+	result := &BatchDestroyResult{Results: make([]InstanceDestroyResult, 0, len(instances))}
 	for _, instance := range instances {
 		if instance == nil {
 			continue
 		}
+		if skip {
+			result.Results = append(result.Results, InstanceDestroyResult{Name: instance.name, Outcome: DestroyOutcomeSkipped})
+			continue
+		}
 		if err := instance.Destroy(); err != nil {
-			return err
+			logrus.Debugf("Error destroying instance '%s': %v", instance.name, err)
+			result.Results = append(result.Results, InstanceDestroyResult{Name: instance.name, Outcome: DestroyOutcomeFailed, Err: err})
+			continue
 		}
+		result.Results = append(result.Results, InstanceDestroyResult{Name: instance.name, Outcome: DestroyOutcomeDestroyed})
 	}
-	return nil
+	return result
 }
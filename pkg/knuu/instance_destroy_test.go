@@ -0,0 +1,173 @@
+package knuu
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/celestiaorg/knuu/pkg/builder"
+	"github.com/celestiaorg/knuu/pkg/container"
+)
+
+// fakeServices is a fake API server holding the services created through it,
+// which fails to delete the services of the instances whose name starts with `failing`
+type fakeServices struct {
+	mu       sync.Mutex
+	services map[string]bool
+	deleted  []string
+}
+
+func (f *fakeServices) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const prefix = "/api/v1/namespaces/test/services"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		echoK8sHandler(w, r)
+		return
+	}
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, prefix), "/")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPost:
+		body, _ := io.ReadAll(r.Body)
+		var service v1.Service
+		if err := json.Unmarshal(body, &service); err == nil {
+			f.services[service.Name] = true
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	case http.MethodGet:
+		if f.services[name] {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"kind":"Service","apiVersion":"v1","metadata":{"name":%q}}`, name)
+			return
+		}
+	case http.MethodDelete:
+		if strings.HasPrefix(name, "failing") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		delete(f.services, name)
+		f.deleted = append(f.deleted, name)
+	}
+	echoK8sHandler(w, r)
+}
+
+// newCommittedWithService returns a committed instance with a port, whose service is deployed like Commit does
+func newCommittedWithService(t *testing.T, name string) *Instance {
+	t.Helper()
+	i := newQuickTestInstance(t, name)
+	require.NoError(t, i.AddPortTCP(8080))
+	require.NoError(t, i.deployOrPatchService(context.Background(), i.portsTCP, i.portsUDP))
+	i.state = Committed
+	return i
+}
+
+func TestBatchDestroyWithResult(t *testing.T) {
+	services := &fakeServices{services: map[string]bool{}}
+	useK8sClient(t, services.ServeHTTP)
+
+	destroyed := newTestInstance(t, "batch-destroyed")
+	destroyed.state = Destroyed
+	failing := newCommittedWithService(t, "failing-batch")
+	alsoDestroyed := newTestInstance(t, "batch-also-destroyed")
+	alsoDestroyed.state = Destroyed
+
+	// the failure of the second instance, whose service cannot be deleted, does not stop the batch
+	result := BatchDestroyWithResult(destroyed, nil, failing, alsoDestroyed)
+	require.Len(t, result.Results, 3)
+	assert.Equal(t, InstanceDestroyResult{Name: "batch-destroyed", Outcome: DestroyOutcomeDestroyed}, result.Results[0])
+	assert.Equal(t, "failing-batch", result.Results[1].Name)
+	assert.Equal(t, DestroyOutcomeFailed, result.Results[1].Outcome)
+	assert.ErrorIs(t, result.Results[1].Err, ErrDestroyingResourcesForInstance)
+	assert.Equal(t, Committed, failing.state, "a failed instance can be destroyed again")
+	assert.Equal(t, InstanceDestroyResult{Name: "batch-also-destroyed", Outcome: DestroyOutcomeDestroyed}, result.Results[2])
+	assert.Equal(t, result.Results[1:2], result.Failed())
+
+	err := BatchDestroy(destroyed, failing)
+	assert.ErrorIs(t, err, ErrBatchDestroyFailed)
+	assert.ErrorContains(t, err, "'failing-batch'")
+	assert.NoError(t, BatchDestroy(destroyed, alsoDestroyed))
+
+	t.Setenv("KNUU_SKIP_CLEANUP", "true")
+	result = BatchDestroyWithResult(destroyed, failing)
+	assert.Equal(t, []InstanceDestroyResult{
+		{Name: "batch-destroyed", Outcome: DestroyOutcomeSkipped},
+		{Name: "failing-batch", Outcome: DestroyOutcomeSkipped},
+	}, result.Results)
+	assert.NoError(t, result.Err())
+}
+
+func TestDestroyCommittedWithService(t *testing.T) {
+	services := &fakeServices{services: map[string]bool{}}
+	useK8sClient(t, services.ServeHTTP)
+
+	// the service of the ports added before Commit is deployed by Commit, so it is deleted with the instance
+	i := newCommittedWithService(t, "committed-service")
+	require.True(t, services.services[i.k8sName])
+	require.NoError(t, i.Destroy())
+	assert.Equal(t, Destroyed, i.state)
+	assert.Equal(t, []string{i.k8sName}, services.deleted)
+	assert.Empty(t, services.services)
+}
+
+func TestDestroyNeverStarted(t *testing.T) {
+	for _, state := range []InstanceState{None, Preparing, Committed} {
+		t.Run(state.String(), func(t *testing.T) {
+			// no k8s client is needed, as nothing was created in the cluster
+			i := newTestInstance(t, "never-started")
+			i.state = state
+			// the files added to the instance are copied to its build dir
+			require.NoError(t, os.MkdirAll(i.getBuildDir(), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(i.getBuildDir(), "file"), []byte("file"), 0o644))
+			hooks := 0
+			if state != None {
+				require.NoError(t, i.OnDestroy(func(*Instance) error {
+					hooks++
+					return nil
+				}))
+			}
+
+			require.NoError(t, i.Destroy())
+			assert.Equal(t, Destroyed, i.state)
+			if state != None {
+				assert.Equal(t, 1, hooks, "the destroy hooks should run for an instance that was never started")
+			}
+			assert.NoDirExists(t, i.getBuildDir(), "the build dir should be removed")
+			assert.NoError(t, i.Destroy(), "destroying twice is a no-op")
+			assert.ErrorIs(t, i.Start(), ErrStartingNotAllowed, "a destroyed instance cannot be started")
+		})
+	}
+}
+
+func TestDestroyLeavesBuilderFactoriesOfOthers(t *testing.T) {
+	// the factory set with UseBuilder belongs to the caller, who may still use its build context
+	f, err := container.NewBuilderFactory("alpine:3.20", filepath.Join(t.TempDir(), "shared"), &builder.FakeBuilder{})
+	require.NoError(t, err)
+	i := newTestInstance(t, "external-builder")
+	require.NoError(t, i.UseBuilder(f))
+	require.NoError(t, i.Destroy())
+	assert.DirExists(t, f.BuildContext(), "the factory of the caller should be left open")
+
+	// a clone shares the factory of the instance it was cloned from
+	original := newTestInstance(t, "original-builder")
+	require.NoError(t, original.SetImage("alpine:3.20"))
+	original.state = Committed
+	clone, err := original.CloneWithName("cloned-builder")
+	require.NoError(t, err)
+	require.NoError(t, clone.Destroy())
+	assert.DirExists(t, original.getBuildDir(), "the clone should not close the factory of the original")
+	require.NoError(t, original.Destroy())
+	assert.NoDirExists(t, original.getBuildDir())
+}
@@ -0,0 +1,55 @@
+package knuu
+
+import (
+	"path"
+	"slices"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/celestiaorg/knuu/pkg/k8s"
+)
+
+// SetReadOnlyRootFilesystem mounts the root filesystem of the container of the instance read-only,
+// e.g. for security tests checking that an app does not write outside of its designated directories
+// Writing is still possible to the volumes of the instance and to the tmpfs mounts added with AddTmpfsMount
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetReadOnlyRootFilesystem(readOnly bool) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingReadOnlyRootFilesystemNotAllowed.WithParams(i.state.String())
+	}
+	i.securityContext.readOnlyRootFilesystem = readOnly
+	logrus.Debugf("Set read-only root filesystem to '%t' for instance '%s'", readOnly, i.name)
+	return nil
+}
+
+// AddTmpfsMount mounts a memory-backed directory at the absolute path in the container of the instance, e.g. /tmp,
+// which stays writable with a read-only root filesystem. Its content is lost when the pod is replaced
+// The size limit, e.g. '64Mi', bounds its content, which counts against the memory limit of the container;
+// an empty size limit sets no other bound
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) AddTmpfsMount(mountPath string, sizeLimit string) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrAddingTmpfsMountNotAllowed.WithParams(i.state.String())
+	}
+	if !path.IsAbs(mountPath) || path.Clean(mountPath) == "/" {
+		return ErrInvalidTmpfsPath.WithParams(mountPath)
+	}
+	mountPath = path.Clean(mountPath)
+	if slices.ContainsFunc(i.tmpfsMounts, func(m *k8s.TmpfsMount) bool { return m.Path == mountPath }) ||
+		slices.ContainsFunc(i.volumes, func(v *k8s.Volume) bool { return path.Clean(v.Path) == mountPath }) {
+		return ErrTmpfsPathAlreadyMounted.WithParams(mountPath)
+	}
+	if sizeLimit != "" {
+		quantity, err := resource.ParseQuantity(sizeLimit)
+		if err != nil {
+			return ErrInvalidTmpfsSizeLimit.WithParams(sizeLimit).Wrap(err)
+		}
+		if quantity.Sign() <= 0 {
+			return ErrInvalidTmpfsSizeLimit.WithParams(sizeLimit)
+		}
+	}
+	i.tmpfsMounts = append(i.tmpfsMounts, &k8s.TmpfsMount{Path: mountPath, SizeLimit: sizeLimit})
+	logrus.Debugf("Added tmpfs mount '%s' with size limit '%s' to instance '%s'", mountPath, sizeLimit, i.name)
+	return nil
+}
@@ -0,0 +1,40 @@
+package knuu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/k8s"
+)
+
+func TestReadOnlyRootFilesystem(t *testing.T) {
+	lockK8sClient(t)
+
+	i := newTestInstance(t, "read-only-root")
+	assert.Nil(t, prepareSecurityContext(i.securityContext).ReadOnlyRootFilesystem)
+	require.NoError(t, i.SetReadOnlyRootFilesystem(true))
+	sc := prepareSecurityContext(i.securityContext)
+	require.NotNil(t, sc.ReadOnlyRootFilesystem)
+	assert.True(t, *sc.ReadOnlyRootFilesystem)
+
+	require.NoError(t, i.AddVolume("/data", "1Gi"))
+	assert.ErrorIs(t, i.AddTmpfsMount("tmp", ""), ErrInvalidTmpfsPath)
+	assert.ErrorIs(t, i.AddTmpfsMount("/", ""), ErrInvalidTmpfsPath)
+	assert.ErrorIs(t, i.AddTmpfsMount("/tmp", "lots"), ErrInvalidTmpfsSizeLimit)
+	assert.ErrorIs(t, i.AddTmpfsMount("/tmp", "0"), ErrInvalidTmpfsSizeLimit)
+	assert.ErrorIs(t, i.AddTmpfsMount("/data/", ""), ErrTmpfsPathAlreadyMounted)
+	require.NoError(t, i.AddTmpfsMount("/tmp/", "64Mi"))
+	assert.ErrorIs(t, i.AddTmpfsMount("/tmp", ""), ErrTmpfsPathAlreadyMounted)
+
+	k8sClient = &k8s.Client{}
+	config := i.prepareReplicaSetConfig().PodConfig.ContainerConfig
+	assert.Equal(t, []*k8s.TmpfsMount{{Path: "/tmp", SizeLimit: "64Mi"}}, config.TmpfsMounts)
+	require.NotNil(t, config.SecurityContext.ReadOnlyRootFilesystem)
+	assert.True(t, *config.SecurityContext.ReadOnlyRootFilesystem)
+
+	i.state = Started
+	assert.ErrorIs(t, i.SetReadOnlyRootFilesystem(false), ErrSettingReadOnlyRootFilesystemNotAllowed)
+	assert.ErrorIs(t, i.AddTmpfsMount("/run", ""), ErrAddingTmpfsMountNotAllowed)
+}
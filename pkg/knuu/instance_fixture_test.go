@@ -0,0 +1,210 @@
+package knuu
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/celestiaorg/knuu/pkg/k8s"
+)
+
+func newTestInstance(t *testing.T, name string) *Instance {
+	t.Helper()
+	i, err := NewInstance(name)
+	require.NoError(t, err)
+	i.state = Preparing
+	return i
+}
+
+// newQuickTestInstance returns a test instance whose operations and waits against the fake API server
+// time out and poll quickly, so a failing test does not hang
+func newQuickTestInstance(t *testing.T, name string) *Instance {
+	t.Helper()
+	i := newTestInstance(t, name)
+	require.NoError(t, i.SetOperationTimeout(5*time.Second))
+	require.NoError(t, i.SetPollInterval(10*time.Millisecond))
+	return i
+}
+
+// k8sClientMu is held by the test using the package-level k8sClient, which all the instances share
+var k8sClientMu sync.Mutex
+
+// lockK8sClient gives the test the exclusive use of k8sClient until it ends, and resets the client then
+// The tests setting k8sClient must call it before, so they never run concurrently, even with t.Parallel
+// Their subtests must not run in parallel, as they share the lock of the test
+func lockK8sClient(t *testing.T) {
+	t.Helper()
+	k8sClientMu.Lock()
+	t.Cleanup(func() {
+		k8sClient = nil
+		k8sClientMu.Unlock()
+	})
+}
+
+// useK8sClient locks k8sClient for the test, see lockK8sClient, and makes the instances use a fake API server
+// passing the requests to the handler, see newTestK8sClient. A nil handler creates every object it receives, see echoK8sHandler
+// The test can replace the client afterwards, but must not call useK8sClient again
+func useK8sClient(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	if handler == nil {
+		handler = echoK8sHandler
+	}
+	lockK8sClient(t)
+	k8sClient = newTestK8sClient(t, handler)
+}
+
+// newTestK8sClient returns a client of an API server that answers the namespace lookup
+// and passes all the other requests to the handler
+func newTestK8sClient(t *testing.T, handler http.HandlerFunc) *k8s.Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/namespaces/test" {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"kind":"Namespace","apiVersion":"v1","metadata":{"name":"test"}}`)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	home := t.TempDir()
+	kubeconfig := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: %s
+contexts:
+- name: test
+  context:
+    cluster: test
+current-context: test
+`, server.URL)
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".kube"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".kube", "config"), []byte(kubeconfig), 0600))
+	t.Setenv("HOME", home)
+
+	client, err := k8s.New(context.Background(), "test")
+	require.NoError(t, err)
+	return client
+}
+
+// newSlowK8sClient returns a client of an API server that hangs on all the requests until they are canceled
+func newSlowK8sClient(t *testing.T) *k8s.Client {
+	return newTestK8sClient(t, func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+}
+
+// newRateLimitedK8sClient returns a client of an API server whose instance is rate limited when pulling its image
+// until its pod is deleted `recoverAfter` times, or forever if it is negative
+func newRateLimitedK8sClient(t *testing.T, recoverAfter int) (*k8s.Client, *atomic.Int32) {
+	var deletes atomic.Int32
+	client := newTestK8sClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		recovered := recoverAfter >= 0 && int(deletes.Load()) >= recoverAfter
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/apis/apps/v1/namespaces/test/replicasets/"):
+			ready := 0
+			if recovered {
+				ready = 1
+			}
+			fmt.Fprintf(w, `{"kind":"ReplicaSet","apiVersion":"apps/v1","metadata":{"name":"pull"},`+
+				`"spec":{"replicas":1,"selector":{"matchLabels":{"app":"pull"}}},"status":{"readyReplicas":%d}}`, ready)
+		case r.URL.Path == "/api/v1/namespaces/test/pods":
+			fmt.Fprint(w, `{"kind":"PodList","apiVersion":"v1","items":[{"metadata":{"name":"pull-pod"}}]}`)
+		case r.URL.Path == "/api/v1/namespaces/test/pods/pull-pod" && r.Method == http.MethodDelete:
+			deletes.Add(1)
+			fmt.Fprint(w, `{"kind":"Status","apiVersion":"v1","status":"Success"}`)
+		case r.URL.Path == "/api/v1/namespaces/test/pods/pull-pod":
+			state := `{"running":{}}`
+			if !recovered {
+				state = `{"waiting":{"reason":"ImagePullBackOff","message":"toomanyrequests: You have reached your pull rate limit"}}`
+			}
+			fmt.Fprintf(w, `{"kind":"Pod","apiVersion":"v1","metadata":{"name":"pull-pod"},"status":{"containerStatuses":[{"name":"pull","state":%s}]}}`, state)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	return client, &deletes
+}
+
+// echoKinds are the kinds of the resources created by the instances, by their name in the API
+var echoKinds = map[string]string{
+	"replicasets":     "ReplicaSet",
+	"serviceaccounts": "ServiceAccount",
+	"services":        "Service",
+	"configmaps":      "ConfigMap",
+	"roles":           "Role",
+	"rolebindings":    "RoleBinding",
+}
+
+// newEchoK8sClient returns a client of an API server that creates every object it receives and has no other objects
+func newEchoK8sClient(t *testing.T) *k8s.Client {
+	return newTestK8sClient(t, echoK8sHandler)
+}
+
+// createdPodSpec returns the pod spec of the replica set created by the request, and false if the request creates none
+// The body of the request is left to be read again by the next handler
+func createdPodSpec(t *testing.T, r *http.Request) (v1.PodSpec, bool) {
+	t.Helper()
+	if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/replicasets") {
+		return v1.PodSpec{}, false
+	}
+	body, err := io.ReadAll(r.Body)
+	assert.NoError(t, err)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	var rs struct {
+		Spec struct {
+			Template struct {
+				Spec v1.PodSpec `json:"spec"`
+			} `json:"template"`
+		} `json:"spec"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &rs))
+	return rs.Spec.Template.Spec, true
+}
+
+// echoK8sHandler creates every object it receives and has no other objects
+func echoK8sHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method {
+	case http.MethodPost, http.MethodPut:
+		// typed clients do not send the kind of the objects, which is needed to decode the response
+		var object map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&object); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		segments := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		resource := segments[len(segments)-1]
+		if r.Method == http.MethodPut {
+			resource = segments[len(segments)-2]
+		}
+		object["kind"] = echoKinds[resource]
+		object["apiVersion"] = strings.Join(segments[1:slices.Index(segments, "namespaces")], "/")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(object)
+	case http.MethodDelete:
+		fmt.Fprint(w, `{"kind":"Status","apiVersion":"v1","status":"Success"}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`)
+	}
+}
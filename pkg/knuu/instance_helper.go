@@ -4,32 +4,337 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"maps"
 	"net"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	"github.com/celestiaorg/knuu/pkg/k8s"
+	"github.com/celestiaorg/knuu/pkg/registry"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
 // getImageRegistry returns the name of the temporary image registry
-func (i *Instance) getImageRegistry() (string, error) {
+// The name is derived from the image hash, so identical images share the same name
+func (i *Instance) getImageRegistry(imageHash string) (string, error) {
 	if i.imageName != "" {
 		return i.imageName, nil
 	}
-	// If not already set, generate a random name using ttl.sh
-	uuid, err := uuid.NewRandom()
-	if err != nil {
-		return "", fmt.Errorf("error generating UUID: %w", err)
+	if imageHash == "" {
+		return "", ErrEmptyImageHash
+	}
+	// If not already set, use the hash as the image name in ttl.sh
+	return registry.BuiltImageName(imageHash), nil
+}
+
+// pushImage builds and pushes the image of the builder factory, unless it is unchanged or already pushed,
+// and sets it as the image of the instance
+// The base image is verified first, if a public key was set, as pinning it changes the hash of the image
+func (i *Instance) pushImage() error {
+	ctx, cancel := context.WithTimeout(context.Background(), i.operationTimeout())
+	defer cancel()
+	if err := i.builderFactory.VerifyBaseImage(ctx); err != nil {
+		return ErrVerifyingBaseImage.WithParams(i.name).Wrap(err)
+	}
+
+	if i.builderFactory.Changed() {
+		// Generate a hash for the current image
+		imageHash, err := i.builderFactory.GenerateImageHash()
+		if err != nil {
+			return ErrGeneratingImageHash.Wrap(err)
+		}
+		if i.stableImage != "" {
+			return i.pushStableImage(ctx, imageHash)
+		}
+
+		// The image name depends on the hash, so an identical image that was
+		// already pushed to the registry does not need to be built again
+		imageName, err := i.getImageRegistry(imageHash)
+		if err != nil {
+			return ErrGettingImageRegistry.Wrap(err)
+		}
+
+		// Check if the generated image hash already exists in the cache, otherwise, we build it.
+		cachedImageName, exists := checkImageHashInCache(imageHash)
+		if exists {
+			i.imageName = cachedImageName
+			logrus.Debugf("Using cached image for instance '%s'", i.name)
+		} else {
+			logrus.Debugf("Cannot use any cached image for instance '%s'", i.name)
+			err = i.builderFactory.PushBuilderImage(imageName)
+			if err != nil {
+				return ErrPushingImage.WithParams(i.name).Wrap(err)
+			}
+			updateImageCacheWithHash(imageHash, imageName)
+			i.imageName = imageName
+			logrus.Debugf("Pushed new image for instance '%s'", i.name)
+		}
+	} else {
+		i.imageName = i.builderFactory.ImageNameFrom()
+		logrus.Debugf("No need to build and push image for instance '%s'", i.name)
+	}
+	return nil
+}
+
+const (
+	// allCapabilities is the special capability name that matches all capabilities
+	allCapabilities = "ALL"
+)
+
+// linuxCapabilities are the names of the linux capabilities, as expected by kubernetes
+// ref: https://man7.org/linux/man-pages/man7/capabilities.7.html
+var linuxCapabilities = map[string]bool{
+	"AUDIT_CONTROL": true, "AUDIT_READ": true, "AUDIT_WRITE": true, "BLOCK_SUSPEND": true, "BPF": true,
+	"CHECKPOINT_RESTORE": true, "CHOWN": true, "DAC_OVERRIDE": true, "DAC_READ_SEARCH": true, "FOWNER": true,
+	"FSETID": true, "IPC_LOCK": true, "IPC_OWNER": true, "KILL": true, "LEASE": true, "LINUX_IMMUTABLE": true,
+	"MAC_ADMIN": true, "MAC_OVERRIDE": true, "MKNOD": true, "NET_ADMIN": true, "NET_BIND_SERVICE": true,
+	"NET_BROADCAST": true, "NET_RAW": true, "PERFMON": true, "SETFCAP": true, "SETGID": true, "SETPCAP": true,
+	"SETUID": true, "SYS_ADMIN": true, "SYS_BOOT": true, "SYS_CHROOT": true, "SYS_MODULE": true, "SYS_NICE": true,
+	"SYS_PACCT": true, "SYS_PTRACE": true, "SYS_RAWIO": true, "SYS_RESOURCE": true, "SYS_TIME": true,
+	"SYS_TTY_CONFIG": true, "SYSLOG": true, "WAKE_ALARM": true,
+}
+
+// validateCapability validates the capability name, e.g. NET_ADMIN, so a typo fails when the capability is set
+// rather than when the pod is created
+func validateCapability(capability string) error {
+	if capability != allCapabilities && !linuxCapabilities[capability] {
+		return ErrInvalidCapability.WithParams(capability)
+	}
+	return nil
+}
+
+// validateFolderLimits checks that the folder does not exceed the size and file count limits of the instance
+// This is done before copying anything, so a folder pointed at by mistake fails early with a clear error
+func (i *Instance) validateFolderLimits(src string) error {
+	var (
+		totalSize int64
+		fileCount int
+	)
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		totalSize += info.Size()
+		fileCount++
+		if i.maxFolderSize > 0 && totalSize > i.maxFolderSize {
+			return ErrFolderTooLarge.WithParams(src, i.maxFolderSize)
+		}
+		if i.maxFolderFiles > 0 && fileCount > i.maxFolderFiles {
+			return ErrFolderHasTooManyFiles.WithParams(src, i.maxFolderFiles)
+		}
+		return nil
+	})
+}
+
+// podRunningAndRestarts returns whether all the containers of the pod are running and ready,
+// and the total number of times they have been restarted
+func podRunningAndRestarts(pod *v1.Pod) (bool, int32) {
+	running := len(pod.Status.ContainerStatuses) > 0
+	restarts := int32(0)
+	for _, status := range pod.Status.ContainerStatuses {
+		restarts += status.RestartCount
+		if status.State.Running == nil || !status.Ready {
+			running = false
+		}
+	}
+	return running, restarts
+}
+
+// containerExitCode returns the exit code of the last run of the container of the pod, if it has terminated
+func containerExitCode(pod *v1.Pod, containerName string) (int, bool) {
+	terminated := lastTermination(pod, containerName)
+	if terminated == nil {
+		return 0, false
+	}
+	return int(terminated.ExitCode), true
+}
+
+// lastTermination returns the state of the last run of the container of the pod, or nil if it has not terminated
+// The replica set restarts terminated containers, so the previous run is used while it waits to restart
+func lastTermination(pod *v1.Pod, containerName string) *v1.ContainerStateTerminated {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != containerName {
+			continue
+		}
+		if status.State.Terminated != nil {
+			return status.State.Terminated
+		}
+		if status.State.Running == nil && status.LastTerminationState.Terminated != nil {
+			return status.LastTerminationState.Terminated
+		}
+	}
+	return nil
+}
+
+// sysctlRegex matches a sysctl name, using dots or slashes as separators
+var sysctlRegex = regexp.MustCompile(`^([a-z0-9]([-_a-z0-9]*[a-z0-9])?[./])*[a-z0-9]([-_a-z0-9]*[a-z0-9])?$`)
+
+// safeSysctls are the sysctls that are allowed by default in kubernetes
+var safeSysctls = map[string]bool{
+	"kernel.shm_rmid_forced":              true,
+	"net.ipv4.ip_local_port_range":        true,
+	"net.ipv4.tcp_syncookies":             true,
+	"net.ipv4.ping_group_range":           true,
+	"net.ipv4.ip_unprivileged_port_start": true,
+	"net.ipv4.ip_local_reserved_ports":    true,
+	"net.ipv4.tcp_keepalive_time":         true,
+	"net.ipv4.tcp_fin_timeout":            true,
+	"net.ipv4.tcp_keepalive_intvl":        true,
+	"net.ipv4.tcp_keepalive_probes":       true,
+}
+
+// validateSysctl validates the sysctl name, e.g. net.core.somaxconn
+func validateSysctl(name string) error {
+	if !sysctlRegex.MatchString(name) {
+		return ErrInvalidSysctl.WithParams(name)
+	}
+	return nil
+}
+
+// isSafeSysctl returns true if the sysctl is allowed by default in kubernetes
+func isSafeSysctl(name string) bool {
+	return safeSysctls[strings.ReplaceAll(name, "/", ".")]
+}
+
+// envFieldPaths are the pod fields that can be used as environment variables with the downward API
+var envFieldPaths = map[string]bool{
+	"metadata.name":           true,
+	"metadata.namespace":      true,
+	"metadata.uid":            true,
+	"spec.nodeName":           true,
+	"spec.serviceAccountName": true,
+	"status.hostIP":           true,
+	"status.hostIPs":          true,
+	"status.podIP":            true,
+	"status.podIPs":           true,
+}
+
+// envLabelFieldRegex matches a single label or annotation, e.g. metadata.labels['app']
+var envLabelFieldRegex = regexp.MustCompile(`^metadata\.(labels|annotations)\['[^']+'\]$`)
+
+// envResourceFields are the container resources that can be used as environment variables with the downward API
+var envResourceFields = map[string]bool{
+	"limits.cpu":                 true,
+	"limits.memory":              true,
+	"limits.ephemeral-storage":   true,
+	"requests.cpu":               true,
+	"requests.memory":            true,
+	"requests.ephemeral-storage": true,
+}
+
+// validateEnvFieldPath validates that the field path is supported by the downward API
+func validateEnvFieldPath(fieldPath string) error {
+	if !envFieldPaths[fieldPath] && !envLabelFieldRegex.MatchString(fieldPath) {
+		return ErrInvalidEnvFieldPath.WithParams(fieldPath)
+	}
+	return nil
+}
+
+// setEnvValueFrom sets an environment variable sourced from the pod, replacing a literal value set in the state 'Committed'
+func (i *Instance) setEnvValueFrom(envName string, source *v1.EnvVarSource) {
+	i.envValueFrom[envName] = source
+	delete(i.env, envName)
+}
+
+// validateConfigMapName validates the name of a ConfigMap, which must be a DNS subdomain
+func validateConfigMapName(name string) error {
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) != 0 {
+		return ErrInvalidConfigMapName.WithParams(name, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// validateConfigMapKey validates a key of a ConfigMap
+func validateConfigMapKey(key string) error {
+	if errs := validation.IsConfigMapKey(key); len(errs) != 0 {
+		return ErrInvalidConfigMapKey.WithParams(key, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// envConfigMapName returns the name in the cluster of a ConfigMap used for environment variables,
+// which is named after the instance if it is created by the instance
+func (i *Instance) envConfigMapName(name string) string {
+	if _, ok := i.envConfigMaps[name]; ok {
+		return i.k8sName + "-" + name
+	}
+	return name
+}
+
+// prepareEnvValueFrom returns the sources of the environment variables,
+// referencing the ConfigMaps created by the instance by their name in the cluster
+func (i *Instance) prepareEnvValueFrom() map[string]*v1.EnvVarSource {
+	valueFrom := make(map[string]*v1.EnvVarSource, len(i.envValueFrom))
+	for name, source := range i.envValueFrom {
+		if source.ConfigMapKeyRef != nil {
+			source = source.DeepCopy()
+			source.ConfigMapKeyRef.Name = i.envConfigMapName(source.ConfigMapKeyRef.Name)
+		}
+		valueFrom[name] = source
+	}
+	return valueFrom
+}
+
+// prepareEnvFrom returns the ConfigMaps to import all the keys of as environment variables
+func (i *Instance) prepareEnvFrom() []v1.EnvFromSource {
+	if len(i.envFromConfigMaps) == 0 {
+		return nil
+	}
+	envFrom := make([]v1.EnvFromSource, 0, len(i.envFromConfigMaps))
+	for _, name := range i.envFromConfigMaps {
+		envFrom = append(envFrom, v1.EnvFromSource{
+			ConfigMapRef: &v1.ConfigMapEnvSource{
+				LocalObjectReference: v1.LocalObjectReference{Name: i.envConfigMapName(name)},
+			},
+		})
+	}
+	return envFrom
+}
+
+// envConfigMapNames returns the names of the ConfigMaps created by the instance, sorted
+func (i *Instance) envConfigMapNames() []string {
+	names := make([]string, 0, len(i.envConfigMaps))
+	for name := range i.envConfigMaps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// deployEnvConfigMaps creates the ConfigMaps used for environment variables
+func (i *Instance) deployEnvConfigMaps(ctx context.Context) error {
+	for _, name := range i.envConfigMapNames() {
+		if _, err := k8sClient.CreateConfigMap(ctx, i.envConfigMapName(name), i.getLabels(), i.envConfigMaps[name]); err != nil {
+			return ErrFailedToCreateConfigMap.Wrap(err)
+		}
+		logrus.Debugf("Deployed env configmap '%s'", i.envConfigMapName(name))
+	}
+	return nil
+}
+
+// destroyEnvConfigMaps deletes the ConfigMaps used for environment variables
+func (i *Instance) destroyEnvConfigMaps(ctx context.Context) error {
+	for _, name := range i.envConfigMapNames() {
+		if err := k8sClient.DeleteConfigMap(ctx, i.envConfigMapName(name)); err != nil {
+			return ErrFailedToDeleteConfigMap.Wrap(err)
+		}
+		logrus.Debugf("Destroyed env configmap '%s'", i.envConfigMapName(name))
 	}
-	imageName := fmt.Sprintf("ttl.sh/%s:24h", uuid.String())
-	return imageName, nil
+	return nil
 }
 
 // validatePort validates the port
@@ -130,9 +435,40 @@ func (i *Instance) deployPod(ctx context.Context) error {
 	// Get labels for the pod
 	labels := i.getLabels()
 
-	// create a service account for the pod
-	if err := k8sClient.CreateServiceAccount(ctx, i.k8sName, labels); err != nil {
-		return ErrFailedToCreateServiceAccount.Wrap(err)
+	if i.serviceAccount != "" {
+		// the service account is managed by the user, so it must exist already
+		exists, err := k8sClient.ServiceAccountExists(ctx, i.serviceAccount)
+		if err != nil {
+			return ErrCheckingServiceAccountExists.WithParams(i.serviceAccount).Wrap(err)
+		}
+		if !exists {
+			return ErrServiceAccountNotFound.WithParams(i.serviceAccount)
+		}
+	} else {
+		// create a service account for the pod
+		if err := k8sClient.CreateServiceAccount(ctx, i.k8sName, labels); err != nil {
+			return ErrFailedToCreateServiceAccount.Wrap(err)
+		}
+	}
+
+	if i.priorityClass != "" {
+		exists, err := k8sClient.PriorityClassExists(ctx, i.priorityClass)
+		if err != nil {
+			return ErrCheckingPriorityClassExists.WithParams(i.priorityClass).Wrap(err)
+		}
+		if !exists {
+			return ErrPriorityClassNotFound.WithParams(i.priorityClass)
+		}
+	}
+
+	if i.runtimeClass != "" {
+		exists, err := k8sClient.RuntimeClassExists(ctx, i.runtimeClass)
+		if err != nil {
+			return ErrCheckingRuntimeClassExists.WithParams(i.runtimeClass).Wrap(err)
+		}
+		if !exists {
+			return ErrRuntimeClassNotFound.WithParams(i.runtimeClass)
+		}
 	}
 
 	// create a role and role binding for the pod if there are policy rules
@@ -140,13 +476,21 @@ func (i *Instance) deployPod(ctx context.Context) error {
 		if err := k8sClient.CreateRole(ctx, i.k8sName, labels, i.policyRules); err != nil {
 			return ErrFailedToCreateRole.Wrap(err)
 		}
-		if err := k8sClient.CreateRoleBinding(ctx, i.k8sName, labels, i.k8sName, i.k8sName); err != nil {
+		if err := k8sClient.CreateRoleBinding(ctx, i.k8sName, labels, i.k8sName, i.ServiceAccountName()); err != nil {
 			return ErrFailedToCreateRoleBinding.Wrap(err)
 		}
 	}
 
+	if err := i.pinImageArchitecture(ctx); err != nil {
+		return err
+	}
+
 	replicaSetSetConfig := i.prepareReplicaSetConfig()
 
+	if i.cronSchedule != "" {
+		return i.deployCronJob(ctx, replicaSetSetConfig)
+	}
+
 	// Deploy the statefulSet
 	replicaSet, err := k8sClient.CreateReplicaSet(ctx, replicaSetSetConfig, true)
 	if err != nil {
@@ -166,15 +510,23 @@ func (i *Instance) deployPod(ctx context.Context) error {
 // destroyPod destroys the pod for the instance (no grace period)
 // Skips if the pod is already destroyed
 func (i *Instance) destroyPod(ctx context.Context) error {
-	grace := int64(0)
-	err := k8sClient.DeleteReplicaSetWithGracePeriod(ctx, i.k8sName, &grace)
-	if err != nil {
-		return ErrFailedToDeletePod.Wrap(err)
+	if i.cronSchedule != "" {
+		if err := k8sClient.DeleteCronJob(ctx, i.k8sName); err != nil {
+			return ErrFailedToDeletePod.Wrap(err)
+		}
+	} else {
+		grace := int64(0)
+		err := k8sClient.DeleteReplicaSetWithGracePeriod(ctx, i.k8sName, &grace)
+		if err != nil {
+			return ErrFailedToDeletePod.Wrap(err)
+		}
 	}
 
-	// Delete the service account for the pod
-	if err := k8sClient.DeleteServiceAccount(ctx, i.k8sName); err != nil {
-		return ErrFailedToDeleteServiceAccount.Wrap(err)
+	// Delete the service account for the pod, unless it is managed by the user
+	if i.serviceAccount == "" {
+		if err := k8sClient.DeleteServiceAccount(ctx, i.k8sName); err != nil {
+			return ErrFailedToDeleteServiceAccount.Wrap(err)
+		}
 	}
 	// Delete the role and role binding for the pod if there are policy rules
 	if len(i.policyRules) > 0 {
@@ -297,29 +649,58 @@ func (i *Instance) deployResources(ctx context.Context) error {
 			return ErrDeployingVolumeForInstance.WithParams(i.k8sName).Wrap(err)
 		}
 	}
+	if len(i.sharedVolumes) != 0 {
+		if err := i.deploySharedVolumes(ctx); err != nil {
+			return ErrDeployingSharedVolumesForInstance.WithParams(i.k8sName).Wrap(err)
+		}
+	}
 	if len(i.files) != 0 {
 		if err := i.deployFiles(ctx); err != nil {
 			return ErrDeployingFilesForInstance.WithParams(i.k8sName).Wrap(err)
 		}
 	}
+	if len(i.envConfigMaps) != 0 {
+		if err := i.deployEnvConfigMaps(ctx); err != nil {
+			return ErrDeployingEnvConfigMapsForInstance.WithParams(i.k8sName).Wrap(err)
+		}
+	}
 
 	return nil
 }
 
 // destroyResources destroys the resources for the instance
 func (i *Instance) destroyResources(ctx context.Context) error {
+	// the service of a committed instance may be deployed already, its other resources are deployed when it is started
+	started := i.state != Committed
+	if len(i.manifestResources) != 0 {
+		if err := i.destroyManifestResources(ctx); err != nil {
+			return ErrDestroyingManifestResources.WithParams(i.k8sName).Wrap(err)
+		}
+	}
 	if len(i.volumes) != 0 {
 		err := i.destroyVolume(ctx)
 		if err != nil {
 			return ErrDestroyingVolumeForInstance.WithParams(i.k8sName).Wrap(err)
 		}
 	}
-	if len(i.files) != 0 {
+	if len(i.sharedVolumes) != 0 {
+		err := i.destroySharedVolumes(ctx)
+		if err != nil {
+			return ErrDestroyingSharedVolumesForInstance.WithParams(i.k8sName).Wrap(err)
+		}
+	}
+	if len(i.files) != 0 && started {
 		err := i.destroyFiles(ctx)
 		if err != nil {
 			return ErrDestroyingFilesForInstance.WithParams(i.k8sName).Wrap(err)
 		}
 	}
+	if len(i.envConfigMaps) != 0 && started {
+		err := i.destroyEnvConfigMaps(ctx)
+		if err != nil {
+			return ErrDestroyingEnvConfigMapsForInstance.WithParams(i.k8sName).Wrap(err)
+		}
+	}
 	if i.kubernetesService != nil {
 		err := i.destroyService(ctx)
 		if err != nil {
@@ -328,7 +709,7 @@ func (i *Instance) destroyResources(ctx context.Context) error {
 	}
 
 	// disable network only for non-sidecar instances
-	if !i.isSidecar {
+	if !i.isSidecar && started {
 		// enable network when network is disabled
 		disableNetwork, err := i.NetworkIsDisabled()
 		if err != nil {
@@ -356,6 +737,8 @@ func (i *Instance) cloneWithSuffix(suffix string) *Instance {
 
 	// Deep copy of securityContext to ensure cloned instance has its own copy
 	clonedSecurityContext := *i.securityContext
+	clonedSecurityContext.capabilitiesAdd = append([]string(nil), i.securityContext.capabilitiesAdd...)
+	clonedSecurityContext.capabilitiesDrop = append([]string(nil), i.securityContext.capabilitiesDrop...)
 
 	clonedBitTwister := *i.BitTwister
 	clonedBitTwister.SetClient(nil) // reset client to avoid reusing the same client
@@ -371,13 +754,22 @@ func (i *Instance) cloneWithSuffix(suffix string) *Instance {
 		kubernetesReplicaSet: i.kubernetesReplicaSet,
 		portsTCP:             i.portsTCP,
 		portsUDP:             i.portsUDP,
+		namedPorts:           maps.Clone(i.namedPorts),
 		command:              i.command,
 		args:                 i.args,
 		env:                  i.env,
+		envValueFrom:         maps.Clone(i.envValueFrom),
+		envFromConfigMaps:    slices.Clone(i.envFromConfigMaps),
+		envConfigMaps:        maps.Clone(i.envConfigMaps),
+		imageEnv:             maps.Clone(i.imageEnv),
+		specFiles:            slices.Clone(i.specFiles),
+		specFolders:          slices.Clone(i.specFolders),
 		volumes:              i.volumes,
 		memoryRequest:        i.memoryRequest,
 		memoryLimit:          i.memoryLimit,
 		cpuRequest:           i.cpuRequest,
+		ephemeralRequest:     i.ephemeralRequest,
+		ephemeralLimit:       i.ephemeralLimit,
 		policyRules:          i.policyRules,
 		livenessProbe:        i.livenessProbe,
 		readinessProbe:       i.readinessProbe,
@@ -388,15 +780,145 @@ func (i *Instance) cloneWithSuffix(suffix string) *Instance {
 		obsyConfig:           i.obsyConfig,
 		securityContext:      &clonedSecurityContext,
 		BitTwister:           &clonedBitTwister,
+		maxFolderSize:        i.maxFolderSize,
+		maxFolderFiles:       i.maxFolderFiles,
+		sysctls:              append([]v1.Sysctl(nil), i.sysctls...),
+		fsGroup:              i.fsGroup,
+		serviceAccount:       i.serviceAccount,
+		priorityClass:        i.priorityClass,
+		runtimeClass:         i.runtimeClass,
+		automountToken:       i.automountToken,
+		symlinkPolicy:        i.symlinkPolicy,
+		logBufferSize:        i.logBufferSize,
+		workingDir:           i.workingDir,
+		sharePidNamespace:    i.sharePidNamespace,
+		opTimeout:            i.opTimeout,
+		sharedVolumes:        slices.Clone(i.sharedVolumes),
+		pollInterval:         i.pollInterval,
+		termMsgPath:          i.termMsgPath,
+		termMsgPolicy:        i.termMsgPolicy,
+		pullRetries:          i.pullRetries,
+		pullRetryDelay:       i.pullRetryDelay,
+		externalBuilder:      i.externalBuilder,
+		readinessGates:       slices.Clone(i.readinessGates),
+		activeDeadline:       i.activeDeadline,
+		topologySpread:       slices.Clone(i.topologySpread),
+		tmpfsMounts:          slices.Clone(i.tmpfsMounts),
+		syncedFolders:        maps.Clone(i.syncedFolders),
+		serviceMesh:          i.serviceMesh,
+		meshInjection:        i.meshInjection,
+		backoffLimit:         i.backoffLimit,
+		stableImage:          i.stableImage,
+		cronSchedule:         i.cronSchedule,
+		postStartCommand:     slices.Clone(i.postStartCommand),
+		imageArch:            i.imageArch,
+		serviceStale:         i.serviceStale,
+		progressDeadline:     i.progressDeadline,
+		replicas:             i.replicas,
+		startHooks:           slices.Clone(i.startHooks),
+		readyHooks:           slices.Clone(i.readyHooks),
+		destroyHooks:         slices.Clone(i.destroyHooks),
 	}
 }
 
+// maxK8sNameAttempts is the number of random suffixes tried before giving up on a unique name
+const maxK8sNameAttempts = 10
+
+var (
+	k8sNamesMu sync.Mutex
+	// k8sNames are the unique names given to the instances in this run, so instances created
+	// with the same name, e.g. by parallel tests, never share their resources
+	k8sNames = make(map[string]bool)
+)
+
+// generateK8sName returns the name with a random suffix that no other instance of this run uses
 func generateK8sName(name string) (string, error) {
-	uuid, err := uuid.NewRandom()
+	k8sNamesMu.Lock()
+	defer k8sNamesMu.Unlock()
+	for attempt := 0; attempt < maxK8sNameAttempts; attempt++ {
+		uuid, err := uuid.NewRandom()
+		if err != nil {
+			return "", ErrGeneratingUUID.Wrap(err)
+		}
+		k8sName := fmt.Sprintf("%s-%s", name, uuid.String()[:8])
+		if !k8sNames[k8sName] {
+			k8sNames[k8sName] = true
+			return k8sName, nil
+		}
+	}
+	return "", ErrGeneratingUniqueName.WithParams(name, maxK8sNameAttempts)
+}
+
+// readinessGatesMet returns true if the conditions of all the readiness gates of the instance are True in its pod
+func (i *Instance) readinessGatesMet() (bool, error) {
+	if len(i.readinessGates) == 0 {
+		return true, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), i.operationTimeout())
+	defer cancel()
+	pod, err := k8sClient.GetFirstPodFromReplicaSet(ctx, i.k8sName)
 	if err != nil {
-		return "", ErrGeneratingUUID.Wrap(err)
+		return false, err
+	}
+	for _, gate := range i.readinessGates {
+		if !podConditionTrue(pod, v1.PodConditionType(gate)) {
+			logrus.Debugf("Readiness gate '%s' of instance '%s' is not met yet", gate, i.name)
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// validateStartupProbe checks the probe has a single handler and valid thresholds, nil unsets the probe
+func validateStartupProbe(probe *v1.Probe) error {
+	if probe == nil {
+		return nil
+	}
+	handlers := 0
+	for _, set := range []bool{probe.Exec != nil, probe.HTTPGet != nil, probe.TCPSocket != nil, probe.GRPC != nil} {
+		if set {
+			handlers++
+		}
+	}
+	if handlers != 1 {
+		return ErrInvalidStartupProbe.WithParams("it must have exactly one of exec, httpGet, tcpSocket or grpc")
 	}
-	return fmt.Sprintf("%s-%s", name, uuid.String()[:8]), nil
+	if probe.InitialDelaySeconds < 0 || probe.PeriodSeconds < 0 || probe.TimeoutSeconds < 0 || probe.FailureThreshold < 0 {
+		return ErrInvalidStartupProbe.WithParams("its delay, period, timeout and failure threshold must not be negative")
+	}
+	// kubernetes rejects startup probes needing several successes
+	if probe.SuccessThreshold > 1 {
+		return ErrInvalidStartupProbe.WithParams("its success threshold must be 1")
+	}
+	return validateProbe(probe)
+}
+
+// startupDuration returns the longest time the startup probe of the instance lets the app take to start,
+// with the defaults of kubernetes for the unset fields, or 0 without a startup probe
+func (i *Instance) startupDuration() time.Duration {
+	probe := i.startupProbe
+	if probe == nil {
+		return 0
+	}
+	period, failures := probe.PeriodSeconds, probe.FailureThreshold
+	if period == 0 {
+		period = 10
+	}
+	if failures == 0 {
+		failures = 3
+	}
+	return time.Duration(probe.InitialDelaySeconds+period*failures) * time.Second
+}
+
+// podConditionTrue returns true if the condition of the pod has the status True
+func podConditionTrue(pod *v1.Pod, conditionType v1.PodConditionType) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == conditionType {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
 }
 
 // getFreePort returns a free port
@@ -419,6 +941,30 @@ func (i *Instance) getBuildDir() string {
 	return filepath.Join("/tmp", "knuu", i.k8sName)
 }
 
+// getImageFilesDir returns the directory the files added to the image are copied to, which is the build context
+// of the builder factory, i.e. the build dir of the instance unless the factory was set with UseBuilder
+// The files added in the state 'Committed' are not part of the image and are copied to the build dir of the instance
+func (i *Instance) getImageFilesDir() string {
+	if i.state == Preparing {
+		return i.builderFactory.BuildContext()
+	}
+	return i.getBuildDir()
+}
+
+// closeBuilderFactory closes the builder factory of the instance, to release its Docker client
+// A factory set with UseBuilder belongs to the caller, and the factory of a clone to the instance it was cloned from,
+// whose build dir is the build context of the factory, so they are left open
+func (i *Instance) closeBuilderFactory() error {
+	f := i.builderFactory
+	if f == nil || i.externalBuilder || f.BuildContext() != i.getBuildDir() {
+		return nil
+	}
+	if err := f.Close(); err != nil {
+		return ErrClosingBuilderFactory.WithParams(i.name).Wrap(err)
+	}
+	return nil
+}
+
 // validateFileArgs validates the file arguments
 func (i *Instance) validateFileArgs(src, dest, chown string) error {
 	// check src
@@ -459,13 +1005,13 @@ func prepareSecurityContext(config *SecurityContext) *v1.SecurityContext {
 		if config.privileged {
 			securityContext.Privileged = &config.privileged
 		}
-		if len(config.capabilitiesAdd) > 0 {
-			capabilities := make([]v1.Capability, len(config.capabilitiesAdd))
-			for i, cap := range config.capabilitiesAdd {
-				capabilities[i] = v1.Capability(cap)
-			}
+		if config.readOnlyRootFilesystem {
+			securityContext.ReadOnlyRootFilesystem = &config.readOnlyRootFilesystem
+		}
+		if len(config.capabilitiesAdd) > 0 || len(config.capabilitiesDrop) > 0 {
 			securityContext.Capabilities = &v1.Capabilities{
-				Add: capabilities,
+				Add:  toCapabilities(config.capabilitiesAdd),
+				Drop: toCapabilities(config.capabilitiesDrop),
 			}
 		}
 	}
@@ -473,62 +1019,104 @@ func prepareSecurityContext(config *SecurityContext) *v1.SecurityContext {
 	return securityContext
 }
 
+// toCapabilities converts a list of capability names to a list of v1.Capability
+func toCapabilities(names []string) []v1.Capability {
+	if len(names) == 0 {
+		return nil
+	}
+	capabilities := make([]v1.Capability, len(names))
+	for i, name := range names {
+		capabilities[i] = v1.Capability(name)
+	}
+	return capabilities
+}
+
 // prepareConfig prepares the config for the instance
 func (i *Instance) prepareReplicaSetConfig() k8s.ReplicaSetConfig {
 
 	// Generate the container configuration
 	containerConfig := k8s.ContainerConfig{
-		Name:            i.k8sName,
-		Image:           i.imageName,
-		Command:         i.command,
-		Args:            i.args,
-		Env:             i.env,
-		Volumes:         i.volumes,
-		MemoryRequest:   i.memoryRequest,
-		MemoryLimit:     i.memoryLimit,
-		CPURequest:      i.cpuRequest,
-		LivenessProbe:   i.livenessProbe,
-		ReadinessProbe:  i.readinessProbe,
-		StartupProbe:    i.startupProbe,
-		Files:           i.files,
-		SecurityContext: prepareSecurityContext(i.securityContext),
+		Name:                    i.k8sName,
+		Image:                   i.imageName,
+		Command:                 i.command,
+		Args:                    i.args,
+		Env:                     i.env,
+		EnvValueFrom:            i.prepareEnvValueFrom(),
+		EnvFrom:                 i.prepareEnvFrom(),
+		Volumes:                 i.volumes,
+		SharedVolumes:           i.prepareSharedVolumes(),
+		TmpfsMounts:             i.tmpfsMounts,
+		MemoryRequest:           i.memoryRequest,
+		MemoryLimit:             i.memoryLimit,
+		CPURequest:              i.cpuRequest,
+		EphemeralStorageRequest: i.ephemeralRequest,
+		EphemeralStorageLimit:   i.ephemeralLimit,
+		LivenessProbe:           i.livenessProbe,
+		ReadinessProbe:          i.readinessProbe,
+		StartupProbe:            i.startupProbe,
+		Files:                   i.files,
+		SecurityContext:         prepareSecurityContext(i.securityContext),
+		WorkingDir:              i.workingDir,
+		TermMsgPath:             i.termMsgPath,
+		TermMsgPolicy:           i.termMsgPolicy,
+		PostStartCommand:        i.postStartCommand,
 	}
 	// Generate the sidecar configurations
 	sidecarConfigs := make([]k8s.ContainerConfig, 0)
 	for _, sidecar := range i.sidecars {
 		sidecarConfigs = append(sidecarConfigs, k8s.ContainerConfig{
-			Name:            sidecar.k8sName,
-			Image:           sidecar.imageName,
-			Command:         sidecar.command,
-			Args:            sidecar.args,
-			Env:             sidecar.env,
-			Volumes:         sidecar.volumes,
-			MemoryRequest:   sidecar.memoryRequest,
-			MemoryLimit:     sidecar.memoryLimit,
-			CPURequest:      sidecar.cpuRequest,
-			LivenessProbe:   sidecar.livenessProbe,
-			ReadinessProbe:  sidecar.readinessProbe,
-			StartupProbe:    sidecar.startupProbe,
-			Files:           sidecar.files,
-			SecurityContext: prepareSecurityContext(sidecar.securityContext),
+			Name:                    sidecar.k8sName,
+			Image:                   sidecar.imageName,
+			Command:                 sidecar.command,
+			Args:                    sidecar.args,
+			Env:                     sidecar.env,
+			EnvValueFrom:            sidecar.prepareEnvValueFrom(),
+			EnvFrom:                 sidecar.prepareEnvFrom(),
+			Volumes:                 sidecar.volumes,
+			SharedVolumes:           sidecar.prepareSharedVolumes(),
+			TmpfsMounts:             sidecar.tmpfsMounts,
+			MemoryRequest:           sidecar.memoryRequest,
+			MemoryLimit:             sidecar.memoryLimit,
+			CPURequest:              sidecar.cpuRequest,
+			EphemeralStorageRequest: sidecar.ephemeralRequest,
+			EphemeralStorageLimit:   sidecar.ephemeralLimit,
+			LivenessProbe:           sidecar.livenessProbe,
+			ReadinessProbe:          sidecar.readinessProbe,
+			StartupProbe:            sidecar.startupProbe,
+			Files:                   sidecar.files,
+			SecurityContext:         prepareSecurityContext(sidecar.securityContext),
+			WorkingDir:              sidecar.workingDir,
+			TermMsgPath:             sidecar.termMsgPath,
+			TermMsgPolicy:           sidecar.termMsgPolicy,
+			PostStartCommand:        sidecar.postStartCommand,
 		})
 	}
 	// Generate the pod configuration
 	podConfig := k8s.PodConfig{
-		Namespace:          k8sClient.Namespace(),
-		Name:               i.k8sName,
-		Labels:             i.getLabels(),
-		ServiceAccountName: i.k8sName,
-		FsGroup:            i.fsGroup,
-		ContainerConfig:    containerConfig,
-		SidecarConfigs:     sidecarConfigs,
+		Namespace:                    k8sClient.Namespace(),
+		Name:                         i.k8sName,
+		Labels:                       i.getLabels(),
+		ServiceAccountName:           i.ServiceAccountName(),
+		FsGroup:                      i.fsGroup,
+		Sysctls:                      i.sysctls,
+		PriorityClassName:            i.priorityClass,
+		RuntimeClassName:             i.runtimeClass,
+		AutomountServiceAccountToken: i.automountToken,
+		ShareProcessNamespace:        i.sharePidNamespace,
+		ReadinessGates:               i.readinessGates,
+		ActiveDeadlineSeconds:        i.activeDeadlineSeconds(),
+		TopologySpreadConstraints:    i.topologySpreadConstraints(),
+		NodeSelector:                 i.nodeSelector(),
+		Annotations:                  i.podAnnotations(),
+		ContainerConfig:              containerConfig,
+		SidecarConfigs:               sidecarConfigs,
 	}
 	// Generate the ReplicaSet configuration
 	statefulSetConfig := k8s.ReplicaSetConfig{
 		Namespace: k8sClient.Namespace(),
 		Name:      i.k8sName,
 		Labels:    i.getLabels(),
-		Replicas:  1,
+		Replicas:  i.replicas,
 		PodConfig: podConfig,
 	}
 
@@ -537,7 +1125,12 @@ func (i *Instance) prepareReplicaSetConfig() k8s.ReplicaSetConfig {
 
 // setImageWithGracePeriod sets the image of the instance with a grace period
 func (i *Instance) setImageWithGracePeriod(ctx context.Context, imageName string, gracePeriod *int64) error {
-	i.imageName = imageName
+	// pull the new image from the registry mirror, if any, like the images set before the instance is started
+	i.imageName = registry.Rewrite(imageName)
+	i.forgetSyncedImageFiles()
+	if err := i.pinImageArchitecture(ctx); err != nil {
+		return err
+	}
 
 	replicaSetConfig := i.prepareReplicaSetConfig()
 
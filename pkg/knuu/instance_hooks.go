@@ -0,0 +1,98 @@
+package knuu
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Hook is a callback invoked by knuu at a lifecycle transition of an instance
+type Hook func(*Instance) error
+
+// OnStart registers a hook that is invoked each time the instance is started, once its pod is deployed
+// The hooks are invoked in the order they were registered, the first error is returned by the start
+// This function can only be called in the states 'Preparing', 'Committed', 'Started' and 'Stopped'
+func (i *Instance) OnStart(hook Hook) error {
+	if err := i.validateHook(hook); err != nil {
+		return err
+	}
+	i.startHooks = append(i.startHooks, hook)
+	logrus.Debugf("Registered start hook in instance '%s'", i.name)
+	return nil
+}
+
+// OnReady registers a hook that is invoked once per start, the first time the instance is seen running,
+// e.g. to seed data into it. The hooks are invoked in the order they were registered,
+// the first error fails the wait, so Start and WaitInstanceIsRunning return it
+// This function can only be called in the states 'Preparing', 'Committed', 'Started' and 'Stopped'
+func (i *Instance) OnReady(hook Hook) error {
+	if err := i.validateHook(hook); err != nil {
+		return err
+	}
+	i.readyHooks = append(i.readyHooks, hook)
+	logrus.Debugf("Registered ready hook in instance '%s'", i.name)
+	return nil
+}
+
+// OnDestroy registers a hook that is invoked when the instance is destroyed, before its resources are deleted,
+// e.g. to collect its data. All the hooks are invoked even if some of them fail, and the instance is destroyed anyway;
+// their errors are aggregated in the error returned by Destroy
+// This function can only be called in the states 'Preparing', 'Committed', 'Started' and 'Stopped'
+func (i *Instance) OnDestroy(hook Hook) error {
+	if err := i.validateHook(hook); err != nil {
+		return err
+	}
+	i.destroyHooks = append(i.destroyHooks, hook)
+	logrus.Debugf("Registered destroy hook in instance '%s'", i.name)
+	return nil
+}
+
+// validateHook checks that a hook can be registered in the instance
+func (i *Instance) validateHook(hook Hook) error {
+	if !i.IsInState(Preparing, Committed, Started, Stopped) {
+		return ErrRegisteringHookNotAllowed.WithParams(i.state.String())
+	}
+	if hook == nil {
+		return ErrHookIsNil.WithParams(i.name)
+	}
+	return nil
+}
+
+// runStartHooks invokes the start hooks of the instance, stopping at the first error
+func (i *Instance) runStartHooks() error {
+	for _, hook := range i.startHooks {
+		if err := hook(i); err != nil {
+			return ErrRunningStartHook.WithParams(i.name).Wrap(err)
+		}
+	}
+	return nil
+}
+
+// runReadyHooks invokes the ready hooks of the instance, unless they were already invoked since it was started
+func (i *Instance) runReadyHooks() error {
+	if i.readyHooksDone {
+		return nil
+	}
+	// a failing hook is not retried by the next wait, as the previous hooks already ran
+	i.readyHooksDone = true
+	for _, hook := range i.readyHooks {
+		if err := hook(i); err != nil {
+			return ErrRunningReadyHook.WithParams(i.name).Wrap(err)
+		}
+	}
+	return nil
+}
+
+// runDestroyHooks invokes all the destroy hooks of the instance and aggregates their errors
+func (i *Instance) runDestroyHooks() error {
+	var errs []error
+	for _, hook := range i.destroyHooks {
+		if err := hook(i); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return ErrRunningDestroyHooks.WithParams(len(errs), i.name).Wrap(errors.Join(errs...))
+}
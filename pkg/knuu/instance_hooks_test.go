@@ -0,0 +1,64 @@
+package knuu
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHooks(t *testing.T) {
+	var events []string
+	record := func(event string, err error) Hook {
+		return func(i *Instance) error {
+			events = append(events, event+":"+i.state.String())
+			return err
+		}
+	}
+
+	i := newQuickTestInstance(t, "hooks")
+	require.NoError(t, i.OnStart(record("start", nil)))
+	require.NoError(t, i.OnReady(record("ready", nil)))
+	require.NoError(t, i.OnDestroy(record("destroy", errors.New("first"))))
+	require.NoError(t, i.OnDestroy(record("destroy", errors.New("second"))))
+	assert.ErrorIs(t, i.OnStart(nil), ErrHookIsNil)
+
+	useK8sClient(t, nil)
+	i.state = Stopped
+	require.NoError(t, i.StartWithoutWait())
+	assert.Equal(t, []string{"start:Started"}, events)
+
+	// the ready hooks run once per start
+	k8sClient, _ = newRateLimitedK8sClient(t, 0)
+	require.NoError(t, i.WaitInstanceIsRunning())
+	require.NoError(t, i.WaitInstanceIsRunning())
+	assert.Equal(t, []string{"start:Started", "ready:Started"}, events)
+
+	// all the destroy hooks run before the instance is destroyed, and it is destroyed anyway
+	k8sClient = newEchoK8sClient(t)
+	err := i.Destroy()
+	assert.ErrorIs(t, err, ErrRunningDestroyHooks)
+	assert.ErrorContains(t, err, "first")
+	assert.ErrorContains(t, err, "second")
+	assert.Equal(t, []string{"start:Started", "ready:Started", "destroy:Started", "destroy:Started"}, events)
+	assert.Equal(t, Destroyed, i.state)
+	assert.ErrorIs(t, i.OnDestroy(record("destroy", nil)), ErrRegisteringHookNotAllowed)
+
+	t.Run("Errors", func(t *testing.T) {
+		i := newQuickTestInstance(t, "hooks-errors")
+		require.NoError(t, i.OnStart(record("start", errors.New("start failed"))))
+		require.NoError(t, i.OnReady(record("ready", errors.New("not seeded"))))
+
+		k8sClient = newEchoK8sClient(t)
+		i.state = Stopped
+		err := i.StartWithoutWait()
+		assert.ErrorIs(t, err, ErrRunningStartHook)
+		assert.ErrorContains(t, err, "start failed")
+
+		k8sClient, _ = newRateLimitedK8sClient(t, 0)
+		err = i.WaitInstanceIsRunning()
+		assert.ErrorIs(t, err, ErrRunningReadyHook)
+		assert.ErrorContains(t, err, "not seeded")
+	})
+}
@@ -0,0 +1,34 @@
+package knuu
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/celestiaorg/knuu/pkg/registry"
+)
+
+// ImageInfo describes the image of an instance as stored in its registry: its compressed size,
+// the size of each layer and the configuration the image runs with
+type ImageInfo = registry.ImageInfo
+
+// InspectBuiltImage reads the manifest and the config of the image of the instance from its registry
+// after it was pushed, e.g. to assert that the image is under a size budget before running it:
+//
+//	info, err := instance.InspectBuiltImage(ctx)
+//	require.Less(t, info.Size, int64(200<<20))
+//
+// The sizes are the compressed sizes of the layers, i.e. the size to pull; for multi-platform images the first platform is inspected
+// This function can only be called in the states 'Committed', 'Started' and 'Stopped'
+func (i *Instance) InspectBuiltImage(ctx context.Context) (ImageInfo, error) {
+	if !i.IsInState(Committed, Started, Stopped) {
+		return ImageInfo{}, ErrInspectingImageNotAllowed.WithParams(i.state.String())
+	}
+
+	info, err := registry.InspectImage(ctx, i.imageName)
+	if err != nil {
+		return ImageInfo{}, ErrInspectingBuiltImage.WithParams(i.imageName, i.name).Wrap(err)
+	}
+	logrus.Debugf("Inspected image '%s' of instance '%s': %d bytes in %d layers", i.imageName, i.name, info.Size, len(info.LayerSizes))
+	return info, nil
+}
@@ -0,0 +1,50 @@
+package knuu
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspectBuiltImage(t *testing.T) {
+	i := newTestInstance(t, "inspect")
+	_, err := i.InspectBuiltImage(context.Background())
+	assert.ErrorIs(t, err, ErrInspectingImageNotAllowed)
+
+	// a registry serving a small image with two layers
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/knuu/manifests/built":
+			w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+			w.Header().Set("Docker-Content-Digest", "sha256:manifest")
+			fmt.Fprint(w, `{"schemaVersion":2,"config":{"digest":"sha256:config","size":100},`+
+				`"layers":[{"digest":"sha256:base","size":3500000},{"digest":"sha256:app","size":500000}]}`)
+		case "/v2/knuu/blobs/sha256:config":
+			fmt.Fprint(w, `{"config":{"Cmd":["sleep","infinity"]}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	i.imageName = host + "/knuu:built"
+	i.state = Committed
+	info, err := i.InspectBuiltImage(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:manifest", info.Digest)
+	assert.Equal(t, int64(4000000), info.Size)
+	assert.Equal(t, []int64{3500000, 500000}, info.LayerSizes)
+	assert.Equal(t, []string{"sleep", "infinity"}, info.Config.Cmd)
+	assert.Less(t, info.Size, int64(200<<20), "the image is under the size budget")
+
+	i.imageName = host + "/knuu:missing"
+	_, err = i.InspectBuiltImage(context.Background())
+	assert.ErrorIs(t, err, ErrInspectingBuiltImage)
+}
@@ -0,0 +1,48 @@
+package knuu
+
+import (
+	"context"
+	"io"
+
+	"github.com/celestiaorg/knuu/pkg/k8s"
+)
+
+// InteractiveOptions configures the streams attached to an interactive command
+type InteractiveOptions struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer // Ignored when TTY is set, as the terminal merges stderr into stdout
+	// TTY allocates a terminal for the command, like `kubectl exec -it`
+	// If Stdin is a local terminal (e.g. os.Stdin), it is put into raw mode and resizes are forwarded
+	TTY bool
+}
+
+// ExecuteInteractiveCommand runs the command in the instance and proxies the given streams until it exits
+// Unlike ExecuteCommand the command is not wrapped in a shell, so e.g. `sh` or a REPL can be run directly
+// This function can only be called in the state 'Started'
+func (i *Instance) ExecuteInteractiveCommand(ctx context.Context, opts InteractiveOptions, command ...string) error {
+	if !i.IsInState(Started) {
+		return ErrExecutingInteractiveCommandNotAllowed.WithParams(i.state.String())
+	}
+
+	instanceName := i.k8sName
+	if i.isSidecar {
+		instanceName = i.parentInstance.k8sName
+	}
+
+	pod, err := k8sClient.GetFirstPodFromReplicaSet(ctx, instanceName)
+	if err != nil {
+		return ErrGettingPodFromReplicaSet.WithParams(i.k8sName).Wrap(err)
+	}
+
+	err = k8sClient.RunInteractiveCommandInPod(ctx, pod.Name, i.k8sName, command, k8s.ExecStreams{
+		Stdin:  opts.Stdin,
+		Stdout: opts.Stdout,
+		Stderr: opts.Stderr,
+		TTY:    opts.TTY,
+	})
+	if err != nil {
+		return ErrExecutingCommandInInstance.WithParams(command, i.k8sName).Wrap(err)
+	}
+	return nil
+}
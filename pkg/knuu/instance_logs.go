@@ -0,0 +1,202 @@
+package knuu
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/celestiaorg/knuu/pkg/k8s"
+)
+
+// maxLogLineSize is the maximum size of a log line kept in the log buffer, longer lines are split
+const maxLogLineSize = 1024 * 1024
+
+// logRingBuffer keeps the last lines written to it
+type logRingBuffer struct {
+	mu        sync.Mutex
+	lines     []string
+	next      int  // index of the next line to overwrite once the buffer is full
+	full      bool // whether the buffer has wrapped around
+	capturing bool // whether the logs of a pod are currently being captured
+}
+
+func newLogRingBuffer(size int) *logRingBuffer {
+	return &logRingBuffer{lines: make([]string, size)}
+}
+
+// addLine adds a line, overwriting the oldest one if the buffer is full
+func (b *logRingBuffer) addLine(line string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lines[b.next] = line
+	b.next = (b.next + 1) % len(b.lines)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// readFrom adds all the lines read from r until it is closed
+func (b *logRingBuffer) readFrom(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLogLineSize)
+	for scanner.Scan() {
+		b.addLine(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// String returns the retained lines, oldest first
+func (b *logRingBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.full {
+		return strings.Join(b.lines[:b.next], "\n")
+	}
+	return strings.Join(append(b.lines[b.next:len(b.lines):len(b.lines)], b.lines[:b.next]...), "\n")
+}
+
+// SetLogBufferSize keeps the last lines of the logs of the instance in memory, so they can be dumped with DumpRecentLogs
+// e.g. when a test fails. The logs are captured once the instance is running, a size of 0 disables the buffer (default)
+// The lines are kept across restarts of the instance, so the buffer covers the logs of all the runs
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetLogBufferSize(lines int) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingLogBufferSizeNotAllowed.WithParams(i.state.String())
+	}
+	if lines < 0 {
+		return ErrInvalidLogBufferSize.WithParams(lines)
+	}
+	i.logBufferSize = lines
+	i.logBuffer = nil
+	if lines > 0 {
+		i.logBuffer = newLogRingBuffer(lines)
+	}
+	logrus.Debugf("Set log buffer size to '%d' lines in instance '%s'", lines, i.name)
+	return nil
+}
+
+// DumpRecentLogs returns the last lines of the logs of the instance, as configured with SetLogBufferSize
+// It returns an empty string if the log buffer is disabled
+func (i *Instance) DumpRecentLogs() string {
+	if i.logBuffer == nil {
+		return ""
+	}
+	return i.logBuffer.String()
+}
+
+// startLogCapture follows the logs of the running pod into the log buffer, unless they are already captured
+func (i *Instance) startLogCapture() {
+	if i.logBufferSize == 0 {
+		return
+	}
+	if i.logBuffer == nil {
+		// clones only copy the size of the buffer
+		i.logBuffer = newLogRingBuffer(i.logBufferSize)
+	}
+
+	buffer := i.logBuffer
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+	if buffer.capturing {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), i.operationTimeout())
+	defer cancel()
+	pod, err := k8sClient.GetFirstPodFromReplicaSet(ctx, i.k8sName)
+	if err != nil {
+		logrus.Warnf("Error getting pod to capture the logs of instance '%s': %v", i.name, err)
+		return
+	}
+	// the stream is not bound by a timeout, it ends when the container terminates
+	stream, err := k8sClient.StreamPodLogs(context.Background(), pod.Name, i.k8sName)
+	if err != nil {
+		logrus.Warnf("Error capturing the logs of instance '%s': %v", i.name, err)
+		return
+	}
+	buffer.capturing = true
+
+	go func() {
+		defer stream.Close()
+		if err := buffer.readFrom(stream); err != nil {
+			logrus.Debugf("Stopped capturing the logs of instance '%s': %v", i.name, err)
+		}
+		buffer.mu.Lock()
+		buffer.capturing = false
+		buffer.mu.Unlock()
+	}()
+}
+
+// CollectLogs writes the logs and the events of the pod of each instance to files in dir, which is created if needed,
+// e.g. to upload them as the artifacts of a failed test. The files are named by the unique name of the instance,
+// see GetUniqueName: `<name>.log` holds the logs of its container and `<name>.events` the events of its pod
+// It continues past the instances whose logs cannot be collected, e.g. the ones that are not started,
+// and returns an error listing all of them
+func CollectLogs(ctx context.Context, dir string, instances ...*Instance) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return ErrCreatingLogsDir.WithParams(dir).Wrap(err)
+	}
+
+	var (
+		names []string
+		errs  []error
+	)
+	for _, instance := range instances {
+		if err := instance.collectLogs(ctx, dir); err != nil {
+			names = append(names, instance.name)
+			// the message is formatted now, as the errors of the instances may share the same error value
+			errs = append(errs, fmt.Errorf("instance '%s': %w", instance.name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return ErrCollectingLogs.WithParams(strings.Join(names, "', '")).Wrap(errors.Join(errs...))
+	}
+	return nil
+}
+
+// collectLogs writes the logs of the container of the instance and the events of its pod to files in dir
+// The events are written even if the logs cannot be read, as they tell e.g. why the container never started
+func (i *Instance) collectLogs(ctx context.Context, dir string) error {
+	if !i.IsInState(Started) {
+		return ErrCollectingLogsNotAllowed.WithParams(i.state.String())
+	}
+	parent := i
+	if i.isSidecar {
+		parent = i.parentInstance
+	}
+	pod, err := k8sClient.GetFirstPodFromReplicaSet(ctx, parent.k8sName)
+	if err != nil {
+		return ErrGettingPodFromReplicaSet.WithParams(parent.k8sName).Wrap(err)
+	}
+
+	var errs []error
+	logs, err := k8sClient.GetPodLogs(ctx, pod.Name, i.k8sName)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, i.k8sName+".log"), logs, 0644)
+	}
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	events, err := k8sClient.ListPodEvents(ctx, pod.Name)
+	if err == nil {
+		var b strings.Builder
+		for _, event := range events {
+			fmt.Fprintf(&b, "%s\t%s\t%s\t%s\n", k8s.EventTime(event).UTC().Format(time.RFC3339), event.Type, event.Reason, event.Message)
+		}
+		err = os.WriteFile(filepath.Join(dir, i.k8sName+".events"), []byte(b.String()), 0644)
+	}
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
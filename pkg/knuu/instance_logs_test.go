@@ -0,0 +1,96 @@
+package knuu
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogBuffer(t *testing.T) {
+	i := newTestInstance(t, "log-buffer")
+	assert.Empty(t, i.DumpRecentLogs(), "the log buffer should be disabled by default")
+
+	require.NoError(t, i.SetLogBufferSize(3))
+	require.NoError(t, i.logBuffer.readFrom(strings.NewReader("line 1\nline 2\n")))
+	assert.Equal(t, "line 1\nline 2", i.DumpRecentLogs())
+
+	var logs strings.Builder
+	for n := 3; n <= 10; n++ {
+		fmt.Fprintf(&logs, "line %d\n", n)
+	}
+	require.NoError(t, i.logBuffer.readFrom(strings.NewReader(logs.String())))
+	assert.Equal(t, "line 8\nline 9\nline 10", i.DumpRecentLogs(), "only the last lines should be retained")
+
+	assert.ErrorIs(t, i.SetLogBufferSize(-1), ErrInvalidLogBufferSize)
+	i.state = Started
+	assert.ErrorIs(t, i.SetLogBufferSize(5), ErrSettingLogBufferSizeNotAllowed)
+}
+
+func TestCollectLogs(t *testing.T) {
+	first := newTestInstance(t, "collect-first")
+	second := newTestInstance(t, "collect-second")
+	notStarted := newTestInstance(t, "collect-not-started")
+	first.state, second.state = Started, Started
+
+	useK8sClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/apis/apps/v1/namespaces/test/replicasets/"):
+			name := path.Base(r.URL.Path)
+			fmt.Fprintf(w, `{"kind":"ReplicaSet","apiVersion":"apps/v1","metadata":{"name":%q},`+
+				`"spec":{"replicas":1,"selector":{"matchLabels":{"app":%q}}}}`, name, name)
+		case r.URL.Path == "/api/v1/namespaces/test/pods":
+			app := strings.TrimPrefix(r.URL.Query().Get("labelSelector"), "app=")
+			fmt.Fprintf(w, `{"kind":"PodList","apiVersion":"v1","items":[{"metadata":{"name":"%s-pod"}}]}`, app)
+		case strings.HasSuffix(r.URL.Path, "/log"):
+			if r.URL.Query().Get("previous") == "true" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"BadRequest","code":400}`)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain")
+			fmt.Fprintf(w, "hello from %s\n", r.URL.Query().Get("container"))
+		case strings.HasPrefix(r.URL.Path, "/api/v1/namespaces/test/pods/"):
+			fmt.Fprintf(w, `{"kind":"Pod","apiVersion":"v1","metadata":{"name":%q}}`, path.Base(r.URL.Path))
+		case r.URL.Path == "/api/v1/namespaces/test/events":
+			// the terms of the selector are in no particular order
+			var pod string
+			for _, term := range strings.Split(r.URL.Query().Get("fieldSelector"), ",") {
+				if name, ok := strings.CutPrefix(term, "involvedObject.name="); ok {
+					pod = name
+				}
+			}
+			fmt.Fprintf(w, `{"kind":"EventList","apiVersion":"v1","items":[`+
+				`{"metadata":{"name":"e2"},"type":"Warning","reason":"Unhealthy","message":"probe failed on %s","lastTimestamp":"2024-01-01T00:00:02Z"},`+
+				`{"metadata":{"name":"e1"},"type":"Normal","reason":"Started","message":"started %s","lastTimestamp":"2024-01-01T00:00:01Z"}]}`, pod, pod)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	dir := filepath.Join(t.TempDir(), "artifacts")
+	err := CollectLogs(context.Background(), dir, first, notStarted, second)
+	assert.ErrorIs(t, err, ErrCollectingLogs)
+	assert.ErrorContains(t, err, "collect-not-started")
+
+	// the logs of the other instances are collected despite the failure
+	for _, i := range []*Instance{first, second} {
+		logs, err := os.ReadFile(filepath.Join(dir, i.k8sName+".log"))
+		require.NoError(t, err)
+		assert.Equal(t, "hello from "+i.k8sName+"\n", string(logs))
+
+		events, err := os.ReadFile(filepath.Join(dir, i.k8sName+".events"))
+		require.NoError(t, err)
+		assert.Equal(t, "2024-01-01T00:00:01Z\tNormal\tStarted\tstarted "+i.k8sName+"-pod\n"+
+			"2024-01-01T00:00:02Z\tWarning\tUnhealthy\tprobe failed on "+i.k8sName+"-pod\n", string(events))
+	}
+	assert.NoFileExists(t, filepath.Join(dir, notStarted.k8sName+".log"))
+}
@@ -0,0 +1,44 @@
+package knuu
+
+import (
+	"context"
+	"slices"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ApplyManifest applies the resources of the YAML or JSON manifest alongside the instance, e.g. a ConfigMap, a Service
+// or a custom resource needed by a test, and deletes them when the instance is destroyed
+// The manifest may hold several resources separated by `---`, which get the labels of the instance unless they set them,
+// and the namespaced resources without a namespace are applied in the namespace of knuu
+// The resources are applied with server-side apply, so applying a manifest again updates its resources
+// If a resource fails to apply, the resources applied before it are still deleted with the instance
+// This function can only be called in the states 'Preparing', 'Committed', 'Started' and 'Stopped'
+func (i *Instance) ApplyManifest(ctx context.Context, yaml []byte) error {
+	if !i.IsInState(Preparing, Committed, Started, Stopped) {
+		return ErrApplyingManifestNotAllowed.WithParams(i.state.String())
+	}
+
+	applied, err := k8sClient.ApplyManifest(ctx, yaml, i.getLabels())
+	for _, resource := range applied {
+		if !slices.Contains(i.manifestResources, resource) {
+			i.manifestResources = append(i.manifestResources, resource)
+		}
+		logrus.Debugf("Applied %s for instance '%s'", resource, i.name)
+	}
+	if err != nil {
+		return ErrApplyingManifest.WithParams(i.name).Wrap(err)
+	}
+	return nil
+}
+
+// destroyManifestResources deletes the resources applied with ApplyManifest, in the reverse order they were applied
+// They are kept track of once deleted, so WaitForDeletion waits for them as well
+func (i *Instance) destroyManifestResources(ctx context.Context) error {
+	for idx := len(i.manifestResources) - 1; idx >= 0; idx-- {
+		if err := k8sClient.DeleteManifestResource(ctx, i.manifestResources[idx]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package knuu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyManifest(t *testing.T) {
+	var (
+		mu         sync.Mutex
+		configMaps = map[string]map[string]interface{}{}
+	)
+	useK8sClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		name, isConfigMap := strings.CutPrefix(r.URL.Path, "/api/v1/namespaces/test/configmaps/")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1":
+			fmt.Fprint(w, `{"kind":"APIResourceList","groupVersion":"v1","resources":[`+
+				`{"name":"configmaps","namespaced":true,"kind":"ConfigMap","verbs":["get","patch","delete"]}]}`)
+		case isConfigMap && r.Method == http.MethodPatch:
+			assert.Equal(t, "application/apply-patch+yaml", r.Header.Get("Content-Type"))
+			assert.Equal(t, "knuu", r.URL.Query().Get("fieldManager"))
+			var object map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&object))
+			configMaps[name] = object
+			_ = json.NewEncoder(w).Encode(object)
+		case isConfigMap && r.Method == http.MethodGet && configMaps[name] != nil:
+			_ = json.NewEncoder(w).Encode(configMaps[name])
+		case isConfigMap && r.Method == http.MethodDelete:
+			delete(configMaps, name)
+			fmt.Fprint(w, `{"kind":"Status","apiVersion":"v1","status":"Success"}`)
+		default:
+			echoK8sHandler(w, r)
+		}
+	})
+
+	const manifest = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  labels:
+    app: custom
+data:
+  level: debug
+`
+	ctx := context.Background()
+	i := newQuickTestInstance(t, "manifest")
+	i.state = Started
+	require.NoError(t, i.ApplyManifest(ctx, []byte(manifest)))
+	require.NoError(t, i.ApplyManifest(ctx, []byte(manifest)), "applying a manifest again should update its resources")
+	assert.Len(t, i.manifestResources, 1)
+
+	mu.Lock()
+	require.Contains(t, configMaps, "settings")
+	metadata := configMaps["settings"]["metadata"].(map[string]interface{})
+	assert.Equal(t, "test", metadata["namespace"])
+	labels := metadata["labels"].(map[string]interface{})
+	assert.Equal(t, "custom", labels["app"], "the labels of the manifest should be kept")
+	assert.Equal(t, i.k8sName, labels["knuu.sh/k8s-name"])
+	mu.Unlock()
+
+	err := i.ApplyManifest(ctx, []byte("apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: widget\n"))
+	assert.ErrorIs(t, err, ErrApplyingManifest)
+	assert.ErrorContains(t, err, "kind Widget is not served")
+
+	// the applied resources are deleted along with the instance
+	require.NoError(t, i.Destroy())
+	mu.Lock()
+	assert.Empty(t, configMaps)
+	mu.Unlock()
+
+	t.Run("NeverStarted", func(t *testing.T) {
+		i := newTestInstance(t, "manifest-committed")
+		require.NoError(t, i.SetOperationTimeout(5*time.Second))
+		i.state = Committed
+		require.NoError(t, i.ApplyManifest(ctx, []byte(manifest)))
+		require.NoError(t, i.Destroy())
+		mu.Lock()
+		assert.Empty(t, configMaps)
+		mu.Unlock()
+		assert.ErrorIs(t, i.ApplyManifest(ctx, []byte(manifest)), ErrApplyingManifestNotAllowed)
+	})
+}
@@ -0,0 +1,81 @@
+package knuu
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// ServiceMesh is a service mesh that injects its proxy as a sidecar into the pods of the meshed namespaces
+type ServiceMesh string
+
+const (
+	// ServiceMeshIstio controls the injection with the `sidecar.istio.io/inject: "true"|"false"` annotation
+	ServiceMeshIstio ServiceMesh = "istio"
+	// ServiceMeshLinkerd controls the injection with the `linkerd.io/inject: enabled|disabled` annotation
+	ServiceMeshLinkerd ServiceMesh = "linkerd"
+
+	// DefaultServiceMesh is the mesh whose annotation is set by EnableMeshInjection if SetServiceMesh is not called
+	DefaultServiceMesh = ServiceMeshIstio
+)
+
+// IsValid returns true if the service mesh is supported
+func (m ServiceMesh) IsValid() bool {
+	return m == ServiceMeshIstio || m == ServiceMeshLinkerd
+}
+
+// injectionAnnotation returns the annotation of the pod enabling or disabling the injection of the sidecar of the mesh
+func (m ServiceMesh) injectionAnnotation(enabled bool) (string, string) {
+	switch m {
+	case ServiceMeshLinkerd:
+		if enabled {
+			return "linkerd.io/inject", "enabled"
+		}
+		return "linkerd.io/inject", "disabled"
+	default:
+		if enabled {
+			return "sidecar.istio.io/inject", "true"
+		}
+		return "sidecar.istio.io/inject", "false"
+	}
+}
+
+// SetServiceMesh sets the service mesh of the cluster, whose annotation is set by EnableMeshInjection
+// The supported meshes are ServiceMeshIstio, the default, and ServiceMeshLinkerd
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetServiceMesh(mesh ServiceMesh) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingServiceMeshNotAllowed.WithParams(i.state.String())
+	}
+	if !mesh.IsValid() {
+		return ErrInvalidServiceMesh.WithParams(mesh)
+	}
+	i.serviceMesh = mesh
+	logrus.Debugf("Set service mesh '%s' in instance '%s'", mesh, i.name)
+	return nil
+}
+
+// EnableMeshInjection sets whether the service mesh injects its proxy as a sidecar into the pod of the instance,
+// overriding the setting of the namespace, so the injected proxy does not interfere with tests that do not expect it,
+// e.g. by intercepting the traffic or by keeping the pod from terminating. By default the setting of the namespace is used
+// The annotation depends on the service mesh, see SetServiceMesh
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) EnableMeshInjection(enabled bool) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrEnablingMeshInjectionNotAllowed.WithParams(i.state.String())
+	}
+	i.meshInjection = &enabled
+	logrus.Debugf("Set service mesh injection to '%t' in instance '%s'", enabled, i.name)
+	return nil
+}
+
+// podAnnotations returns the annotations of the pod of the instance, nil if there are none
+func (i *Instance) podAnnotations() map[string]string {
+	if i.meshInjection == nil {
+		return nil
+	}
+	mesh := i.serviceMesh
+	if mesh == "" {
+		mesh = DefaultServiceMesh
+	}
+	key, value := mesh.injectionAnnotation(*i.meshInjection)
+	return map[string]string{key: value}
+}
@@ -0,0 +1,31 @@
+package knuu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/k8s"
+)
+
+func TestEnableMeshInjection(t *testing.T) {
+	lockK8sClient(t)
+	k8sClient = &k8s.Client{}
+
+	i := newTestInstance(t, "mesh")
+	assert.Nil(t, i.prepareReplicaSetConfig().PodConfig.Annotations, "the setting of the namespace is used by default")
+
+	require.NoError(t, i.EnableMeshInjection(false))
+	assert.Equal(t, map[string]string{"sidecar.istio.io/inject": "false"}, i.prepareReplicaSetConfig().PodConfig.Annotations)
+
+	assert.ErrorIs(t, i.SetServiceMesh("consul"), ErrInvalidServiceMesh)
+	require.NoError(t, i.SetServiceMesh(ServiceMeshLinkerd))
+	assert.Equal(t, map[string]string{"linkerd.io/inject": "disabled"}, i.prepareReplicaSetConfig().PodConfig.Annotations)
+	require.NoError(t, i.EnableMeshInjection(true))
+	assert.Equal(t, map[string]string{"linkerd.io/inject": "enabled"}, i.prepareReplicaSetConfig().PodConfig.Annotations)
+
+	i.state = Started
+	assert.ErrorIs(t, i.EnableMeshInjection(false), ErrEnablingMeshInjectionNotAllowed)
+	assert.ErrorIs(t, i.SetServiceMesh(ServiceMeshIstio), ErrSettingServiceMeshNotAllowed)
+}
@@ -0,0 +1,53 @@
+package knuu
+
+import (
+	"context"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ResourceUsage is the resource usage of all the containers of an instance
+type ResourceUsage struct {
+	// CPU is the CPU usage, e.g. 250m is a quarter of a core
+	CPU resource.Quantity
+	// Memory is the memory working set, e.g. 64Mi
+	Memory resource.Quantity
+	// Timestamp is the time at which the usage was collected
+	Timestamp time.Time
+	// Window is the time window over which the CPU usage was calculated
+	Window time.Duration
+}
+
+// GetMetrics returns the CPU and memory usage of the instance
+// The metrics are read from the metrics API, so metrics-server must be installed in the cluster, otherwise k8s.ErrMetricsServerNotInstalled is returned
+// This function can only be called in the state 'Started'
+func (i *Instance) GetMetrics(ctx context.Context) (ResourceUsage, error) {
+	if !i.IsInState(Started) {
+		return ResourceUsage{}, ErrGettingMetricsNotAllowed.WithParams(i.state.String())
+	}
+	pod, err := k8sClient.GetFirstPodFromReplicaSet(ctx, i.k8sName)
+	if err != nil {
+		return ResourceUsage{}, ErrGettingPodFromReplicaSet.WithParams(i.k8sName).Wrap(err)
+	}
+
+	metrics, err := k8sClient.GetPodMetrics(ctx, pod.Name)
+	if err != nil {
+		return ResourceUsage{}, err
+	}
+
+	usage := ResourceUsage{
+		Timestamp: metrics.Timestamp.Time,
+		Window:    metrics.Window.Duration,
+	}
+	for _, c := range metrics.Containers {
+		if cpu, ok := c.Usage[v1.ResourceCPU]; ok {
+			usage.CPU.Add(cpu)
+		}
+		if memory, ok := c.Usage[v1.ResourceMemory]; ok {
+			usage.Memory.Add(memory)
+		}
+	}
+	return usage, nil
+}
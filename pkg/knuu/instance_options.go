@@ -0,0 +1,104 @@
+package knuu
+
+// InstanceOption configures an instance when it is created with NewInstance
+// Options are applied in the given order using the setters of the instance,
+// so WithImage must come before options that require the state 'Preparing'
+type InstanceOption func(*Instance) error
+
+// WithImage sets the image of the instance
+func WithImage(image string) InstanceOption {
+	return func(i *Instance) error {
+		return i.SetImage(image)
+	}
+}
+
+// WithCommand sets the command to run in the instance
+func WithCommand(command ...string) InstanceOption {
+	return func(i *Instance) error {
+		return i.SetCommand(command...)
+	}
+}
+
+// WithArgs sets the arguments passed to the instance
+func WithArgs(args ...string) InstanceOption {
+	return func(i *Instance) error {
+		return i.SetArgs(args...)
+	}
+}
+
+// WithPortTCP adds a TCP port to the instance
+func WithPortTCP(port int) InstanceOption {
+	return func(i *Instance) error {
+		return i.AddPortTCP(port)
+	}
+}
+
+// WithPortUDP adds a UDP port to the instance
+func WithPortUDP(port int) InstanceOption {
+	return func(i *Instance) error {
+		return i.AddPortUDP(port)
+	}
+}
+
+// WithEnv sets an environment variable in the instance
+func WithEnv(key, value string) InstanceOption {
+	return func(i *Instance) error {
+		return i.SetEnvironmentVariable(key, value)
+	}
+}
+
+// WithFile adds a file to the instance
+func WithFile(src, dest, chown string) InstanceOption {
+	return func(i *Instance) error {
+		return i.AddFile(src, dest, chown)
+	}
+}
+
+// WithFolder adds a folder to the instance
+func WithFolder(src, dest, chown string) InstanceOption {
+	return func(i *Instance) error {
+		return i.AddFolder(src, dest, chown)
+	}
+}
+
+// WithUser sets the user of the instance
+func WithUser(user string) InstanceOption {
+	return func(i *Instance) error {
+		return i.SetUser(user)
+	}
+}
+
+// WithVolume adds a volume to the instance
+func WithVolume(path, size string, owner int64) InstanceOption {
+	return func(i *Instance) error {
+		return i.AddVolumeWithOwner(path, size, owner)
+	}
+}
+
+// WithMemory sets the memory request and limit of the instance
+func WithMemory(request, limit string) InstanceOption {
+	return func(i *Instance) error {
+		return i.SetMemory(request, limit)
+	}
+}
+
+// WithCPU sets the CPU request of the instance
+func WithCPU(request string) InstanceOption {
+	return func(i *Instance) error {
+		return i.SetCPU(request)
+	}
+}
+
+// WithCapabilities adds capabilities to the instance
+func WithCapabilities(capabilities ...string) InstanceOption {
+	return func(i *Instance) error {
+		return i.AddCapabilities(capabilities)
+	}
+}
+
+// WithPrivileged sets the privileged status of the instance
+func WithPrivileged(privileged bool) InstanceOption {
+	return func(i *Instance) error {
+		return i.SetPrivileged(privileged)
+	}
+}
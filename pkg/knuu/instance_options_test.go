@@ -0,0 +1,40 @@
+package knuu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewInstanceWithOptions(t *testing.T) {
+	i, err := NewInstance("options",
+		WithImage("docker.io/nginx:latest"),
+		WithCommand("nginx", "-g", "daemon off;"),
+		WithPortTCP(80),
+		WithCPU("100m"),
+		WithMemory("64Mi", "128Mi"),
+		WithCapabilities("NET_ADMIN"),
+		WithEnv("FOO", "bar"),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, Preparing, i.state)
+	assert.Equal(t, "docker.io/nginx:latest", i.builderFactory.ImageNameFrom())
+	assert.Equal(t, []string{"nginx", "-g", "daemon off;"}, i.command)
+	assert.Equal(t, []int{80}, i.portsTCP)
+	assert.Equal(t, "100m", i.cpuRequest)
+	assert.Equal(t, "64Mi", i.memoryRequest)
+	assert.Equal(t, "128Mi", i.memoryLimit)
+	assert.Equal(t, []string{"NET_ADMIN"}, i.securityContext.capabilitiesAdd)
+	assert.True(t, i.builderFactory.Changed(), "env in state 'Preparing' should be added to the image")
+}
+
+func TestNewInstanceWithInvalidOptions(t *testing.T) {
+	_, err := NewInstance("options", WithCPU("100m"))
+	assert.ErrorIs(t, err, ErrApplyingInstanceOption, "setting CPU requires an image")
+
+	_, err = NewInstance("options", WithImage("docker.io/nginx:latest"), WithPortTCP(70000))
+	assert.ErrorIs(t, err, ErrApplyingInstanceOption)
+	assert.ErrorContains(t, err, ErrPortNumberOutOfRange.Error())
+}
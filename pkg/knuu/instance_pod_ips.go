@@ -0,0 +1,56 @@
+package knuu
+
+import (
+	"context"
+	"net"
+)
+
+// GetPodIPs returns all the IPs assigned to the pod of the instance, e.g. an IPv4 and an IPv6 address on a dual-stack
+// cluster, with the primary IP first, unlike GetIP that returns the IP of the service of the instance
+// On a single-stack cluster, only the primary IP is returned
+// This function can only be called in the state 'Started'
+func (i *Instance) GetPodIPs() ([]string, error) {
+	if !i.IsInState(Started) {
+		return nil, ErrGettingPodIPsNotAllowed.WithParams(i.state.String())
+	}
+
+	instanceName := i.k8sName
+	if i.isSidecar {
+		instanceName = i.parentInstance.k8sName
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), i.operationTimeout())
+	defer cancel()
+	pod, err := k8sClient.GetFirstPodFromReplicaSet(ctx, instanceName)
+	if err != nil {
+		return nil, ErrGettingPodFromReplicaSet.WithParams(i.k8sName).Wrap(err)
+	}
+	ips := make([]string, 0, len(pod.Status.PodIPs))
+	for _, podIP := range pod.Status.PodIPs {
+		ips = append(ips, podIP.IP)
+	}
+	// older clusters only set the primary IP
+	if len(ips) == 0 && pod.Status.PodIP != "" {
+		ips = append(ips, pod.Status.PodIP)
+	}
+	if len(ips) == 0 {
+		return nil, ErrPodHasNoIP.WithParams(pod.Name, i.name)
+	}
+	return ips, nil
+}
+
+// GetIPv6 returns the IPv6 address of the pod of the instance, see GetPodIPs
+// It returns ErrNoIPv6Address if the pod has none, e.g. on an IPv4 single-stack cluster
+// This function can only be called in the state 'Started'
+func (i *Instance) GetIPv6() (string, error) {
+	ips, err := i.GetPodIPs()
+	if err != nil {
+		return "", err
+	}
+	for _, ip := range ips {
+		if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+			return ip, nil
+		}
+	}
+	return "", ErrNoIPv6Address.WithParams(i.name, ips)
+}
@@ -0,0 +1,65 @@
+package knuu
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPodIPs(t *testing.T) {
+	lockK8sClient(t)
+
+	newInstance := func(t *testing.T, status string) *Instance {
+		k8sClient = newTestK8sClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch {
+			case strings.HasPrefix(r.URL.Path, "/apis/apps/v1/namespaces/test/replicasets/"):
+				fmt.Fprint(w, `{"kind":"ReplicaSet","apiVersion":"apps/v1","metadata":{"name":"ips"},`+
+					`"spec":{"replicas":1,"selector":{"matchLabels":{"app":"ips"}}}}`)
+			case r.URL.Path == "/api/v1/namespaces/test/pods":
+				fmt.Fprint(w, `{"kind":"PodList","apiVersion":"v1","items":[{"metadata":{"name":"ips-pod"}}]}`)
+			case r.URL.Path == "/api/v1/namespaces/test/pods/ips-pod":
+				fmt.Fprintf(w, `{"kind":"Pod","apiVersion":"v1","metadata":{"name":"ips-pod"},"status":%s}`, status)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		})
+		i := newTestInstance(t, "ips")
+		i.k8sName = "ips"
+		require.NoError(t, i.SetOperationTimeout(5*time.Second))
+		i.state = Started
+		return i
+	}
+
+	t.Run("DualStack", func(t *testing.T) {
+		i := newInstance(t, `{"podIP":"10.0.0.5","podIPs":[{"ip":"10.0.0.5"},{"ip":"fd00::5"}]}`)
+		ips, err := i.GetPodIPs()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"10.0.0.5", "fd00::5"}, ips)
+		ipv6, err := i.GetIPv6()
+		require.NoError(t, err)
+		assert.Equal(t, "fd00::5", ipv6)
+	})
+
+	t.Run("SingleStack", func(t *testing.T) {
+		i := newInstance(t, `{"podIP":"10.0.0.5"}`)
+		ips, err := i.GetPodIPs()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"10.0.0.5"}, ips, "the primary IP should be returned without the list of IPs")
+		_, err = i.GetIPv6()
+		assert.ErrorIs(t, err, ErrNoIPv6Address)
+	})
+
+	t.Run("NotAssigned", func(t *testing.T) {
+		_, err := newInstance(t, `{}`).GetPodIPs()
+		assert.ErrorIs(t, err, ErrPodHasNoIP)
+	})
+
+	_, err := newTestInstance(t, "ips").GetPodIPs()
+	assert.ErrorIs(t, err, ErrGettingPodIPsNotAllowed)
+}
@@ -0,0 +1,112 @@
+package knuu
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// waitForPortInterval is the interval between the checks of WaitForPort
+	waitForPortInterval = 500 * time.Millisecond
+	// tcpStateListen is the state of a listening socket in /proc/net/tcp
+	tcpStateListen = "0A"
+)
+
+// AddPort adds a TCP or UDP port with a name, e.g. "http", "metrics" or "grpc", so tests can address it with GetPort
+// The protocol is "tcp" or "udp", and the port is exposed by the service of the instance
+// like the ports added with AddPortTCP and AddPortUDP, so GetIP still returns the address of all the ports
+// The name must be a valid port name in kubernetes, i.e. at most 15 lowercase alphanumeric characters or '-'
+// This function can be called in the states 'Preparing' and 'Committed'
+func (i *Instance) AddPort(name string, port int, proto string) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrAddingPortNotAllowed.WithParams(i.state.String())
+	}
+	if errs := validation.IsValidPortName(name); len(errs) > 0 {
+		return ErrInvalidPortName.WithParams(name, strings.Join(errs, ", "))
+	}
+	if _, ok := i.namedPorts[name]; ok {
+		return ErrPortNameAlreadyRegistered.WithParams(name)
+	}
+
+	var err error
+	switch strings.ToLower(proto) {
+	case "tcp":
+		err = i.AddPortTCP(port)
+	case "udp":
+		err = i.AddPortUDP(port)
+	default:
+		return ErrInvalidPortProtocol.WithParams(proto)
+	}
+	if err != nil {
+		return err
+	}
+	i.namedPorts[name] = port
+	logrus.Debugf("Named port '%d' '%s' in instance '%s'", port, name, i.name)
+	return nil
+}
+
+// GetPort returns the port added with the given name with AddPort
+func (i *Instance) GetPort(name string) (int, error) {
+	port, ok := i.namedPorts[name]
+	if !ok {
+		return 0, ErrPortNameNotRegistered.WithParams(name, i.name)
+	}
+	return port, nil
+}
+
+// WaitForPort waits until a process of the instance listens on the given TCP port, or the context expires
+// This is more reliable than a fixed sleep after StartAsync, as apps usually open their listening socket once they are ready
+// The sockets of the pod are read from /proc/net/tcp inside the instance, so the port does not need to be registered
+// and the pod network does not need to be reachable from the host running the tests; the image must provide `sh` and `cat`
+// A port nobody listens on yet, which would refuse connections, and a pod that is not running yet are retried
+// This function can only be called in the state 'Started'
+func (i *Instance) WaitForPort(ctx context.Context, port int) error {
+	if !i.IsInState(Started) {
+		return ErrWaitingForPortNotAllowed.WithParams(i.state.String())
+	}
+	if err := validatePort(port); err != nil {
+		return err
+	}
+
+	tick := time.NewTicker(i.pollIntervalOr(waitForPortInterval))
+	defer tick.Stop()
+
+	for {
+		// a missing tcp6 file, e.g. with IPv6 disabled, must not fail the check
+		sockets, err := i.ExecuteCommandWithContext(ctx, "sh", "-c", "cat /proc/net/tcp /proc/net/tcp6 2>/dev/null; true")
+		if err == nil && isTCPPortListening(sockets, port) {
+			logrus.Debugf("Port '%d' is open in instance '%s'", port, i.name)
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if err == nil {
+				err = ctx.Err()
+			}
+			return ErrWaitingForPortTimeout.WithParams(port, i.name).Wrap(err)
+		case <-tick.C:
+		}
+	}
+}
+
+// isTCPPortListening returns true if the sockets, in the format of /proc/net/tcp, contain a socket listening on the port
+// Each line describes a socket, e.g. `0: 00000000:0050 00000000:0000 0A ...` is listening on port 80
+func isTCPPortListening(sockets string, port int) bool {
+	localPort := fmt.Sprintf(":%04X", port)
+	for _, line := range strings.Split(sockets, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		if strings.HasSuffix(fields[1], localPort) && fields[3] == tcpStateListen {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package knuu
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsTCPPortListening(t *testing.T) {
+	sockets := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0050 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:1F90 0100007F:A1B2 01 00000000:00000000 00:00000000 00000000     0        0 2 1 0000000000000000 20 4 30 10 -1
+  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000000000000:01BB 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 3 1 0000000000000000 100 0 0 10 0
+`
+	assert.True(t, isTCPPortListening(sockets, 80))
+	assert.True(t, isTCPPortListening(sockets, 443), "IPv6 sockets should be considered")
+	assert.False(t, isTCPPortListening(sockets, 8080), "established connections are not listening")
+	assert.False(t, isTCPPortListening(sockets, 8))
+	assert.False(t, isTCPPortListening("", 80))
+
+	i := newTestInstance(t, "wait-for-port")
+	assert.ErrorIs(t, i.WaitForPort(context.Background(), 80), ErrWaitingForPortNotAllowed)
+}
+
+func TestAddPort(t *testing.T) {
+	useK8sClient(t, nil)
+
+	i := newTestInstance(t, "named-ports")
+	require.NoError(t, i.AddPort("http", 8080, "tcp"))
+	require.NoError(t, i.AddPort("metrics", 9090, "TCP"))
+	require.NoError(t, i.AddPort("discovery", 30303, "udp"))
+	assert.ErrorIs(t, i.AddPort("http", 8081, "tcp"), ErrPortNameAlreadyRegistered)
+	assert.ErrorIs(t, i.AddPort("Admin_Port", 8082, "tcp"), ErrInvalidPortName)
+	assert.ErrorIs(t, i.AddPort("admin", 8082, "sctp"), ErrInvalidPortProtocol)
+	assert.ErrorIs(t, i.AddPort("admin", 8080, "tcp"), ErrPortAlreadyRegistered)
+	_, err := i.GetPort("admin")
+	assert.ErrorIs(t, err, ErrPortNameNotRegistered, "the failed ports are not registered")
+
+	for name, want := range map[string]int{"http": 8080, "metrics": 9090, "discovery": 30303} {
+		port, err := i.GetPort(name)
+		require.NoError(t, err)
+		assert.Equal(t, want, port, name)
+	}
+
+	// the service exposes all the named ports
+	require.NoError(t, i.deployService(context.Background(), i.portsTCP, i.portsUDP))
+	var ports []string
+	for _, port := range i.kubernetesService.Spec.Ports {
+		ports = append(ports, fmt.Sprintf("%s/%d", port.Protocol, port.Port))
+	}
+	assert.Equal(t, []string{"TCP/8080", "TCP/9090", "UDP/30303"}, ports)
+
+	port, err := i.cloneWithSuffix("-clone").GetPort("metrics")
+	require.NoError(t, err)
+	assert.Equal(t, 9090, port)
+}
@@ -0,0 +1,26 @@
+package knuu
+
+import (
+	"slices"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SetPostStartCommand sets the command executed in the container of the instance right after it is created,
+// e.g. to register the instance with a discovery service, as the postStart hook of the container
+// Kubernetes runs it alongside the command of the instance, so it may run before the app is listening,
+// but the container is only reported running, and its probes only start, once the hook has completed,
+// so WaitInstanceIsRunning and the hooks registered with OnReady run after it
+// If the command fails, the container is killed and restarted like after a failure of the app
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetPostStartCommand(command []string) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingPostStartCommandNotAllowed.WithParams(i.state.String())
+	}
+	if len(command) == 0 {
+		return ErrEmptyPostStartCommand.WithParams(i.name)
+	}
+	i.postStartCommand = slices.Clone(command)
+	logrus.Debugf("Set post start command to '%s' in instance '%s'", command, i.name)
+	return nil
+}
@@ -0,0 +1,86 @@
+package knuu
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// pprofProfiles are the profiles served by net/http/pprof, cpu is served as `profile`
+var pprofProfiles = map[string]string{
+	"cpu":          "profile",
+	"heap":         "heap",
+	"allocs":       "allocs",
+	"goroutine":    "goroutine",
+	"block":        "block",
+	"mutex":        "mutex",
+	"threadcreate": "threadcreate",
+}
+
+// FetchPprofProfile fetches a Go pprof profile, e.g. cpu, heap or goroutine, from the /debug/pprof endpoint
+// the instance serves on the given port with net/http/pprof, and returns the raw profile, which can be opened
+// with `go tool pprof`. The port must be added with AddPortTCP, as it is forwarded to the host.
+// The cpu profile is recorded for the given duration, which must be at least 1 second; for the other profiles,
+// a duration of 0 returns a snapshot and a positive duration returns the difference over that duration
+// This function can only be called in the state 'Started'
+func (i *Instance) FetchPprofProfile(ctx context.Context, profile string, port int, d time.Duration) ([]byte, error) {
+	if !i.IsInState(Started) {
+		return nil, ErrFetchingPprofProfileNotAllowed.WithParams(i.state.String())
+	}
+	if err := validatePprofProfile(profile, d); err != nil {
+		return nil, err
+	}
+
+	localPort, err := i.PortForwardTCP(port)
+	if err != nil {
+		return nil, ErrFetchingPprofProfile.WithParams(profile, i.name).Wrap(err)
+	}
+	data, err := fetchPprofProfile(ctx, fmt.Sprintf("http://localhost:%d", localPort), profile, d)
+	if err != nil {
+		return nil, ErrFetchingPprofProfile.WithParams(profile, i.name).Wrap(err)
+	}
+	logrus.Debugf("Fetched pprof profile '%s' of %d bytes from instance '%s'", profile, len(data), i.name)
+	return data, nil
+}
+
+// validatePprofProfile checks that the profile is served by net/http/pprof and the duration is valid for it
+func validatePprofProfile(profile string, d time.Duration) error {
+	if _, ok := pprofProfiles[profile]; !ok {
+		return ErrInvalidPprofProfile.WithParams(profile)
+	}
+	if d < 0 || (profile == "cpu" && d < time.Second) {
+		return ErrInvalidPprofDuration.WithParams(d, profile)
+	}
+	return nil
+}
+
+// fetchPprofProfile fetches the profile from the net/http/pprof endpoint of the server at baseURL
+func fetchPprofProfile(ctx context.Context, baseURL, profile string, d time.Duration) ([]byte, error) {
+	url := baseURL + "/debug/pprof/" + pprofProfiles[profile]
+	if seconds := int(d.Seconds()); seconds > 0 {
+		url += fmt.Sprintf("?seconds=%d", seconds)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, ErrUnexpectedPprofStatus.WithParams(resp.StatusCode, url, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
@@ -0,0 +1,52 @@
+package knuu
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/pprof"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchPprofProfile(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	for _, tc := range []struct {
+		profile  string
+		duration time.Duration
+	}{
+		{"heap", 0},
+		{"goroutine", 0},
+		{"allocs", time.Second},
+		{"cpu", time.Second},
+	} {
+		t.Run(tc.profile, func(t *testing.T) {
+			data, err := fetchPprofProfile(context.Background(), server.URL, tc.profile, tc.duration)
+			require.NoError(t, err)
+			// the profiles are gzipped protocol buffers
+			reader, err := gzip.NewReader(bytes.NewReader(data))
+			require.NoError(t, err)
+			_, err = io.ReadAll(reader)
+			require.NoError(t, err)
+		})
+	}
+
+	assert.ErrorIs(t, validatePprofProfile("trace", time.Second), ErrInvalidPprofProfile)
+	assert.ErrorIs(t, validatePprofProfile("cpu", 0), ErrInvalidPprofDuration)
+	assert.ErrorIs(t, validatePprofProfile("heap", -time.Second), ErrInvalidPprofDuration)
+	assert.NoError(t, validatePprofProfile("heap", 0))
+
+	_, err := fetchPprofProfile(context.Background(), server.URL+"/missing", "heap", 0)
+	assert.ErrorIs(t, err, ErrUnexpectedPprofStatus)
+}
@@ -0,0 +1,84 @@
+package knuu
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// SetLivenessHTTPHeaders sets the headers sent by the HTTP liveness probe of the instance, e.g. an Authorization header
+// for a secured health endpoint. The headers replace the ones of the probe set with SetLivenessProbe,
+// so they must be set after it, and are sent in the order of their names
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetLivenessHTTPHeaders(headers map[string]string) error {
+	probe, err := i.probeWithHTTPHeaders("liveness", i.livenessProbe, headers)
+	if err != nil {
+		return err
+	}
+	i.livenessProbe = probe
+	logrus.Debugf("Set %d HTTP headers of the liveness probe in instance '%s'", len(headers), i.name)
+	return nil
+}
+
+// SetReadinessHTTPHeaders sets the headers sent by the HTTP readiness probe of the instance, e.g. an Authorization header
+// for a secured health endpoint. The headers replace the ones of the probe set with SetReadinessProbe,
+// so they must be set after it, and are sent in the order of their names
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetReadinessHTTPHeaders(headers map[string]string) error {
+	probe, err := i.probeWithHTTPHeaders("readiness", i.readinessProbe, headers)
+	if err != nil {
+		return err
+	}
+	i.readinessProbe = probe
+	logrus.Debugf("Set %d HTTP headers of the readiness probe in instance '%s'", len(headers), i.name)
+	return nil
+}
+
+// probeWithHTTPHeaders returns a copy of the HTTP probe with the given headers,
+// so the probe given to the setter by the caller is not modified
+func (i *Instance) probeWithHTTPHeaders(kind string, probe *v1.Probe, headers map[string]string) (*v1.Probe, error) {
+	if err := i.checkStateForProbe(); err != nil {
+		return nil, err
+	}
+	if probe == nil || probe.HTTPGet == nil {
+		return nil, ErrProbeNotHTTP.WithParams(kind, i.name)
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	httpHeaders := make([]v1.HTTPHeader, 0, len(names))
+	for _, name := range names {
+		httpHeaders = append(httpHeaders, v1.HTTPHeader{Name: name, Value: headers[name]})
+	}
+	if err := validateProbeHeaders(httpHeaders); err != nil {
+		return nil, err
+	}
+
+	probe = probe.DeepCopy()
+	probe.HTTPGet.HTTPHeaders = httpHeaders
+	return probe, nil
+}
+
+// validateProbeHeaders returns an error if the name of a header of an HTTP probe is invalid
+func validateProbeHeaders(headers []v1.HTTPHeader) error {
+	for _, header := range headers {
+		if errs := validation.IsHTTPHeaderName(header.Name); len(errs) != 0 {
+			return ErrInvalidProbeHeader.WithParams(header.Name, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+// validateProbe returns an error if the probe set with one of the probe setters is invalid
+func validateProbe(probe *v1.Probe) error {
+	if probe == nil || probe.HTTPGet == nil {
+		return nil
+	}
+	return validateProbeHeaders(probe.HTTPGet.HTTPHeaders)
+}
@@ -0,0 +1,46 @@
+package knuu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/celestiaorg/knuu/pkg/k8s"
+)
+
+func TestSetProbeHTTPHeaders(t *testing.T) {
+	lockK8sClient(t)
+
+	i := newTestInstance(t, "probe-headers")
+	headers := map[string]string{"Authorization": "Bearer secret", "X-Probe": "knuu"}
+	assert.ErrorIs(t, i.SetReadinessHTTPHeaders(headers), ErrProbeNotHTTP)
+	require.NoError(t, i.SetLivenessProbe(&v1.Probe{ProbeHandler: v1.ProbeHandler{Exec: &v1.ExecAction{Command: []string{"true"}}}}))
+	assert.ErrorIs(t, i.SetLivenessHTTPHeaders(headers), ErrProbeNotHTTP)
+
+	probe := &v1.Probe{ProbeHandler: v1.ProbeHandler{HTTPGet: &v1.HTTPGetAction{Path: "/healthz", Port: intstr.FromInt(8080)}}}
+	require.NoError(t, i.SetReadinessProbe(probe))
+	require.NoError(t, i.SetLivenessProbe(probe))
+	assert.ErrorIs(t, i.SetReadinessHTTPHeaders(map[string]string{"Bad Header": "x"}), ErrInvalidProbeHeader)
+	require.NoError(t, i.SetReadinessHTTPHeaders(headers))
+	require.NoError(t, i.SetLivenessHTTPHeaders(map[string]string{"Authorization": "Bearer other"}))
+	assert.Empty(t, probe.HTTPGet.HTTPHeaders, "the probe of the caller should not be modified")
+
+	k8sClient = &k8s.Client{}
+	config := i.prepareReplicaSetConfig().PodConfig.ContainerConfig
+	assert.Equal(t, []v1.HTTPHeader{{Name: "Authorization", Value: "Bearer secret"}, {Name: "X-Probe", Value: "knuu"}},
+		config.ReadinessProbe.HTTPGet.HTTPHeaders)
+	assert.Equal(t, []v1.HTTPHeader{{Name: "Authorization", Value: "Bearer other"}}, config.LivenessProbe.HTTPGet.HTTPHeaders)
+
+	// the headers of a probe given to the setters are validated too
+	invalid := probe.DeepCopy()
+	invalid.HTTPGet.HTTPHeaders = []v1.HTTPHeader{{Name: "Authorization:", Value: "Bearer secret"}}
+	assert.ErrorIs(t, i.SetLivenessProbe(invalid), ErrInvalidProbeHeader)
+	assert.ErrorIs(t, i.SetReadinessProbe(invalid), ErrInvalidProbeHeader)
+	assert.ErrorIs(t, i.SetStartupProbe(invalid), ErrInvalidProbeHeader)
+
+	i.state = Started
+	assert.ErrorIs(t, i.SetLivenessHTTPHeaders(headers), ErrSettingProbeNotAllowed)
+}
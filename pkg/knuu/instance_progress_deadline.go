@@ -0,0 +1,45 @@
+package knuu
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+)
+
+// SetProgressDeadline makes WaitForReplicas fail with ErrProgressDeadlineExceeded once no additional pod of the
+// instance became ready for the given duration, like the progressDeadlineSeconds of a Deployment, so a wedged rollout,
+// e.g. with unschedulable pods, fails fast instead of waiting until the context is done
+// The instance is run by a replica set, which has no progress deadline, so the deadline is tracked by the wait itself
+// A deadline of 0 disables it
+// This function can only be called in the states 'Preparing', 'Committed', 'Started' and 'Stopped'
+func (i *Instance) SetProgressDeadline(d time.Duration) error {
+	if !i.IsInState(Preparing, Committed, Started, Stopped) {
+		return ErrSettingProgressDeadlineNotAllowed.WithParams(i.state.String())
+	}
+	if d < 0 {
+		return ErrInvalidProgressDeadline.WithParams(d)
+	}
+	i.progressDeadline = d
+	logrus.Debugf("Set progress deadline to '%s' in instance '%s'", d, i.name)
+	return nil
+}
+
+// stalledReason returns why the pods of the instance do not become ready, e.g. the scheduler message of an unschedulable pod
+func (i *Instance) stalledReason(ctx context.Context) string {
+	pods, err := k8sClient.ListPodsFromReplicaSet(ctx, i.k8sName)
+	if err != nil {
+		logrus.Debugf("Error listing the pods of instance '%s': %v", i.k8sName, err)
+		return "the pods are not ready"
+	}
+	for _, pod := range pods {
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == v1.PodScheduled && condition.Status == v1.ConditionFalse {
+				return fmt.Sprintf("pod %s is %s: %s", pod.Name, condition.Reason, condition.Message)
+			}
+		}
+	}
+	return "the pods are not ready"
+}
@@ -0,0 +1,57 @@
+package knuu
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetProgressDeadline(t *testing.T) {
+	i := newTestInstance(t, "progress")
+	i.k8sName = "progress"
+	assert.ErrorIs(t, i.SetProgressDeadline(-time.Second), ErrInvalidProgressDeadline)
+	require.NoError(t, i.SetProgressDeadline(200*time.Millisecond))
+	require.NoError(t, i.SetPollInterval(10*time.Millisecond))
+	require.NoError(t, i.SetReplicas(2))
+	i.state = Started
+	require.NoError(t, i.SetProgressDeadline(time.Second), "the deadline can be changed once started")
+
+	// one of the two pods cannot be scheduled
+	useK8sClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/apis/apps/v1/namespaces/test/replicasets/progress":
+			fmt.Fprint(w, `{"kind":"ReplicaSet","apiVersion":"apps/v1","metadata":{"name":"progress"},`+
+				`"spec":{"replicas":2,"selector":{"matchLabels":{"app":"progress"}}},"status":{"replicas":2,"readyReplicas":1}}`)
+		case "/api/v1/namespaces/test/pods":
+			fmt.Fprint(w, `{"kind":"PodList","apiVersion":"v1","items":[`+
+				`{"metadata":{"name":"progress-a"},"status":{"phase":"Running","conditions":[{"type":"PodScheduled","status":"True"}]}},`+
+				`{"metadata":{"name":"progress-b"},"status":{"phase":"Pending","conditions":[{"type":"PodScheduled","status":"False",`+
+				`"reason":"Unschedulable","message":"0/3 nodes are available: 3 Insufficient cpu."}]}}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, i.SetProgressDeadline(200*time.Millisecond))
+	start := time.Now()
+	err := i.WaitForReplicas(ctx, 2)
+	assert.ErrorIs(t, err, ErrProgressDeadlineExceeded)
+	assert.ErrorContains(t, err, "1 of 2 replicas are ready")
+	assert.ErrorContains(t, err, "pod progress-b is Unschedulable: 0/3 nodes are available: 3 Insufficient cpu.")
+	assert.Less(t, time.Since(start), 5*time.Second, "the wait should fail at the deadline rather than at the timeout")
+	assert.NoError(t, ctx.Err())
+
+	// without a deadline, the wait lasts until the context is done
+	require.NoError(t, i.SetProgressDeadline(0))
+	ctx, cancel = context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, i.WaitForReplicas(ctx, 2), ErrWaitingForReplicas)
+}
@@ -0,0 +1,33 @@
+package knuu
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/celestiaorg/knuu/pkg/registry"
+)
+
+// RegistryAuth holds the credentials of a registry, empty for anonymous access
+type RegistryAuth struct {
+	Username string
+	Password string
+}
+
+// PromoteImage copies the committed image of the instance to another registry without rebuilding it,
+// e.g. to push the image that was tested to a production registry under destRef, like `registry.example.com/app:v1`.
+// The manifest is copied by digest so the promoted image has the same digest, and the layers are copied
+// between the registries without pulling the image. The destination registry is accessed with the given credentials
+// This function can only be called in the states 'Committed', 'Started' and 'Stopped'
+func (i *Instance) PromoteImage(ctx context.Context, destRef string, auth RegistryAuth) error {
+	if !i.IsInState(Committed, Started, Stopped) {
+		return ErrPromotingImageNotAllowed.WithParams(i.state.String())
+	}
+
+	digest, err := registry.CopyImage(ctx, i.imageName, destRef, registry.Auth(auth))
+	if err != nil {
+		return ErrPromotingImage.WithParams(i.imageName, i.name, destRef).Wrap(err)
+	}
+	logrus.Debugf("Promoted image '%s' of instance '%s' to '%s' with digest '%s'", i.imageName, i.name, destRef, digest)
+	return nil
+}
@@ -0,0 +1,24 @@
+package knuu
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPromoteImage(t *testing.T) {
+	i := newTestInstance(t, "promote")
+	assert.ErrorIs(t, i.PromoteImage(context.Background(), "registry.example.com/app:v1", RegistryAuth{}), ErrPromotingImageNotAllowed)
+
+	source := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(source.Close)
+	i.imageName = strings.TrimPrefix(source.URL, "http://") + "/knuu:missing"
+	i.state = Committed
+	err := i.PromoteImage(context.Background(), "registry.example.com/app:v1", RegistryAuth{Username: "user", Password: "secret"})
+	assert.ErrorIs(t, err, ErrPromotingImage)
+	assert.ErrorContains(t, err, "unexpected status code 404")
+}
@@ -0,0 +1,82 @@
+package knuu
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+)
+
+// rateLimitMessages are the parts of the image pull errors returned by registries that rate limit the pulls
+var rateLimitMessages = []string{"toomanyrequests", "429 Too Many Requests", "rate limit"}
+
+// SetGracefulImagePull makes WaitInstanceIsRunning retry the image pull when the registry rate limits it,
+// e.g. Docker Hub with `toomanyrequests`, instead of timing out while kubernetes backs off the pull
+// The pod is recreated after the delay, doubled on every retry, and the wait starts over, up to the given number of retries
+// Once the retries are exhausted, ErrImagePullRateLimited is returned; 0 retries disables the retries (default)
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetGracefulImagePull(retries int, delay time.Duration) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingGracefulImagePullNotAllowed.WithParams(i.state.String())
+	}
+	if retries < 0 || delay < 0 {
+		return ErrInvalidGracefulImagePull.WithParams(retries, delay)
+	}
+	i.pullRetries = retries
+	i.pullRetryDelay = delay
+	logrus.Debugf("Set graceful image pull with %d retries and a delay of '%s' in instance '%s'", retries, delay, i.name)
+	return nil
+}
+
+// retryRateLimitedPull recreates the pod of the instance if its image pull is rate limited
+// It returns true if the pod was recreated, and an error once the retries are exhausted
+func (i *Instance) retryRateLimitedPull(attempt int) (bool, error) {
+	if i.pullRetries == 0 {
+		return false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), i.operationTimeout())
+	defer cancel()
+	pod, err := k8sClient.GetFirstPodFromReplicaSet(ctx, i.k8sName)
+	if err != nil {
+		// the pod may not be created yet
+		return false, nil
+	}
+	message, limited := imagePullRateLimited(pod)
+	if !limited {
+		return false, nil
+	}
+	if attempt >= i.pullRetries {
+		return false, ErrImagePullRateLimited.WithParams(i.name, attempt).Wrap(errors.New(message))
+	}
+
+	delay := i.pullRetryDelay << attempt
+	logrus.Infof("Image pull of instance '%s' is rate limited, retrying in %s (%d/%d): %s", i.name, delay, attempt+1, i.pullRetries, message)
+	time.Sleep(delay)
+
+	ctx, cancel = context.WithTimeout(context.Background(), i.operationTimeout())
+	defer cancel()
+	if err := k8sClient.DeletePod(ctx, pod.Name); err != nil {
+		return false, ErrRetryingImagePull.WithParams(i.name).Wrap(err)
+	}
+	return true, nil
+}
+
+// imagePullRateLimited returns the message of the first container of the pod whose image pull is rate limited
+func imagePullRateLimited(pod *v1.Pod) (string, bool) {
+	for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		waiting := status.State.Waiting
+		if waiting == nil || (waiting.Reason != "ErrImagePull" && waiting.Reason != "ImagePullBackOff") {
+			continue
+		}
+		for _, rateLimit := range rateLimitMessages {
+			if strings.Contains(waiting.Message, rateLimit) {
+				return waiting.Message, true
+			}
+		}
+	}
+	return "", false
+}
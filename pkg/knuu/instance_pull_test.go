@@ -0,0 +1,46 @@
+package knuu
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestSetGracefulImagePull(t *testing.T) {
+	lockK8sClient(t)
+
+	newInstance := func(t *testing.T) *Instance {
+		i := newQuickTestInstance(t, "graceful-pull")
+		require.NoError(t, i.SetGracefulImagePull(2, 10*time.Millisecond))
+		i.state = Started
+		return i
+	}
+
+	t.Run("SucceedsAfterRetry", func(t *testing.T) {
+		var deletes *atomic.Int32
+		k8sClient, deletes = newRateLimitedK8sClient(t, 1)
+		require.NoError(t, newInstance(t).WaitInstanceIsRunning())
+		assert.Equal(t, int32(1), deletes.Load())
+	})
+
+	t.Run("RetriesExhausted", func(t *testing.T) {
+		var deletes *atomic.Int32
+		k8sClient, deletes = newRateLimitedK8sClient(t, -1)
+		err := newInstance(t).WaitInstanceIsRunning()
+		assert.ErrorIs(t, err, ErrImagePullRateLimited)
+		assert.ErrorContains(t, err, "toomanyrequests")
+		assert.Equal(t, int32(2), deletes.Load())
+	})
+
+	i := newTestInstance(t, "graceful-pull")
+	assert.ErrorIs(t, i.SetGracefulImagePull(-1, 0), ErrInvalidGracefulImagePull)
+	pod := &v1.Pod{Status: v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{{
+		State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "manifest unknown"}},
+	}}}}
+	_, limited := imagePullRateLimited(pod)
+	assert.False(t, limited, "other pull errors are not retried")
+}
@@ -0,0 +1,155 @@
+package knuu
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+)
+
+// SetReplicas sets the number of pods the replica set of the instance runs, 1 by default, e.g. to test
+// that the other replicas keep serving while one of them is restarted, see RolloutRestart and WaitForReplicas
+// The pods are identical and are behind the service of the instance. The functions acting on the pod
+// of the instance, e.g. ExecuteCommand, GetLogs or the network settings, act on the first of them
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetReplicas(replicas int) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingReplicasNotAllowed.WithParams(i.state.String())
+	}
+	if replicas < 1 {
+		return ErrInvalidReplicas.WithParams(replicas)
+	}
+	i.replicas = int32(replicas)
+	logrus.Debugf("Set replicas to '%d' in instance '%s'", replicas, i.name)
+	return nil
+}
+
+// WaitForReplicas waits until at least `ready` pods of the instance report Ready, i.e. all their containers pass
+// their readiness probes, which is stricter than running. It returns an error with the number of ready pods
+// if the context is done before, e.g. to assert how many replicas stay available
+// The pods are counted from the status of the ReplicaSet of the instance, so `ready` cannot exceed its replicas,
+// see SetReplicas
+// If a progress deadline is set, see SetProgressDeadline, it fails with ErrProgressDeadlineExceeded once no
+// additional pod became ready for that long, reporting why the pods are stuck, e.g. they cannot be scheduled
+// This function can only be called in the state 'Started'
+func (i *Instance) WaitForReplicas(ctx context.Context, ready int) error {
+	if !i.IsInState(Started) {
+		return ErrWaitingForInstanceNotAllowed.WithParams(i.state.String())
+	}
+	if ready < 1 {
+		return ErrInvalidReadyReplicas.WithParams(ready)
+	}
+	if ready > int(i.replicas) {
+		return ErrReadyReplicasExceedDesired.WithParams(ready, i.k8sName, i.replicas)
+	}
+
+	tick := time.NewTicker(i.pollIntervalOr(1 * time.Second))
+	defer tick.Stop()
+
+	current := int32(0)
+	lastProgress := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return ErrWaitingForReplicas.WithParams(ready, i.k8sName, current).Wrap(ctx.Err())
+		case <-tick.C:
+			readyReplicas, desired, err := k8sClient.GetReplicaSetReplicas(ctx, i.k8sName)
+			if err != nil {
+				logrus.Debugf("Error getting the replicas of instance '%s': %v", i.k8sName, err)
+				continue
+			}
+			if int32(ready) > desired {
+				return ErrReadyReplicasExceedDesired.WithParams(ready, i.k8sName, desired)
+			}
+			if readyReplicas > current {
+				lastProgress = time.Now()
+			}
+			current = readyReplicas
+			if current >= int32(ready) {
+				logrus.Debugf("Instance '%s' has %d ready replicas", i.k8sName, current)
+				return nil
+			}
+			if i.progressDeadline != 0 && time.Since(lastProgress) >= i.progressDeadline {
+				return ErrProgressDeadlineExceeded.WithParams(i.k8sName, i.progressDeadline, current, desired, i.stalledReason(ctx))
+			}
+		}
+	}
+}
+
+// RolloutRestart restarts the pods of the instance one at a time, like `kubectl rollout restart`, e.g. to test
+// that the other replicas keep serving during a rolling update. Each pod is deleted so that the ReplicaSet
+// recreates it, and the next pod is only deleted once all the replicas are ready again, so it returns once
+// the rollout is complete
+// The instance stays available only if it runs several replicas, see SetReplicas: an instance with a single pod
+// is down until its replacement is ready
+// The progress is logged, and the error reports how many pods were restarted if a replacement pod does not become
+// ready before the context is done
+// This function can only be called in the state 'Started'
+func (i *Instance) RolloutRestart(ctx context.Context) error {
+	if !i.IsInState(Started) {
+		return ErrRolloutRestartNotAllowed.WithParams(i.state.String())
+	}
+	parent := i
+	if i.isSidecar {
+		parent = i.parentInstance
+	}
+
+	pods, err := k8sClient.ListPodsFromReplicaSet(ctx, parent.k8sName)
+	if err != nil {
+		return ErrRollingOutRestart.WithParams(parent.k8sName, 0, 0).Wrap(err)
+	}
+	var old []string
+	for _, pod := range pods {
+		if pod.DeletionTimestamp == nil && pod.Status.Phase != v1.PodSucceeded && pod.Status.Phase != v1.PodFailed {
+			old = append(old, pod.Name)
+		}
+	}
+
+	deleted := make(map[string]bool, len(old))
+	for n, name := range old {
+		if err := k8sClient.DeletePod(ctx, name); err != nil {
+			return ErrRollingOutRestart.WithParams(parent.k8sName, n, len(old)).Wrap(err)
+		}
+		deleted[name] = true
+		if err := parent.waitForReplacedPods(ctx, deleted); err != nil {
+			return ErrRollingOutRestart.WithParams(parent.k8sName, n, len(old)).Wrap(err)
+		}
+		logrus.Infof("Restarted pod %d/%d of instance '%s'", n+1, len(old), parent.name)
+	}
+	return nil
+}
+
+// waitForReplacedPods waits until the ReplicaSet of the instance runs as many ready pods as its replicas,
+// without counting the deleted pods, which may still be terminating
+func (i *Instance) waitForReplacedPods(ctx context.Context, deleted map[string]bool) error {
+	tick := time.NewTicker(i.pollIntervalOr(1 * time.Second))
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+			_, desired, err := k8sClient.GetReplicaSetReplicas(ctx, i.k8sName)
+			if err != nil {
+				logrus.Debugf("Error getting the replicas of instance '%s': %v", i.k8sName, err)
+				continue
+			}
+			pods, err := k8sClient.ListPodsFromReplicaSet(ctx, i.k8sName)
+			if err != nil {
+				logrus.Debugf("Error listing the pods of instance '%s': %v", i.k8sName, err)
+				continue
+			}
+			ready := int32(0)
+			for _, pod := range pods {
+				if !deleted[pod.Name] && pod.DeletionTimestamp == nil && podConditionTrue(&pod, v1.PodReady) {
+					ready++
+				}
+			}
+			if ready >= desired {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package knuu
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/k8s"
+)
+
+func TestSetReplicas(t *testing.T) {
+	lockK8sClient(t)
+	k8sClient = &k8s.Client{}
+
+	i := newTestInstance(t, "set-replicas")
+	assert.Equal(t, int32(1), i.prepareReplicaSetConfig().Replicas, "an instance runs a single pod by default")
+	assert.ErrorIs(t, i.SetReplicas(0), ErrInvalidReplicas)
+	require.NoError(t, i.SetReplicas(3))
+	assert.Equal(t, int32(3), i.prepareReplicaSetConfig().Replicas)
+	i.state = Committed
+	clone, err := i.CloneWithName("set-replicas-clone")
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), clone.prepareReplicaSetConfig().Replicas, "the clone should run as many replicas")
+
+	i.state = Started
+	assert.ErrorIs(t, i.SetReplicas(2), ErrSettingReplicasNotAllowed)
+}
+
+func TestWaitForReplicas(t *testing.T) {
+	i := newTestInstance(t, "replicas")
+	assert.ErrorIs(t, i.WaitForReplicas(context.Background(), 1), ErrWaitingForInstanceNotAllowed)
+	require.NoError(t, i.SetReplicas(3))
+	i.state = Started
+	require.NoError(t, i.SetPollInterval(10*time.Millisecond))
+	assert.ErrorIs(t, i.WaitForReplicas(context.Background(), 0), ErrInvalidReadyReplicas)
+
+	// the ReplicaSet is scaled to 3 and its pods become ready one by one
+	var polls atomic.Int32
+	useK8sClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		ready := min(polls.Add(1), 3)
+		fmt.Fprintf(w, `{"kind":"ReplicaSet","apiVersion":"apps/v1","metadata":{"name":"replicas"},`+
+			`"spec":{"replicas":3},"status":{"replicas":3,"readyReplicas":%d}}`, ready)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, i.WaitForReplicas(ctx, 3))
+	assert.Equal(t, int32(3), polls.Load(), "the wait ends once all the replicas are ready")
+
+	// more ready replicas than the instance runs are rejected without polling
+	err := i.WaitForReplicas(ctx, 4)
+	assert.ErrorIs(t, err, ErrReadyReplicasExceedDesired)
+	assert.ErrorContains(t, err, "it runs 3 replicas")
+	assert.Equal(t, int32(3), polls.Load())
+
+	// the timeout reports the number of ready replicas
+	k8sClient = newTestK8sClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"kind":"ReplicaSet","apiVersion":"apps/v1","metadata":{"name":"replicas"},`+
+			`"spec":{"replicas":3},"status":{"replicas":3,"readyReplicas":2}}`)
+	})
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err = i.WaitForReplicas(ctx, 3)
+	assert.ErrorIs(t, err, ErrWaitingForReplicas)
+	assert.ErrorContains(t, err, "2 are ready")
+}
+
+func TestRolloutRestart(t *testing.T) {
+	i := newTestInstance(t, "rollout")
+	assert.ErrorIs(t, i.RolloutRestart(context.Background()), ErrRolloutRestartNotAllowed)
+	require.NoError(t, i.SetReplicas(3))
+	i.state = Started
+	require.NoError(t, i.SetPollInterval(10*time.Millisecond))
+
+	// the ReplicaSet runs 3 ready pods and recreates each deleted one, which becomes ready at the next list
+	var (
+		mu      sync.Mutex
+		pods    = map[string]bool{"rollout-a": true, "rollout-b": true, "rollout-c": true}
+		deleted []string
+	)
+	useK8sClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/apis/apps/v1/namespaces/test/replicasets/"):
+			fmt.Fprint(w, `{"kind":"ReplicaSet","apiVersion":"apps/v1","metadata":{"name":"rollout"},`+
+				`"spec":{"replicas":3,"selector":{"matchLabels":{"app":"rollout"}}}}`)
+		case r.URL.Path == "/api/v1/namespaces/test/pods":
+			items := make([]string, 0, len(pods))
+			for name, ready := range pods {
+				status := "False"
+				if ready {
+					status = "True"
+				}
+				items = append(items, fmt.Sprintf(`{"metadata":{"name":%q},"status":{"phase":"Running",`+
+					`"conditions":[{"type":"Ready","status":%q}]}}`, name, status))
+				pods[name] = true
+			}
+			sort.Strings(items)
+			fmt.Fprintf(w, `{"kind":"PodList","apiVersion":"v1","items":[%s]}`, strings.Join(items, ","))
+		case r.Method == http.MethodDelete:
+			name := path.Base(r.URL.Path)
+			delete(pods, name)
+			pods[name+"-new"] = false
+			deleted = append(deleted, name)
+			fmt.Fprint(w, `{"kind":"Status","apiVersion":"v1","status":"Success"}`)
+		case strings.HasPrefix(r.URL.Path, "/api/v1/namespaces/test/pods/"):
+			fmt.Fprintf(w, `{"kind":"Pod","apiVersion":"v1","metadata":{"name":%q}}`, path.Base(r.URL.Path))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, i.RolloutRestart(ctx))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"rollout-a", "rollout-b", "rollout-c"}, deleted, "the pods are restarted one at a time")
+	assert.Equal(t, map[string]bool{"rollout-a-new": true, "rollout-b-new": true, "rollout-c-new": true}, pods,
+		"all the replicas are recreated and ready")
+}
@@ -0,0 +1,56 @@
+package knuu
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ExecuteCommandWithRetry executes the given command in the instance like ExecuteCommand and retries it while it fails,
+// e.g. exits with a nonzero code or cannot connect to an app still warming up, with the backoff between the attempts
+// It returns the output of the first successful attempt, otherwise the output and the error of the last attempt
+// The context bounds all the attempts, including the backoff
+// This function can only be called in the state 'Started'
+func (i *Instance) ExecuteCommandWithRetry(ctx context.Context, attempts int, backoff time.Duration, args ...string) (string, error) {
+	if !i.IsInState(Started) {
+		return "", ErrExecutingCommandNotAllowed.WithParams(i.state.String())
+	}
+	if attempts < 1 {
+		return "", ErrInvalidRetryAttempts.WithParams(attempts)
+	}
+	if backoff < 0 {
+		return "", ErrInvalidRetryBackoff.WithParams(backoff.String())
+	}
+
+	output, made, err := retryCommand(ctx, attempts, backoff, func(ctx context.Context) (string, error) {
+		return i.ExecuteCommandWithContext(ctx, args...)
+	})
+	if err != nil {
+		return output, ErrExecutingCommandWithRetry.WithParams(args, i.name, made).Wrap(err)
+	}
+	return output, nil
+}
+
+// retryCommand runs the command until it succeeds, the attempts are exhausted or the context is done,
+// waiting the backoff between the attempts, and returns the result of the last attempt with the number of attempts made
+func retryCommand(
+	ctx context.Context,
+	attempts int,
+	backoff time.Duration,
+	run func(context.Context) (string, error),
+) (string, int, error) {
+	for attempt := 1; ; attempt++ {
+		output, err := run(ctx)
+		if err == nil || attempt == attempts {
+			return output, attempt, err
+		}
+		logrus.Debugf("Attempt %d/%d of command failed, retrying in %s: %v", attempt, attempts, backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return output, attempt, err
+		case <-time.After(backoff):
+		}
+	}
+}
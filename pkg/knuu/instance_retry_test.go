@@ -0,0 +1,71 @@
+package knuu
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteCommandWithRetry(t *testing.T) {
+	i := newTestInstance(t, "retry")
+	_, err := i.ExecuteCommandWithRetry(context.Background(), 3, 0, "true")
+	assert.ErrorIs(t, err, ErrExecutingCommandNotAllowed)
+	i.state = Started
+	_, err = i.ExecuteCommandWithRetry(context.Background(), 0, 0, "true")
+	assert.ErrorIs(t, err, ErrInvalidRetryAttempts)
+	_, err = i.ExecuteCommandWithRetry(context.Background(), 3, -time.Second, "true")
+	assert.ErrorIs(t, err, ErrInvalidRetryBackoff)
+
+	// the command fails twice while the app warms up, then succeeds
+	calls := 0
+	flaky := func(context.Context) (string, error) {
+		calls++
+		if calls <= 2 {
+			return fmt.Sprintf("attempt %d", calls), fmt.Errorf("connection refused")
+		}
+		return "Hello World!", nil
+	}
+	output, made, err := retryCommand(context.Background(), 5, 10*time.Millisecond, flaky)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello World!", output)
+	assert.Equal(t, 3, made)
+
+	// the result of the last attempt is returned when all of them fail
+	calls = 0
+	output, made, err = retryCommand(context.Background(), 2, 10*time.Millisecond, flaky)
+	assert.ErrorContains(t, err, "connection refused")
+	assert.Equal(t, "attempt 2", output)
+	assert.Equal(t, 2, made)
+
+	// the context stops the retries during the backoff
+	calls = 0
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, made, err = retryCommand(ctx, 5, time.Minute, flaky)
+	assert.Error(t, err)
+	assert.Equal(t, 1, made)
+}
+
+func TestExecuteCommandWithRetryCanceled(t *testing.T) {
+	var requests atomic.Int32
+	useK8sClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	})
+	i := newQuickTestInstance(t, "retry-canceled")
+	i.state = Started
+
+	// the attempts are bound by the context, so a canceled context stops them before the pod is looked up
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := i.ExecuteCommandWithRetry(ctx, 3, time.Minute, "true")
+	assert.ErrorIs(t, err, ErrExecutingCommandWithRetry)
+	assert.ErrorContains(t, err, context.Canceled.Error())
+	assert.Zero(t, requests.Load(), "no request should be sent with a canceled context")
+}
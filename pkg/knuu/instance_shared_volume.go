@@ -0,0 +1,173 @@
+package knuu
+
+import (
+	"context"
+	"path"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/celestiaorg/knuu/pkg/k8s"
+	"github.com/celestiaorg/knuu/pkg/names"
+)
+
+// sharedVolumeSize is the size of the volumes shared between instances
+const sharedVolumeSize = "1Gi"
+
+// sharedVolume is a ReadWriteMany volume mounted by several instances at the same path
+// Its claim is created by the first instance that is started and deleted once all the sharers are destroyed
+type sharedVolume struct {
+	mu        sync.Mutex
+	claimName string
+	path      string
+	sharers   map[string]bool // k8s names of the instances using the volume
+	deployed  bool
+}
+
+// share adds the instance to the sharers of the volume
+func (v *sharedVolume) share(i *Instance) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.sharers[i.k8sName] = true
+}
+
+// deploy creates the claim of the volume, unless it is already created by another sharer
+func (v *sharedVolume) deploy(ctx context.Context, i *Instance) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.sharers[i.k8sName] = true
+	if v.deployed {
+		return nil
+	}
+	labels := map[string]string{
+		"app":                          v.claimName,
+		"k8s.kubernetes.io/managed-by": "knuu",
+		"knuu.sh/scope":                testScope,
+		"knuu.sh/test-started":         startTime,
+	}
+	if err := k8sClient.CreateSharedPersistentVolumeClaim(ctx, v.claimName, labels, resource.MustParse(sharedVolumeSize)); err != nil {
+		return err
+	}
+	v.deployed = true
+	logrus.Debugf("Deployed shared volume '%s' for instance '%s'", v.claimName, i.name)
+	return nil
+}
+
+// release removes the instance from the sharers of the volume and deletes its claim if it was the last one
+func (v *sharedVolume) release(ctx context.Context, i *Instance) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.sharers, i.k8sName)
+	if len(v.sharers) != 0 || !v.deployed {
+		return nil
+	}
+	if err := k8sClient.DeletePersistentVolumeClaim(ctx, v.claimName); err != nil {
+		return err
+	}
+	v.deployed = false
+	logrus.Debugf("Destroyed shared volume '%s' with instance '%s'", v.claimName, i.name)
+	return nil
+}
+
+// unused returns true if no instance uses the volume anymore
+func (v *sharedVolume) unused() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return len(v.sharers) == 0
+}
+
+// ShareVolumeWith mounts a volume shared with the other instance at the given path in both instances,
+// so files written by one of them are visible to the other. If one of the instances already shares a volume
+// at that path, the other one joins it, so a volume can be shared by more than two instances.
+// The volume is backed by a ReadWriteMany claim of 1Gi, which needs a default storage class supporting it,
+// e.g. NFS or CephFS, as the pods may run on different nodes. The claim is created when the first sharer is started
+// and deleted when the last sharer is destroyed. Unlike AddVolume, the content of the image at the path is not copied
+// This function can only be called in the states 'Preparing' and 'Committed', for both instances
+func (i *Instance) ShareVolumeWith(other *Instance, mountPath string) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSharingVolumeNotAllowed.WithParams(i.state.String())
+	}
+	if other == nil || other == i {
+		return ErrSharingVolumeWithInvalidInstance.WithParams(i.name)
+	}
+	if !other.IsInState(Preparing, Committed) {
+		return ErrSharingVolumeNotAllowed.WithParams(other.state.String())
+	}
+	if !path.IsAbs(mountPath) {
+		return ErrSharedVolumePathNotAbsolute.WithParams(mountPath)
+	}
+	mountPath = path.Clean(mountPath)
+
+	volume, otherVolume := i.sharedVolumeAt(mountPath), other.sharedVolumeAt(mountPath)
+	if volume != nil && volume == otherVolume {
+		return nil
+	}
+	if volume != nil && otherVolume != nil {
+		return ErrSharedVolumePathInUse.WithParams(mountPath, i.name, other.name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), i.operationTimeout())
+	defer cancel()
+	if err := k8sClient.ValidateReadWriteManyStorageClass(ctx); err != nil {
+		return ErrSharedVolumeNotSupported.Wrap(err)
+	}
+
+	// the instance without the volume joins the one that has it
+	joiner := other
+	if volume == nil && otherVolume == nil {
+		claimName, err := names.NewRandomK8("shared-volume")
+		if err != nil {
+			return ErrGeneratingUUID.Wrap(err)
+		}
+		volume = &sharedVolume{claimName: claimName, path: mountPath, sharers: make(map[string]bool)}
+		volume.share(i)
+		i.sharedVolumes = append(i.sharedVolumes, volume)
+	} else if volume == nil {
+		volume = otherVolume
+		joiner = i
+	}
+	volume.share(joiner)
+	joiner.sharedVolumes = append(joiner.sharedVolumes, volume)
+	logrus.Debugf("Shared volume '%s' at '%s' between instances '%s' and '%s'", volume.claimName, mountPath, i.name, other.name)
+	return nil
+}
+
+// sharedVolumeAt returns the shared volume mounted at the path, or nil if there is none
+func (i *Instance) sharedVolumeAt(mountPath string) *sharedVolume {
+	for _, volume := range i.sharedVolumes {
+		if volume.path == mountPath {
+			return volume
+		}
+	}
+	return nil
+}
+
+// prepareSharedVolumes returns the shared volumes to mount in the container of the instance
+func (i *Instance) prepareSharedVolumes() []*k8s.SharedVolume {
+	volumes := make([]*k8s.SharedVolume, 0, len(i.sharedVolumes))
+	for _, volume := range i.sharedVolumes {
+		volumes = append(volumes, &k8s.SharedVolume{ClaimName: volume.claimName, Path: volume.path})
+	}
+	return volumes
+}
+
+// deploySharedVolumes creates the claims of the shared volumes that are not created by another sharer yet
+func (i *Instance) deploySharedVolumes(ctx context.Context) error {
+	for _, volume := range i.sharedVolumes {
+		if err := volume.deploy(ctx, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// destroySharedVolumes deletes the claims of the shared volumes the instance is the last sharer of
+func (i *Instance) destroySharedVolumes(ctx context.Context) error {
+	for _, volume := range i.sharedVolumes {
+		if err := volume.release(ctx, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
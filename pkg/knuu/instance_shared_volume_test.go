@@ -0,0 +1,114 @@
+package knuu
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/k8s"
+)
+
+func TestShareVolumeWith(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		provisioner = "nfs.csi.k8s.io"
+		requests    []string
+	)
+	useK8sClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/apis/storage.k8s.io/v1/storageclasses":
+			fmt.Fprintf(w, `{"kind":"StorageClassList","apiVersion":"storage.k8s.io/v1","items":[`+
+				`{"metadata":{"name":"default","annotations":{"storageclass.kubernetes.io/is-default-class":"true"}},"provisioner":"%s"}]}`, provisioner)
+		case strings.HasPrefix(r.URL.Path, "/api/v1/namespaces/test/persistentvolumeclaims"):
+			requests = append(requests, r.Method)
+			fmt.Fprint(w, `{"kind":"PersistentVolumeClaim","apiVersion":"v1","metadata":{"name":"shared"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	a, b, c := newTestInstance(t, "share-a"), newTestInstance(t, "share-b"), newTestInstance(t, "share-c")
+	for _, i := range []*Instance{a, b, c} {
+		require.NoError(t, i.SetOperationTimeout(time.Minute))
+	}
+	require.NoError(t, a.ShareVolumeWith(b, "/shared/"))
+	require.NoError(t, c.ShareVolumeWith(a, "/shared"))
+	require.NoError(t, b.ShareVolumeWith(c, "/shared"), "sharing the same volume again is a no-op")
+	require.Len(t, a.sharedVolumes, 1)
+	volume := a.sharedVolumes[0]
+	assert.Equal(t, []*sharedVolume{volume}, b.sharedVolumes)
+	assert.Equal(t, []*sharedVolume{volume}, c.sharedVolumes)
+	assert.Equal(t, []*k8s.SharedVolume{{ClaimName: volume.claimName, Path: "/shared"}}, b.prepareReplicaSetConfig().PodConfig.ContainerConfig.SharedVolumes)
+
+	assert.ErrorIs(t, a.ShareVolumeWith(a, "/other"), ErrSharingVolumeWithInvalidInstance)
+	assert.ErrorIs(t, a.ShareVolumeWith(b, "other"), ErrSharedVolumePathNotAbsolute)
+
+	// the claim is created by the first sharer and deleted with the last one
+	ctx := context.Background()
+	require.NoError(t, a.deploySharedVolumes(ctx))
+	require.NoError(t, b.deploySharedVolumes(ctx))
+	require.NoError(t, a.destroySharedVolumes(ctx))
+	require.NoError(t, b.destroySharedVolumes(ctx))
+	assert.Equal(t, []string{http.MethodPost}, requests)
+	assert.False(t, volume.unused())
+	require.NoError(t, c.destroySharedVolumes(ctx))
+	assert.Equal(t, []string{http.MethodPost, http.MethodGet, http.MethodDelete}, requests)
+	assert.True(t, volume.unused())
+
+	mu.Lock()
+	provisioner = "rancher.io/local-path"
+	mu.Unlock()
+	d := newTestInstance(t, "share-d")
+	require.NoError(t, d.SetOperationTimeout(time.Minute))
+	err := d.ShareVolumeWith(newTestInstance(t, "share-e"), "/shared")
+	assert.ErrorIs(t, err, ErrSharedVolumeNotSupported)
+	assert.ErrorContains(t, err, "rancher.io/local-path")
+}
+
+func TestShareVolumeWithDestroyedBeforeStart(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		claims []string
+	)
+	useK8sClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/apis/storage.k8s.io/v1/storageclasses":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"kind":"StorageClassList","apiVersion":"storage.k8s.io/v1","items":[`+
+				`{"metadata":{"name":"default","annotations":{"storageclass.kubernetes.io/is-default-class":"true"}},"provisioner":"nfs.csi.k8s.io"}]}`)
+		case strings.HasPrefix(r.URL.Path, "/api/v1/namespaces/test/persistentvolumeclaims"):
+			mu.Lock()
+			claims = append(claims, r.Method)
+			mu.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"kind":"PersistentVolumeClaim","apiVersion":"v1","metadata":{"name":"shared"}}`)
+		default:
+			echoK8sHandler(w, r)
+		}
+	})
+
+	started, preparing := newQuickTestInstance(t, "share-started"), newQuickTestInstance(t, "share-preparing")
+	require.NoError(t, started.ShareVolumeWith(preparing, "/shared"))
+	volume := started.sharedVolumes[0]
+	require.NoError(t, started.deploySharedVolumes(context.Background()))
+	started.state = Started
+
+	// the instance destroyed before it starts leaves the volume to the started one
+	require.NoError(t, preparing.Destroy())
+	assert.False(t, volume.unused())
+	require.NoError(t, started.Destroy())
+	assert.True(t, volume.unused())
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{http.MethodPost, http.MethodGet, http.MethodDelete}, claims, "the claim should be deleted with the last sharer")
+}
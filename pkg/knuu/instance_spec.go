@@ -0,0 +1,180 @@
+package knuu
+
+import (
+	"bytes"
+	"maps"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// InstanceSpec is the declarative configuration of an instance before it is started,
+// which can be stored as YAML with MarshalSpec and loaded with NewInstanceFromSpec
+type InstanceSpec struct {
+	Name      string            `yaml:"name"`
+	Image     string            `yaml:"image"`
+	Command   []string          `yaml:"command,omitempty"`
+	Args      []string          `yaml:"args,omitempty"`
+	Env       map[string]string `yaml:"env,omitempty"`
+	Resources ResourcesSpec     `yaml:"resources,omitempty"`
+	PortsTCP  []int             `yaml:"portsTCP,omitempty"`
+	PortsUDP  []int             `yaml:"portsUDP,omitempty"`
+	Volumes   []VolumeSpec      `yaml:"volumes,omitempty"`
+	Files     []FileSpec        `yaml:"files,omitempty"`
+	Folders   []FileSpec        `yaml:"folders,omitempty"`
+}
+
+// ResourcesSpec describes the resources of an instance, empty values are not set
+type ResourcesSpec struct {
+	MemoryRequest           string `yaml:"memoryRequest,omitempty"`
+	MemoryLimit             string `yaml:"memoryLimit,omitempty"`
+	CPURequest              string `yaml:"cpuRequest,omitempty"`
+	EphemeralStorageRequest string `yaml:"ephemeralStorageRequest,omitempty"`
+	EphemeralStorageLimit   string `yaml:"ephemeralStorageLimit,omitempty"`
+}
+
+// VolumeSpec describes a volume of an instance
+type VolumeSpec struct {
+	Path  string `yaml:"path"`
+	Size  string `yaml:"size"`
+	Owner int64  `yaml:"owner,omitempty"`
+}
+
+// Spec returns the declarative configuration of the instance
+// It covers the image, command, args, environment variables, resources, ports, volumes and the files and folders
+// added from the host; other settings, commands executed in the image and files added with AddFileBytes are not covered
+// The environment variables set before and after Commit are merged, the latter taking precedence
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) Spec() (*InstanceSpec, error) {
+	if !i.IsInState(Preparing, Committed) {
+		return nil, ErrGettingSpecNotAllowed.WithParams(i.state.String())
+	}
+
+	env := maps.Clone(i.imageEnv)
+	maps.Copy(env, i.env)
+	spec := &InstanceSpec{
+		Name:     i.name,
+		Image:    i.builderFactory.ImageNameFrom(),
+		Command:  slices.Clone(i.command),
+		Args:     slices.Clone(i.args),
+		Env:      env,
+		PortsTCP: slices.Clone(i.portsTCP),
+		PortsUDP: slices.Clone(i.portsUDP),
+		Resources: ResourcesSpec{
+			MemoryRequest:           i.memoryRequest,
+			MemoryLimit:             i.memoryLimit,
+			CPURequest:              i.cpuRequest,
+			EphemeralStorageRequest: i.ephemeralRequest,
+			EphemeralStorageLimit:   i.ephemeralLimit,
+		},
+		Files:   slices.Clone(i.specFiles),
+		Folders: slices.Clone(i.specFolders),
+	}
+	for _, volume := range i.volumes {
+		spec.Volumes = append(spec.Volumes, VolumeSpec{Path: volume.Path, Size: volume.Size, Owner: volume.Owner})
+	}
+	return spec, nil
+}
+
+// MarshalSpec returns the declarative configuration of the instance as YAML, see Spec for what it covers
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) MarshalSpec() ([]byte, error) {
+	spec, err := i.Spec()
+	if err != nil {
+		return nil, err
+	}
+	data, err := yaml.Marshal(spec)
+	if err != nil {
+		return nil, ErrMarshalingYAML.Wrap(err)
+	}
+	return data, nil
+}
+
+// NewInstanceFromSpec creates an instance from its declarative configuration in YAML, as returned by MarshalSpec
+// Unknown fields are rejected, so typos do not silently drop settings
+// The instance is returned in the state 'Preparing', so it can be configured further before it is committed
+func NewInstanceFromSpec(data []byte) (*Instance, error) {
+	var spec InstanceSpec
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&spec); err != nil {
+		return nil, ErrUnmarshalingSpec.Wrap(err)
+	}
+	return NewInstanceWithSpec(&spec)
+}
+
+// NewInstanceWithSpec creates an instance from its declarative configuration
+// The instance is returned in the state 'Preparing', so it can be configured further before it is committed
+func NewInstanceWithSpec(spec *InstanceSpec) (*Instance, error) {
+	if spec.Image == "" {
+		return nil, ErrSpecImageEmpty.WithParams(spec.Name)
+	}
+
+	i, err := NewInstance(spec.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := i.applySpec(spec); err != nil {
+		return nil, ErrApplyingSpec.WithParams(spec.Name).Wrap(err)
+	}
+	return i, nil
+}
+
+// applySpec configures the new instance according to the spec
+func (i *Instance) applySpec(spec *InstanceSpec) error {
+	if err := i.SetImage(spec.Image); err != nil {
+		return err
+	}
+	if err := i.SetEnvMap(spec.Env); err != nil {
+		return err
+	}
+	if err := i.AddFiles(spec.Files); err != nil {
+		return err
+	}
+	for _, folder := range spec.Folders {
+		if err := i.AddFolder(folder.Src, folder.Dest, folder.Chown); err != nil {
+			return err
+		}
+	}
+	if len(spec.Command) != 0 {
+		if err := i.SetCommand(spec.Command...); err != nil {
+			return err
+		}
+	}
+	if len(spec.Args) != 0 {
+		if err := i.SetArgs(spec.Args...); err != nil {
+			return err
+		}
+	}
+	if spec.Resources.MemoryRequest != "" || spec.Resources.MemoryLimit != "" {
+		if err := i.SetMemory(spec.Resources.MemoryRequest, spec.Resources.MemoryLimit); err != nil {
+			return err
+		}
+	}
+	if spec.Resources.CPURequest != "" {
+		if err := i.SetCPU(spec.Resources.CPURequest); err != nil {
+			return err
+		}
+	}
+	if spec.Resources.EphemeralStorageRequest != "" || spec.Resources.EphemeralStorageLimit != "" {
+		if err := i.SetEphemeralStorage(spec.Resources.EphemeralStorageRequest, spec.Resources.EphemeralStorageLimit); err != nil {
+			return err
+		}
+	}
+	for _, port := range spec.PortsTCP {
+		if err := i.AddPortTCP(port); err != nil {
+			return err
+		}
+	}
+	for _, port := range spec.PortsUDP {
+		if err := i.AddPortUDP(port); err != nil {
+			return err
+		}
+	}
+	for _, volume := range spec.Volumes {
+		if err := i.AddVolumeWithOwner(volume.Path, volume.Size, volume.Owner); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package knuu
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/k8s"
+)
+
+func TestInstanceSpecRoundTrip(t *testing.T) {
+	lockK8sClient(t)
+	k8sClient = &k8s.Client{}
+
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, "app.conf"), []byte("conf"), 0644))
+	folder := filepath.Join(src, "data")
+	require.NoError(t, os.Mkdir(folder, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(folder, "genesis.json"), []byte("{}"), 0644))
+
+	i, err := NewInstance("spec")
+	require.NoError(t, err)
+	require.NoError(t, i.SetImage("docker.io/alpine:3.20"))
+	t.Cleanup(func() { os.RemoveAll(i.getBuildDir()) })
+	require.NoError(t, i.SetEnvironmentVariable("MODE", "test"))
+	require.NoError(t, i.AddFile(filepath.Join(src, "app.conf"), "/etc/app/app.conf", "0:0"))
+	require.NoError(t, i.AddFolder(folder, "/data", "0:0"))
+	require.NoError(t, i.SetCommand("sleep"))
+	require.NoError(t, i.SetArgs("infinity"))
+	require.NoError(t, i.SetMemory("64Mi", "128Mi"))
+	require.NoError(t, i.SetCPU("100m"))
+	require.NoError(t, i.SetEphemeralStorage("1Gi", "4Gi"))
+	require.NoError(t, i.AddPortTCP(8080))
+	require.NoError(t, i.AddPortUDP(9090))
+	require.NoError(t, i.AddVolumeWithOwner("/data", "1Gi", 1000))
+
+	data, err := i.MarshalSpec()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "image: docker.io/alpine:3.20")
+	assert.Contains(t, string(data), "ephemeralStorageLimit: 4Gi")
+
+	clone, err := NewInstanceFromSpec(data)
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(clone.getBuildDir()) })
+	cloneData, err := clone.MarshalSpec()
+	require.NoError(t, err)
+	assert.Equal(t, string(data), string(cloneData))
+	assert.Equal(t, i.volumes, clone.volumes)
+
+	content, err := os.ReadFile(filepath.Join(clone.getBuildDir(), "/data/genesis.json"))
+	require.NoError(t, err)
+	assert.Equal(t, "{}", string(content))
+
+	_, err = NewInstanceFromSpec([]byte("name: spec\nimage: docker.io/alpine:3.20\nportTCP: [80]\n"))
+	assert.ErrorIs(t, err, ErrUnmarshalingSpec)
+	_, err = NewInstanceFromSpec([]byte("name: spec\n"))
+	assert.ErrorIs(t, err, ErrSpecImageEmpty)
+}
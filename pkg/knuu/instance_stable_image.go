@@ -0,0 +1,67 @@
+package knuu
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/celestiaorg/knuu/pkg/registry"
+)
+
+// SetStableImageTag makes the instance push its image to the given stable tag, e.g. `localhost:5000/app:dev`,
+// in addition to a tag with the hash of the image in the same repository, and reuse the stable tag in the next runs
+// The image is built only once for a given hash, so the following runs start without building it, e.g. to iterate locally
+// An image tagged in another run is reused only if it was built from the same hash, i.e. the same Dockerfile
+// instructions, build args and files, otherwise it is built again and the stable tag is moved to the new image
+// The hash does not cover what the base image tag points to, so the reused image keeps the base image it was built from,
+// e.g. `alpine:latest` of the first run, until the stable tag is deleted or changed, which is the price of skipping the build
+// The stable tag is set with a manifest copy, which needs a registry accepting anonymous pushes, e.g. a local registry,
+// and pruning the hash tags with registry.PruneBuiltImages deletes the stable tag of a pruned image as well
+// This function can only be called in the state 'Preparing'
+func (i *Instance) SetStableImageTag(ref string) error {
+	if !i.IsInState(Preparing) {
+		return ErrSettingStableImageTagNotAllowed.WithParams(i.state.String())
+	}
+	r, err := registry.ParseReference(ref)
+	if err != nil || r.Digest != "" {
+		return ErrInvalidStableImageTag.WithParams(ref)
+	}
+	i.stableImage = r.String()
+	logrus.Debugf("Set stable image tag to '%s' in instance '%s'", i.stableImage, i.name)
+	return nil
+}
+
+// pushStableImage sets the image of the instance to its stable tag, pushing the image with the hash to it first,
+// unless the stable tag already points to the image with the hash
+func (i *Instance) pushStableImage(ctx context.Context, imageHash string) error {
+	stableImage := registry.Rewrite(i.stableImage)
+	// the reference was validated by SetStableImageTag
+	hashRef, _ := registry.ParseReference(stableImage)
+	hashRef.Tag = imageHash
+	hashImage := hashRef.String()
+
+	stableDigest, err := registry.ImageDigest(ctx, stableImage)
+	if err != nil {
+		logrus.Warnf("Cannot check stable image '%s' of instance '%s', pushing it: %v", stableImage, i.name, err)
+	}
+	if stableDigest != "" {
+		hashDigest, err := registry.ImageDigest(ctx, hashImage)
+		if err == nil && hashDigest == stableDigest {
+			i.imageName = stableImage
+			logrus.Debugf("Reusing stable image '%s' built from the same hash for instance '%s'", stableImage, i.name)
+			return nil
+		}
+		logrus.Infof("Stable image '%s' of instance '%s' was built from another hash, updating it", stableImage, i.name)
+	}
+
+	// the build is skipped if the image with the hash is still in the registry
+	if err := i.builderFactory.PushBuilderImage(hashImage); err != nil {
+		return ErrPushingImage.WithParams(i.name).Wrap(err)
+	}
+	if _, err := registry.CopyImage(ctx, hashImage, stableImage, registry.Auth{}); err != nil {
+		return ErrTaggingStableImage.WithParams(stableImage, i.name).Wrap(err)
+	}
+	i.imageName = stableImage
+	logrus.Debugf("Pushed stable image '%s' for instance '%s'", stableImage, i.name)
+	return nil
+}
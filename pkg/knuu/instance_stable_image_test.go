@@ -0,0 +1,123 @@
+package knuu
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/knuu/pkg/builder"
+	"github.com/celestiaorg/knuu/pkg/registry"
+)
+
+// tagRegistry stores the manifests pushed to it by tag and serves them with their digest, and has all the blobs
+type tagRegistry struct {
+	mu        sync.Mutex
+	manifests map[string][]byte
+	puts      int
+}
+
+func (r *tagRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch {
+	case strings.Contains(req.URL.Path, "/blobs/"):
+		w.WriteHeader(http.StatusOK)
+	case req.Method == http.MethodPut:
+		data, _ := io.ReadAll(req.Body)
+		r.manifests[req.URL.Path] = data
+		r.puts++
+		w.WriteHeader(http.StatusCreated)
+	default:
+		data, ok := r.manifests[req.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+		w.Header().Set("Docker-Content-Digest", fmt.Sprintf("sha256:%x", sha256.Sum256(data)))
+		_, _ = w.Write(data)
+	}
+}
+
+// tagRegistryBuilder counts the builds and pushes a manifest specific to the destination to the tag registry
+type tagRegistryBuilder struct {
+	registry *tagRegistry
+	builds   int
+}
+
+func (b *tagRegistryBuilder) Build(_ context.Context, opts *builder.BuilderOptions) (string, error) {
+	b.builds++
+	ref, err := registry.ParseReference(opts.Destination)
+	if err != nil {
+		return "", err
+	}
+	b.registry.mu.Lock()
+	defer b.registry.mu.Unlock()
+	b.registry.manifests[fmt.Sprintf("/v2/%s/manifests/%s", ref.Repository, ref.Tag)] = []byte(fmt.Sprintf(
+		`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json","config":{"digest":"sha256:%s"},"layers":[]}`, ref.Tag))
+	return "", nil
+}
+
+func (b *tagRegistryBuilder) ImageExists(ctx context.Context, ref string) (bool, error) {
+	return registry.ImageExists(ctx, ref)
+}
+
+func TestSetStableImageTag(t *testing.T) {
+	reg := &tagRegistry{manifests: map[string][]byte{}}
+	server := httptest.NewServer(reg)
+	t.Cleanup(server.Close)
+	stable := strings.TrimPrefix(server.URL, "http://") + "/app:dev"
+
+	prev := ImageBuilder()
+	tb := &tagRegistryBuilder{registry: reg}
+	SetImageBuilder(tb)
+	t.Cleanup(func() { SetImageBuilder(prev) })
+
+	// each run commits a new instance with the same stable tag
+	run := func(value string) *Instance {
+		i, err := NewInstance("stable")
+		require.NoError(t, err)
+		t.Cleanup(func() { os.RemoveAll(i.getBuildDir()) })
+		require.NoError(t, i.SetImage("alpine:latest"))
+		require.NoError(t, i.SetEnvironmentVariable("FOO", value))
+		require.NoError(t, i.SetStableImageTag(stable))
+		require.NoError(t, i.SetOperationTimeout(5*time.Second))
+		require.NoError(t, i.Commit())
+		return i
+	}
+
+	first := run("bar")
+	assert.Equal(t, 1, tb.builds)
+	assert.Equal(t, stable, first.imageName, "the instance should run the stable tag")
+	digest, err := registry.ImageDigest(context.Background(), stable)
+	require.NoError(t, err)
+	assert.NotEmpty(t, digest, "the stable tag should be pushed")
+	puts := reg.puts
+
+	second := run("bar")
+	assert.Equal(t, 1, tb.builds, "the next run should reuse the stable tag without building")
+	assert.Equal(t, puts, reg.puts, "the stable tag should not be pushed again")
+	assert.Equal(t, stable, second.imageName)
+
+	run("baz")
+	assert.Equal(t, 2, tb.builds, "a stable tag built from another hash should be rebuilt")
+	updated, err := registry.ImageDigest(context.Background(), stable)
+	require.NoError(t, err)
+	assert.NotEqual(t, digest, updated, "the stable tag should be moved to the new image")
+
+	i := newTestInstance(t, "stable")
+	assert.ErrorIs(t, i.SetStableImageTag("app@sha256:abc"), ErrInvalidStableImageTag)
+	i.state = Committed
+	assert.ErrorIs(t, i.SetStableImageTag(stable), ErrSettingStableImageTagNotAllowed)
+}
@@ -0,0 +1,20 @@
+package knuu
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// SetStopSignal sets the signal the container of the instance is stopped with in its image, e.g. SIGQUIT for
+// an app shutting down gracefully on it, as a name with or without the SIG prefix or as a number
+// The container is still killed once the termination grace period of the pod expires
+// This function can only be called in the state 'Preparing'
+func (i *Instance) SetStopSignal(signal string) error {
+	if !i.IsInState(Preparing) {
+		return ErrSettingStopSignalNotAllowed.WithParams(i.state.String())
+	}
+	if err := i.builderFactory.SetStopSignal(signal); err != nil {
+		return ErrSettingStopSignal.WithParams(signal, i.name).Wrap(err)
+	}
+	logrus.Debugf("Set stop signal '%s' for instance '%s'", signal, i.name)
+	return nil
+}
@@ -0,0 +1,120 @@
+package knuu
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SymlinkPolicy defines how symlinks in a folder added with AddFolder are handled
+type SymlinkPolicy int
+
+// Possible symlink policies
+const (
+	// SymlinkCopy copies the symlink itself, so it points to the same target in the instance.
+	// A relative target inside the folder keeps working, an absolute target must exist in the image.
+	// This is the default, as it never copies more than what is in the folder.
+	SymlinkCopy SymlinkPolicy = iota
+	// SymlinkFollow copies the content of the target instead of the symlink, including whole directories
+	SymlinkFollow
+	// SymlinkError fails adding a folder that contains a symlink
+	SymlinkError
+)
+
+// String returns the string representation of the policy
+func (p SymlinkPolicy) String() string {
+	if p < 0 || p > 2 {
+		return "Unknown"
+	}
+	return [...]string{"SymlinkCopy", "SymlinkFollow", "SymlinkError"}[p]
+}
+
+// SetSymlinkPolicy sets how symlinks in the folders added with AddFolder are handled, the default is SymlinkCopy
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetSymlinkPolicy(policy SymlinkPolicy) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingSymlinkPolicyNotAllowed.WithParams(i.state.String())
+	}
+	if policy.String() == "Unknown" {
+		return ErrInvalidSymlinkPolicy.WithParams(int(policy))
+	}
+	i.symlinkPolicy = policy
+	logrus.Debugf("Set symlink policy to '%s' in instance '%s'", policy.String(), i.name)
+	return nil
+}
+
+// addFolderContents adds the files of the src folder to the dest folder of the instance,
+// handling symlinks according to the symlink policy. The visited folders prevent symlink loops.
+func (i *Instance) addFolderContents(src, dest, chown string, visited map[string]bool) error {
+	realSrc, err := filepath.EvalSymlinks(src)
+	if err != nil {
+		return err
+	}
+	if visited[realSrc] {
+		return ErrSymlinkLoop.WithParams(src)
+	}
+	visited[realSrc] = true
+	defer delete(visited, realSrc)
+
+	// walk the resolved folder, as Walk does not descend into a symlink to a folder
+	return filepath.Walk(realSrc, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// create the destination path
+		relPath, err := filepath.Rel(realSrc, path)
+		if err != nil {
+			return err
+		}
+		dstPath := filepath.Join(i.getImageFilesDir(), dest, relPath)
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			return i.addSymlink(path, filepath.Join(dest, relPath), chown, visited)
+		}
+		if info.IsDir() {
+			// create directory at destination path
+			return os.MkdirAll(dstPath, os.ModePerm)
+		}
+		// copy file to destination path
+		return i.addFile(path, filepath.Join(dest, relPath), chown)
+	})
+}
+
+// addSymlink adds the symlink at path to dest according to the symlink policy
+func (i *Instance) addSymlink(path, dest, chown string, visited map[string]bool) error {
+	switch i.symlinkPolicy {
+	case SymlinkError:
+		return ErrSymlinkNotAllowed.WithParams(path)
+	case SymlinkFollow:
+		target, err := os.Stat(path)
+		if err != nil {
+			return ErrResolvingSymlink.WithParams(path).Wrap(err)
+		}
+		if target.IsDir() {
+			return i.addFolderContents(path, dest, chown, visited)
+		}
+		return i.addFile(path, dest, chown)
+	}
+
+	// the files added in the state 'Committed' are stored in a configmap, which cannot hold symlinks
+	if i.state != Preparing {
+		return ErrCopyingSymlinkNotAllowed.WithParams(path, i.state.String())
+	}
+	target, err := os.Readlink(path)
+	if err != nil {
+		return ErrResolvingSymlink.WithParams(path).Wrap(err)
+	}
+	dstPath := filepath.Join(i.getImageFilesDir(), dest)
+	if err := os.MkdirAll(filepath.Dir(dstPath), os.ModePerm); err != nil {
+		return ErrCreatingDirectory.Wrap(err)
+	}
+	if err := os.Remove(dstPath); err != nil && !os.IsNotExist(err) {
+		return ErrFailedToCreateDestFile.WithParams(dstPath).Wrap(err)
+	}
+	if err := os.Symlink(target, dstPath); err != nil {
+		return ErrFailedToCreateDestFile.WithParams(dstPath).Wrap(err)
+	}
+	return i.addFileToBuilder(path, dest, chown)
+}
@@ -0,0 +1,71 @@
+package knuu
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddFolderSymlinkPolicy(t *testing.T) {
+	src := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "dir"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "dir", "file.txt"), []byte("content"), 0644))
+	require.NoError(t, os.Symlink("dir/file.txt", filepath.Join(src, "file-link")))
+	require.NoError(t, os.Symlink("dir", filepath.Join(src, "dir-link")))
+
+	newSymlinkInstance := func(name string, policy SymlinkPolicy) *Instance {
+		i, err := NewInstance(name)
+		require.NoError(t, err)
+		require.NoError(t, i.SetImage("docker.io/alpine:3.20"))
+		t.Cleanup(func() { os.RemoveAll(i.getBuildDir()) })
+		require.NoError(t, i.SetSymlinkPolicy(policy))
+		return i
+	}
+
+	copyInstance := newSymlinkInstance("symlink-copy", SymlinkCopy)
+	require.NoError(t, copyInstance.AddFolder(src, "/data", "0:0"))
+	for link, target := range map[string]string{"file-link": "dir/file.txt", "dir-link": "dir"} {
+		path := filepath.Join(copyInstance.getBuildDir(), "data", link)
+		info, err := os.Lstat(path)
+		require.NoError(t, err)
+		assert.NotZero(t, info.Mode()&os.ModeSymlink, "%s should be copied as a symlink", link)
+		linkTarget, err := os.Readlink(path)
+		require.NoError(t, err)
+		assert.Equal(t, target, linkTarget)
+	}
+
+	followInstance := newSymlinkInstance("symlink-follow", SymlinkFollow)
+	require.NoError(t, followInstance.AddFolder(src, "/data", "0:0"))
+	for _, file := range []string{"file-link", "dir-link/file.txt"} {
+		path := filepath.Join(followInstance.getBuildDir(), "data", file)
+		info, err := os.Lstat(path)
+		require.NoError(t, err)
+		assert.True(t, info.Mode().IsRegular(), "%s should be a regular file", file)
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "content", string(content))
+	}
+
+	errorInstance := newSymlinkInstance("symlink-error", SymlinkError)
+	assert.ErrorIs(t, errorInstance.AddFolder(src, "/data", "0:0"), ErrCopyingFolderToInstance)
+	assert.ErrorContains(t, errorInstance.AddFolder(src, "/data", "0:0"), "symlink")
+
+	assert.ErrorIs(t, errorInstance.SetSymlinkPolicy(SymlinkPolicy(42)), ErrInvalidSymlinkPolicy)
+}
+
+func TestAddFolderSymlinkLoop(t *testing.T) {
+	src := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "dir"), 0755))
+	require.NoError(t, os.Symlink("..", filepath.Join(src, "dir", "parent")))
+
+	i, err := NewInstance("symlink-loop")
+	require.NoError(t, err)
+	require.NoError(t, i.SetImage("docker.io/alpine:3.20"))
+	t.Cleanup(func() { os.RemoveAll(i.getBuildDir()) })
+	require.NoError(t, i.SetSymlinkPolicy(SymlinkFollow))
+
+	assert.ErrorContains(t, i.AddFolder(src, "/data", "0:0"), "loop")
+}
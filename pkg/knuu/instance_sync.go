@@ -0,0 +1,220 @@
+package knuu
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+)
+
+// SyncFolder adds a folder to the instance and keeps it in sync, e.g. in the inner loop of iterative development
+// Before the instance is started, the folder is added to the image like with AddFolder
+// Once the instance is started, only the files whose content changed since the last call for the same destination
+// are copied into the running pod, instead of rebuilding the image. The container needs a tar binary for it
+// Files deleted from src are not removed from the pod and symlinks are not synced
+// The files copied into the pod are lost when the pod is replaced, e.g. by Stop and Start, SetImage or RolloutRestart,
+// or when the container restarts, so the next call copies all the files the new container does not have from its image
+// It returns the paths of the added or synced files, relative to src
+// This function can only be called in the states 'Preparing', 'Committed' and 'Started'
+func (i *Instance) SyncFolder(ctx context.Context, src, dest, chown string) ([]string, error) {
+	if !i.IsInState(Preparing, Committed, Started) {
+		return nil, ErrSyncingFolderNotAllowed.WithParams(i.state.String())
+	}
+	if err := i.validateFileArgs(src, dest, chown); err != nil {
+		return nil, err
+	}
+	dest = path.Clean(dest)
+	if !path.IsAbs(dest) {
+		return nil, ErrSyncDestinationNotAbsolute.WithParams(dest)
+	}
+
+	digests, err := folderDigests(src)
+	if err != nil {
+		return nil, ErrSyncingFolder.WithParams(src, i.name).Wrap(err)
+	}
+
+	if !i.IsInState(Started) {
+		if err := i.AddFolder(src, dest, chown); err != nil {
+			return nil, err
+		}
+		i.syncedFolders[dest] = syncedFolder{image: digests}
+		return sortedKeys(digests), nil
+	}
+
+	parent := i
+	if i.isSidecar {
+		parent = i.parentInstance
+	}
+	pod, err := k8sClient.GetFirstPodFromReplicaSet(ctx, parent.k8sName)
+	if err != nil {
+		return nil, ErrGettingPodFromReplicaSet.WithParams(parent.k8sName).Wrap(err)
+	}
+	podKey := syncedPodKey(pod, i.k8sName)
+
+	folder := i.syncedFolders[dest]
+	changed := folder.filesToSync(podKey, digests)
+	if len(changed) == 0 {
+		i.syncedFolders[dest] = syncedFolder{image: folder.image, pod: podKey, digests: digests}
+		logrus.Debugf("Folder '%s' of instance '%s' is already in sync", dest, i.name)
+		return nil, nil
+	}
+	archive, err := folderArchive(src, dest, changed, chown)
+	if err != nil {
+		return nil, ErrSyncingFolder.WithParams(src, i.name).Wrap(err)
+	}
+	if err := k8sClient.CopyToPod(ctx, pod.Name, i.k8sName, "/", archive); err != nil {
+		return nil, ErrSyncingFolder.WithParams(src, i.name).Wrap(err)
+	}
+
+	i.syncedFolders[dest] = syncedFolder{image: folder.image, pod: podKey, digests: digests}
+	logrus.Debugf("Synced %d files of folder '%s' to instance '%s'", len(changed), dest, i.name)
+	return changed, nil
+}
+
+// syncedFolder is the state of a folder synced with SyncFolder, the digests are keyed by the paths relative to the folder
+type syncedFolder struct {
+	image   map[string]string // digests of the files added to the image before the instance was started
+	pod     string            // run of the container the files were synced into, see syncedPodKey
+	digests map[string]string // digests of the files synced into that run of the container
+}
+
+// filesToSync returns the sorted paths of the files to copy into the run of the container identified by podKey
+// The files synced into another pod or before the container restarted are lost, so the files are compared with the image
+func (f syncedFolder) filesToSync(podKey string, digests map[string]string) []string {
+	if f.pod != podKey {
+		return changedFiles(f.image, digests)
+	}
+	return changedFiles(f.digests, digests)
+}
+
+// syncedPodKey identifies the run of the container in the pod, which changes when the pod is replaced
+// or the container restarts
+func syncedPodKey(pod *v1.Pod, container string) string {
+	restarts := int32(0)
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == container {
+			restarts = status.RestartCount
+		}
+	}
+	return fmt.Sprintf("%s/%d", pod.UID, restarts)
+}
+
+// forgetSyncedImageFiles forgets the files added to the image by SyncFolder, once the instance runs another image
+func (i *Instance) forgetSyncedImageFiles() {
+	for dest, folder := range i.syncedFolders {
+		folder.image = nil
+		i.syncedFolders[dest] = folder
+	}
+}
+
+// folderDigests returns the sha256 digest of the content of each regular file of the folder, keyed by its path relative to it
+func folderDigests(src string) (map[string]string, error) {
+	realSrc, err := filepath.EvalSymlinks(src)
+	if err != nil {
+		return nil, err
+	}
+	digests := make(map[string]string)
+	err = filepath.Walk(realSrc, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		relPath, err := filepath.Rel(realSrc, filePath)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		hash := sha256.New()
+		if _, err := io.Copy(hash, file); err != nil {
+			return err
+		}
+		digests[filepath.ToSlash(relPath)] = hex.EncodeToString(hash.Sum(nil))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return digests, nil
+}
+
+// changedFiles returns the sorted paths of the files that are new or whose digest changed since the last sync
+func changedFiles(synced, digests map[string]string) []string {
+	changed := maps.Clone(digests)
+	maps.DeleteFunc(changed, func(relPath, digest string) bool { return synced[relPath] == digest })
+	return sortedKeys(changed)
+}
+
+// folderArchive returns a tar archive of the files of the folder, at their path in dest, owned by chown
+func folderArchive(src, dest string, files []string, chown string) (io.Reader, error) {
+	realSrc, err := filepath.EvalSymlinks(src)
+	if err != nil {
+		return nil, err
+	}
+	user, group, _ := strings.Cut(chown, ":")
+
+	var buffer bytes.Buffer
+	tarWriter := tar.NewWriter(&buffer)
+	for _, relPath := range files {
+		filePath := filepath.Join(realSrc, filepath.FromSlash(relPath))
+		info, err := os.Stat(filePath)
+		if err != nil {
+			return nil, err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return nil, err
+		}
+		// the archive is extracted at the root of the container
+		header.Name = strings.TrimPrefix(path.Join(dest, relPath), "/")
+		header.Uid, header.Uname = tarOwner(user)
+		header.Gid, header.Gname = tarOwner(group)
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tarWriter.Write(content); err != nil {
+			return nil, err
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		return nil, err
+	}
+	return &buffer, nil
+}
+
+// tarOwner returns the id and name of the owner of a file in a tar archive, from a numeric id or a name
+func tarOwner(owner string) (int, string) {
+	if id, err := strconv.Atoi(owner); err == nil {
+		return id, ""
+	}
+	return 0, owner
+}
+
+// sortedKeys returns the keys of the map in ascending order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,87 @@
+package knuu
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/celestiaorg/knuu/pkg/k8s"
+)
+
+func TestSyncFolder(t *testing.T) {
+	lockK8sClient(t)
+	k8sClient = &k8s.Client{}
+
+	src := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "config"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "config", "app.toml"), []byte("version = 1"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "genesis.json"), []byte("{}"), 0644))
+
+	i, err := NewInstance("sync-folder")
+	require.NoError(t, err)
+	require.NoError(t, i.SetImage("docker.io/alpine:3.20"))
+	t.Cleanup(func() { os.RemoveAll(i.getBuildDir()) })
+
+	_, err = i.SyncFolder(context.Background(), src, "data", "0:0")
+	assert.ErrorIs(t, err, ErrSyncDestinationNotAbsolute)
+
+	// before the instance is started, the whole folder is added to the image
+	synced, err := i.SyncFolder(context.Background(), src, "/data/", "0:0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"config/app.toml", "genesis.json"}, synced)
+	content, err := os.ReadFile(filepath.Join(i.getBuildDir(), "data", "config", "app.toml"))
+	require.NoError(t, err)
+	assert.Equal(t, "version = 1", string(content))
+
+	// only the changed file is synced afterwards
+	require.NoError(t, os.WriteFile(filepath.Join(src, "config", "app.toml"), []byte("version = 2"), 0644))
+	digests, err := folderDigests(src)
+	require.NoError(t, err)
+	folder := i.syncedFolders["/data"]
+	changed := folder.filesToSync("pod-a/0", digests)
+	assert.Equal(t, []string{"config/app.toml"}, changed)
+	assert.Empty(t, changedFiles(digests, digests))
+
+	// the files synced into a container are lost when the pod is replaced or the container restarts
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{UID: "pod-a"},
+		Status:     v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{{Name: i.k8sName, RestartCount: 0}}},
+	}
+	folder = syncedFolder{image: folder.image, pod: syncedPodKey(pod, i.k8sName), digests: digests}
+	assert.Empty(t, folder.filesToSync("pod-a/0", digests), "the files synced into the same container should not be copied again")
+	pod.Status.ContainerStatuses[0].RestartCount = 1
+	assert.Equal(t, []string{"config/app.toml"}, folder.filesToSync(syncedPodKey(pod, i.k8sName), digests))
+	pod.UID = "pod-b"
+	assert.Equal(t, []string{"config/app.toml"}, folder.filesToSync(syncedPodKey(pod, i.k8sName), digests))
+
+	// a new image does not have the files added to the previous one
+	i.syncedFolders["/data"] = folder
+	i.forgetSyncedImageFiles()
+	assert.Equal(t, []string{"config/app.toml", "genesis.json"}, i.syncedFolders["/data"].filesToSync("pod-c/0", digests))
+
+	archive, err := folderArchive(src, "/data", changed, "1000:1000")
+	require.NoError(t, err)
+	tarReader := tar.NewReader(archive)
+	header, err := tarReader.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "data/config/app.toml", header.Name)
+	assert.Equal(t, 1000, header.Uid)
+	assert.Equal(t, 1000, header.Gid)
+	content, err = io.ReadAll(tarReader)
+	require.NoError(t, err)
+	assert.Equal(t, "version = 2", string(content))
+	_, err = tarReader.Next()
+	assert.Equal(t, io.EOF, err, "only the changed file should be in the archive")
+
+	i.state = Stopped
+	_, err = i.SyncFolder(context.Background(), src, "/data", "0:0")
+	assert.ErrorIs(t, err, ErrSyncingFolderNotAllowed)
+}
@@ -0,0 +1,66 @@
+package knuu
+
+import (
+	"context"
+	"path"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+)
+
+// SetTerminationMessagePath sets the file the app writes the reason of its termination to, e.g. a failure reason,
+// which can then be read with GetTerminationMessage instead of parsing the logs
+// By default kubernetes uses /dev/termination-log, the message is limited to 4096 bytes
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetTerminationMessagePath(messagePath string) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingTerminationMessageNotAllowed.WithParams(i.state.String())
+	}
+	if !path.IsAbs(messagePath) {
+		return ErrTerminationMessagePathNotAbsolute.WithParams(messagePath)
+	}
+	i.termMsgPath = path.Clean(messagePath)
+	logrus.Debugf("Set termination message path to '%s' in instance '%s'", i.termMsgPath, i.name)
+	return nil
+}
+
+// SetTerminationMessagePolicy sets how the termination message is gathered
+// With v1.TerminationMessageFallbackToLogsOnError, the end of the logs is used when the app fails without writing the file
+// By default, v1.TerminationMessageReadFile, only the file is used
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) SetTerminationMessagePolicy(policy v1.TerminationMessagePolicy) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrSettingTerminationMessageNotAllowed.WithParams(i.state.String())
+	}
+	if policy != v1.TerminationMessageReadFile && policy != v1.TerminationMessageFallbackToLogsOnError {
+		return ErrInvalidTerminationMessagePolicy.WithParams(policy)
+	}
+	i.termMsgPolicy = policy
+	logrus.Debugf("Set termination message policy to '%s' in instance '%s'", policy, i.name)
+	return nil
+}
+
+// GetTerminationMessage returns the termination message of the container of the instance once it has terminated
+// Like GetContainerExitCode, the message of the last run is returned until the container is running again
+// It returns an error if the container has not terminated yet
+// This function can only be called in the state 'Started'
+func (i *Instance) GetTerminationMessage(ctx context.Context) (string, error) {
+	if !i.IsInState(Started) {
+		return "", ErrGettingTerminationMessageNotAllowed.WithParams(i.state.String())
+	}
+
+	instanceName := i.k8sName
+	if i.isSidecar {
+		instanceName = i.parentInstance.k8sName
+	}
+
+	pod, err := k8sClient.GetFirstPodFromReplicaSet(ctx, instanceName)
+	if err != nil {
+		return "", ErrGettingPodFromReplicaSet.WithParams(i.k8sName).Wrap(err)
+	}
+	terminated := lastTermination(pod, i.k8sName)
+	if terminated == nil {
+		return "", ErrContainerNotTerminated.WithParams(i.name)
+	}
+	return terminated.Message, nil
+}
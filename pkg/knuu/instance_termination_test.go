@@ -0,0 +1,36 @@
+package knuu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/celestiaorg/knuu/pkg/k8s"
+)
+
+func TestTerminationMessage(t *testing.T) {
+	lockK8sClient(t)
+	i := newTestInstance(t, "termination-message")
+	require.NoError(t, i.SetTerminationMessagePath("/tmp/../var/reason"))
+	require.NoError(t, i.SetTerminationMessagePolicy(v1.TerminationMessageFallbackToLogsOnError))
+	assert.ErrorIs(t, i.SetTerminationMessagePath("reason"), ErrTerminationMessagePathNotAbsolute)
+	assert.ErrorIs(t, i.SetTerminationMessagePolicy("Logs"), ErrInvalidTerminationMessagePolicy)
+
+	k8sClient = &k8s.Client{}
+	container := i.prepareReplicaSetConfig().PodConfig.ContainerConfig
+	assert.Equal(t, "/var/reason", container.TermMsgPath)
+	assert.Equal(t, v1.TerminationMessageFallbackToLogsOnError, container.TermMsgPolicy)
+
+	pod := &v1.Pod{Status: v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{{
+		Name:  "job",
+		State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1, Message: "config not found"}},
+	}}}}
+	terminated := lastTermination(pod, "job")
+	require.NotNil(t, terminated)
+	assert.Equal(t, "config not found", terminated.Message)
+
+	i.state = Started
+	assert.ErrorIs(t, i.SetTerminationMessagePath("/var/reason"), ErrSettingTerminationMessageNotAllowed)
+}
@@ -0,0 +1,743 @@
+package knuu
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/celestiaorg/knuu/pkg/builder"
+	"github.com/celestiaorg/knuu/pkg/container"
+	"github.com/celestiaorg/knuu/pkg/k8s"
+	"github.com/celestiaorg/knuu/pkg/registry"
+)
+
+func TestCapabilities(t *testing.T) {
+	i := newTestInstance(t, "capabilities")
+
+	require.NoError(t, i.DropAllCapabilities())
+	require.NoError(t, i.AddCapability("NET_ADMIN"))
+
+	sc := prepareSecurityContext(i.securityContext)
+	require.NotNil(t, sc.Capabilities)
+	assert.Equal(t, []v1.Capability{"NET_ADMIN"}, sc.Capabilities.Add)
+	assert.Equal(t, []v1.Capability{"ALL"}, sc.Capabilities.Drop)
+
+	for _, capability := range []string{"", "net_raw", "CAP_NET_RAW", "NET-RAW", "sys admin", "NET_ADMN"} {
+		assert.ErrorIs(t, i.AddCapability(capability), ErrInvalidCapability, capability)
+		assert.ErrorIs(t, i.AddCapabilities([]string{"NET_BIND_SERVICE", capability}), ErrInvalidCapability, capability)
+		assert.ErrorIs(t, i.DropCapability(capability), ErrInvalidCapability, capability)
+	}
+	require.NoError(t, i.AddCapabilities([]string{"NET_BIND_SERVICE", "SYS_TIME"}))
+	assert.Equal(t, []v1.Capability{"NET_ADMIN", "NET_BIND_SERVICE", "SYS_TIME"}, prepareSecurityContext(i.securityContext).Capabilities.Add,
+		"the invalid capabilities should not be added")
+
+	i.state = Started
+	assert.ErrorIs(t, i.DropCapability("NET_RAW"), ErrDroppingCapabilityNotAllowed)
+}
+
+func TestAddFolderLimits(t *testing.T) {
+	src := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(src, name), []byte("0123456789"), 0644))
+	}
+
+	i := newTestInstance(t, "folder-limits")
+
+	require.NoError(t, i.SetFolderLimits(0, 2))
+	assert.ErrorIs(t, i.AddFolder(src, "/data", "0:0"), ErrFolderHasTooManyFiles)
+
+	require.NoError(t, i.SetFolderLimits(25, 0))
+	err := i.AddFolder(src, "/data", "0:0")
+	assert.ErrorIs(t, err, ErrFolderTooLarge)
+	assert.ErrorContains(t, err, src)
+
+	assert.ErrorIs(t, i.SetFolderLimits(-1, 0), ErrInvalidFolderLimits)
+}
+
+func TestSetImageResetsImage(t *testing.T) {
+	i, err := NewInstance("set-image")
+	require.NoError(t, err)
+
+	require.NoError(t, i.SetImage("docker.io/alpine:3.19"))
+	_, err = i.ExecuteCommand("echo", "hello")
+	require.NoError(t, err)
+	require.NoError(t, i.SetUser("1000"))
+	require.NoError(t, i.SetBuildCacheDir("/var/cache/knuu"))
+	require.NoError(t, i.SetCommand("sleep", "infinity"))
+	require.True(t, i.builderFactory.Changed())
+	hash, err := i.builderFactory.GenerateImageHash()
+	require.NoError(t, err)
+	previous := i.builderFactory
+
+	require.NoError(t, i.SetImage("docker.io/alpine:3.20"))
+	assert.NotSame(t, previous, i.builderFactory, "the build settings, e.g. the cache dir, should be discarded with the builder")
+	assert.Equal(t, Preparing, i.state)
+	assert.Equal(t, "docker.io/alpine:3.20", i.builderFactory.ImageNameFrom())
+	assert.False(t, i.builderFactory.Changed(), "previous image changes, including the user, should be discarded")
+	assert.Equal(t, []string{"sleep", "infinity"}, i.command, "the settings of the instance should be kept")
+
+	newHash, err := i.builderFactory.GenerateImageHash()
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, newHash)
+
+	require.NoError(t, i.Commit())
+	assert.Equal(t, "docker.io/alpine:3.20", i.imageName)
+
+	require.NoError(t, i.SetImage("docker.io/alpine:3.21"))
+	assert.Equal(t, Preparing, i.state)
+	require.NoError(t, i.Commit())
+	assert.Equal(t, "docker.io/alpine:3.21", i.imageName)
+
+	i.state = Stopped
+	assert.ErrorIs(t, i.SetImage("docker.io/alpine:3.21"), ErrSettingImageNotAllowed)
+}
+
+func TestPodRunningAndRestarts(t *testing.T) {
+	running := v1.ContainerState{Running: &v1.ContainerStateRunning{}}
+	waiting := v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}
+
+	tt := []struct {
+		name             string
+		statuses         []v1.ContainerStatus
+		expectedRunning  bool
+		expectedRestarts int32
+	}{
+		{"NoStatuses", nil, false, 0},
+		{"Running", []v1.ContainerStatus{{State: running, Ready: true}}, true, 0},
+		{"NotReady", []v1.ContainerStatus{{State: running, Ready: false}}, false, 0},
+		{"CrashLooping", []v1.ContainerStatus{{State: waiting, RestartCount: 2}}, false, 2},
+		{"SidecarRestarted", []v1.ContainerStatus{
+			{State: running, Ready: true},
+			{State: running, Ready: true, RestartCount: 1},
+		}, true, 1},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := &v1.Pod{Status: v1.PodStatus{ContainerStatuses: tc.statuses}}
+			running, restarts := podRunningAndRestarts(pod)
+			assert.Equal(t, tc.expectedRunning, running)
+			assert.Equal(t, tc.expectedRestarts, restarts)
+		})
+	}
+}
+
+func TestContainerExitCode(t *testing.T) {
+	running := v1.ContainerState{Running: &v1.ContainerStateRunning{}}
+	terminated := v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 3}}
+	waiting := v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}
+	lastTerminated := v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1}}
+
+	tt := []struct {
+		name               string
+		statuses           []v1.ContainerStatus
+		expectedCode       int
+		expectedTerminated bool
+	}{
+		{"NoStatuses", nil, 0, false},
+		{"Running", []v1.ContainerStatus{{Name: "job", State: running}}, 0, false},
+		{"Terminated", []v1.ContainerStatus{{Name: "job", State: terminated}}, 3, true},
+		{"WaitingToRestart", []v1.ContainerStatus{{Name: "job", State: waiting, LastTerminationState: lastTerminated}}, 1, true},
+		{"RunningAgain", []v1.ContainerStatus{{Name: "job", State: running, LastTerminationState: lastTerminated}}, 0, false},
+		{"OtherContainer", []v1.ContainerStatus{{Name: "sidecar", State: terminated}, {Name: "job", State: running}}, 0, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := &v1.Pod{Status: v1.PodStatus{ContainerStatuses: tc.statuses}}
+			code, terminated := containerExitCode(pod, "job")
+			assert.Equal(t, tc.expectedCode, code)
+			assert.Equal(t, tc.expectedTerminated, terminated)
+		})
+	}
+}
+
+func TestSetSysctl(t *testing.T) {
+	i := newTestInstance(t, "sysctl")
+
+	require.NoError(t, i.SetSysctl("net.ipv4.tcp_syncookies", "0"))
+	require.NoError(t, i.SetSysctl("net.core.somaxconn", "1024"))
+	require.NoError(t, i.SetSysctl("net.ipv4.tcp_syncookies", "1"))
+	assert.Equal(t, []v1.Sysctl{
+		{Name: "net.ipv4.tcp_syncookies", Value: "1"},
+		{Name: "net.core.somaxconn", Value: "1024"},
+	}, i.sysctls)
+
+	assert.True(t, isSafeSysctl("net.ipv4.tcp_syncookies"))
+	assert.False(t, isSafeSysctl("net.core.somaxconn"))
+
+	for _, name := range []string{"", "net..core", "Net.Core.Somaxconn", "net.core.somaxconn "} {
+		assert.ErrorIs(t, i.SetSysctl(name, "1"), ErrInvalidSysctl, name)
+	}
+}
+
+func TestSetPriorityClass(t *testing.T) {
+	lockK8sClient(t)
+	k8sClient = &k8s.Client{}
+
+	i := newTestInstance(t, "priority")
+	assert.ErrorIs(t, i.SetPriorityClass(""), ErrPriorityClassNameEmpty)
+	require.NoError(t, i.SetPriorityClass("high-priority"))
+
+	config := i.prepareReplicaSetConfig()
+	assert.Equal(t, "high-priority", config.PodConfig.PriorityClassName)
+}
+
+func TestSetRuntimeClass(t *testing.T) {
+	lockK8sClient(t)
+	k8sClient = &k8s.Client{}
+
+	i := newTestInstance(t, "runtime-class")
+	assert.ErrorIs(t, i.SetRuntimeClass(""), ErrRuntimeClassNameEmpty)
+	require.NoError(t, i.SetRuntimeClass("gvisor"))
+
+	config := i.prepareReplicaSetConfig()
+	assert.Equal(t, "gvisor", config.PodConfig.RuntimeClassName)
+
+	// the runtime class is not installed in the cluster
+	var requested string
+	k8sClient = newTestK8sClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/apis/node.k8s.io/v1/runtimeclasses/") {
+			requested = path.Base(r.URL.Path)
+		}
+		echoK8sHandler(w, r)
+	})
+	assert.ErrorIs(t, i.deployPod(context.Background()), ErrRuntimeClassNotFound)
+	assert.Equal(t, "gvisor", requested)
+
+	i.state = Started
+	assert.ErrorIs(t, i.SetRuntimeClass("kata"), ErrSettingRuntimeClassNotAllowed)
+}
+
+func TestSetEnvMap(t *testing.T) {
+	i := newTestInstance(t, "env-map")
+	i.state = Committed
+
+	require.NoError(t, i.SetEnvironmentVariable("A", "single"))
+	require.NoError(t, i.SetEnvMap(map[string]string{"A": "1", "B": "2"}))
+	require.NoError(t, i.SetEnvMap(map[string]string{"B": "override", "C": "3"}))
+	assert.Equal(t, map[string]string{"A": "1", "B": "override", "C": "3"}, i.env)
+
+	i.state = Started
+	assert.ErrorIs(t, i.SetEnvMap(map[string]string{"D": "4"}), ErrSettingEnvNotAllowed)
+}
+
+func TestSetEnvFromField(t *testing.T) {
+	lockK8sClient(t)
+	k8sClient = &k8s.Client{}
+
+	i := newTestInstance(t, "env-from-field")
+	require.NoError(t, i.SetEnvFromField("POD_NAME", "metadata.name"))
+	require.NoError(t, i.SetEnvFromField("APP", "metadata.labels['app']"))
+	require.NoError(t, i.SetEnvFromResourceField("MEMORY_LIMIT", "limits.memory", "1Mi"))
+
+	assert.ErrorIs(t, i.SetEnvFromField("", "metadata.name"), ErrEnvNameEmpty)
+	for _, fieldPath := range []string{"", "metadata", "spec.containers", "metadata.labels"} {
+		assert.ErrorIs(t, i.SetEnvFromField("FIELD", fieldPath), ErrInvalidEnvFieldPath, fieldPath)
+	}
+	assert.ErrorIs(t, i.SetEnvFromResourceField("CPU", "limits.gpu", ""), ErrInvalidEnvResourceField)
+	assert.ErrorIs(t, i.SetEnvFromResourceField("CPU", "limits.cpu", "one"), ErrInvalidEnvResourceDivisor)
+
+	config := i.prepareReplicaSetConfig().PodConfig.ContainerConfig
+	assert.Equal(t, "metadata.name", config.EnvValueFrom["POD_NAME"].FieldRef.FieldPath)
+	assert.Equal(t, "metadata.labels['app']", config.EnvValueFrom["APP"].FieldRef.FieldPath)
+	assert.Equal(t, "limits.memory", config.EnvValueFrom["MEMORY_LIMIT"].ResourceFieldRef.Resource)
+	assert.Equal(t, "1Mi", config.EnvValueFrom["MEMORY_LIMIT"].ResourceFieldRef.Divisor.String())
+
+	// a literal value replaces the sourced value
+	i.state = Committed
+	require.NoError(t, i.SetEnvironmentVariable("POD_NAME", "literal"))
+	assert.NotContains(t, i.envValueFrom, "POD_NAME")
+}
+
+func TestGetPodNameNotStarted(t *testing.T) {
+	i := newTestInstance(t, "pod-name")
+
+	_, err := i.GetPodName()
+	assert.ErrorIs(t, err, ErrGettingPodNameNotAllowed)
+	assert.Empty(t, i.GetNamespace(), "the namespace is empty before knuu is initialized")
+}
+
+func TestSetWorkingDir(t *testing.T) {
+	lockK8sClient(t)
+	k8sClient = &k8s.Client{}
+
+	i := newTestInstance(t, "working-dir")
+	for _, dir := range []string{"", "app", "./app"} {
+		assert.ErrorIs(t, i.SetWorkingDir(dir), ErrWorkingDirNotAbsolute, dir)
+	}
+	require.NoError(t, i.SetWorkingDir("/srv/app/"))
+
+	config := i.prepareReplicaSetConfig()
+	assert.Equal(t, "/srv/app", config.PodConfig.ContainerConfig.WorkingDir)
+
+	i.state = Started
+	assert.ErrorIs(t, i.SetWorkingDir("/tmp"), ErrSettingWorkingDirNotAllowed)
+}
+
+func TestRegistryMirror(t *testing.T) {
+	require.NoError(t, registry.SetMirror("myregistry"))
+	t.Cleanup(func() { require.NoError(t, registry.SetMirror("")) })
+
+	i, err := NewInstance("registry-mirror")
+	require.NoError(t, err)
+	require.NoError(t, i.SetImage("docker.io/library/nginx:1.27"))
+	require.NoError(t, i.Commit())
+	assert.Equal(t, "myregistry/library/nginx:1.27", i.imageName, "the image of the instance should be pulled from the mirror")
+
+	i.imageName = ""
+	imageName, err := i.getImageRegistry("abc")
+	require.NoError(t, err)
+	assert.Equal(t, "myregistry/abc:24h", imageName, "built images should be pushed to the mirror")
+
+	// the image swapped in once the instance is started is pulled from the mirror as well
+	var (
+		mu      sync.Mutex
+		created []string
+	)
+	useK8sClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if spec, ok := createdPodSpec(t, r); ok {
+			created = append(created, spec.Containers[0].Image)
+		}
+		if r.Method == http.MethodGet && len(created) != 0 && strings.HasSuffix(r.URL.Path, "/replicasets/"+i.k8sName) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"kind":"ReplicaSet","apiVersion":"apps/v1","metadata":{"name":%q},`+
+				`"spec":{"replicas":1},"status":{"replicas":1,"readyReplicas":1}}`, i.k8sName)
+			return
+		}
+		echoK8sHandler(w, r)
+	})
+	require.NoError(t, i.SetOperationTimeout(5*time.Second))
+	require.NoError(t, i.SetPollInterval(10*time.Millisecond))
+	i.state = Started
+	require.NoError(t, i.SetImage("docker.io/library/nginx:1.28"))
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"myregistry/library/nginx:1.28"}, created)
+	assert.Equal(t, "myregistry/library/nginx:1.28", i.imageName)
+}
+
+func TestSetShareProcessNamespace(t *testing.T) {
+	lockK8sClient(t)
+	k8sClient = &k8s.Client{}
+
+	i := newTestInstance(t, "share-pid")
+	assert.False(t, i.prepareReplicaSetConfig().PodConfig.ShareProcessNamespace)
+
+	require.NoError(t, i.SetShareProcessNamespace(true))
+	assert.True(t, i.prepareReplicaSetConfig().PodConfig.ShareProcessNamespace)
+
+	i.state = Started
+	assert.ErrorIs(t, i.SetShareProcessNamespace(false), ErrSettingShareProcessNamespaceNotAllowed)
+}
+
+func TestAddFiles(t *testing.T) {
+	lockK8sClient(t)
+	k8sClient = &k8s.Client{}
+
+	src := t.TempDir()
+	files := make([]FileSpec, 0, 3)
+	for _, name := range []string{"c.conf", "a.conf", "b.conf"} {
+		require.NoError(t, os.WriteFile(filepath.Join(src, name), []byte(name), 0644))
+		files = append(files, FileSpec{Src: filepath.Join(src, name), Dest: "/etc/app/" + name, Chown: "0:0"})
+	}
+
+	i, err := NewInstance("add-files")
+	require.NoError(t, err)
+	require.NoError(t, i.SetImage("docker.io/alpine:3.20"))
+	t.Cleanup(func() { os.RemoveAll(i.getBuildDir()) })
+
+	require.NoError(t, i.AddFiles(files))
+	for _, file := range files {
+		content, err := os.ReadFile(filepath.Join(i.getBuildDir(), file.Dest))
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Base(file.Dest), string(content))
+	}
+
+	// files added to a committed instance are mounted in the given order
+	i.state = Committed
+	require.NoError(t, i.AddVolume("/etc/app", "1Mi"))
+	require.NoError(t, i.AddFiles(files))
+	require.Len(t, i.files, 3)
+	for n, file := range files {
+		assert.Equal(t, file.Dest, i.files[n].Dest)
+	}
+
+	missing := append(files[:1:1], FileSpec{Src: filepath.Join(src, "missing.conf"), Dest: "/etc/app/missing.conf", Chown: "0:0"})
+	err = i.AddFiles(missing)
+	assert.ErrorIs(t, err, ErrAddingFiles)
+	assert.ErrorContains(t, err, "file 1 '"+missing[1].Src+"'")
+}
+
+func TestEnvFromConfigMap(t *testing.T) {
+	lockK8sClient(t)
+	k8sClient = &k8s.Client{}
+
+	i := newTestInstance(t, "env-configmap")
+	require.NoError(t, i.CreateEnvConfigMap("app-config", map[string]string{"LOG_LEVEL": "debug", "MODE": "test"}))
+	require.NoError(t, i.SetEnvFromConfigMap("APP_MODE", "app-config", "MODE"))
+	require.NoError(t, i.SetEnvFromConfigMap("SHARED", "shared-config", "value"))
+	require.NoError(t, i.AddEnvFromConfigMap("app-config"))
+	require.NoError(t, i.AddEnvFromConfigMap("shared-config"))
+
+	config := i.prepareReplicaSetConfig().PodConfig.ContainerConfig
+	managedName := i.k8sName + "-app-config"
+	require.Contains(t, config.EnvValueFrom, "APP_MODE")
+	assert.Equal(t, managedName, config.EnvValueFrom["APP_MODE"].ConfigMapKeyRef.Name, "configmaps created by the instance are named after it")
+	assert.Equal(t, "MODE", config.EnvValueFrom["APP_MODE"].ConfigMapKeyRef.Key)
+	assert.Equal(t, "shared-config", config.EnvValueFrom["SHARED"].ConfigMapKeyRef.Name)
+	assert.Equal(t, "app-config", i.envValueFrom["APP_MODE"].ConfigMapKeyRef.Name, "the configuration of the instance must not be changed")
+	require.Len(t, config.EnvFrom, 2)
+	assert.Equal(t, managedName, config.EnvFrom[0].ConfigMapRef.Name)
+	assert.Equal(t, "shared-config", config.EnvFrom[1].ConfigMapRef.Name)
+
+	clone := i.cloneWithSuffix("-clone")
+	cloneConfig := clone.prepareReplicaSetConfig().PodConfig.ContainerConfig
+	assert.Equal(t, clone.k8sName+"-app-config", cloneConfig.EnvFrom[0].ConfigMapRef.Name, "clones should get their own configmap")
+
+	assert.ErrorIs(t, i.CreateEnvConfigMap("app-config", nil), ErrEnvConfigMapAlreadyExists)
+	assert.ErrorIs(t, i.CreateEnvConfigMap("Invalid_Name", nil), ErrInvalidConfigMapName)
+	assert.ErrorIs(t, i.CreateEnvConfigMap("other", map[string]string{"bad key": "x"}), ErrInvalidConfigMapKey)
+	assert.ErrorIs(t, i.SetEnvFromConfigMap("", "app-config", "MODE"), ErrEnvNameEmpty)
+	assert.ErrorIs(t, i.AddEnvFromConfigMap("app-config"), ErrEnvFromConfigMapAlreadyAdded)
+
+	i.state = Started
+	assert.ErrorIs(t, i.AddEnvFromConfigMap("other"), ErrSettingEnvNotAllowed)
+}
+
+func TestGetUniqueName(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		names []string
+	)
+	// the subtests run in parallel, and finish before the group returns
+	// they only create instances, which does not use k8sClient, see lockK8sClient
+	t.Run("Group", func(t *testing.T) {
+		for _, test := range []string{"First", "Second"} {
+			t.Run(test, func(t *testing.T) {
+				t.Parallel()
+				for n := 0; n < 50; n++ {
+					i, err := NewInstance("web1")
+					require.NoError(t, err)
+					assert.Equal(t, "web1", i.Name())
+					assert.True(t, strings.HasPrefix(i.GetUniqueName(), "web1-"))
+
+					mu.Lock()
+					names = append(names, i.GetUniqueName())
+					mu.Unlock()
+				}
+			})
+		}
+	})
+
+	require.Len(t, names, 100)
+	slices.Sort(names)
+	assert.Len(t, slices.Compact(names), 100, "the unique names collide")
+}
+
+func TestUseBuilder(t *testing.T) {
+	// the build context is created by the factory, so it would be removed after the first push
+	fb := &builder.FakeBuilder{}
+	f, err := container.NewBuilderFactory("alpine:latest", filepath.Join(t.TempDir(), "shared"), fb)
+	require.NoError(t, err)
+	_, err = f.ExecuteCmdInBuilder([]string{"echo", "use-builder"})
+	require.NoError(t, err)
+
+	// the instances share the factory, the files added by one of them are part of the image of both
+	instances := make([]*Instance, 2)
+	for n := range instances {
+		i, err := NewInstance(fmt.Sprintf("use-builder-%d", n))
+		require.NoError(t, err)
+		assert.ErrorIs(t, i.UseBuilder(nil), ErrBuilderFactoryIsNil)
+		require.NoError(t, i.UseBuilder(f))
+		require.NoError(t, i.SetOperationTimeout(5*time.Second))
+		require.NoError(t, i.SetPollInterval(10*time.Millisecond))
+		instances[n] = i
+	}
+	src := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(src, []byte("use-builder"), 0o644))
+	require.NoError(t, instances[0].AddFile(src, "/etc/app/config", "0:0"))
+	assert.FileExists(t, filepath.Join(f.BuildContext(), "etc", "app", "config"), "the file should be copied to the build context of the factory")
+	assert.NoFileExists(t, filepath.Join(instances[0].getBuildDir(), "etc", "app", "config"))
+	hash, err := f.GenerateImageHash()
+	require.NoError(t, err)
+
+	for _, i := range instances {
+		require.NoError(t, i.Commit())
+		assert.ErrorIs(t, i.UseBuilder(f), ErrUsingBuilderNotAllowed)
+	}
+	assert.Empty(t, fb.Builds(), "the image is built when the instance is started")
+
+	var (
+		mu     sync.Mutex
+		images []string
+	)
+	useK8sClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/apis/apps/v1/namespaces/test/replicasets/") {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"kind":"ReplicaSet","apiVersion":"apps/v1","metadata":{"name":"use-builder"},"spec":{"replicas":1},"status":{"readyReplicas":1}}`)
+			return
+		}
+		if spec, ok := createdPodSpec(t, r); ok {
+			mu.Lock()
+			images = append(images, spec.Containers[0].Image)
+			mu.Unlock()
+		}
+		echoK8sHandler(w, r)
+	})
+
+	// building and running the image is a single call
+	for _, i := range instances {
+		require.NoError(t, i.Start())
+	}
+	expected := "ttl.sh/" + hash + ":24h"
+	require.Len(t, fb.Builds(), 1, "the image of the second instance is the image of the first one")
+	assert.Equal(t, expected, fb.LastBuild().Destination)
+	dockerfile, err := os.ReadFile(filepath.Join(f.BuildContext(), "Dockerfile"))
+	require.NoError(t, err, "the build context should be kept for the other instances")
+	assert.Contains(t, string(dockerfile), "ADD --chown=0:0 /etc/app/config /etc/app/config")
+	assert.FileExists(t, filepath.Join(f.BuildContext(), "etc", "app", "config"), "the added file should be in the build context")
+	mu.Lock()
+	assert.Equal(t, []string{expected, expected}, images)
+	mu.Unlock()
+}
+
+func TestAddReadinessGate(t *testing.T) {
+	lockK8sClient(t)
+	i := newQuickTestInstance(t, "readiness-gate")
+	assert.ErrorIs(t, i.AddReadinessGate("not a condition"), ErrInvalidReadinessGate)
+	require.NoError(t, i.AddReadinessGate("example.com/seeded"))
+	require.NoError(t, i.AddReadinessGate("example.com/seeded"))
+	k8sClient = &k8s.Client{}
+	assert.Equal(t, []string{"example.com/seeded"}, i.prepareReplicaSetConfig().PodConfig.ReadinessGates)
+
+	// the gates added to a clone are not added to the instance, even if the slice has room for them
+	other := newTestInstance(t, "readiness-gate-clone")
+	for _, gate := range []string{"example.com/a", "example.com/b", "example.com/c"} {
+		require.NoError(t, other.AddReadinessGate(gate))
+	}
+	clone := other.cloneWithSuffix("-clone")
+	require.NoError(t, clone.AddReadinessGate("example.com/clone"))
+	require.NoError(t, other.AddReadinessGate("example.com/other"))
+	assert.Equal(t, []string{"example.com/a", "example.com/b", "example.com/c", "example.com/clone"}, clone.readinessGates)
+	assert.Equal(t, []string{"example.com/a", "example.com/b", "example.com/c", "example.com/other"}, other.readinessGates)
+
+	var (
+		checks atomic.Int32
+		seeded atomic.Bool
+	)
+	k8sClient = newTestK8sClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/apis/apps/v1/namespaces/test/replicasets/"):
+			// the containers are ready, only the readiness gate is pending
+			fmt.Fprint(w, `{"kind":"ReplicaSet","apiVersion":"apps/v1","metadata":{"name":"gate"},`+
+				`"spec":{"replicas":1,"selector":{"matchLabels":{"app":"gate"}}},"status":{"readyReplicas":1}}`)
+		case r.URL.Path == "/api/v1/namespaces/test/pods":
+			fmt.Fprint(w, `{"kind":"PodList","apiVersion":"v1","items":[{"metadata":{"name":"gate-pod"}}]}`)
+		case r.URL.Path == "/api/v1/namespaces/test/pods/gate-pod":
+			checks.Add(1)
+			status := "False"
+			if seeded.Load() {
+				status = "True"
+			}
+			fmt.Fprintf(w, `{"kind":"Pod","apiVersion":"v1","metadata":{"name":"gate-pod"},`+
+				`"status":{"conditions":[{"type":"example.com/seeded","status":%q}]}}`, status)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	// a fake controller sets the condition once the wait has seen it unset a few times
+	go func() {
+		for checks.Load() < 3 {
+			time.Sleep(5 * time.Millisecond)
+		}
+		seeded.Store(true)
+	}()
+
+	i.state = Started
+	require.NoError(t, i.WaitInstanceIsRunning())
+	assert.True(t, seeded.Load(), "the wait returned before the condition was set")
+	assert.GreaterOrEqual(t, checks.Load(), int32(4))
+}
+
+func TestSetFSGroup(t *testing.T) {
+	lockK8sClient(t)
+	k8sClient = &k8s.Client{}
+
+	i := newTestInstance(t, "fs-group")
+	assert.ErrorIs(t, i.SetFSGroup(0), ErrInvalidFSGroup)
+	require.NoError(t, i.SetFSGroup(1000))
+	assert.Equal(t, int64(1000), i.prepareReplicaSetConfig().PodConfig.FsGroup)
+	assert.Equal(t, int64(1000), i.cloneWithSuffix("-clone").fsGroup)
+
+	i.fsGroup = 10001 // set by a file added in the state 'Committed'
+	assert.ErrorIs(t, i.SetFSGroup(1000), ErrAllFilesMustHaveSameGroup)
+
+	i.state = Started
+	assert.ErrorIs(t, i.SetFSGroup(1000), ErrSettingFSGroupNotAllowed)
+}
+
+func TestSetStartupProbe(t *testing.T) {
+	i := newTestInstance(t, "startup-probe")
+	handler := v1.ProbeHandler{TCPSocket: &v1.TCPSocketAction{Port: intstr.FromInt(8080)}}
+
+	assert.ErrorIs(t, i.SetStartupProbe(&v1.Probe{}), ErrInvalidStartupProbe)
+	assert.ErrorIs(t, i.SetStartupProbe(&v1.Probe{ProbeHandler: v1.ProbeHandler{
+		TCPSocket: handler.TCPSocket,
+		Exec:      &v1.ExecAction{Command: []string{"true"}},
+	}}), ErrInvalidStartupProbe)
+	assert.ErrorIs(t, i.SetStartupProbe(&v1.Probe{ProbeHandler: handler, PeriodSeconds: -1}), ErrInvalidStartupProbe)
+	assert.ErrorIs(t, i.SetStartupProbe(&v1.Probe{ProbeHandler: handler, SuccessThreshold: 2}), ErrInvalidStartupProbe)
+	assert.Zero(t, i.startupDuration())
+
+	require.NoError(t, i.SetStartupProbe(&v1.Probe{ProbeHandler: handler}))
+	assert.Equal(t, 30*time.Second, i.startupDuration(), "the defaults of kubernetes apply")
+
+	// an app taking up to a minute to boot
+	require.NoError(t, i.SetStartupProbe(&v1.Probe{ProbeHandler: handler, InitialDelaySeconds: 5, PeriodSeconds: 5, FailureThreshold: 11}))
+	assert.Equal(t, time.Minute, i.startupDuration())
+
+	require.NoError(t, i.SetStartupProbe(nil))
+	assert.Zero(t, i.startupDuration())
+}
+
+func TestSetEphemeralStorage(t *testing.T) {
+	lockK8sClient(t)
+
+	i := newTestInstance(t, "ephemeral-storage")
+	assert.ErrorIs(t, i.SetEphemeralStorage("lots", "4Gi"), ErrInvalidEphemeralStorage)
+	assert.ErrorIs(t, i.SetEphemeralStorage("1Gi", "4G!"), ErrInvalidEphemeralStorage)
+	assert.ErrorIs(t, i.SetEphemeralStorage("8Gi", "4Gi"), ErrEphemeralStorageRequestExceedsLimit)
+	require.NoError(t, i.SetEphemeralStorage("1Gi", "4Gi"))
+
+	k8sClient = &k8s.Client{}
+	config := i.prepareReplicaSetConfig().PodConfig.ContainerConfig
+	assert.Equal(t, "1Gi", config.EphemeralStorageRequest)
+	assert.Equal(t, "4Gi", config.EphemeralStorageLimit)
+
+	i.state = Started
+	assert.ErrorIs(t, i.SetEphemeralStorage("1Gi", ""), ErrSettingEphemeralStorageNotAllowed)
+}
+
+func TestSetGitRepo(t *testing.T) {
+	prev := ImageBuilder()
+	fb := &builder.FakeBuilder{}
+	SetImageBuilder(fb)
+	t.Cleanup(func() { SetImageBuilder(prev) })
+
+	i, err := NewInstance("git-repo")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(i.getBuildDir()) })
+	require.NoError(t, i.SetGitRepo(context.Background(), builder.GitContext{Repo: "https://github.com/celestiaorg/knuu.git", Commit: "abc123"}))
+	assert.Equal(t, Preparing, i.state)
+
+	opts := fb.LastBuild()
+	require.NotNil(t, opts)
+	assert.Equal(t, "git://github.com/celestiaorg/knuu#abc123", opts.BuildContext)
+	imageName, err := builder.DefaultImageName(opts.BuildContext)
+	require.NoError(t, err)
+	assert.Equal(t, imageName, opts.Destination)
+	assert.Equal(t, imageName, i.builderFactory.ImageNameFrom())
+	assert.True(t, opts.Cache.Enabled)
+
+	assert.ErrorIs(t, i.SetGitRepo(context.Background(), builder.GitContext{Repo: "github.com/celestiaorg/knuu"}), ErrSettingGitRepo)
+}
+
+func TestSetBaseImagePublicKey(t *testing.T) {
+	// the registry has no signature for the image
+	reg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(reg.Close)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	i, err := NewInstance("base-image-key")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(i.getBuildDir()) })
+	require.NoError(t, i.SetImage(strings.TrimPrefix(reg.URL, "http://")+"/unsigned:latest"))
+
+	assert.ErrorIs(t, i.SetBaseImagePublicKey([]byte("not a key")), ErrSettingBaseImagePublicKey)
+	require.NoError(t, i.SetBaseImagePublicKey(pemKey))
+	assert.ErrorIs(t, i.Commit(), ErrVerifyingBaseImage)
+	assert.Equal(t, Preparing, i.state, "the instance is not committed with an unverified image")
+
+	i.state = Started
+	assert.ErrorIs(t, i.SetBaseImagePublicKey(pemKey), ErrSettingBaseImagePublicKeyNotAllowed)
+}
+
+func TestGetIPAfterRestart(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		serviceIP   = "10.96.0.10"
+		serviceGets int
+	)
+	useK8sClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.URL.Path == "/api/v1/namespaces/test/services/get-ip" {
+			mu.Lock()
+			defer mu.Unlock()
+			serviceGets++
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"kind":"Service","apiVersion":"v1","metadata":{"name":"get-ip"},"spec":{"clusterIP":"%s"}}`, serviceIP)
+			return
+		}
+		echoK8sHandler(w, r)
+	})
+
+	i := newTestInstance(t, "get-ip")
+	i.k8sName = "get-ip"
+	require.NoError(t, i.SetOperationTimeout(5*time.Second))
+	i.state = Started
+
+	ip, err := i.GetIP()
+	require.NoError(t, err)
+	assert.Equal(t, "10.96.0.10", ip)
+
+	// the IP is cached while the instance runs, a service recreated behind the back of knuu is not looked up
+	mu.Lock()
+	serviceIP = "10.96.0.20"
+	mu.Unlock()
+	ip, err = i.GetIP()
+	require.NoError(t, err)
+	assert.Equal(t, "10.96.0.10", ip)
+
+	// the IP is resolved again once after the instance is restarted
+	require.NoError(t, i.Stop())
+	require.NoError(t, i.StartWithoutWait())
+	for range 2 {
+		ip, err = i.GetIP()
+		require.NoError(t, err)
+		assert.Equal(t, "10.96.0.20", ip)
+	}
+	mu.Lock()
+	assert.Equal(t, 2, serviceGets)
+	mu.Unlock()
+}
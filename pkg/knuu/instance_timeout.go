@@ -0,0 +1,87 @@
+package knuu
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// WaitInstanceIsRunning polls quickly at first for the pods that start fast, and backs off up to the max for the slow ones
+	waitInitialPollInterval = 250 * time.Millisecond
+	waitMaxPollInterval     = 5 * time.Second
+)
+
+// waitAfter waits for the intervals between the checks of the waits, it is replaced in the tests to record them
+var waitAfter = time.After
+
+// SetOperationTimeout overrides the timeout of the operations of the instance, e.g. starting, waiting for it
+// to be running and destroying it, so slow environments can extend it and fast CI can shorten it
+// A timeout of 0 restores the defaults, which are the timeout of knuu for the calls to kubernetes
+// and 1 minute to wait for the instance to be running
+// The operations that receive a context from the caller are bounded by that context only
+// This function can only be called in the states 'Preparing', 'Committed', 'Started' and 'Stopped'
+func (i *Instance) SetOperationTimeout(d time.Duration) error {
+	if !i.IsInState(Preparing, Committed, Started, Stopped) {
+		return ErrSettingOperationTimeoutNotAllowed.WithParams(i.state.String())
+	}
+	if d < 0 {
+		return ErrInvalidOperationTimeout.WithParams(d)
+	}
+	i.opTimeout = d
+	logrus.Debugf("Set operation timeout to '%s' in instance '%s'", d, i.name)
+	return nil
+}
+
+// SetPollInterval sets the interval between the checks of the functions waiting for the instance,
+// e.g. WaitInstanceIsRunning, WaitStable, WaitForPort, WaitForReplicas and WaitForDeletion
+// A longer interval reduces the load on the API server in large suites, a shorter one reduces the latency of the waits
+// An interval of 0 restores the default interval of each function, which backs off exponentially for WaitInstanceIsRunning
+// This function can only be called in the states 'Preparing', 'Committed', 'Started' and 'Stopped'
+func (i *Instance) SetPollInterval(d time.Duration) error {
+	if !i.IsInState(Preparing, Committed, Started, Stopped) {
+		return ErrSettingPollIntervalNotAllowed.WithParams(i.state.String())
+	}
+	if d < 0 {
+		return ErrInvalidPollInterval.WithParams(d)
+	}
+	i.pollInterval = d
+	logrus.Debugf("Set poll interval to '%s' in instance '%s'", d, i.name)
+	return nil
+}
+
+// pollIntervalOr returns the poll interval of the instance, or the given default if it is not set
+func (i *Instance) pollIntervalOr(def time.Duration) time.Duration {
+	if i.pollInterval != 0 {
+		return i.pollInterval
+	}
+	return def
+}
+
+// pollBackoff returns the intervals between the checks of a wait, doubling from the initial interval up to the max
+type pollBackoff struct {
+	next, max time.Duration
+}
+
+func (b *pollBackoff) interval() time.Duration {
+	d := b.next
+	b.next = min(2*b.next, b.max)
+	return d
+}
+
+// pollBackoffOr returns a backoff with the fixed poll interval of the instance if it is set,
+// or doubling from the given initial interval up to the max otherwise
+func (i *Instance) pollBackoffOr(initial, maxInterval time.Duration) *pollBackoff {
+	if i.pollInterval != 0 {
+		return &pollBackoff{next: i.pollInterval, max: i.pollInterval}
+	}
+	return &pollBackoff{next: initial, max: maxInterval}
+}
+
+// operationTimeout returns the timeout of the calls to kubernetes made by the instance
+func (i *Instance) operationTimeout() time.Duration {
+	if i.opTimeout != 0 {
+		return i.opTimeout
+	}
+	return timeout
+}
@@ -0,0 +1,134 @@
+package knuu
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetOperationTimeout(t *testing.T) {
+	lockK8sClient(t)
+	k8sClient = newSlowK8sClient(t)
+
+	i := newTestInstance(t, "operation-timeout")
+	assert.Equal(t, timeout, i.operationTimeout())
+	assert.ErrorIs(t, i.SetOperationTimeout(-time.Second), ErrInvalidOperationTimeout)
+	require.NoError(t, i.SetOperationTimeout(200*time.Millisecond))
+	assert.Equal(t, 200*time.Millisecond, i.operationTimeout())
+
+	// the shortened timeout makes the destroy fail promptly instead of waiting for the default timeout
+	i.state = Started
+	start := time.Now()
+	err := i.Destroy()
+	assert.ErrorIs(t, err, ErrDestroyingPod)
+	assert.ErrorContains(t, err, context.DeadlineExceeded.Error())
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	require.NoError(t, i.SetOperationTimeout(0))
+	assert.Equal(t, timeout, i.operationTimeout())
+	i.state = Destroyed
+	assert.ErrorIs(t, i.SetOperationTimeout(time.Second), ErrSettingOperationTimeoutNotAllowed)
+}
+
+func TestSetPollInterval(t *testing.T) {
+	var polls atomic.Int32
+	useK8sClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/apis/apps/v1/namespaces/test/replicasets/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		polls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"kind":"ReplicaSet","apiVersion":"apps/v1","metadata":{"name":"poll"},"spec":{"replicas":1},"status":{"readyReplicas":0}}`)
+	})
+
+	// the fake clock elapses the first 10 intervals at once, then never again, so the wait times out
+	var intervals []time.Duration
+	waitAfter = func(d time.Duration) <-chan time.Time {
+		intervals = append(intervals, d)
+		if len(intervals) > 10 {
+			return nil
+		}
+		elapsed := make(chan time.Time, 1)
+		elapsed <- time.Now()
+		return elapsed
+	}
+	t.Cleanup(func() { waitAfter = time.After })
+
+	i := newTestInstance(t, "poll-interval")
+	assert.Equal(t, time.Second, i.pollIntervalOr(time.Second))
+	assert.ErrorIs(t, i.SetPollInterval(-time.Second), ErrInvalidPollInterval)
+	require.NoError(t, i.SetPollInterval(50*time.Millisecond))
+	require.NoError(t, i.SetOperationTimeout(100*time.Millisecond))
+
+	// the instance never becomes ready, so it is polled at the interval until the timeout
+	i.state = Started
+	assert.ErrorIs(t, i.WaitInstanceIsRunning(), ErrWaitingForInstanceTimeout)
+	assert.Equal(t, int32(10), polls.Load())
+	require.Len(t, intervals, 11)
+	for _, d := range intervals {
+		assert.Equal(t, 50*time.Millisecond, d)
+	}
+
+	require.NoError(t, i.SetPollInterval(0))
+	assert.Equal(t, time.Second, i.pollIntervalOr(time.Second))
+}
+
+func TestWaitInstanceIsRunningBackoff(t *testing.T) {
+	var polls atomic.Int32
+	useK8sClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/apis/apps/v1/namespaces/test/replicasets/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		ready := 0
+		if polls.Add(1) >= 9 {
+			ready = 1
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"kind":"ReplicaSet","apiVersion":"apps/v1","metadata":{"name":"poll"},"spec":{"replicas":1},"status":{"readyReplicas":%d}}`, ready)
+	})
+
+	// the fake clock records the intervals and elapses them at once
+	var intervals []time.Duration
+	waitAfter = func(d time.Duration) <-chan time.Time {
+		intervals = append(intervals, d)
+		elapsed := make(chan time.Time, 1)
+		elapsed <- time.Now()
+		return elapsed
+	}
+	t.Cleanup(func() { waitAfter = time.After })
+
+	i := newTestInstance(t, "poll-backoff")
+	require.NoError(t, i.SetOperationTimeout(5*time.Second))
+	i.state = Started
+	require.NoError(t, i.WaitInstanceIsRunning())
+	assert.Equal(t, []time.Duration{
+		250 * time.Millisecond,
+		500 * time.Millisecond,
+		time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		5 * time.Second,
+		5 * time.Second,
+		5 * time.Second,
+		5 * time.Second,
+	}, intervals)
+
+	// a fixed interval disables the backoff
+	polls.Store(0)
+	intervals = nil
+	require.NoError(t, i.SetPollInterval(time.Second))
+	require.NoError(t, i.WaitInstanceIsRunning())
+	assert.Len(t, intervals, 9)
+	for _, d := range intervals {
+		assert.Equal(t, time.Second, d)
+	}
+}
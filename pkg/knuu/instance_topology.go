@@ -0,0 +1,52 @@
+package knuu
+
+import (
+	"slices"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AddTopologySpreadConstraint adds a constraint on how the pods of the instance are spread across the topology domains
+// of the cluster, e.g. `topology.kubernetes.io/zone` or `kubernetes.io/hostname`. The pods are the replicas of the replica set
+// of the instance, selected by its `app` label. maxSkew is the maximum difference of the number of pods between two domains
+// and whenUnsatisfiable is either 'DoNotSchedule' or 'ScheduleAnyway'
+// This function can only be called in the states 'Preparing' and 'Committed'
+func (i *Instance) AddTopologySpreadConstraint(maxSkew int, topologyKey string, whenUnsatisfiable string) error {
+	if !i.IsInState(Preparing, Committed) {
+		return ErrAddingTopologySpreadConstraintNotAllowed.WithParams(i.state.String())
+	}
+	if maxSkew < 1 {
+		return ErrInvalidTopologySpreadMaxSkew.WithParams(maxSkew)
+	}
+	if topologyKey == "" {
+		return ErrTopologyKeyRequired
+	}
+	action := v1.UnsatisfiableConstraintAction(whenUnsatisfiable)
+	if action != v1.DoNotSchedule && action != v1.ScheduleAnyway {
+		return ErrInvalidWhenUnsatisfiable.WithParams(whenUnsatisfiable)
+	}
+	i.topologySpread = append(i.topologySpread, v1.TopologySpreadConstraint{
+		MaxSkew:           int32(maxSkew),
+		TopologyKey:       topologyKey,
+		WhenUnsatisfiable: action,
+	})
+	logrus.Debugf("Added topology spread constraint on '%s' with max skew '%d' to instance '%s'", topologyKey, maxSkew, i.name)
+	return nil
+}
+
+// topologySpreadConstraints returns the topology spread constraints of the instance, selecting the pods of the instance
+// The selector is set here, as the name of the instance changes when it is cloned
+func (i *Instance) topologySpreadConstraints() []v1.TopologySpreadConstraint {
+	if len(i.topologySpread) == 0 {
+		return nil
+	}
+	constraints := slices.Clone(i.topologySpread)
+	for c := range constraints {
+		constraints[c].LabelSelector = &metav1.LabelSelector{
+			MatchLabels: map[string]string{"app": i.k8sName},
+		}
+	}
+	return constraints
+}
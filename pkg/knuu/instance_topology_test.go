@@ -0,0 +1,46 @@
+package knuu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/celestiaorg/knuu/pkg/k8s"
+)
+
+func TestAddTopologySpreadConstraint(t *testing.T) {
+	lockK8sClient(t)
+
+	i := newTestInstance(t, "topology")
+	assert.ErrorIs(t, i.AddTopologySpreadConstraint(0, "kubernetes.io/hostname", "DoNotSchedule"), ErrInvalidTopologySpreadMaxSkew)
+	assert.ErrorIs(t, i.AddTopologySpreadConstraint(1, "", "DoNotSchedule"), ErrTopologyKeyRequired)
+	assert.ErrorIs(t, i.AddTopologySpreadConstraint(1, "kubernetes.io/hostname", "Never"), ErrInvalidWhenUnsatisfiable)
+	require.NoError(t, i.AddTopologySpreadConstraint(1, "kubernetes.io/hostname", "DoNotSchedule"))
+	require.NoError(t, i.AddTopologySpreadConstraint(2, "topology.kubernetes.io/zone", "ScheduleAnyway"))
+
+	k8sClient = &k8s.Client{}
+	config := i.prepareReplicaSetConfig()
+	assert.Equal(t, int32(1), config.Replicas)
+	constraints := config.PodConfig.TopologySpreadConstraints
+	require.Len(t, constraints, 2)
+	assert.Equal(t, int32(1), constraints[0].MaxSkew)
+	assert.Equal(t, "kubernetes.io/hostname", constraints[0].TopologyKey)
+	assert.Equal(t, v1.DoNotSchedule, constraints[0].WhenUnsatisfiable)
+	assert.Equal(t, v1.ScheduleAnyway, constraints[1].WhenUnsatisfiable)
+	for _, c := range constraints {
+		require.NotNil(t, c.LabelSelector)
+		assert.Equal(t, map[string]string{"app": i.k8sName}, c.LabelSelector.MatchLabels, "the constraint selects the replicas of the instance")
+	}
+
+	// the selector follows the name of a clone
+	i.state = Committed
+	clone, err := i.Clone()
+	require.NoError(t, err)
+	selector := clone.prepareReplicaSetConfig().PodConfig.TopologySpreadConstraints[0].LabelSelector
+	assert.Equal(t, map[string]string{"app": clone.k8sName}, selector.MatchLabels)
+
+	i.state = Started
+	assert.ErrorIs(t, i.AddTopologySpreadConstraint(1, "kubernetes.io/hostname", "DoNotSchedule"), ErrAddingTopologySpreadConstraintNotAllowed)
+}
@@ -16,12 +16,16 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
 	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 
 	"github.com/celestiaorg/knuu/pkg/builder"
 	"github.com/celestiaorg/knuu/pkg/builder/docker"
 	"github.com/celestiaorg/knuu/pkg/builder/kaniko"
+	"github.com/celestiaorg/knuu/pkg/container"
 	"github.com/celestiaorg/knuu/pkg/k8s"
 	"github.com/celestiaorg/knuu/pkg/minio"
+	"github.com/celestiaorg/knuu/pkg/registry"
 	"github.com/celestiaorg/knuu/pkg/traefik"
 )
 
@@ -31,6 +35,8 @@ var (
 	startTime    string
 	timeout      time.Duration
 	imageBuilder builder.Builder
+	// builtImageCache is the cache the built images are restored from, nil to always build them
+	builtImageCache container.ImageCache
 
 	// TODO: these are temporary until we refactor knuu pkg
 	k8sClient     *k8s.Client
@@ -123,13 +129,30 @@ func InitializeWithScope(scope string) error {
 		return ErrCannotHandleTimeout.Wrap(err)
 	}
 
+	// pull and push all the images through the registry mirror, if set
+	if err := registry.SetMirror(os.Getenv("KNUU_REGISTRY_MIRROR")); err != nil {
+		return ErrInvalidRegistryMirror.Wrap(err)
+	}
+
 	builderType := os.Getenv("KNUU_BUILDER")
 	switch builderType {
 	case "kubernetes":
+		var jobTTL time.Duration
+		if ttl := os.Getenv("KNUU_BUILDER_JOB_TTL"); ttl != "" {
+			parsedTTL, err := time.ParseDuration(ttl)
+			if err != nil {
+				return ErrCannotParseBuilderJobTTL.Wrap(err)
+			}
+			jobTTL = parsedTTL
+		}
 		SetImageBuilder(&kaniko.Kaniko{
 			K8sClientset: k8sClient.Clientset(),
 			K8sNamespace: k8sClient.Namespace(),
 			Minio:        minioClient, // same client is used to make the best use of the resources
+			Compression:  kaniko.Compression(os.Getenv("KNUU_BUILD_CONTEXT_COMPRESSION")),
+			// the registries the host is logged in to, e.g. in CI
+			HostDockerConfig: os.Getenv("KNUU_BUILDER_HOST_CREDENTIALS") == "true",
+			JobTTL:           jobTTL,
 		})
 	case "docker", "":
 		SetImageBuilder(&docker.Docker{
@@ -140,6 +163,15 @@ func InitializeWithScope(scope string) error {
 		return ErrInvalidKnuuBuilder.WithParams(builderType)
 	}
 
+	imageCacheType := os.Getenv("KNUU_IMAGE_CACHE")
+	switch imageCacheType {
+	case "minio":
+		SetImageCache(minioImageCache{})
+	case "":
+	default:
+		return ErrInvalidImageCache.WithParams(imageCacheType)
+	}
+
 	HandleStopSignal()
 	return nil
 }
@@ -198,6 +230,38 @@ func ImageBuilder() builder.Builder {
 	return imageBuilder
 }
 
+// SetImageCache sets the cache the images built for the instances are restored from instead of building them,
+// e.g. to share the images between CI runners, nil disables it. See container.BuilderFactory.SetImageCache
+// It applies to the instances whose image is set afterwards
+func SetImageCache(c container.ImageCache) {
+	builtImageCache = c
+}
+
+// ImageCache returns the cache the built images are restored from, or nil if it is disabled
+func ImageCache() container.ImageCache {
+	return builtImageCache
+}
+
+// Clientset returns the kubernetes clientset used by knuu, or nil if knuu is not initialized
+// It allows to use the kubernetes API directly when knuu does not provide the needed functionality,
+// e.g. to list the pods in the namespace returned by Instance.GetNamespace.
+// Modifying the resources managed by knuu this way is at your own risk, as knuu does not expect it.
+func Clientset() *kubernetes.Clientset {
+	if k8sClient == nil {
+		return nil
+	}
+	return k8sClient.Clientset()
+}
+
+// RESTConfig returns a copy of the config knuu uses to connect to the cluster, or nil if knuu is not initialized
+// It can be used to create other clients, e.g. a dynamic client, with the same authentication.
+func RESTConfig() *rest.Config {
+	if k8sClient == nil {
+		return nil
+	}
+	return k8sClient.RESTConfig()
+}
+
 // IsInitialized returns true if knuu is initialized, and false otherwise
 func IsInitialized() bool {
 	return k8sClient != nil
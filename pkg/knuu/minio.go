@@ -4,10 +4,15 @@ import (
 	"context"
 	"io"
 
+	"github.com/celestiaorg/knuu/pkg/container"
 	"github.com/celestiaorg/knuu/pkg/minio"
 )
 
-const minioBucketName = "knuu"
+const (
+	minioBucketName = "knuu"
+	// minioImageCacheDir is the directory of the bucket holding the archives of the built images
+	minioImageCacheDir = "image-cache/"
+)
 
 var minioClient *minio.Minio
 
@@ -40,3 +45,22 @@ func GetMinioURL(ctx context.Context, contentName string) (string, error) {
 	}
 	return minioClient.GetMinioURL(ctx, contentName, minioBucketName)
 }
+
+// minioImageCache stores the archives of the built images in Minio, which is shared by the test runs using the namespace
+type minioImageCache struct{}
+
+var _ container.ImageCache = minioImageCache{}
+
+func (minioImageCache) Get(ctx context.Context, imageHash string, w io.Writer) error {
+	if err := initMinio(ctx); err != nil {
+		return err
+	}
+	return minioClient.PullFromMinio(ctx, w, minioImageCacheDir+imageHash+".tar", minioBucketName)
+}
+
+func (minioImageCache) Put(ctx context.Context, imageHash string, r io.Reader) error {
+	if err := initMinio(ctx); err != nil {
+		return err
+	}
+	return minioClient.PushToMinio(ctx, r, minioImageCacheDir+imageHash+".tar", minioBucketName)
+}
@@ -0,0 +1,136 @@
+package knuu
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/celestiaorg/knuu/pkg/k8s"
+)
+
+// Feature is a functionality of knuu that needs specific capabilities of the cluster
+type Feature string
+
+const (
+	// FeatureDebugContainers is AddDebugContainer, which needs ephemeral containers
+	FeatureDebugContainers Feature = "debug-containers"
+	// FeatureSysctls is SetSysctl, which needs the sysctls of the pods
+	FeatureSysctls Feature = "sysctls"
+	// FeatureSharedVolumes is ShareVolumeWith, which needs a default storage class supporting ReadWriteMany volumes
+	FeatureSharedVolumes Feature = "shared-volumes"
+	// FeatureMetrics is GetMetrics, which needs metrics-server
+	FeatureMetrics Feature = "metrics"
+)
+
+// FeatureSupport tells whether a feature is usable in the cluster
+type FeatureSupport struct {
+	Available bool
+	Reason    string // why the feature is not usable, empty if it is available
+}
+
+// CapabilityReport tells which features of knuu are usable in the cluster
+type CapabilityReport struct {
+	ServerVersion string // version of the API server, e.g. v1.28.2
+	Features      map[Feature]FeatureSupport
+}
+
+// Available returns true if the feature is usable in the cluster
+func (r CapabilityReport) Available(feature Feature) bool {
+	return r.Features[feature].Available
+}
+
+// check returns an error if the feature is not usable in the cluster
+func (r CapabilityReport) check(feature Feature) error {
+	if support := r.Features[feature]; !support.Available {
+		return ErrFeatureNotAvailable.WithParams(feature, support.Reason)
+	}
+	return nil
+}
+
+var (
+	capabilitiesMu sync.Mutex
+	// capabilities is the last report, probed with capabilitiesClient
+	capabilities       *CapabilityReport
+	capabilitiesClient *k8s.Client
+)
+
+// Preflight probes the version and the APIs of the cluster and reports which features of knuu are usable,
+// so a test can skip or fail early instead of hitting cryptic errors of the cluster later on.
+// The report is kept, and the features check it to return ErrFeatureNotAvailable with the reason,
+// e.g. AddDebugContainer runs the preflight checks if they have not been run yet.
+// knuu must be initialized.
+func Preflight(ctx context.Context) (CapabilityReport, error) {
+	if k8sClient == nil {
+		return CapabilityReport{}, ErrKnuuNotInitialized
+	}
+	client := k8sClient
+
+	clusterCapabilities, err := client.ProbeCapabilities()
+	if err != nil {
+		return CapabilityReport{}, ErrRunningPreflight.Wrap(err)
+	}
+
+	report := CapabilityReport{
+		ServerVersion: clusterCapabilities.ServerVersion,
+		Features: map[Feature]FeatureSupport{
+			FeatureDebugContainers: featureSupport(clusterCapabilities.EphemeralContainers,
+				"ephemeral containers are not supported or disabled in the cluster"),
+			FeatureSysctls: featureSupport(clusterCapabilities.Sysctls,
+				"sysctls need kubernetes v1.21 or later, the cluster runs "+clusterCapabilities.ServerVersion),
+			FeatureMetrics: featureSupport(clusterCapabilities.MetricsServer,
+				"the metrics API is not available, make sure metrics-server is installed in the cluster"),
+		},
+	}
+	report.Features[FeatureSharedVolumes] = FeatureSupport{Available: true}
+	if err := client.ValidateReadWriteManyStorageClass(ctx); err != nil {
+		report.Features[FeatureSharedVolumes] = FeatureSupport{Reason: err.Error()}
+	}
+
+	capabilitiesMu.Lock()
+	defer capabilitiesMu.Unlock()
+	capabilities, capabilitiesClient = &report, client
+	logrus.Debugf("Ran preflight checks against kubernetes %s: %v", report.ServerVersion, report.Features)
+	return report, nil
+}
+
+// featureSupport returns the support of a feature, with the reason if it is not available
+func featureSupport(available bool, reason string) FeatureSupport {
+	if available {
+		return FeatureSupport{Available: true}
+	}
+	return FeatureSupport{Reason: reason}
+}
+
+// cachedCapabilities returns the report of the last preflight checks of the cluster, or nil if they were not run
+func cachedCapabilities() *CapabilityReport {
+	capabilitiesMu.Lock()
+	defer capabilitiesMu.Unlock()
+	if capabilities == nil || capabilitiesClient != k8sClient {
+		return nil
+	}
+	return capabilities
+}
+
+// requireFeature returns an error if the feature is not usable in the cluster, running the preflight checks if needed
+// If the checks fail, the feature is assumed to be available, so it fails with the error of the cluster if it is not
+func requireFeature(ctx context.Context, feature Feature) error {
+	report := cachedCapabilities()
+	if report == nil {
+		probed, err := Preflight(ctx)
+		if err != nil {
+			logrus.Debugf("Skipping the preflight check of feature '%s': %v", feature, err)
+			return nil
+		}
+		report = &probed
+	}
+	return report.check(feature)
+}
+
+// checkCachedFeature returns an error if the last preflight checks, if any, reported the feature as not usable
+func checkCachedFeature(feature Feature) error {
+	if report := cachedCapabilities(); report != nil {
+		return report.check(feature)
+	}
+	return nil
+}
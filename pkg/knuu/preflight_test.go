@@ -0,0 +1,52 @@
+package knuu
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreflight(t *testing.T) {
+	_, err := Preflight(context.Background())
+	assert.ErrorIs(t, err, ErrKnuuNotInitialized)
+
+	useK8sClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/version":
+			fmt.Fprint(w, `{"major":"1","minor":"20","gitVersion":"v1.20.15"}`)
+		case "/api/v1":
+			fmt.Fprint(w, `{"kind":"APIResourceList","groupVersion":"v1","resources":[{"name":"pods","namespaced":true,"kind":"Pod","verbs":["get"]}]}`)
+		case "/apis/metrics.k8s.io/v1beta1":
+			fmt.Fprint(w, `{"kind":"APIResourceList","groupVersion":"metrics.k8s.io/v1beta1","resources":[{"name":"pods","namespaced":true,"kind":"PodMetrics","verbs":["get"]}]}`)
+		case "/apis/storage.k8s.io/v1/storageclasses":
+			fmt.Fprint(w, `{"kind":"StorageClassList","apiVersion":"storage.k8s.io/v1","items":[`+
+				`{"metadata":{"name":"default","annotations":{"storageclass.kubernetes.io/is-default-class":"true"}},"provisioner":"nfs.csi.k8s.io"}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	i := newTestInstance(t, "preflight")
+	require.NoError(t, i.SetSysctl("net.core.somaxconn", "1024"), "the features are not checked before the preflight checks")
+
+	report, err := Preflight(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "v1.20.15", report.ServerVersion)
+	assert.True(t, report.Available(FeatureMetrics))
+	assert.True(t, report.Available(FeatureSharedVolumes))
+	assert.False(t, report.Available(FeatureDebugContainers))
+	assert.False(t, report.Available(FeatureSysctls))
+	assert.Contains(t, report.Features[FeatureSysctls].Reason, "v1.20.15")
+
+	assert.ErrorIs(t, i.SetSysctl("net.core.somaxconn", "2048"), ErrFeatureNotAvailable)
+
+	i.state = Started
+	_, err = i.AddDebugContainer(context.Background(), "busybox")
+	assert.ErrorIs(t, err, ErrFeatureNotAvailable)
+	assert.ErrorContains(t, err, "ephemeral containers")
+}
@@ -47,6 +47,7 @@ var (
 	ErrMinioFailedToInitializeClient            = &Error{Code: "MinioFailedToInitializeClient", Message: "failed to initialize Minio client"}
 	ErrMinioFailedToCreateBucket                = &Error{Code: "MinioFailedToCreateBucket", Message: "failed to create bucket"}
 	ErrMinioFailedToUploadData                  = &Error{Code: "MinioFailedToUploadData", Message: "failed to upload data to Minio"}
+	ErrMinioFailedToDownloadData                = &Error{Code: "MinioFailedToDownloadData", Message: "failed to download data from Minio"}
 	ErrMinioFailedToGetPresignedURL             = &Error{Code: "MinioFailedToGetPresignedURL", Message: "failed to generate presigned URL for Minio object"}
 	ErrMinioFailedToUpdateService               = &Error{Code: "MinioFailedToUpdateService", Message: "failed to update Minio service"}
 	ErrMinioFailedToFindFileBeforeDeletion      = &Error{Code: "MinioFailedToFindFileBeforeDeletion", Message: "failed to find file in Minio before deletion"}
@@ -245,6 +245,64 @@ func (m *Minio) GetMinioURL(ctx context.Context, minioFilePath, bucketName strin
 	return presignedURL.String(), nil
 }
 
+// GetMinioUploadURL returns a presigned URL that can be used to upload a file to Minio with a PUT request
+func (m *Minio) GetMinioUploadURL(ctx context.Context, minioFilePath, bucketName string) (string, error) {
+	minioEndpoint, err := m.getEndpoint(ctx)
+	if err != nil {
+		return "", ErrMinioFailedToGetMinioEndpoint.Wrap(err)
+	}
+	minioClient, err := miniogo.New(minioEndpoint, &miniogo.Options{
+		Creds:  credentials.NewStaticV4(rootUser, rootPassword, ""),
+		Secure: false,
+	})
+	if err != nil {
+		return "", ErrMinioFailedToInitializeClient.Wrap(err)
+	}
+
+	if err := m.createBucketIfNotExists(ctx, minioClient, bucketName); err != nil {
+		return "", ErrMinioFailedToCreateBucket.Wrap(err)
+	}
+
+	// Set the expiration time for the URL (e.g., 24h from now)
+	expiration := time.Duration(24 * time.Hour)
+
+	presignedURL, err := minioClient.PresignedPutObject(ctx, bucketName, minioFilePath, expiration)
+	if err != nil {
+		return "", ErrMinioFailedToGeneratePresignedURL.Wrap(err)
+	}
+
+	return presignedURL.String(), nil
+}
+
+// PullFromMinio downloads a file from Minio and writes it to the given writer
+func (m *Minio) PullFromMinio(ctx context.Context, localWriter io.Writer, minioFilePath, bucketName string) error {
+	endpoint, err := m.getEndpoint(ctx)
+	if err != nil {
+		return ErrMinioFailedToGetEndpoint.Wrap(err)
+	}
+
+	cli, err := miniogo.New(endpoint, &miniogo.Options{
+		Creds:  credentials.NewStaticV4(rootUser, rootPassword, ""),
+		Secure: false,
+	})
+	if err != nil {
+		return ErrMinioFailedToInitializeClient.Wrap(err)
+	}
+
+	object, err := cli.GetObject(ctx, bucketName, minioFilePath, miniogo.GetObjectOptions{})
+	if err != nil {
+		return ErrMinioFailedToDownloadData.Wrap(err)
+	}
+	defer object.Close()
+
+	if _, err := io.Copy(localWriter, object); err != nil {
+		return ErrMinioFailedToDownloadData.Wrap(err)
+	}
+
+	logrus.Debugf("Data downloaded successfully from %s in bucket %s", minioFilePath, bucketName)
+	return nil
+}
+
 func (m *Minio) createOrUpdateService(ctx context.Context) error {
 	serviceClient := m.Clientset.CoreV1().Services(m.Namespace)
 
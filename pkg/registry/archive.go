@@ -0,0 +1,324 @@
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// archiveIndexName is the first entry of the image archives, listing what they contain
+	archiveIndexName = "index.json"
+	// archiveManifestsDir and archiveBlobsDir hold the manifests and the blobs of the image archives, named by digest
+	archiveManifestsDir = "manifests/"
+	archiveBlobsDir     = "blobs/"
+)
+
+// imageArchive is the index of an image archive
+type imageArchive struct {
+	Digest string `json:"digest"`
+	// Manifests are ordered so the manifests referenced by an index come before it, the root manifest is the last one
+	Manifests []descriptor `json:"manifests"`
+	Blobs     []descriptor `json:"blobs"`
+}
+
+// imageSave collects the manifests and the blobs of an image to archive it
+type imageSave struct {
+	ref       *Reference
+	sess      *session
+	index     imageArchive
+	manifests map[string][]byte
+	blobs     map[string]bool
+}
+
+// SaveImage writes the image to w as a tar archive holding its manifests and blobs, which LoadImage pushes
+// back to a registry, and returns its digest. Unlike `docker save`, the manifests are kept byte for byte,
+// so the loaded image has the same digest, and for multi-platform images all the platforms are saved.
+// The registry is accessed anonymously.
+func SaveImage(ctx context.Context, ref string, w io.Writer) (string, error) {
+	r, err := ParseReference(ref)
+	if err != nil {
+		return "", err
+	}
+
+	s := &imageSave{ref: r, sess: &session{}, manifests: make(map[string][]byte), blobs: make(map[string]bool)}
+	digest, err := s.collect(ctx, r.Identifier())
+	if err != nil {
+		return "", ErrSavingImage.WithParams(ref).Wrap(err)
+	}
+	s.index.Digest = digest
+	if err := s.write(ctx, w); err != nil {
+		return "", ErrSavingImage.WithParams(ref).Wrap(err)
+	}
+	logrus.Debugf("Saved image %s with digest %s", ref, digest)
+	return digest, nil
+}
+
+// collect fetches the manifest with the identifier and the manifests it references, and returns its digest
+func (s *imageSave) collect(ctx context.Context, id string) (string, error) {
+	manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", s.ref.baseURL(), s.ref.Repository, id)
+	resp, err := s.sess.do(ctx, http.MethodGet, manifestURL, nil, "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", ErrUnexpectedStatus.WithParams(resp.StatusCode, manifestURL)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", ErrDecodingResponse.WithParams(manifestURL).Wrap(err)
+	}
+	digest := digestOf(data)
+	if strings.HasPrefix(id, "sha256:") && id != digest {
+		return "", ErrDigestMismatch.WithParams(digest, manifestURL, id)
+	}
+	if _, ok := s.manifests[digest]; ok {
+		return digest, nil
+	}
+
+	var m imageManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return "", ErrDecodingResponse.WithParams(manifestURL).Wrap(err)
+	}
+	for _, child := range m.Manifests {
+		if _, err := s.collect(ctx, child.Digest); err != nil {
+			return "", err
+		}
+	}
+	blobs := m.Layers
+	if m.Config != nil {
+		blobs = append([]descriptor{*m.Config}, blobs...)
+	}
+	for _, blob := range blobs {
+		// foreign layers are not stored in the registry
+		if len(blob.URLs) != 0 || s.blobs[blob.Digest] {
+			continue
+		}
+		s.blobs[blob.Digest] = true
+		s.index.Blobs = append(s.index.Blobs, blob)
+	}
+
+	s.manifests[digest] = data
+	s.index.Manifests = append(s.index.Manifests, descriptor{MediaType: resp.Header.Get("Content-Type"), Digest: digest, Size: int64(len(data))})
+	return digest, nil
+}
+
+// write writes the archive of the collected manifests, streaming the blobs from the registry
+func (s *imageSave) write(ctx context.Context, w io.Writer) error {
+	tw := tar.NewWriter(w)
+	index, err := json.Marshal(s.index)
+	if err != nil {
+		return err
+	}
+	if err := writeArchiveEntry(tw, archiveIndexName, int64(len(index)), bytes.NewReader(index)); err != nil {
+		return err
+	}
+	for _, m := range s.index.Manifests {
+		data := s.manifests[m.Digest]
+		if err := writeArchiveEntry(tw, archiveManifestsDir+m.Digest, int64(len(data)), bytes.NewReader(data)); err != nil {
+			return err
+		}
+	}
+	for _, blob := range s.index.Blobs {
+		if err := s.writeBlob(ctx, tw, blob.Digest); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// writeBlob streams the blob from the registry to the archive
+func (s *imageSave) writeBlob(ctx context.Context, tw *tar.Writer, digest string) error {
+	blobURL := fmt.Sprintf("%s/v2/%s/blobs/%s", s.ref.baseURL(), s.ref.Repository, digest)
+	resp, err := s.sess.do(ctx, http.MethodGet, blobURL, nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ErrUnexpectedStatus.WithParams(resp.StatusCode, blobURL)
+	}
+
+	size := resp.ContentLength
+	var body io.Reader = resp.Body
+	if size < 0 {
+		// the size is needed before the content in a tar archive
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return ErrDecodingResponse.WithParams(blobURL).Wrap(err)
+		}
+		size, body = int64(len(data)), bytes.NewReader(data)
+	}
+	return writeArchiveEntry(tw, archiveBlobsDir+digest, size, newDigestReader(body, digest))
+}
+
+func writeArchiveEntry(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: size, Typeflag: tar.TypeReg}); err != nil {
+		return err
+	}
+	_, err := io.Copy(tw, r)
+	return err
+}
+
+// LoadImage pushes the image archived by SaveImage to ref and returns its digest.
+// The content of the archive is checked against its digests. The blobs are streamed to the registry
+// and the upload of a corrupted one is aborted, so a corrupted archive fails with ErrCorruptedImageArchive
+// before any manifest is pushed, and ref is left untouched. Blobs already in the registry are skipped.
+func LoadImage(ctx context.Context, r io.Reader, ref string, auth Auth) (string, error) {
+	dest, err := ParseReference(ref)
+	if err != nil {
+		return "", err
+	}
+
+	digest, err := loadImage(ctx, tar.NewReader(r), &imageCopy{src: dest, dest: dest, destSess: &session{auth: auth}})
+	if err != nil {
+		return "", ErrLoadingImage.WithParams(ref).Wrap(err)
+	}
+	logrus.Debugf("Loaded image %s with digest %s", ref, digest)
+	return digest, nil
+}
+
+func loadImage(ctx context.Context, tr *tar.Reader, c *imageCopy) (string, error) {
+	header, err := tr.Next()
+	if err != nil || header.Name != archiveIndexName {
+		return "", ErrCorruptedImageArchive.WithParams("missing " + archiveIndexName)
+	}
+	var index imageArchive
+	if err := json.NewDecoder(tr).Decode(&index); err != nil || index.Digest == "" || len(index.Manifests) == 0 {
+		return "", ErrCorruptedImageArchive.WithParams("invalid " + archiveIndexName)
+	}
+	if c.dest.Digest != "" && c.dest.Digest != index.Digest {
+		return "", ErrDigestMismatch.WithParams(index.Digest, archiveIndexName, c.dest.Digest)
+	}
+
+	manifests := make(map[string][]byte)
+	blobs := make(map[string]bool)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", ErrCorruptedImageArchive.WithParams(err.Error())
+		}
+		switch {
+		case strings.HasPrefix(header.Name, archiveManifestsDir):
+			digest := strings.TrimPrefix(header.Name, archiveManifestsDir)
+			data, err := io.ReadAll(tr)
+			if err != nil || digestOf(data) != digest {
+				return "", ErrCorruptedImageArchive.WithParams("manifest " + digest + " does not match its digest")
+			}
+			manifests[digest] = data
+		case strings.HasPrefix(header.Name, archiveBlobsDir):
+			digest := strings.TrimPrefix(header.Name, archiveBlobsDir)
+			content := newDigestReader(tr, digest)
+			if err := c.pushBlob(ctx, digest, content); err != nil {
+				if content.corrupted {
+					return "", ErrCorruptedImageArchive.WithParams("blob " + digest + " does not match its digest")
+				}
+				return "", ErrCopyingBlob.WithParams(digest, c.dest.Repository).Wrap(err)
+			}
+			blobs[digest] = true
+		}
+	}
+
+	for _, blob := range index.Blobs {
+		if !blobs[blob.Digest] {
+			return "", ErrCorruptedImageArchive.WithParams("missing blob " + blob.Digest)
+		}
+	}
+	for _, m := range index.Manifests {
+		if _, ok := manifests[m.Digest]; !ok {
+			return "", ErrCorruptedImageArchive.WithParams("missing manifest " + m.Digest)
+		}
+	}
+	// the referenced manifests must exist before the manifests referencing them are pushed
+	for _, m := range index.Manifests {
+		id := m.Digest
+		if m.Digest == index.Digest {
+			id = c.dest.Identifier()
+		}
+		if err := c.putManifest(ctx, id, m.Digest, m.MediaType, manifests[m.Digest]); err != nil {
+			return "", err
+		}
+	}
+	return index.Digest, nil
+}
+
+// pushBlob uploads the blob to the destination unless it is already there
+func (c *imageCopy) pushBlob(ctx context.Context, digest string, r io.Reader) error {
+	blobURL := fmt.Sprintf("%s/v2/%s/blobs/%s", c.dest.baseURL(), c.dest.Repository, digest)
+	resp, err := c.destSess.do(ctx, http.MethodHead, blobURL, nil, "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	uploadURL := fmt.Sprintf("%s/v2/%s/blobs/uploads/", c.dest.baseURL(), c.dest.Repository)
+	resp, err = c.destSess.do(ctx, http.MethodPost, uploadURL, nil, "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return ErrUnexpectedStatus.WithParams(resp.StatusCode, uploadURL)
+	}
+	location, err := c.uploadLocation(resp, digest)
+	if err != nil {
+		return err
+	}
+
+	// the upload was authorized when it was started, so the blob is streamed without buffering it
+	resp, err = c.destSess.do(ctx, http.MethodPut, location, r, "application/octet-stream")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return ErrUnexpectedStatus.WithParams(resp.StatusCode, location)
+	}
+	return nil
+}
+
+// digestReader fails at the end of the content instead of returning io.EOF if it does not match the digest,
+// which aborts the requests streaming it
+type digestReader struct {
+	r         io.Reader
+	hash      hash.Hash
+	digest    string
+	corrupted bool
+}
+
+func newDigestReader(r io.Reader, digest string) *digestReader {
+	return &digestReader{r: r, hash: sha256.New(), digest: digest}
+}
+
+func (d *digestReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	d.hash.Write(p[:n])
+	if err == io.EOF && "sha256:"+hex.EncodeToString(d.hash.Sum(nil)) != d.digest {
+		d.corrupted = true
+		return n, ErrCorruptedImageArchive.WithParams("content does not match " + d.digest)
+	}
+	return n, err
+}
+
+// digestOf returns the digest of the content, as computed by registries
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,84 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveAndLoadImage(t *testing.T) {
+	ctx := context.Background()
+	auth := Auth{Username: "user", Password: "secret"}
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		src, dest := newMockRegistry(t, Auth{}), newMockRegistry(t, auth)
+		digest := src.addImage("knuu", "built", "amd64")
+
+		var archive bytes.Buffer
+		saved, err := SaveImage(ctx, src.host()+"/knuu:built", &archive)
+		require.NoError(t, err)
+		assert.Equal(t, digest, saved)
+
+		loaded, err := LoadImage(ctx, bytes.NewReader(archive.Bytes()), dest.host()+"/restored:v1", auth)
+		require.NoError(t, err)
+		assert.Equal(t, digest, loaded, "the manifest should be loaded byte for byte")
+		assert.Equal(t, src.manifests["knuu:built"], dest.manifests["restored:v1"])
+		assert.Equal(t, "application/vnd.oci.image.manifest.v1+json", dest.types[digest])
+		assert.Equal(t, src.blobs["knuu"], dest.blobs["restored"])
+
+		// the blobs already in the registry are not uploaded again
+		_, err = LoadImage(ctx, bytes.NewReader(archive.Bytes()), dest.host()+"/restored:v2", auth)
+		require.NoError(t, err)
+		assert.Equal(t, 2, dest.uploads)
+	})
+
+	t.Run("MultiPlatform", func(t *testing.T) {
+		src, dest := newMockRegistry(t, Auth{}), newMockRegistry(t, Auth{})
+		amd64, arm64 := src.addImage("knuu", "amd64", "amd64"), src.addImage("knuu", "arm64", "arm64")
+		index := fmt.Sprintf(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.index.v1+json",`+
+			`"manifests":[{"digest":%q},{"digest":%q}]}`, amd64, arm64)
+		digest := src.addManifest("knuu", "built", "application/vnd.oci.image.index.v1+json", []byte(index))
+
+		var archive bytes.Buffer
+		_, err := SaveImage(ctx, src.host()+"/knuu:built", &archive)
+		require.NoError(t, err)
+		loaded, err := LoadImage(ctx, &archive, dest.host()+"/restored:v1", Auth{})
+		require.NoError(t, err)
+		assert.Equal(t, digest, loaded)
+		assert.Contains(t, dest.manifests, "restored:"+amd64)
+		assert.Contains(t, dest.manifests, "restored:"+arm64)
+		assert.Len(t, dest.blobs["restored"], 4)
+	})
+
+	t.Run("Corrupted", func(t *testing.T) {
+		src, dest := newMockRegistry(t, Auth{}), newMockRegistry(t, Auth{})
+		src.addImage("knuu", "built", "amd64")
+
+		var archive bytes.Buffer
+		_, err := SaveImage(ctx, src.host()+"/knuu:built", &archive)
+		require.NoError(t, err)
+
+		corrupted := bytes.Replace(archive.Bytes(), []byte("layer of amd64"), []byte("layer of arm64"), 1)
+		_, err = LoadImage(ctx, bytes.NewReader(corrupted), dest.host()+"/restored:v1", Auth{})
+		assert.ErrorContains(t, err, "does not match its digest")
+		assert.NotContains(t, dest.manifests, "restored:v1", "no manifest should be pushed for a corrupted archive")
+		assert.NotContains(t, dest.blobs["restored"], digestOf([]byte("layer of amd64")), "the upload of the corrupted blob should be aborted")
+		assert.NotContains(t, dest.blobs["restored"], digestOf([]byte("layer of arm64")))
+
+		_, err = LoadImage(ctx, bytes.NewReader(archive.Bytes()[:archive.Len()/2]), dest.host()+"/restored:v1", Auth{})
+		assert.ErrorContains(t, err, "corrupted image archive")
+
+		_, err = LoadImage(ctx, bytes.NewReader([]byte("not an archive")), dest.host()+"/restored:v1", Auth{})
+		assert.ErrorContains(t, err, "corrupted image archive")
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		src := newMockRegistry(t, Auth{})
+		_, err := SaveImage(ctx, src.host()+"/knuu:missing", &bytes.Buffer{})
+		assert.ErrorIs(t, err, ErrSavingImage)
+	})
+}
@@ -0,0 +1,87 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// ImageConfig is the configuration the containers of an image run with by default
+type ImageConfig struct {
+	User         string              `json:"User"`
+	Env          []string            `json:"Env"`
+	Entrypoint   []string            `json:"Entrypoint"`
+	Cmd          []string            `json:"Cmd"`
+	WorkingDir   string              `json:"WorkingDir"`
+	ExposedPorts map[string]struct{} `json:"ExposedPorts"`
+	Labels       map[string]string   `json:"Labels"`
+	StopSignal   string              `json:"StopSignal"`
+}
+
+// ImageInfo describes an image as stored in its registry
+type ImageInfo struct {
+	Digest     string      // digest of the manifest, of the first platform for multi-platform images
+	Size       int64       // compressed size of the layers, i.e. the size to pull
+	LayerSizes []int64     // compressed size of each layer, from the base layer up
+	Config     ImageConfig // configuration of the image
+}
+
+// InspectImage returns the size, the layers and the config of the image from its registry, without pulling it.
+// For multi-platform images, the first platform is inspected.
+func InspectImage(ctx context.Context, ref string) (ImageInfo, error) {
+	r, err := ParseReference(ref)
+	if err != nil {
+		return ImageInfo{}, err
+	}
+
+	manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", r.baseURL(), r.Repository, r.Identifier())
+	var m imageManifest
+	digest, err := getJSON(ctx, manifestURL, &m)
+	if err != nil {
+		return ImageInfo{}, ErrInspectingImage.WithParams(r.Identifier(), r.Repository).Wrap(err)
+	}
+	if m.Config == nil && len(m.Manifests) > 0 {
+		manifestURL = fmt.Sprintf("%s/v2/%s/manifests/%s", r.baseURL(), r.Repository, m.Manifests[0].Digest)
+		if digest, err = getJSON(ctx, manifestURL, &m); err != nil {
+			return ImageInfo{}, ErrInspectingImage.WithParams(r.Identifier(), r.Repository).Wrap(err)
+		}
+	}
+	if m.Config == nil {
+		return ImageInfo{}, ErrInspectingImage.WithParams(r.Identifier(), r.Repository).Wrap(ErrMissingImageConfig.WithParams(manifestURL))
+	}
+
+	info := ImageInfo{Digest: digest, LayerSizes: make([]int64, 0, len(m.Layers))}
+	for _, layer := range m.Layers {
+		info.Size += layer.Size
+		info.LayerSizes = append(info.LayerSizes, layer.Size)
+	}
+
+	configURL := fmt.Sprintf("%s/v2/%s/blobs/%s", r.baseURL(), r.Repository, m.Config.Digest)
+	var config struct {
+		Config ImageConfig `json:"config"`
+	}
+	if _, err := getJSON(ctx, configURL, &config); err != nil {
+		return ImageInfo{}, ErrInspectingImage.WithParams(r.Identifier(), r.Repository).Wrap(err)
+	}
+	info.Config = config.Config
+	return info, nil
+}
+
+// ImageUser returns the user the image runs as, as set by the USER instruction of its Dockerfile,
+// or an empty string if it runs as root by default. For multi-platform images, the user of the first platform is returned.
+func ImageUser(ctx context.Context, ref string) (string, error) {
+	info, err := InspectImage(ctx, ref)
+	if err != nil {
+		return "", ErrReadingImageConfig.WithParams(ref).Wrap(err)
+	}
+	return info.Config.User, nil
+}
+
+// getJSON fetches the URL from the registry, decodes its JSON body and returns its digest, if the registry sent it
+func getJSON(ctx context.Context, reqURL string, v interface{}) (string, error) {
+	resp, err := do(ctx, http.MethodGet, reqURL)
+	if err != nil {
+		return "", err
+	}
+	return resp.Header.Get("Docker-Content-Digest"), decodeResponse(resp, reqURL, v)
+}
@@ -0,0 +1,216 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// descriptor references a manifest or a blob by its digest
+type descriptor struct {
+	MediaType string    `json:"mediaType"`
+	Digest    string    `json:"digest"`
+	Size      int64     `json:"size"`
+	URLs      []string  `json:"urls"`
+	Platform  *platform `json:"platform,omitempty"` // platform of a manifest in an index
+}
+
+// platform is the platform a manifest of an index, or the config of an image, is built for
+type platform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// imageManifest holds the fields of image manifests and indexes needed to copy and inspect an image
+type imageManifest struct {
+	Config    *descriptor  `json:"config"`
+	Layers    []descriptor `json:"layers"`
+	Manifests []descriptor `json:"manifests"`
+}
+
+// imageCopy copies the manifests and the blobs of an image from a repository to another
+type imageCopy struct {
+	src, dest         *Reference
+	srcSess, destSess *session
+}
+
+// CopyImage copies the image from srcRef to destRef without pulling it, e.g. to promote an image that was tested
+// to a production registry, and returns its digest. The manifest is copied byte for byte, so the digest is preserved,
+// and for multi-platform images all the platforms are copied. Blobs already in the destination are skipped,
+// and within the same registry they are mounted from the source repository instead of being uploaded again.
+// The destination is accessed with the given credentials, and so is the source if it is in the same registry,
+// otherwise the source is accessed anonymously.
+func CopyImage(ctx context.Context, srcRef, destRef string, destAuth Auth) (string, error) {
+	src, err := ParseReference(srcRef)
+	if err != nil {
+		return "", err
+	}
+	dest, err := ParseReference(destRef)
+	if err != nil {
+		return "", err
+	}
+
+	c := &imageCopy{src: src, dest: dest, srcSess: &session{}, destSess: &session{auth: destAuth}}
+	if src.Registry == dest.Registry {
+		c.srcSess.auth = destAuth
+	}
+	digest, err := c.copyManifest(ctx, src.Identifier(), dest.Identifier())
+	if err != nil {
+		return "", ErrCopyingImage.WithParams(srcRef, destRef).Wrap(err)
+	}
+	logrus.Debugf("Copied image %s to %s with digest %s", srcRef, destRef, digest)
+	return digest, nil
+}
+
+// copyManifest copies the manifest with the source identifier, with everything it references,
+// to the destination identifier and returns its digest
+func (c *imageCopy) copyManifest(ctx context.Context, srcID, destID string) (string, error) {
+	manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", c.src.baseURL(), c.src.Repository, srcID)
+	resp, err := c.srcSess.do(ctx, http.MethodGet, manifestURL, nil, "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", ErrUnexpectedStatus.WithParams(resp.StatusCode, manifestURL)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", ErrDecodingResponse.WithParams(manifestURL).Wrap(err)
+	}
+
+	sum := sha256.Sum256(data)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	for _, id := range []string{srcID, destID} {
+		if strings.HasPrefix(id, "sha256:") && id != digest {
+			return "", ErrDigestMismatch.WithParams(digest, manifestURL, id)
+		}
+	}
+
+	var m imageManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return "", ErrDecodingResponse.WithParams(manifestURL).Wrap(err)
+	}
+	// the referenced manifests and blobs must exist before the manifest is pushed
+	for _, child := range m.Manifests {
+		if _, err := c.copyManifest(ctx, child.Digest, child.Digest); err != nil {
+			return "", err
+		}
+	}
+	blobs := m.Layers
+	if m.Config != nil {
+		blobs = append([]descriptor{*m.Config}, blobs...)
+	}
+	for _, blob := range blobs {
+		// foreign layers are not stored in the registry
+		if len(blob.URLs) != 0 {
+			continue
+		}
+		if err := c.copyBlob(ctx, blob.Digest); err != nil {
+			return "", ErrCopyingBlob.WithParams(blob.Digest, c.dest.Repository).Wrap(err)
+		}
+	}
+
+	return digest, c.putManifest(ctx, destID, digest, resp.Header.Get("Content-Type"), data)
+}
+
+// putManifest pushes the manifest to the destination with the identifier and checks its digest
+func (c *imageCopy) putManifest(ctx context.Context, destID, digest, mediaType string, data []byte) error {
+	manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", c.dest.baseURL(), c.dest.Repository, destID)
+	resp, err := c.destSess.do(ctx, http.MethodPut, manifestURL, bytes.NewReader(data), mediaType)
+	if err != nil {
+		return ErrPushingManifest.WithParams(manifestURL).Wrap(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return ErrPushingManifest.WithParams(manifestURL).Wrap(ErrUnexpectedStatus.WithParams(resp.StatusCode, manifestURL))
+	}
+	if pushed := resp.Header.Get("Docker-Content-Digest"); pushed != "" && pushed != digest {
+		return ErrDigestMismatch.WithParams(pushed, manifestURL, digest)
+	}
+	return nil
+}
+
+// copyBlob copies the blob to the destination unless it is already there,
+// mounting it from the source repository if both are in the same registry
+func (c *imageCopy) copyBlob(ctx context.Context, digest string) error {
+	blobURL := fmt.Sprintf("%s/v2/%s/blobs/%s", c.dest.baseURL(), c.dest.Repository, digest)
+	resp, err := c.destSess.do(ctx, http.MethodHead, blobURL, nil, "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	uploadURL := fmt.Sprintf("%s/v2/%s/blobs/uploads/", c.dest.baseURL(), c.dest.Repository)
+	if c.src.Registry == c.dest.Registry {
+		uploadURL += "?" + url.Values{"mount": {digest}, "from": {c.src.Repository}}.Encode()
+	}
+	resp, err = c.destSess.do(ctx, http.MethodPost, uploadURL, nil, "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		logrus.Debugf("Mounted blob %s from %s", digest, c.src.Repository)
+		return nil
+	case http.StatusAccepted:
+	default:
+		return ErrUnexpectedStatus.WithParams(resp.StatusCode, uploadURL)
+	}
+	location, err := c.uploadLocation(resp, digest)
+	if err != nil {
+		return err
+	}
+
+	srcURL := fmt.Sprintf("%s/v2/%s/blobs/%s", c.src.baseURL(), c.src.Repository, digest)
+	blob, err := c.srcSess.do(ctx, http.MethodGet, srcURL, nil, "")
+	if err != nil {
+		return err
+	}
+	defer blob.Body.Close()
+	if blob.StatusCode != http.StatusOK {
+		return ErrUnexpectedStatus.WithParams(blob.StatusCode, srcURL)
+	}
+
+	// the upload was authorized when it was started, so the blob is streamed without buffering it
+	resp, err = c.destSess.do(ctx, http.MethodPut, location, blob.Body, "application/octet-stream")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return ErrUnexpectedStatus.WithParams(resp.StatusCode, location)
+	}
+	return nil
+}
+
+// uploadLocation returns the URL to complete the upload of the blob started with the response
+func (c *imageCopy) uploadLocation(resp *http.Response, digest string) (string, error) {
+	base, err := url.Parse(c.dest.baseURL() + "/")
+	if err != nil {
+		return "", ErrCreatingRequest.WithParams(c.dest.baseURL()).Wrap(err)
+	}
+	header := resp.Header.Get("Location")
+	location, err := base.Parse(header)
+	if err != nil || header == "" {
+		return "", ErrMissingUploadLocation.WithParams(resp.Request.URL.String())
+	}
+	query := location.Query()
+	query.Set("digest", digest)
+	location.RawQuery = query.Encode()
+	return location.String(), nil
+}
@@ -0,0 +1,267 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockRegistry is an in-memory registry implementing the parts of the distribution API used to copy images
+type mockRegistry struct {
+	*httptest.Server
+	mu        sync.Mutex
+	auth      Auth                         // required credentials, empty for anonymous access
+	manifests map[string][]byte            // by repository and tag or digest
+	types     map[string]string            // media types of the manifests, by digest
+	blobs     map[string]map[string][]byte // by repository and digest
+	uploads   int                          // number of blobs uploaded, without the mounted ones
+}
+
+func newMockRegistry(t *testing.T, auth Auth) *mockRegistry {
+	m := &mockRegistry{
+		auth:      auth,
+		manifests: make(map[string][]byte),
+		types:     make(map[string]string),
+		blobs:     make(map[string]map[string][]byte),
+	}
+	m.Server = httptest.NewServer(http.HandlerFunc(m.serve))
+	t.Cleanup(m.Close)
+	return m
+}
+
+// host returns the registry host used in the image references
+func (m *mockRegistry) host() string {
+	return strings.TrimPrefix(m.URL, "http://")
+}
+
+// addBlob stores a blob in the repository and returns its digest
+func (m *mockRegistry) addBlob(repo string, data []byte) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.blobs[repo] == nil {
+		m.blobs[repo] = make(map[string][]byte)
+	}
+	digest := digestOf(data)
+	m.blobs[repo][digest] = data
+	return digest
+}
+
+// addManifest stores a manifest in the repository with the tag and returns its digest
+func (m *mockRegistry) addManifest(repo, tag, mediaType string, data []byte) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	digest := digestOf(data)
+	m.manifests[repo+":"+tag] = data
+	m.manifests[repo+":"+digest] = data
+	m.types[digest] = mediaType
+	return digest
+}
+
+// addImage stores an image with a config and a layer in the repository with the tag and returns its digest
+func (m *mockRegistry) addImage(repo, tag, platform string) string {
+	config := m.addBlob(repo, []byte(fmt.Sprintf(`{"architecture":%q}`, platform)))
+	layer := m.addBlob(repo, []byte("layer of "+platform))
+	manifest := fmt.Sprintf(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json",`+
+		`"config":{"mediaType":"application/vnd.oci.image.config.v1+json","digest":%q},`+
+		`"layers":[{"mediaType":"application/vnd.oci.image.layer.v1.tar+gzip","digest":%q}]}`, config, layer)
+	return m.addManifest(repo, tag, "application/vnd.oci.image.manifest.v1+json", []byte(manifest))
+}
+
+func (m *mockRegistry) serve(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.auth.Username != "" && r.Header.Get("Authorization") != m.auth.basic() {
+		w.Header().Set("WWW-Authenticate", `Basic realm="mock"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v2/")
+	switch {
+	case strings.Contains(path, "/manifests/"):
+		repo, ref, _ := strings.Cut(path, "/manifests/")
+		if r.Method == http.MethodPut {
+			data, _ := io.ReadAll(r.Body)
+			digest := digestOf(data)
+			m.manifests[repo+":"+ref] = data
+			m.manifests[repo+":"+digest] = data
+			m.types[digest] = r.Header.Get("Content-Type")
+			w.Header().Set("Docker-Content-Digest", digest)
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		data, ok := m.manifests[repo+":"+ref]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", m.types[digestOf(data)])
+		w.Header().Set("Docker-Content-Digest", digestOf(data))
+		_, _ = w.Write(data)
+	case strings.Contains(path, "/blobs/uploads/"):
+		repo, id, _ := strings.Cut(path, "/blobs/uploads/")
+		if r.Method == http.MethodPost {
+			from, digest := r.URL.Query().Get("from"), r.URL.Query().Get("mount")
+			if data, ok := m.blobs[from][digest]; ok && from != "" {
+				if m.blobs[repo] == nil {
+					m.blobs[repo] = make(map[string][]byte)
+				}
+				m.blobs[repo][digest] = data
+				w.WriteHeader(http.StatusCreated)
+				return
+			}
+			w.Header().Set("Location", "/v2/"+repo+"/blobs/uploads/upload-1?state=abc")
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		digest := r.URL.Query().Get("digest")
+		if id != "upload-1" || r.URL.Query().Get("state") != "abc" || digestOf(data) != digest {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if m.blobs[repo] == nil {
+			m.blobs[repo] = make(map[string][]byte)
+		}
+		m.blobs[repo][digest] = data
+		m.uploads++
+		w.WriteHeader(http.StatusCreated)
+	case strings.Contains(path, "/blobs/"):
+		repo, digest, _ := strings.Cut(path, "/blobs/")
+		data, ok := m.blobs[repo][digest]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestCopyImage(t *testing.T) {
+	ctx := context.Background()
+	auth := Auth{Username: "user", Password: "secret"}
+
+	t.Run("BetweenRegistries", func(t *testing.T) {
+		src, dest := newMockRegistry(t, Auth{}), newMockRegistry(t, auth)
+		digest := src.addImage("knuu", "built", "amd64")
+
+		copied, err := CopyImage(ctx, src.host()+"/knuu:built", dest.host()+"/prod/app:v1", auth)
+		require.NoError(t, err)
+		assert.Equal(t, digest, copied)
+		assert.Equal(t, src.manifests["knuu:built"], dest.manifests["prod/app:v1"])
+		assert.Equal(t, "application/vnd.oci.image.manifest.v1+json", dest.types[digest])
+		assert.Equal(t, src.blobs["knuu"], dest.blobs["prod/app"])
+		assert.Equal(t, 2, dest.uploads)
+
+		// the blobs already in the destination are not uploaded again
+		_, err = CopyImage(ctx, src.host()+"/knuu@"+digest, dest.host()+"/prod/app:v2", auth)
+		require.NoError(t, err)
+		assert.Equal(t, 2, dest.uploads)
+		assert.Equal(t, dest.manifests["prod/app:v1"], dest.manifests["prod/app:v2"])
+	})
+
+	t.Run("MultiPlatform", func(t *testing.T) {
+		src, dest := newMockRegistry(t, Auth{}), newMockRegistry(t, auth)
+		amd64, arm64 := src.addImage("knuu", "amd64", "amd64"), src.addImage("knuu", "arm64", "arm64")
+		index := fmt.Sprintf(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.index.v1+json",`+
+			`"manifests":[{"digest":%q},{"digest":%q}]}`, amd64, arm64)
+		digest := src.addManifest("knuu", "built", "application/vnd.oci.image.index.v1+json", []byte(index))
+
+		copied, err := CopyImage(ctx, src.host()+"/knuu:built", dest.host()+"/prod/app:v1", auth)
+		require.NoError(t, err)
+		assert.Equal(t, digest, copied)
+		assert.Equal(t, []byte(index), dest.manifests["prod/app:v1"])
+		assert.Contains(t, dest.manifests, "prod/app:"+amd64)
+		assert.Contains(t, dest.manifests, "prod/app:"+arm64)
+		assert.Len(t, dest.blobs["prod/app"], 4)
+	})
+
+	t.Run("SameRegistry", func(t *testing.T) {
+		r := newMockRegistry(t, auth)
+		digest := r.addImage("knuu", "built", "amd64")
+
+		copied, err := CopyImage(ctx, r.host()+"/knuu:built", r.host()+"/prod/app:v1", auth)
+		require.NoError(t, err)
+		assert.Equal(t, digest, copied)
+		assert.Equal(t, 0, r.uploads, "the blobs are mounted")
+		assert.Equal(t, r.blobs["knuu"], r.blobs["prod/app"])
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		src, dest := newMockRegistry(t, Auth{}), newMockRegistry(t, auth)
+		src.addImage("knuu", "built", "amd64")
+
+		_, err := CopyImage(ctx, src.host()+"/knuu@sha256:"+strings.Repeat("0", 64), dest.host()+"/prod/app:v1", auth)
+		assert.ErrorIs(t, err, ErrCopyingImage)
+
+		_, err = CopyImage(ctx, src.host()+"/knuu:built", dest.host()+"/prod/app:v1", Auth{})
+		assert.ErrorIs(t, err, ErrCopyingImage)
+		assert.ErrorContains(t, err, "unsupported auth scheme 'Basic'")
+		assert.Empty(t, dest.manifests)
+	})
+}
+
+func TestImageUser(t *testing.T) {
+	ctx := context.Background()
+	r := newMockRegistry(t, Auth{})
+	config := r.addBlob("app", []byte(`{"architecture":"amd64","config":{"User":"1000:1000"}}`))
+	manifest := fmt.Sprintf(`{"schemaVersion":2,"config":{"digest":%q},"layers":[]}`, config)
+	image := r.addManifest("app", "amd64", "application/vnd.oci.image.manifest.v1+json", []byte(manifest))
+	r.addManifest("app", "latest", "application/vnd.oci.image.index.v1+json",
+		[]byte(fmt.Sprintf(`{"schemaVersion":2,"manifests":[{"digest":%q}]}`, image)))
+	r.addImage("root", "latest", "amd64")
+
+	user, err := ImageUser(ctx, r.host()+"/app:amd64")
+	require.NoError(t, err)
+	assert.Equal(t, "1000:1000", user)
+
+	user, err = ImageUser(ctx, r.host()+"/app:latest")
+	require.NoError(t, err)
+	assert.Equal(t, "1000:1000", user, "the user of the first platform is returned")
+
+	user, err = ImageUser(ctx, r.host()+"/root:latest")
+	require.NoError(t, err)
+	assert.Empty(t, user)
+
+	_, err = ImageUser(ctx, r.host()+"/missing:latest")
+	assert.ErrorIs(t, err, ErrReadingImageConfig)
+}
+
+func TestInspectImage(t *testing.T) {
+	ctx := context.Background()
+	r := newMockRegistry(t, Auth{})
+	config := r.addBlob("small", []byte(`{"config":{"User":"app","Env":["PATH=/bin"],"Cmd":["sh"],"ExposedPorts":{"80/tcp":{}},"StopSignal":"SIGQUIT"}}`))
+	base, app := r.addBlob("small", []byte("base layer")), r.addBlob("small", []byte("app layer"))
+	manifest := fmt.Sprintf(`{"schemaVersion":2,"config":{"digest":%q,"size":90},"layers":[`+
+		`{"digest":%q,"size":3400000},{"digest":%q,"size":1200}]}`, config, base, app)
+	digest := r.addManifest("small", "v1", "application/vnd.oci.image.manifest.v1+json", []byte(manifest))
+
+	info, err := InspectImage(ctx, r.host()+"/small:v1")
+	require.NoError(t, err)
+	assert.Equal(t, ImageInfo{
+		Digest:     digest,
+		Size:       3401200,
+		LayerSizes: []int64{3400000, 1200},
+		Config: ImageConfig{
+			User:         "app",
+			Env:          []string{"PATH=/bin"},
+			Cmd:          []string{"sh"},
+			ExposedPorts: map[string]struct{}{"80/tcp": {}},
+			StopSignal:   "SIGQUIT",
+		},
+	}, info)
+
+	_, err = InspectImage(ctx, r.host()+"/small:v2")
+	assert.ErrorIs(t, err, ErrInspectingImage)
+}
@@ -0,0 +1,72 @@
+package registry
+
+import (
+	"fmt"
+)
+
+type Error struct {
+	Code    string
+	Message string
+	Err     error
+	Params  []interface{}
+}
+
+func (e *Error) Error() string {
+	if e.Err == e {
+		return e.Message
+	}
+
+	msg := fmt.Sprintf(e.Message, e.Params...)
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", msg, e.Err)
+	}
+	return msg
+}
+
+func (e *Error) Wrap(err error) error {
+	e.Err = err
+	return e
+}
+
+func (e *Error) WithParams(params ...interface{}) *Error {
+	e.Params = params
+	return e
+}
+
+var (
+	ErrInvalidReference      = &Error{Code: "InvalidReference", Message: "invalid image reference '%s'"}
+	ErrCreatingRequest       = &Error{Code: "CreatingRequest", Message: "error creating request for '%s'"}
+	ErrSendingRequest        = &Error{Code: "SendingRequest", Message: "error sending request to '%s'"}
+	ErrUnexpectedStatus      = &Error{Code: "UnexpectedStatus", Message: "unexpected status code %d from '%s'"}
+	ErrUnauthorized          = &Error{Code: "Unauthorized", Message: "unauthorized to access '%s'"}
+	ErrParsingAuthChallenge  = &Error{Code: "ParsingAuthChallenge", Message: "error parsing auth challenge '%s'"}
+	ErrFetchingToken         = &Error{Code: "FetchingToken", Message: "error fetching token from '%s'"}
+	ErrDecodingTokenResponse = &Error{Code: "DecodingTokenResponse", Message: "error decoding token response"}
+	ErrUnsupportedAuthScheme = &Error{Code: "UnsupportedAuthScheme", Message: "unsupported auth scheme '%s'"}
+	ErrEmptyToken            = &Error{Code: "EmptyToken", Message: "empty token in auth response"}
+	ErrInvalidMirror         = &Error{Code: "InvalidMirror", Message: "invalid registry mirror '%s', it must be a host with an optional path, e.g. myregistry:5000/dockerhub"}
+	ErrInvalidKeep           = &Error{Code: "InvalidKeep", Message: "invalid number of images to keep %d, it must not be negative"}
+	ErrListingTags           = &Error{Code: "ListingTags", Message: "error listing the tags of '%s'"}
+	ErrListingRepositories   = &Error{Code: "ListingRepositories", Message: "error listing the repositories of '%s'"}
+	ErrInspectingImage       = &Error{Code: "InspectingImage", Message: "error inspecting image with tag '%s' in '%s'"}
+	ErrMissingDigest         = &Error{Code: "MissingDigest", Message: "missing digest in the response from '%s'"}
+	ErrDecodingResponse      = &Error{Code: "DecodingResponse", Message: "error decoding response from '%s'"}
+	ErrDeletingImage         = &Error{Code: "DeletingImage", Message: "error deleting image '%s'"}
+	ErrCopyingImage          = &Error{Code: "CopyingImage", Message: "error copying image '%s' to '%s'"}
+	ErrCopyingBlob           = &Error{Code: "CopyingBlob", Message: "error copying blob '%s' to '%s'"}
+	ErrDigestMismatch        = &Error{Code: "DigestMismatch", Message: "digest '%s' of the manifest at '%s' does not match '%s'"}
+	ErrPushingManifest       = &Error{Code: "PushingManifest", Message: "error pushing manifest to '%s'"}
+	ErrMissingUploadLocation = &Error{Code: "MissingUploadLocation", Message: "missing upload location in the response from '%s'"}
+	ErrReadingImageConfig    = &Error{Code: "ReadingImageConfig", Message: "error reading the config of image '%s'"}
+	ErrMissingImageConfig    = &Error{Code: "MissingImageConfig", Message: "the manifest at '%s' has no config"}
+	ErrParsingPublicKey      = &Error{Code: "ParsingPublicKey", Message: "error parsing the public key"}
+	ErrNoPublicKeyBlock      = &Error{Code: "NoPublicKeyBlock", Message: "no PEM block of type 'PUBLIC KEY' found"}
+	ErrUnsupportedPublicKey  = &Error{Code: "UnsupportedPublicKey", Message: "unsupported public key type %s, only ECDSA, RSA and ed25519 keys are supported"}
+	ErrVerifyingSignature    = &Error{Code: "VerifyingSignature", Message: "error verifying the signature of image '%s'"}
+	ErrImageNotSigned        = &Error{Code: "ImageNotSigned", Message: "image '%s' has no cosign signature"}
+	ErrInvalidSignature      = &Error{Code: "InvalidSignature", Message: "no signature of image '%s' is valid for the public key"}
+	ErrSavingImage           = &Error{Code: "SavingImage", Message: "error saving image '%s'"}
+	ErrLoadingImage          = &Error{Code: "LoadingImage", Message: "error loading image to '%s'"}
+	ErrCorruptedImageArchive = &Error{Code: "CorruptedImageArchive", Message: "corrupted image archive: %s"}
+	ErrReadingImagePlatforms = &Error{Code: "ReadingImagePlatforms", Message: "error reading the platforms of image '%s'"}
+)
@@ -0,0 +1,51 @@
+package registry
+
+import (
+	"strings"
+	"sync"
+)
+
+var (
+	mirrorMu sync.RWMutex
+	mirror   string
+)
+
+// SetMirror sets the registry mirror that all image references are rewritten to by Rewrite,
+// e.g. with the mirror `myregistry/dockerhub` the image `nginx` is pulled from `myregistry/dockerhub/library/nginx:latest`.
+// The mirror is a registry host with an optional path prefix and must serve all the images used by knuu,
+// including the images it pushes, so it is typically a pull-through cache in front of the upstream registries.
+// An empty mirror disables rewriting.
+func SetMirror(m string) error {
+	m = strings.TrimSuffix(m, "/")
+	if strings.Contains(m, "://") || strings.ContainsAny(m, " \t\n@") {
+		return ErrInvalidMirror.WithParams(m)
+	}
+	mirrorMu.Lock()
+	defer mirrorMu.Unlock()
+	mirror = m
+	return nil
+}
+
+// Mirror returns the registry mirror, empty if not set
+func Mirror() string {
+	mirrorMu.RLock()
+	defer mirrorMu.RUnlock()
+	return mirror
+}
+
+// Rewrite replaces the registry of the image reference with the mirror, keeping its repository, tag and digest,
+// e.g. `docker.io/library/nginx:1.27` becomes `myregistry/library/nginx:1.27` with the mirror `myregistry`.
+// References already pointing to the mirror, references using build args like `${BASE}`
+// and invalid references are returned unchanged, as is every reference if no mirror is set.
+func Rewrite(ref string) string {
+	m := Mirror()
+	if m == "" || strings.Contains(ref, "$") || strings.HasPrefix(ref, m+"/") {
+		return ref
+	}
+	r, err := ParseReference(ref)
+	if err != nil {
+		return ref
+	}
+	r.Registry = m
+	return r.String()
+}
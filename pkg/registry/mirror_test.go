@@ -0,0 +1,38 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewrite(t *testing.T) {
+	t.Cleanup(func() { require.NoError(t, SetMirror("")) })
+
+	assert.Equal(t, "nginx", Rewrite("nginx"), "rewriting should be a no-op without a mirror")
+
+	require.NoError(t, SetMirror("myregistry/"))
+	assert.Equal(t, "myregistry", Mirror())
+
+	tests := map[string]string{
+		"nginx":                             "myregistry/library/nginx:latest",
+		"docker.io/library/nginx:1.27":      "myregistry/library/nginx:1.27",
+		"ghcr.io/celestiaorg/celestia-node": "myregistry/celestiaorg/celestia-node:latest",
+		"ttl.sh/abc:24h":                    "myregistry/abc:24h",
+		"alpine@sha256:abc":                 "myregistry/library/alpine@sha256:abc",
+		"myregistry/library/nginx:1.27":     "myregistry/library/nginx:1.27",
+		"${BASE}":                           "${BASE}",
+		"":                                  "",
+	}
+	for ref, expected := range tests {
+		assert.Equal(t, expected, Rewrite(ref), ref)
+	}
+
+	require.NoError(t, SetMirror("localhost:5000/dockerhub"))
+	assert.Equal(t, "localhost:5000/dockerhub/library/nginx:latest", Rewrite("nginx"))
+
+	for _, mirror := range []string{"https://myregistry", "my registry", "myregistry@sha256"} {
+		assert.ErrorIs(t, SetMirror(mirror), ErrInvalidMirror, mirror)
+	}
+}
@@ -0,0 +1,73 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+)
+
+// ImagePlatform is a platform an image is built for, with the digest of the manifest of the image for it
+type ImagePlatform struct {
+	OS           string // operating system, e.g. linux
+	Architecture string // CPU architecture, e.g. amd64 or arm64
+	Variant      string // variant of the architecture, e.g. v7 for arm, empty if unset
+	Digest       string // digest of the manifest for the platform
+}
+
+// String returns the platform in the form used by docker, e.g. `linux/arm/v7`
+func (p ImagePlatform) String() string {
+	if p.Variant != "" {
+		return fmt.Sprintf("%s/%s/%s", p.OS, p.Architecture, p.Variant)
+	}
+	return fmt.Sprintf("%s/%s", p.OS, p.Architecture)
+}
+
+// ImagePlatforms returns the platforms of the image from its registry, without pulling it: the platforms listed by
+// the index of a multi-platform image, or the platform in the config of a single-platform image
+// The attestation manifests of an index, whose platform is unknown/unknown, are skipped
+func ImagePlatforms(ctx context.Context, ref string) ([]ImagePlatform, error) {
+	r, err := ParseReference(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", r.baseURL(), r.Repository, r.Identifier())
+	var m imageManifest
+	digest, err := getJSON(ctx, manifestURL, &m)
+	if err != nil {
+		return nil, ErrReadingImagePlatforms.WithParams(r.String()).Wrap(err)
+	}
+
+	if m.Config == nil {
+		platforms := make([]ImagePlatform, 0, len(m.Manifests))
+		for _, manifest := range m.Manifests {
+			if manifest.Platform == nil || manifest.Platform.Architecture == "unknown" {
+				continue
+			}
+			platforms = append(platforms, ImagePlatform{
+				OS:           manifest.Platform.OS,
+				Architecture: manifest.Platform.Architecture,
+				Variant:      manifest.Platform.Variant,
+				Digest:       manifest.Digest,
+			})
+		}
+		return platforms, nil
+	}
+
+	if digest == "" {
+		// the image is pinned by the digest the user asked for, whatever the registry sent
+		if digest = r.Digest; digest == "" {
+			return nil, ErrReadingImagePlatforms.WithParams(r.String()).Wrap(ErrMissingDigest.WithParams(manifestURL))
+		}
+	}
+	var config platform
+	configURL := fmt.Sprintf("%s/v2/%s/blobs/%s", r.baseURL(), r.Repository, m.Config.Digest)
+	if _, err := getJSON(ctx, configURL, &config); err != nil {
+		return nil, ErrReadingImagePlatforms.WithParams(r.String()).Wrap(err)
+	}
+	return []ImagePlatform{{
+		OS:           config.OS,
+		Architecture: config.Architecture,
+		Variant:      config.Variant,
+		Digest:       digest,
+	}}, nil
+}
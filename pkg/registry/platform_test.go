@@ -0,0 +1,39 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImagePlatforms(t *testing.T) {
+	ctx := context.Background()
+	m := newMockRegistry(t, Auth{})
+
+	amd64, arm64 := m.addImage("knuu", "amd64", "amd64"), m.addImage("knuu", "arm64", "arm64")
+	attestation := m.addImage("knuu", "attestation", "unknown")
+	index := fmt.Sprintf(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[`+
+		`{"digest":%q,"platform":{"os":"linux","architecture":"amd64"}},`+
+		`{"digest":%q,"platform":{"os":"linux","architecture":"arm64","variant":"v8"}},`+
+		`{"digest":%q,"platform":{"os":"unknown","architecture":"unknown"}}]}`, amd64, arm64, attestation)
+	m.addManifest("knuu", "multi", "application/vnd.oci.image.index.v1+json", []byte(index))
+
+	platforms, err := ImagePlatforms(ctx, m.host()+"/knuu:multi")
+	require.NoError(t, err)
+	assert.Equal(t, []ImagePlatform{
+		{OS: "linux", Architecture: "amd64", Digest: amd64},
+		{OS: "linux", Architecture: "arm64", Variant: "v8", Digest: arm64},
+	}, platforms, "the attestation manifest should be skipped")
+	assert.Equal(t, "linux/arm64/v8", platforms[1].String())
+
+	platforms, err = ImagePlatforms(ctx, m.host()+"/knuu:arm64")
+	require.NoError(t, err)
+	assert.Equal(t, []ImagePlatform{{Architecture: "arm64", Digest: arm64}}, platforms,
+		"the platform of a single-platform image should be read from its config")
+
+	_, err = ImagePlatforms(ctx, m.host()+"/knuu:missing")
+	assert.ErrorIs(t, err, ErrReadingImagePlatforms)
+}
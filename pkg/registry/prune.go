@@ -0,0 +1,299 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// imageHashRegex matches the sha256 hash in hex of the images built by knuu, which names either their repository,
+// e.g. `ttl.sh/<hash>:24h` as named by BuiltImageName, or their tag, as pushed for the stable images of instances
+var imageHashRegex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// linkNextRegex extracts the URL of the next page from the Link header of the tags list
+var linkNextRegex = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// builtImage is an image built by knuu in a repository
+type builtImage struct {
+	repository string
+	tag        string
+	digest     string
+	created    time.Time
+}
+
+// BuiltImageName returns the name of the image built by knuu with the given hash, `ttl.sh/<hash>:24h`,
+// rewritten to the mirror if one is set, e.g. `registry.local:5000/<hash>:24h` with the mirror `registry.local:5000`
+func BuiltImageName(imageHash string) string {
+	return Rewrite(fmt.Sprintf("ttl.sh/%s:24h", imageHash))
+}
+
+// PruneBuiltImages deletes the images built by knuu from the registry, except for the `keep` most recent ones.
+// The location is the registry the images were pushed to, with an optional path, e.g. the mirror `registry.local:5000`.
+// The images built by knuu are recognized by the hash of the image generated by knuu, which is either the name of
+// their repository right under the location, as named by BuiltImageName, e.g. `registry.local:5000/<hash>:24h`,
+// or their tag in the repository of the location, as pushed for a stable image tag, e.g. `registry.local:5000/app:<hash>`,
+// so other images like `registry.local:5000/app:latest` are never deleted.
+// Deleting an image deletes its manifest, so all the tags pointing to it are deleted as well.
+// The registry must serve its catalog to find the repositories, which the distribution registry does, and allow
+// deleting manifests, e.g. with REGISTRY_STORAGE_DELETE_ENABLED=true for the distribution registry.
+// The deleted blobs are only freed by the garbage collection of the registry.
+// Use BuiltImagesToPrune to list the images that would be deleted without deleting them.
+func PruneBuiltImages(ctx context.Context, location string, keep int) error {
+	r, prune, err := builtImagesToPrune(ctx, location, keep)
+	if err != nil {
+		return err
+	}
+
+	for _, image := range prune {
+		ref := image.reference(r)
+		manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", r.baseURL(), image.repository, image.digest)
+		resp, err := do(ctx, http.MethodDelete, manifestURL)
+		if err != nil {
+			return ErrDeletingImage.WithParams(ref).Wrap(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+			return ErrDeletingImage.WithParams(ref).Wrap(ErrUnexpectedStatus.WithParams(resp.StatusCode, manifestURL))
+		}
+		logrus.Debugf("Pruned image %s", ref)
+	}
+	return nil
+}
+
+// BuiltImagesToPrune returns the references of the images that PruneBuiltImages would delete, oldest first,
+// without deleting them. An image tagged several times is listed with one of its tags.
+func BuiltImagesToPrune(ctx context.Context, location string, keep int) ([]string, error) {
+	r, prune, err := builtImagesToPrune(ctx, location, keep)
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]string, 0, len(prune))
+	for _, image := range prune {
+		refs = append(refs, image.reference(r))
+	}
+	return refs, nil
+}
+
+// reference returns the reference of the image in the registry of r
+func (b builtImage) reference(r *Reference) string {
+	return fmt.Sprintf("%s/%s:%s", r.Registry, b.repository, b.tag)
+}
+
+// builtImagesToPrune returns the images built by knuu in the location, except for the `keep` most recent ones
+func builtImagesToPrune(ctx context.Context, location string, keep int) (*Reference, []builtImage, error) {
+	if keep < 0 {
+		return nil, nil, ErrInvalidKeep.WithParams(keep)
+	}
+	// a location without path is a registry host, e.g. `localhost:5000`, which ParseReference would read as an image
+	r := &Reference{Registry: location}
+	if strings.Contains(location, "/") {
+		var err error
+		r, err = ParseReference(location)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	images, err := builtImages(ctx, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(images) <= keep {
+		return r, nil, nil
+	}
+
+	// the most recent images come last
+	sort.SliceStable(images, func(a, b int) bool {
+		return images[a].created.Before(images[b].created)
+	})
+	return r, images[:len(images)-keep], nil
+}
+
+// builtImages returns the images built by knuu in the repositories named after their hash right under the location,
+// then in the hash tags of the repository of the location, if any
+func builtImages(ctx context.Context, r *Reference) ([]builtImage, error) {
+	repositories, err := listRepositories(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	// tags pointing to the same manifest in a repository are the same image
+	var images []builtImage
+	seen := make(map[string]bool)
+	addImages := func(repository string, isBuilt func(tag string) bool) error {
+		tags, err := listTags(ctx, r, repository)
+		if err != nil {
+			return err
+		}
+		for _, tag := range tags {
+			if !isBuilt(tag) {
+				continue
+			}
+			image, err := inspectImage(ctx, r, repository, tag)
+			if err != nil {
+				return err
+			}
+			if !seen[repository+"@"+image.digest] {
+				images = append(images, image)
+			}
+			seen[repository+"@"+image.digest] = true
+		}
+		return nil
+	}
+
+	for _, repository := range repositories {
+		parent, name := path.Split(repository)
+		if strings.TrimSuffix(parent, "/") != r.Repository || !imageHashRegex.MatchString(name) {
+			continue
+		}
+		// knuu tags these images with their TTL on ttl.sh, so all their tags are built by knuu
+		if err := addImages(repository, func(string) bool { return true }); err != nil {
+			return nil, err
+		}
+	}
+	if r.Repository != "" {
+		if err := addImages(r.Repository, imageHashRegex.MatchString); err != nil {
+			return nil, err
+		}
+	}
+	return images, nil
+}
+
+// listRepositories returns all the repositories of the registry from its catalog
+func listRepositories(ctx context.Context, r *Reference) ([]string, error) {
+	repositories, err := listPages(ctx, r, fmt.Sprintf("%s/v2/_catalog", r.baseURL()))
+	if err != nil {
+		return nil, ErrListingRepositories.WithParams(r.Registry).Wrap(err)
+	}
+	return repositories, nil
+}
+
+// listTags returns all the tags of the repository, none if the repository does not exist
+func listTags(ctx context.Context, r *Reference, repository string) ([]string, error) {
+	tags, err := listPages(ctx, r, fmt.Sprintf("%s/v2/%s/tags/list", r.baseURL(), repository))
+	if errors.Is(err, errNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, ErrListingTags.WithParams(repository).Wrap(err)
+	}
+	return tags, nil
+}
+
+// errNotFound is returned by listPages if the first page is not found
+var errNotFound = errors.New("not found")
+
+// listPages returns the names listed by a paginated endpoint of the registry, i.e. the tags of a repository
+// or the repositories of the catalog, following the pagination of the registry
+func listPages(ctx context.Context, r *Reference, nextURL string) ([]string, error) {
+	var names []string
+	for first := true; nextURL != ""; first = false {
+		resp, err := do(ctx, http.MethodGet, nextURL)
+		if err != nil {
+			return nil, err
+		}
+		if first && resp.StatusCode == http.StatusNotFound {
+			resp.Body.Close()
+			return nil, errNotFound
+		}
+
+		var page struct {
+			Tags         []string `json:"tags"`
+			Repositories []string `json:"repositories"`
+		}
+		if err := decodeResponse(resp, nextURL, &page); err != nil {
+			return nil, err
+		}
+		names = append(names, page.Tags...)
+		names = append(names, page.Repositories...)
+
+		nextURL = ""
+		if match := linkNextRegex.FindStringSubmatch(resp.Header.Get("Link")); match != nil {
+			nextURL = match[1]
+			if strings.HasPrefix(nextURL, "/") {
+				nextURL = r.baseURL() + nextURL
+			}
+		}
+	}
+	return names, nil
+}
+
+// manifest holds the fields of image manifests and indexes needed to find the creation time of an image
+type manifest struct {
+	Config *struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+	Manifests []struct {
+		Digest string `json:"digest"`
+	} `json:"manifests"`
+}
+
+// inspectImage returns the digest and the creation time of the tagged image.
+// The creation time is read from the image config; for multi-platform images, from the first platform.
+func inspectImage(ctx context.Context, r *Reference, repository, tag string) (builtImage, error) {
+	image := builtImage{repository: repository, tag: tag}
+
+	manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", r.baseURL(), repository, tag)
+	resp, err := do(ctx, http.MethodGet, manifestURL)
+	if err != nil {
+		return image, ErrInspectingImage.WithParams(tag, repository).Wrap(err)
+	}
+	image.digest = resp.Header.Get("Docker-Content-Digest")
+	var m manifest
+	if err := decodeResponse(resp, manifestURL, &m); err != nil {
+		return image, ErrInspectingImage.WithParams(tag, repository).Wrap(err)
+	}
+	if image.digest == "" {
+		return image, ErrInspectingImage.WithParams(tag, repository).Wrap(ErrMissingDigest.WithParams(manifestURL))
+	}
+
+	if m.Config == nil && len(m.Manifests) > 0 {
+		manifestURL = fmt.Sprintf("%s/v2/%s/manifests/%s", r.baseURL(), repository, m.Manifests[0].Digest)
+		resp, err := do(ctx, http.MethodGet, manifestURL)
+		if err != nil {
+			return image, ErrInspectingImage.WithParams(tag, repository).Wrap(err)
+		}
+		if err := decodeResponse(resp, manifestURL, &m); err != nil {
+			return image, ErrInspectingImage.WithParams(tag, repository).Wrap(err)
+		}
+	}
+	if m.Config == nil {
+		// without a config the image is considered the oldest, so it is pruned first
+		return image, nil
+	}
+
+	configURL := fmt.Sprintf("%s/v2/%s/blobs/%s", r.baseURL(), repository, m.Config.Digest)
+	resp, err = do(ctx, http.MethodGet, configURL)
+	if err != nil {
+		return image, ErrInspectingImage.WithParams(tag, repository).Wrap(err)
+	}
+	var config struct {
+		Created time.Time `json:"created"`
+	}
+	if err := decodeResponse(resp, configURL, &config); err != nil {
+		return image, ErrInspectingImage.WithParams(tag, repository).Wrap(err)
+	}
+	image.created = config.Created
+	return image, nil
+}
+
+// decodeResponse decodes the JSON body of a successful response and closes it
+func decodeResponse(resp *http.Response, reqURL string, v interface{}) error {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ErrUnexpectedStatus.WithParams(resp.StatusCode, reqURL)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return ErrDecodingResponse.WithParams(reqURL).Wrap(err)
+	}
+	return nil
+}
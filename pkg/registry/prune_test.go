@@ -0,0 +1,209 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockImage is an image served by mockPruneRegistry
+type mockImage struct {
+	digest  string
+	created time.Time
+	index   bool // whether the tag points to a multi-platform index
+}
+
+// mockRepository is a repository served by mockPruneRegistry
+type mockRepository struct {
+	tags   []string // in the order they are listed
+	images map[string]mockImage
+}
+
+// mockPruneRegistry serves the catalog of its repositories and their tags, manifests and configs
+type mockPruneRegistry struct {
+	mu      sync.Mutex
+	repos   map[string]*mockRepository
+	deleted []string // repository@digest of the deleted manifests
+}
+
+// mockPrunePathRegex splits the path of a request to a repository, whose name may contain slashes
+var mockPrunePathRegex = regexp.MustCompile(`^/v2/(.+)/(tags|manifests|blobs)/([^/]+)$`)
+
+func (m *mockPruneRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if r.URL.Path == "/v2/_catalog" {
+		repos := make([]string, 0, len(m.repos))
+		for name := range m.repos {
+			repos = append(repos, name)
+		}
+		sort.Strings(repos)
+		servePage(w, r, "/v2/_catalog", "repositories", repos)
+		return
+	}
+
+	match := mockPrunePathRegex.FindStringSubmatch(r.URL.Path)
+	if match == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	repoName, kind, name := match[1], match[2], match[3]
+	repo, ok := m.repos[repoName]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case kind == "tags" && name == "list":
+		servePage(w, r, "/v2/"+repoName+"/tags/list", "tags", repo.tags)
+	case kind == "manifests" && r.Method == http.MethodDelete:
+		m.deleted = append(m.deleted, repoName+"@"+name)
+		w.WriteHeader(http.StatusAccepted)
+	case kind == "manifests":
+		image, ok := repo.images[name]
+		if !ok {
+			// platform manifest of an index, named after the digest of the index
+			image, ok = repo.images[strings.TrimSuffix(name, "-amd64")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			fmt.Fprintf(w, `{"config":{"digest":"%s-config"}}`, image.digest)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", image.digest)
+		if image.index {
+			fmt.Fprintf(w, `{"manifests":[{"digest":"%s-amd64"}]}`, name)
+			return
+		}
+		fmt.Fprintf(w, `{"config":{"digest":"%s-config"}}`, image.digest)
+	case kind == "blobs":
+		for _, image := range repo.images {
+			if name == image.digest+"-config" {
+				fmt.Fprintf(w, `{"created":%q}`, image.created.Format(time.RFC3339))
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// servePage serves the names in pages of 2 to exercise the pagination
+func servePage(w http.ResponseWriter, r *http.Request, listPath, key string, names []string) {
+	start := 0
+	if last := r.URL.Query().Get("last"); last != "" {
+		for n, name := range names {
+			if name == last {
+				start = n + 1
+			}
+		}
+	}
+	end := min(start+2, len(names))
+	if end < len(names) {
+		w.Header().Set("Link", fmt.Sprintf(`<%s?n=2&last=%s>; rel="next"`, listPath, names[end-1]))
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{key: names[start:end]})
+}
+
+func TestPruneBuiltImages(t *testing.T) {
+	hashTag := func(c string) string { return strings.Repeat(c, 64) }
+	now := time.Now().UTC().Truncate(time.Second)
+
+	reg := &mockPruneRegistry{repos: map[string]*mockRepository{
+		"knuu": {
+			tags: []string{"latest", hashTag("c"), hashTag("a"), hashTag("d"), hashTag("b"), hashTag("e")},
+			images: map[string]mockImage{
+				"latest":     {digest: "sha256:latest", created: now.Add(-10 * time.Hour)},
+				hashTag("a"): {digest: "sha256:a", created: now.Add(-4 * time.Hour)},
+				hashTag("b"): {digest: "sha256:b", created: now.Add(-3 * time.Hour), index: true},
+				hashTag("c"): {digest: "sha256:c", created: now.Add(-2 * time.Hour)},
+				hashTag("d"): {digest: "sha256:d", created: now.Add(-1 * time.Hour)},
+				// same manifest as the most recent image, so it counts as a single image
+				hashTag("e"): {digest: "sha256:d", created: now.Add(-1 * time.Hour)},
+			},
+		},
+	}}
+	server := httptest.NewServer(reg)
+	defer server.Close()
+	repo := strings.TrimPrefix(server.URL, "http://") + "/knuu"
+	ctx := context.Background()
+
+	prune, err := BuiltImagesToPrune(ctx, repo, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{repo + ":" + hashTag("a"), repo + ":" + hashTag("b")}, prune, "the oldest images should be pruned")
+	assert.Empty(t, reg.deleted, "listing the images to prune must not delete them")
+
+	require.NoError(t, PruneBuiltImages(ctx, repo, 2))
+	assert.Equal(t, []string{"knuu@sha256:a", "knuu@sha256:b"}, reg.deleted)
+
+	prune, err = BuiltImagesToPrune(ctx, repo, 10)
+	require.NoError(t, err)
+	assert.Empty(t, prune)
+
+	_, err = BuiltImagesToPrune(ctx, repo, -1)
+	assert.ErrorIs(t, err, ErrInvalidKeep)
+
+	// a repository without images is not an error
+	prune, err = BuiltImagesToPrune(ctx, strings.TrimPrefix(server.URL, "http://")+"/missing", 0)
+	require.NoError(t, err)
+	assert.Empty(t, prune)
+}
+
+func TestPruneBuiltImagesNamedByHash(t *testing.T) {
+	hash := func(c string) string { return strings.Repeat(c, 64) }
+	now := time.Now().UTC().Truncate(time.Second)
+
+	reg := &mockPruneRegistry{repos: map[string]*mockRepository{}}
+	server := httptest.NewServer(reg)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	// the images are pushed to the mirror with the names knuu gives them
+	t.Cleanup(func() { require.NoError(t, SetMirror("")) })
+	require.NoError(t, SetMirror(host))
+	push := func(imageHash string, created time.Time) string {
+		ref, err := ParseReference(BuiltImageName(imageHash))
+		require.NoError(t, err)
+		require.Equal(t, host, ref.Registry)
+		reg.repos[ref.Repository] = &mockRepository{
+			tags:   []string{ref.Tag},
+			images: map[string]mockImage{ref.Tag: {digest: "sha256:" + imageHash, created: created}},
+		}
+		return ref.String()
+	}
+	oldest := push(hash("a"), now.Add(-3*time.Hour))
+	older := push(hash("b"), now.Add(-2*time.Hour))
+	push(hash("c"), now.Add(-1*time.Hour))
+	// other repositories are never pruned
+	reg.repos["app"] = &mockRepository{
+		tags:   []string{"latest"},
+		images: map[string]mockImage{"latest": {digest: "sha256:app", created: now.Add(-10 * time.Hour)}},
+	}
+	reg.repos["app/"+hash("d")] = &mockRepository{
+		tags:   []string{"24h"},
+		images: map[string]mockImage{"24h": {digest: "sha256:nested", created: now.Add(-10 * time.Hour)}},
+	}
+	ctx := context.Background()
+
+	prune, err := BuiltImagesToPrune(ctx, host, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{oldest, older}, prune, "the oldest images should be pruned")
+
+	require.NoError(t, PruneBuiltImages(ctx, host, 1))
+	assert.Equal(t, []string{hash("a") + "@sha256:" + hash("a"), hash("b") + "@sha256:" + hash("b")}, reg.deleted)
+}
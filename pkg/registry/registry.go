@@ -0,0 +1,329 @@
+// Package registry provides a minimal client for the OCI distribution API,
+// used to query container registries without pulling images.
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	defaultRegistry    = "docker.io"
+	defaultRegistryAPI = "registry-1.docker.io"
+	defaultTag         = "latest"
+	officialRepoPrefix = "library/"
+)
+
+// manifestMediaTypes are the manifest types accepted when querying a registry
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Reference represents a parsed image reference
+type Reference struct {
+	Registry   string // Registry host, e.g. ttl.sh or localhost:5000
+	Repository string // Repository path inside the registry
+	Tag        string // Tag of the image, empty if Digest is set
+	Digest     string // Digest of the image, e.g. sha256:...
+}
+
+// ParseReference parses an image reference like `ttl.sh/foo:24h` or `nginx`
+func ParseReference(ref string) (*Reference, error) {
+	if ref == "" {
+		return nil, ErrInvalidReference.WithParams(ref)
+	}
+
+	r := &Reference{}
+	name := ref
+	if i := strings.Index(name, "@"); i >= 0 {
+		r.Digest = name[i+1:]
+		name = name[:i]
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		r.Tag = name[i+1:]
+		name = name[:i]
+	}
+
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		r.Registry = parts[0]
+		r.Repository = parts[1]
+	} else {
+		r.Registry = defaultRegistry
+		r.Repository = name
+	}
+	if r.Registry == defaultRegistry && !strings.Contains(r.Repository, "/") {
+		r.Repository = officialRepoPrefix + r.Repository
+	}
+
+	if r.Repository == "" {
+		return nil, ErrInvalidReference.WithParams(ref)
+	}
+	if r.Tag == "" && r.Digest == "" {
+		r.Tag = defaultTag
+	}
+	return r, nil
+}
+
+// Identifier returns the digest if set, otherwise the tag
+func (r *Reference) Identifier() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+// String returns the full reference
+func (r *Reference) String() string {
+	if r.Digest != "" {
+		return fmt.Sprintf("%s/%s@%s", r.Registry, r.Repository, r.Digest)
+	}
+	return fmt.Sprintf("%s/%s:%s", r.Registry, r.Repository, r.Tag)
+}
+
+// baseURL returns the base URL of the registry API.
+// Like docker, loopback registries are contacted over plain HTTP.
+func (r *Reference) baseURL() string {
+	host := r.Registry
+	if host == defaultRegistry {
+		host = defaultRegistryAPI
+	}
+	scheme := "https"
+	if isLoopback(r.Registry) {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s", scheme, host)
+}
+
+func isLoopback(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// ImageExists checks if the given image reference exists in its registry
+// by sending a HEAD request for its manifest.
+// A missing image is reported as (false, nil); auth and network problems are returned as errors.
+func ImageExists(ctx context.Context, ref string) (bool, error) {
+	r, err := ParseReference(ref)
+	if err != nil {
+		return false, err
+	}
+
+	manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", r.baseURL(), r.Repository, r.Identifier())
+	resp, err := do(ctx, http.MethodHead, manifestURL)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return false, ErrUnauthorized.WithParams(r.String())
+	default:
+		return false, ErrUnexpectedStatus.WithParams(resp.StatusCode, r.String())
+	}
+}
+
+// Auth holds the credentials used to access a registry, empty for anonymous access
+type Auth struct {
+	Username string
+	Password string
+}
+
+// session sends requests to a registry with the given credentials, keeping the authorization
+// obtained from the first challenge for the next requests, e.g. to stream a blob after starting its upload
+type session struct {
+	auth          Auth
+	authorization string
+}
+
+// do sends a request to the registry, handling the anonymous token flow
+// if the registry responds with a Bearer challenge.
+func do(ctx context.Context, method, reqURL string) (*http.Response, error) {
+	return (&session{}).do(ctx, method, reqURL, nil, "")
+}
+
+// do sends a request to the registry, authenticating with the credentials of the session
+// if the registry responds with a Basic or Bearer challenge.
+// The body must be nil or a *bytes.Reader to be sent again after a challenge.
+func (s *session) do(ctx context.Context, method, reqURL string, body io.Reader, contentType string) (*http.Response, error) {
+	resp, err := send(ctx, method, reqURL, body, contentType, s.authorization)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+	if challenge == "" {
+		return nil, ErrUnauthorized.WithParams(reqURL)
+	}
+
+	authorization, err := s.authorize(ctx, challenge)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		reader, ok := body.(*bytes.Reader)
+		if !ok {
+			return nil, ErrUnauthorized.WithParams(reqURL)
+		}
+		if _, err := reader.Seek(0, io.SeekStart); err != nil {
+			return nil, ErrCreatingRequest.WithParams(reqURL).Wrap(err)
+		}
+	}
+	s.authorization = authorization
+	return send(ctx, method, reqURL, body, contentType, authorization)
+}
+
+// authorize returns the Authorization header answering the challenge with the credentials of the session
+func (s *session) authorize(ctx context.Context, challenge string) (string, error) {
+	scheme, params, found := strings.Cut(challenge, " ")
+	if !found {
+		return "", ErrParsingAuthChallenge.WithParams(challenge)
+	}
+	switch {
+	case strings.EqualFold(scheme, "Bearer"):
+		token, err := fetchToken(ctx, challenge, params, s.auth)
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	case strings.EqualFold(scheme, "Basic") && s.auth.Username != "":
+		return s.auth.basic(), nil
+	default:
+		return "", ErrUnsupportedAuthScheme.WithParams(scheme)
+	}
+}
+
+// basic returns the Authorization header of the credentials for the Basic scheme
+func (a Auth) basic() string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(a.Username+":"+a.Password))
+}
+
+func send(ctx context.Context, method, reqURL string, body io.Reader, contentType, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, body)
+	if err != nil {
+		return nil, ErrCreatingRequest.WithParams(reqURL).Wrap(err)
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, ErrSendingRequest.WithParams(reqURL).Wrap(err)
+	}
+	return resp, nil
+}
+
+// fetchToken requests a token as described by the Bearer challenge with the given params,
+// authenticated with the credentials if they are set
+// ref: https://distribution.github.io/distribution/spec/auth/token/
+func fetchToken(ctx context.Context, challenge, params string, auth Auth) (string, error) {
+	values := url.Values{}
+	realm := ""
+	for _, param := range strings.Split(params, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+		if !found {
+			continue
+		}
+		value = strings.Trim(value, `"`)
+		if key == "realm" {
+			realm = value
+			continue
+		}
+		values.Set(key, value)
+	}
+	if realm == "" {
+		return "", ErrParsingAuthChallenge.WithParams(challenge)
+	}
+
+	tokenURL := realm + "?" + values.Encode()
+	authorization := ""
+	if auth.Username != "" {
+		authorization = auth.basic()
+	}
+	resp, err := send(ctx, http.MethodGet, tokenURL, nil, "", authorization)
+	if err != nil {
+		return "", ErrFetchingToken.WithParams(realm).Wrap(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", ErrFetchingToken.WithParams(realm).Wrap(ErrUnexpectedStatus.WithParams(resp.StatusCode, realm))
+	}
+
+	var tokenResp struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return "", ErrDecodingTokenResponse.Wrap(err)
+	}
+	if tokenResp.Token != "" {
+		return tokenResp.Token, nil
+	}
+	if tokenResp.AccessToken != "" {
+		return tokenResp.AccessToken, nil
+	}
+	return "", ErrEmptyToken
+}
+
+// ImageDigest returns the digest of the manifest the image reference points to in its registry,
+// e.g. to check whether two tags point to the same image, without pulling it.
+// A missing image is reported as ("", nil); auth and network problems are returned as errors.
+func ImageDigest(ctx context.Context, ref string) (string, error) {
+	r, err := ParseReference(ref)
+	if err != nil {
+		return "", err
+	}
+
+	manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", r.baseURL(), r.Repository, r.Identifier())
+	resp, err := do(ctx, http.MethodHead, manifestURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		digest := resp.Header.Get("Docker-Content-Digest")
+		if digest == "" {
+			return "", ErrMissingDigest.WithParams(manifestURL)
+		}
+		return digest, nil
+	case http.StatusNotFound:
+		return "", nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return "", ErrUnauthorized.WithParams(r.String())
+	default:
+		return "", ErrUnexpectedStatus.WithParams(resp.StatusCode, r.String())
+	}
+}
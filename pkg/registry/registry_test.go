@@ -0,0 +1,107 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		ref      string
+		expected Reference
+	}{
+		{ref: "nginx", expected: Reference{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}},
+		{ref: "ttl.sh/foo:24h", expected: Reference{Registry: "ttl.sh", Repository: "foo", Tag: "24h"}},
+		{ref: "localhost:5000/a/b", expected: Reference{Registry: "localhost:5000", Repository: "a/b", Tag: "latest"}},
+		{ref: "alpine@sha256:abc", expected: Reference{Registry: "docker.io", Repository: "library/alpine", Digest: "sha256:abc"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			r, err := ParseReference(tt.ref)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, *r)
+		})
+	}
+
+	_, err := ParseReference("")
+	assert.ErrorIs(t, err, ErrInvalidReference)
+}
+
+func TestImageExists(t *testing.T) {
+	const token = "secret"
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			fmt.Fprintf(w, `{"token":%q}`, token)
+		case strings.HasPrefix(r.URL.Path, "/v2/private/"):
+			if r.Header.Get("Authorization") != "Bearer "+token {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, server.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/v2/forbidden/manifests/latest":
+			w.WriteHeader(http.StatusForbidden)
+		case r.URL.Path == "/v2/exists/manifests/latest":
+			assert.Equal(t, http.MethodHead, r.Method)
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	ctx := context.Background()
+
+	exists, err := ImageExists(ctx, host+"/exists:latest")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = ImageExists(ctx, host+"/missing:latest")
+	require.NoError(t, err, "a missing image is not an error")
+	assert.False(t, exists)
+
+	exists, err = ImageExists(ctx, host+"/private/image:latest")
+	require.NoError(t, err, "the anonymous token should be used")
+	assert.True(t, exists)
+
+	_, err = ImageExists(ctx, host+"/forbidden:latest")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
+
+func TestImageExistsUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	host := strings.TrimPrefix(server.URL, "http://")
+	server.Close()
+
+	exists, err := ImageExists(context.Background(), host+"/image:latest")
+	assert.ErrorIs(t, err, ErrSendingRequest)
+	assert.False(t, exists)
+}
+
+func TestImageDigest(t *testing.T) {
+	m := newMockRegistry(t, Auth{})
+	digest := m.addImage("app", "v1", "amd64")
+	m.addManifest("app", "stable", "application/vnd.oci.image.manifest.v1+json", m.manifests["app:v1"])
+	ctx := context.Background()
+
+	got, err := ImageDigest(ctx, m.host()+"/app:v1")
+	require.NoError(t, err)
+	assert.Equal(t, digest, got)
+
+	got, err = ImageDigest(ctx, m.host()+"/app:stable")
+	require.NoError(t, err)
+	assert.Equal(t, digest, got, "tags of the same manifest have the same digest")
+
+	got, err = ImageDigest(ctx, m.host()+"/app:missing")
+	require.NoError(t, err, "a missing image is not an error")
+	assert.Empty(t, got)
+}
@@ -0,0 +1,174 @@
+package registry
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// cosignSignatureMediaType is the media type of the layers holding the payloads signed by cosign
+	cosignSignatureMediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+	// cosignSignatureAnnotation is the annotation of the layers holding the base64 encoded signature of their payload
+	cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+	// cosignSignatureType is the type of the payloads signed by cosign for container images
+	cosignSignatureType = "cosign container image signature"
+)
+
+// signatureManifest holds the fields of the manifests of cosign signatures needed to verify them
+type signatureManifest struct {
+	Layers []struct {
+		MediaType   string            `json:"mediaType"`
+		Digest      string            `json:"digest"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"layers"`
+}
+
+// signaturePayload is the simple signing payload signed by cosign
+// ref: https://github.com/containers/image/blob/main/docs/containers-signature.5.md
+type signaturePayload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
+
+// ParsePublicKey parses a PEM encoded public key, e.g. the cosign.pub file generated by `cosign generate-key-pair`.
+// ECDSA, RSA and ed25519 keys are supported.
+func ParsePublicKey(pemKey []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(pemKey)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, ErrParsingPublicKey.Wrap(ErrNoPublicKeyBlock)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, ErrParsingPublicKey.Wrap(err)
+	}
+	switch key.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	default:
+		return nil, ErrUnsupportedPublicKey.WithParams(fmt.Sprintf("%T", key))
+	}
+}
+
+// VerifySignature verifies that the image was signed by cosign with the private key of the given public key,
+// see ParsePublicKey, and returns the digest of its manifest, which pins the verified image.
+// The signatures are read from the `sha256-<digest>.sig` tag of the repository of the image, where cosign stores them.
+// For multi-platform images, the signature of the index is verified, as cosign signs the digest the tag points to.
+// Keyless signatures, verified with certificates and transparency logs, are not supported.
+func VerifySignature(ctx context.Context, ref string, key crypto.PublicKey) (string, error) {
+	r, err := ParseReference(ref)
+	if err != nil {
+		return "", err
+	}
+
+	manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", r.baseURL(), r.Repository, r.Identifier())
+	var manifest json.RawMessage
+	digest, err := getJSON(ctx, manifestURL, &manifest)
+	if err != nil {
+		return "", ErrVerifyingSignature.WithParams(r.String()).Wrap(err)
+	}
+	if digest == "" {
+		return "", ErrVerifyingSignature.WithParams(r.String()).Wrap(ErrMissingDigest.WithParams(manifestURL))
+	}
+	if r.Digest != "" && r.Digest != digest {
+		return "", ErrVerifyingSignature.WithParams(r.String()).Wrap(ErrDigestMismatch.WithParams(digest, manifestURL, r.Digest))
+	}
+
+	sigURL := fmt.Sprintf("%s/v2/%s/manifests/%s.sig", r.baseURL(), r.Repository, strings.Replace(digest, ":", "-", 1))
+	resp, err := do(ctx, http.MethodGet, sigURL)
+	if err != nil {
+		return "", ErrVerifyingSignature.WithParams(r.String()).Wrap(err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return "", ErrImageNotSigned.WithParams(r.String())
+	}
+	var sigManifest signatureManifest
+	if err := decodeResponse(resp, sigURL, &sigManifest); err != nil {
+		return "", ErrVerifyingSignature.WithParams(r.String()).Wrap(err)
+	}
+
+	for _, layer := range sigManifest.Layers {
+		signature, ok := layer.Annotations[cosignSignatureAnnotation]
+		if layer.MediaType != cosignSignatureMediaType || !ok {
+			continue
+		}
+		payload, err := getBlob(ctx, fmt.Sprintf("%s/v2/%s/blobs/%s", r.baseURL(), r.Repository, layer.Digest))
+		if err != nil {
+			return "", ErrVerifyingSignature.WithParams(r.String()).Wrap(err)
+		}
+		if verifyPayload(key, payload, signature, digest) {
+			return digest, nil
+		}
+	}
+	return "", ErrInvalidSignature.WithParams(r.String())
+}
+
+// verifyPayload reports whether the signature is a valid signature of the payload by the key,
+// and whether the payload is the signature of the manifest with the digest
+func verifyPayload(key crypto.PublicKey, payload []byte, encodedSignature, digest string) bool {
+	signature, err := base64.StdEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return false
+	}
+	hash := sha256.Sum256(payload)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, hash[:], signature) {
+			return false
+		}
+	case *rsa.PublicKey:
+		if rsa.VerifyPKCS1v15(k, crypto.SHA256, hash[:], signature) != nil {
+			return false
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, payload, signature) {
+			return false
+		}
+	default:
+		return false
+	}
+
+	// the signature is only valid for the image whose digest it carries, so it cannot be copied to another image
+	var p signaturePayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return false
+	}
+	return p.Critical.Type == cosignSignatureType && p.Critical.Image.DockerManifestDigest == digest
+}
+
+// getBlob fetches the blob from the registry and checks its content against its digest
+func getBlob(ctx context.Context, blobURL string) ([]byte, error) {
+	resp, err := do(ctx, http.MethodGet, blobURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, ErrUnexpectedStatus.WithParams(resp.StatusCode, blobURL)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, ErrDecodingResponse.WithParams(blobURL).Wrap(err)
+	}
+	sum := sha256.Sum256(data)
+	if digest := fmt.Sprintf("sha256:%x", sum); !strings.HasSuffix(blobURL, "/"+digest) {
+		return nil, ErrDigestMismatch.WithParams(digest, blobURL, blobURL[strings.LastIndex(blobURL, "/")+1:])
+	}
+	return data, nil
+}
@@ -0,0 +1,114 @@
+package registry
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// encodePublicKey returns the key PEM encoded like the cosign.pub files
+func encodePublicKey(t *testing.T, key crypto.PublicKey) []byte {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+// signImage stores a cosign signature of the manifest digest, made with the sign function, like `cosign sign --key`
+func (m *mockRegistry) signImage(repo, digest string, sign func(payload []byte) []byte) {
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":%q},"image":{"docker-manifest-digest":%q},`+
+		`"type":"cosign container image signature"},"optional":null}`, m.host()+"/"+repo, digest))
+	layer := m.addBlob(repo, payload)
+	config := m.addBlob(repo, []byte(`{}`))
+	manifest := fmt.Sprintf(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json",`+
+		`"config":{"mediaType":"application/vnd.oci.image.config.v1+json","digest":%q},`+
+		`"layers":[{"mediaType":"application/vnd.dev.cosign.simplesigning.v1+json","digest":%q,`+
+		`"annotations":{"dev.cosignproject.cosign/signature":%q}}]}`,
+		config, layer, base64.StdEncoding.EncodeToString(sign(payload)))
+	m.addManifest(repo, strings.Replace(digest, ":", "-", 1)+".sig", "application/vnd.oci.image.manifest.v1+json", []byte(manifest))
+}
+
+func ecdsaSigner(t *testing.T, key *ecdsa.PrivateKey) func([]byte) []byte {
+	return func(payload []byte) []byte {
+		hash := sha256.Sum256(payload)
+		signature, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+		require.NoError(t, err)
+		return signature
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	ctx := context.Background()
+	m := newMockRegistry(t, Auth{})
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	publicKey, err := ParsePublicKey(encodePublicKey(t, &key.PublicKey))
+	require.NoError(t, err)
+
+	signed := m.addImage("app", "signed", "amd64")
+	m.signImage("app", signed, ecdsaSigner(t, key))
+	m.addImage("app", "unsigned", "arm64")
+
+	t.Run("signed", func(t *testing.T) {
+		digest, err := VerifySignature(ctx, m.host()+"/app:signed", publicKey)
+		require.NoError(t, err)
+		assert.Equal(t, signed, digest)
+
+		digest, err = VerifySignature(ctx, m.host()+"/app@"+signed, publicKey)
+		require.NoError(t, err)
+		assert.Equal(t, signed, digest)
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		_, err := VerifySignature(ctx, m.host()+"/app:unsigned", publicKey)
+		assert.ErrorIs(t, err, ErrImageNotSigned)
+	})
+
+	t.Run("other key", func(t *testing.T) {
+		other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		_, err = VerifySignature(ctx, m.host()+"/app:signed", &other.PublicKey)
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("signature of another image", func(t *testing.T) {
+		// a valid signature copied to the signature tag of another image must not verify it
+		copied := m.addImage("app", "copied", "riscv64")
+		sig := m.manifests["app:"+strings.Replace(signed, ":", "-", 1)+".sig"]
+		m.addManifest("app", strings.Replace(copied, ":", "-", 1)+".sig", "application/vnd.oci.image.manifest.v1+json", sig)
+
+		_, err := VerifySignature(ctx, m.host()+"/app:copied", publicKey)
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("ed25519", func(t *testing.T) {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		digest := m.addImage("app", "ed25519", "s390x")
+		m.signImage("app", digest, func(payload []byte) []byte { return ed25519.Sign(priv, payload) })
+
+		_, err = VerifySignature(ctx, m.host()+"/app:ed25519", pub)
+		require.NoError(t, err)
+	})
+}
+
+func TestParsePublicKey(t *testing.T) {
+	_, err := ParsePublicKey([]byte("not a key"))
+	assert.ErrorIs(t, err, ErrParsingPublicKey)
+
+	_, err = ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("garbage")}))
+	assert.ErrorIs(t, err, ErrParsingPublicKey)
+}